@@ -0,0 +1,278 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"storj.io/drpc"
+)
+
+const (
+	// maxSamples bounds the latencies kept for percentiles, which are
+	// sampled uniformly from every rpc once there are more.
+	maxSamples = 100000
+
+	// maxErrors bounds the distinct error messages counted. Later distinct
+	// messages are counted as "other".
+	maxErrors = 64
+)
+
+// addrPattern matches the host and port of an address in an error message.
+var addrPattern = regexp.MustCompile(`\[?[0-9a-fA-F.:]+\]?:[0-9]+`)
+
+// generate starts rpcs at conf.qps until the context is done, reporting
+// progress every conf.report, and returns once every rpc has finished.
+func generate(ctx context.Context, conf config, conn drpc.Conn, srv *server, st *stats, w io.Writer) {
+	rng := rand.New(rand.NewSource(conf.seed))
+	payload := make([]byte, conf.size)
+	_, _ = rng.Read(payload)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / conf.qps))
+	defer ticker.Stop()
+
+	var report <-chan time.Time
+	if conf.report > 0 {
+		reporter := time.NewTicker(conf.report)
+		defer reporter.Stop()
+		report = reporter.C
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	sem := make(chan struct{}, conf.concurrency)
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-report:
+			st.progress(w, time.Since(start), srv, len(sem))
+			continue
+		case <-ticker.C:
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			st.skipped.Add(1)
+			continue
+		}
+
+		stream := rng.Float64() < conf.streamRatio
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// rpcs are not canceled by the end of the load so that they are
+			// not counted as failures.
+			ctx, cancel := context.WithTimeout(context.Background(), conf.rpcTimeout)
+			defer cancel()
+
+			start := time.Now()
+			if stream {
+				err := echoStream(ctx, conn, payload, conf.streamMsgs)
+				st.record(&st.stream, time.Since(start), err)
+			} else {
+				err := echoUnary(ctx, conn, payload)
+				st.record(&st.unary, time.Since(start), err)
+			}
+		}()
+	}
+}
+
+// echoUnary issues one unary rpc and checks its response.
+func echoUnary(ctx context.Context, conn drpc.Conn, payload []byte) error {
+	in, out := payload, []byte(nil)
+	if err := conn.Invoke(ctx, rpcUnary, encoding{}, &in, &out); err != nil {
+		return err
+	}
+	if !bytes.Equal(out, payload) {
+		return errors.New("unary response does not match the request")
+	}
+	return nil
+}
+
+// echoStream sends msgs messages on a stream, checking that each is echoed,
+// and then checks that the stream ends cleanly.
+func echoStream(ctx context.Context, conn drpc.Conn, payload []byte, msgs int) (err error) {
+	stream, err := conn.NewStream(ctx, rpcStream, encoding{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = stream.Close()
+		}
+	}()
+
+	for i := 0; i < msgs; i++ {
+		in, out := payload, []byte(nil)
+		if err := stream.MsgSend(&in, encoding{}); err != nil {
+			return err
+		}
+		if err := stream.MsgRecv(&out, encoding{}); err != nil {
+			return err
+		}
+		if !bytes.Equal(out, payload) {
+			return errors.New("stream response does not match the request")
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	var out []byte
+	if err := stream.MsgRecv(&out, encoding{}); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("stream did not end cleanly: %w", err)
+	}
+	return nil
+}
+
+// kindStats counts the outcomes of one kind of rpc.
+type kindStats struct {
+	ok     atomic.Int64
+	failed atomic.Int64
+}
+
+// stats records the outcomes and latencies of the rpcs.
+type stats struct {
+	unary   kindStats
+	stream  kindStats
+	skipped atomic.Int64
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	seen    int64
+	samples []time.Duration
+	errors  map[string]int64
+}
+
+func newStats(seed int64) *stats {
+	return &stats{
+		rng:    rand.New(rand.NewSource(seed)),
+		errors: make(map[string]int64),
+	}
+}
+
+// record records the outcome of an rpc of the kind that took d.
+func (s *stats) record(kind *kindStats, d time.Duration, err error) {
+	if err != nil {
+		kind.failed.Add(1)
+	} else {
+		kind.ok.Add(1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen++
+	if len(s.samples) < maxSamples {
+		s.samples = append(s.samples, d)
+	} else if i := s.rng.Int63n(s.seen); i < maxSamples {
+		s.samples[i] = d
+	}
+
+	if err != nil {
+		// addresses differ between conns, so drop them to group the errors.
+		msg := addrPattern.ReplaceAllString(err.Error(), "<addr>")
+		if _, ok := s.errors[msg]; !ok && len(s.errors) >= maxErrors {
+			msg = "other"
+		}
+		s.errors[msg]++
+	}
+}
+
+// totals returns the number of rpcs that finished and that failed.
+func (s *stats) totals() (total, failed int64) {
+	failed = s.unary.failed.Load() + s.stream.failed.Load()
+	total = s.unary.ok.Load() + s.stream.ok.Load() + failed
+	return total, failed
+}
+
+// progress writes a line summarizing the run so far.
+func (s *stats) progress(w io.Writer, elapsed time.Duration, srv *server, inflight int) {
+	total, failed := s.totals()
+	kills, _ := srv.Kills()
+	fmt.Fprintf(w, "%v: %d rpcs, %d failed, %d skipped, %d in flight, %d kills\n",
+		elapsed.Truncate(time.Second), total, failed, s.skipped.Load(), inflight, kills)
+}
+
+// leaks counts what was left behind once the run was torn down.
+type leaks struct {
+	goroutines int
+	conns      int
+	handlers   int
+}
+
+// summary writes the final report and returns true if the errors were within
+// the budget and nothing leaked.
+func (s *stats) summary(w io.Writer, conf config, elapsed time.Duration, srv *server, l leaks) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total, failed := s.totals()
+	rate := 0.0
+	if total > 0 {
+		rate = float64(failed) / float64(total)
+	}
+	withinBudget := rate <= conf.errorBudget
+	leaked := l.goroutines > 0 || l.conns > 0 || l.handlers > 0
+
+	fmt.Fprintf(w, "\nran %v at %.1f qps (target %.1f), seed %d\n",
+		elapsed.Truncate(time.Millisecond), float64(total)/elapsed.Seconds(), conf.qps, conf.seed)
+	fmt.Fprintf(w, "unary:   %d ok, %d failed\n", s.unary.ok.Load(), s.unary.failed.Load())
+	fmt.Fprintf(w, "stream:  %d ok, %d failed\n", s.stream.ok.Load(), s.stream.failed.Load())
+	fmt.Fprintf(w, "skipped: %d at the concurrency limit of %d\n", s.skipped.Load(), conf.concurrency)
+
+	if len(s.samples) > 0 {
+		sorted := append([]time.Duration(nil), s.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		at := func(q float64) time.Duration { return sorted[int(q*float64(len(sorted)-1))] }
+		fmt.Fprintf(w, "latency: p50 %v, p90 %v, p99 %v, max %v\n",
+			at(0.5), at(0.9), at(0.99), sorted[len(sorted)-1])
+	}
+
+	kills, killed := srv.Kills()
+	fmt.Fprintf(w, "kills:   %d, closing %d conns\n", kills, killed)
+
+	fmt.Fprintf(w, "errors:  %d of %d (%.3f%%), budget %.3f%%: %s\n",
+		failed, total, 100*rate, 100*conf.errorBudget, verdict(withinBudget))
+	msgs := make([]string, 0, len(s.errors))
+	for msg := range s.errors {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if s.errors[msgs[i]] != s.errors[msgs[j]] {
+			return s.errors[msgs[i]] > s.errors[msgs[j]]
+		}
+		return msgs[i] < msgs[j]
+	})
+	for _, msg := range msgs {
+		fmt.Fprintf(w, "  %8d %s\n", s.errors[msg], msg)
+	}
+
+	fmt.Fprintf(w, "leaks:   %d goroutines, %d server conns, %d handlers: %s\n",
+		l.goroutines, l.conns, l.handlers, verdict(!leaked))
+
+	return withinBudget && !leaked
+}
+
+func verdict(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "FAIL"
+}
@@ -0,0 +1,190 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// drpcstress drives a configurable rate of mixed unary and stream rpcs
+// through a ClientConn against a bundled echo server while killing its
+// connections and injecting latency, and reports the errors against a budget
+// along with any leaked goroutines, conns and handlers. It is for validating
+// combinations of resiliency options before rolling them out.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcpool"
+)
+
+type config struct {
+	duration    time.Duration
+	report      time.Duration
+	qps         float64
+	concurrency int
+	streamRatio float64
+	streamMsgs  int
+	size        int
+	rpcTimeout  time.Duration
+	errorBudget float64
+	seed        int64
+
+	killEvery   time.Duration
+	latency     time.Duration
+	latencyTail time.Duration
+
+	serviceConfig    string
+	unaryTimeout     time.Duration
+	idleTimeout      time.Duration
+	keepalive        time.Duration
+	keepaliveTimeout time.Duration
+	poolCapacity     int
+}
+
+func main() {
+	var conf config
+	flag.DurationVar(&conf.duration, "duration", 30*time.Second, "how long to send rpcs for")
+	flag.DurationVar(&conf.report, "report", 5*time.Second, "interval between progress reports, or 0 for none")
+	flag.Float64Var(&conf.qps, "qps", 100, "rpcs started per second")
+	flag.IntVar(&conf.concurrency, "concurrency", 256, "maximum rpcs in flight; rpcs beyond it are skipped")
+	flag.Float64Var(&conf.streamRatio, "stream-ratio", 0.2, "fraction of rpcs that are streams")
+	flag.IntVar(&conf.streamMsgs, "stream-msgs", 10, "messages echoed on each stream")
+	flag.IntVar(&conf.size, "size", 1024, "bytes in each message")
+	flag.DurationVar(&conf.rpcTimeout, "rpc-timeout", 5*time.Second, "timeout of each rpc")
+	flag.Float64Var(&conf.errorBudget, "error-budget", 0.01, "fraction of rpcs allowed to fail")
+	flag.Int64Var(&conf.seed, "seed", time.Now().UnixNano(), "seed of the random choices")
+
+	flag.DurationVar(&conf.killEvery, "kill-every", 0, "interval between killing every server conn, or 0 for never")
+	flag.DurationVar(&conf.latency, "latency", 0, "latency injected before the server handles each rpc")
+	flag.DurationVar(&conf.latencyTail, "latency-tail", 0, "mean of an exponential delay added to the latency")
+
+	flag.StringVar(&conf.serviceConfig, "service-config", "", "path to a JSON service config with timeouts and retry policies")
+	flag.DurationVar(&conf.unaryTimeout, "unary-timeout", 0, "drpcclient.WithUnaryTimeout")
+	flag.DurationVar(&conf.idleTimeout, "idle-timeout", 0, "drpcclient.WithIdleTimeout")
+	flag.DurationVar(&conf.keepalive, "keepalive", 0, "drpcclient.WithStreamKeepalive interval")
+	flag.DurationVar(&conf.keepaliveTimeout, "keepalive-timeout", 0, "drpcclient.WithStreamKeepalive timeout")
+	flag.IntVar(&conf.poolCapacity, "pool-capacity", 0, "conns cached by the pool, or 0 for unlimited")
+	flag.Parse()
+
+	if conf.qps <= 0 || conf.concurrency <= 0 || conf.streamMsgs <= 0 || conf.size < 0 {
+		log.Fatal("qps, concurrency and stream-msgs must be positive and size must not be negative")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ok, err := run(ctx, conf, os.Stdout)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// run sends the load against a bundled server, writing progress and a final
+// report to w. It returns false if the errors exceeded the budget or anything
+// leaked.
+func run(ctx context.Context, conf config, w io.Writer) (bool, error) {
+	before := runtime.NumGoroutine()
+
+	srv, err := startServer(conf)
+	if err != nil {
+		return false, err
+	}
+
+	pool := drpcpool.New[string, drpcpool.Conn](drpcpool.Options{Capacity: conf.poolCapacity})
+	opts, err := dialOptions(conf)
+	if err != nil {
+		_ = srv.Close()
+		return false, err
+	}
+	cc, err := drpcclient.NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+		return pool.Get(ctx, srv.Addr(), func(ctx context.Context, addr string) (drpcpool.Conn, error) {
+			return dialConn(ctx, addr)
+		}), nil
+	}, opts...)
+	if err != nil {
+		_ = pool.Close()
+		_ = srv.Close()
+		return false, err
+	}
+
+	st := newStats(conf.seed)
+	loadCtx, cancel := context.WithTimeout(ctx, conf.duration)
+	start := time.Now()
+	generate(loadCtx, conf, cc, srv, st, w)
+	elapsed := time.Since(start)
+	cancel()
+
+	_ = cc.Close()
+	_ = pool.Close()
+	_ = srv.Close()
+
+	l := leaks{
+		goroutines: settle(before, 5*time.Second),
+		conns:      srv.Conns(),
+		handlers:   srv.Handlers(),
+	}
+	return st.summary(w, conf, elapsed, srv, l), nil
+}
+
+// dialOptions returns the DialOptions selected by the flags.
+func dialOptions(conf config) ([]drpcclient.DialOption, error) {
+	var opts []drpcclient.DialOption
+	if conf.serviceConfig != "" {
+		data, err := os.ReadFile(conf.serviceConfig)
+		if err != nil {
+			return nil, err
+		}
+		sc, err := drpcclient.ParseServiceConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", conf.serviceConfig, err)
+		}
+		opts = append(opts, drpcclient.WithServiceConfig(sc))
+	}
+	if conf.unaryTimeout > 0 {
+		opts = append(opts, drpcclient.WithUnaryTimeout(conf.unaryTimeout))
+	}
+	if conf.idleTimeout > 0 {
+		opts = append(opts, drpcclient.WithIdleTimeout(conf.idleTimeout))
+	}
+	if conf.keepalive > 0 {
+		opts = append(opts, drpcclient.WithStreamKeepalive(conf.keepalive, conf.keepaliveTimeout))
+	}
+	return opts, nil
+}
+
+// dialConn dials a drpcconn to the address for the pool.
+func dialConn(ctx context.Context, addr string) (drpcpool.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return drpcconn.New(conn), nil
+}
+
+// settle waits up to timeout for the number of goroutines to drop to before,
+// returning how many more there are.
+func settle(before int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		extra := runtime.NumGoroutine() - before
+		if extra <= 0 {
+			return 0
+		}
+		if time.Now().After(deadline) {
+			return extra
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
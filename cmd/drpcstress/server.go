@@ -0,0 +1,250 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+)
+
+const (
+	rpcUnary  = "/drpcstress.Echo/Unary"
+	rpcStream = "/drpcstress.Echo/Stream"
+)
+
+// server is the bundled echo server. It tracks the conns it accepts so that
+// they can be killed, and delays every rpc by the injected latency.
+type server struct {
+	lis     net.Listener
+	handler drpc.Handler
+	latency drpcclient.LatencyDist
+	cancel  func()
+	done    chan struct{}
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	mu    sync.Mutex
+	conns map[*trackedConn]struct{}
+
+	kills    atomic.Int64
+	killed   atomic.Int64
+	handlers atomic.Int64
+}
+
+// startServer listens on a loopback port and serves the echo service on it
+// until the server is closed, killing its conns every conf.killEvery.
+func startServer(conf config) (*server, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	mux := drpcmux.New()
+	if err := mux.Register(echo{}, description{}); err != nil {
+		_ = lis.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &server{
+		lis:     lis,
+		handler: mux,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		rng:     rand.New(rand.NewSource(conf.seed)),
+		conns:   make(map[*trackedConn]struct{}),
+	}
+	if conf.latency > 0 || conf.latencyTail > 0 {
+		s.latency = drpcclient.LongTailLatency(conf.latency, conf.latencyTail)
+	}
+
+	go func() {
+		defer close(s.done)
+		_ = drpcserver.New(s).Serve(ctx, trackingListener{Listener: lis, s: s})
+	}()
+	if conf.killEvery > 0 {
+		go s.killLoop(ctx, conf.killEvery)
+	}
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *server) Addr() string { return s.lis.Addr().String() }
+
+// Close stops the server and waits for it to finish serving.
+func (s *server) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Conns returns the number of accepted conns that are not closed.
+func (s *server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Handlers returns the number of rpcs being handled.
+func (s *server) Handlers() int { return int(s.handlers.Load()) }
+
+// Kills returns the number of times the conns were killed and the number of
+// conns killed.
+func (s *server) Kills() (kills, conns int64) { return s.kills.Load(), s.killed.Load() }
+
+// HandleRPC delays the rpc by the injected latency and then handles it.
+func (s *server) HandleRPC(stream drpc.Stream, rpc string) error {
+	s.handlers.Add(1)
+	defer s.handlers.Add(-1)
+
+	if s.latency != nil {
+		s.rngMu.Lock()
+		delay := s.latency(s.rng)
+		s.rngMu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-stream.Context().Done():
+			timer.Stop()
+			return stream.Context().Err()
+		}
+	}
+	return s.handler.HandleRPC(stream, rpc)
+}
+
+// killLoop closes every conn each interval until the context is done.
+func (s *server) killLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		conns := make([]*trackedConn, 0, len(s.conns))
+		for conn := range s.conns {
+			conns = append(conns, conn)
+		}
+		s.mu.Unlock()
+
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		s.kills.Add(1)
+		s.killed.Add(int64(len(conns)))
+	}
+}
+
+// trackingListener records the conns it accepts in the server.
+type trackingListener struct {
+	net.Listener
+	s *server
+}
+
+func (l trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tc := &trackedConn{Conn: conn, s: l.s}
+
+	l.s.mu.Lock()
+	l.s.conns[tc] = struct{}{}
+	l.s.mu.Unlock()
+
+	return tc, nil
+}
+
+// trackedConn removes itself from the server when it is closed.
+type trackedConn struct {
+	net.Conn
+	s    *server
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		c.s.mu.Lock()
+		delete(c.s.conns, c)
+		c.s.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+// echo implements the echo service. Every message is a *[]byte.
+type echo struct{}
+
+// Unary responds with its request.
+func (echo) Unary(ctx context.Context, in *[]byte) (*[]byte, error) {
+	return in, nil
+}
+
+// Stream sends back every message it receives until the client closes its
+// send side.
+func (echo) Stream(stream drpc.Stream) error {
+	for {
+		var msg []byte
+		err := stream.MsgRecv(&msg, encoding{})
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.MsgSend(&msg, encoding{}); err != nil {
+			return err
+		}
+	}
+}
+
+// description describes the echo service to a drpc.Mux.
+type description struct{}
+
+func (description) NumMethods() int { return 2 }
+
+func (description) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	switch n {
+	case 0:
+		return rpcUnary, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(echo).Unary(ctx, in1.(*[]byte))
+			}, echo.Unary, true
+	case 1:
+		return rpcStream, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return nil, srv.(echo).Stream(in1.(drpc.Stream))
+			}, echo.Stream, true
+	default:
+		return "", nil, nil, nil, false
+	}
+}
+
+// encoding is the drpc.Encoding for the *[]byte messages of the service.
+type encoding struct{}
+
+func (encoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return *msg.(*[]byte), nil
+}
+
+func (encoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	*msg.(*[]byte) = append((*msg.(*[]byte))[:0], buf...)
+	return nil
+}
@@ -0,0 +1,277 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcadmission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcadmission")
+
+// retryAfterPrefix precedes the hint in the message of rejections.
+const retryAfterPrefix = "retry after "
+
+// Options configures a Controller.
+type Options struct {
+	// Key returns the key whose limits apply to the rpc. It defaults to the
+	// rpc name, limiting each method separately. Keys combining a tenant read
+	// from the metadata with the rpc name limit each tenant separately.
+	Key func(ctx context.Context, rpc string) string
+
+	// MaxConcurrent is the number of rpcs with the same key handled
+	// concurrently. It defaults to 1.
+	MaxConcurrent int
+
+	// MaxQueue is the number of rpcs with the same key that wait for their
+	// turn once MaxConcurrent are being handled. Rpcs beyond it are rejected.
+	// Zero rejects every rpc over MaxConcurrent.
+	MaxQueue int
+
+	// QueueTimeout is how long an rpc waits in the queue before it is
+	// rejected. Zero waits until the rpc is canceled.
+	QueueTimeout time.Duration
+
+	// RetryAfter is the hint of when to retry sent with rejections. It
+	// defaults to one second.
+	RetryAfter time.Duration
+}
+
+// Stats are the admission metrics of a key.
+type Stats struct {
+	// InFlight and Queued are the rpcs currently being handled and waiting.
+	InFlight int
+	Queued   int
+
+	// Admitted, Rejected and TimedOut count the rpcs that were handled,
+	// rejected because the queue was full, and rejected because they waited
+	// for longer than the QueueTimeout.
+	Admitted uint64
+	Rejected uint64
+	TimedOut uint64
+
+	// QueueWait is the total time admitted rpcs waited in the queue, and
+	// MaxQueueWait the longest.
+	QueueWait    time.Duration
+	MaxQueueWait time.Duration
+}
+
+// MeanQueueWait returns the average time admitted rpcs waited in the queue.
+func (s Stats) MeanQueueWait() time.Duration {
+	if s.Admitted == 0 {
+		return 0
+	}
+	return s.QueueWait / time.Duration(s.Admitted)
+}
+
+// Controller admits rpcs according to its Options. It is safe for concurrent
+// use.
+type Controller struct {
+	opts Options
+
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+// keyState is the admission state of a key.
+type keyState struct {
+	stats Stats
+	queue []chan struct{}
+}
+
+// NewController returns a Controller configured by opts.
+func NewController(opts Options) *Controller {
+	if opts.Key == nil {
+		opts.Key = func(ctx context.Context, rpc string) string { return rpc }
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = 1
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Second
+	}
+	return &Controller{
+		opts: opts,
+		keys: make(map[string]*keyState),
+	}
+}
+
+// Stats returns the metrics of every key that has seen an rpc.
+func (c *Controller) Stats() map[string]Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[string]Stats, len(c.keys))
+	for key, ks := range c.keys {
+		out[key] = ks.stats
+	}
+	return out
+}
+
+// NewHandler returns a drpc.Handler that admits rpcs to the handler through
+// the Controller. The time rpcs wait in the queue is added to their
+// drpcmetadata.QueueTime trailer by servers that report it.
+func (c *Controller) NewHandler(handler drpc.Handler) drpc.Handler {
+	return admissionHandler{handler: handler, c: c}
+}
+
+type admissionHandler struct {
+	handler drpc.Handler
+	c       *Controller
+}
+
+func (h admissionHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	ctx := stream.Context()
+	key := h.c.opts.Key(ctx, rpc)
+	if err := h.c.admit(ctx, key); err != nil {
+		return err
+	}
+	defer h.c.release(key)
+	return h.handler.HandleRPC(stream, rpc)
+}
+
+// admit waits until the rpc with the key may be handled, or returns the error
+// it is rejected with.
+func (c *Controller) admit(ctx context.Context, key string) error {
+	c.mu.Lock()
+	ks := c.keys[key]
+	if ks == nil {
+		ks = new(keyState)
+		c.keys[key] = ks
+	}
+	if ks.stats.InFlight < c.opts.MaxConcurrent && len(ks.queue) == 0 {
+		ks.stats.InFlight++
+		ks.stats.Admitted++
+		c.mu.Unlock()
+		return nil
+	}
+	if len(ks.queue) >= c.opts.MaxQueue {
+		ks.stats.Rejected++
+		c.mu.Unlock()
+		return c.reject("queue full")
+	}
+	ready := make(chan struct{})
+	ks.queue = append(ks.queue, ready)
+	ks.stats.Queued++
+	c.mu.Unlock()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if c.opts.QueueTimeout > 0 {
+		timer := time.NewTimer(c.opts.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+	case <-timeout:
+		err = c.reject("queue timeout")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		select {
+		case <-ready:
+			// admitted concurrently, so pass the turn on.
+			ks.stats.InFlight--
+			c.wakeLocked(ks)
+		default:
+			ks.remove(ready)
+			ks.stats.Queued--
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		ks.stats.TimedOut++
+		return err
+	}
+
+	wait := time.Since(start)
+	drpcmetadata.AddQueueTime(ctx, wait)
+	ks.stats.Admitted++
+	ks.stats.QueueWait += wait
+	if wait > ks.stats.MaxQueueWait {
+		ks.stats.MaxQueueWait = wait
+	}
+	return nil
+}
+
+// release ends an rpc with the key and admits the next queued one.
+func (c *Controller) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ks := c.keys[key]
+	ks.stats.InFlight--
+	c.wakeLocked(ks)
+}
+
+// wakeLocked admits queued rpcs while there is capacity for them. It must be
+// called with c.mu held.
+func (c *Controller) wakeLocked(ks *keyState) {
+	for len(ks.queue) > 0 && ks.stats.InFlight < c.opts.MaxConcurrent {
+		close(ks.queue[0])
+		ks.queue = ks.queue[1:]
+		ks.stats.Queued--
+		ks.stats.InFlight++
+	}
+}
+
+// remove removes the waiter from the queue.
+func (ks *keyState) remove(ready chan struct{}) {
+	for i, other := range ks.queue {
+		if other == ready {
+			ks.queue = append(ks.queue[:i], ks.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// reject returns the error of a rejected rpc.
+func (c *Controller) reject(reason string) error {
+	return WithRetryAfter(Error.New("%s", reason), c.opts.RetryAfter)
+}
+
+// WithRetryAfter returns an error with the gRPC RESOURCE_EXHAUSTED code whose
+// message carries the hint of when to retry, for other limiters to reject rpcs
+// like a Controller does.
+func WithRetryAfter(err error, d time.Duration) error {
+	return drpcerr.WithCode(fmt.Errorf("%w, %s%v", err, retryAfterPrefix, d), drpcerr.ResourceExhausted)
+}
+
+// RetryAfter returns the hint of when to retry carried by the error of an rpc
+// rejected by a Controller or with WithRetryAfter. It works on the errors
+// returned to clients, which only keep the message and code of the error.
+func RetryAfter(err error) (time.Duration, bool) {
+	if !drpcerr.HasCode(err, drpcerr.ResourceExhausted) {
+		return 0, false
+	}
+	msg := err.Error()
+	i := strings.LastIndex(msg, retryAfterPrefix)
+	if i < 0 {
+		return 0, false
+	}
+	d, err := time.ParseDuration(msg[i+len(retryAfterPrefix):])
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcadmission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+func TestController(t *testing.T) {
+	c := NewController(Options{
+		MaxConcurrent: 1,
+		MaxQueue:      1,
+		RetryAfter:    250 * time.Millisecond,
+	})
+
+	entered, release := make(chan string, 3), make(chan struct{})
+	handler := c.NewHandler(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		entered <- rpc
+		if rpc == "a" {
+			<-release
+		}
+		return nil
+	}))
+
+	errch := make(chan error, 2)
+	go func() { errch <- handler.HandleRPC(drpctest.ContextStream(context.Background()), "a") }()
+	assert.Equal(t, <-entered, "a")
+
+	go func() { errch <- handler.HandleRPC(drpctest.ContextStream(context.Background()), "a") }()
+	waitFor(func() bool { return c.Stats()["a"].Queued == 1 })
+
+	// the queue is full, so the next rpc is rejected with a hint
+	err := handler.HandleRPC(drpctest.ContextStream(context.Background()), "a")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.ResourceExhausted)
+	retryAfter, ok := RetryAfter(err)
+	assert.That(t, ok)
+	assert.Equal(t, retryAfter, 250*time.Millisecond)
+
+	// other keys have their own limits
+	assert.NoError(t, handler.HandleRPC(drpctest.ContextStream(context.Background()), "b"))
+	assert.Equal(t, <-entered, "b")
+
+	release <- struct{}{}
+	assert.NoError(t, <-errch)
+	assert.Equal(t, <-entered, "a")
+	close(release)
+	assert.NoError(t, <-errch)
+
+	stats := c.Stats()["a"]
+	assert.Equal(t, stats.Admitted, uint64(2))
+	assert.Equal(t, stats.Rejected, uint64(1))
+	assert.Equal(t, stats.InFlight, 0)
+	assert.Equal(t, stats.Queued, 0)
+	assert.That(t, stats.MaxQueueWait > 0)
+	assert.That(t, stats.MeanQueueWait() > 0)
+}
+
+func TestControllerQueueTimeout(t *testing.T) {
+	c := NewController(Options{
+		Key:           func(ctx context.Context, rpc string) string { return "tenant" },
+		MaxConcurrent: 1,
+		MaxQueue:      10,
+		QueueTimeout:  10 * time.Millisecond,
+	})
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := c.NewHandler(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	}))
+
+	go func() { _ = handler.HandleRPC(drpctest.ContextStream(context.Background()), "a") }()
+	<-entered
+
+	err := handler.HandleRPC(drpctest.ContextStream(context.Background()), "b")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.ResourceExhausted)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = handler.HandleRPC(drpctest.ContextStream(ctx), "c")
+	assert.That(t, errors.Is(err, context.Canceled))
+	_, ok := RetryAfter(err)
+	assert.That(t, !ok)
+
+	close(release)
+
+	stats := c.Stats()["tenant"]
+	assert.Equal(t, stats.TimedOut, uint64(1))
+	assert.Equal(t, stats.Queued, 0)
+}
+
+// waitFor spins until the condition holds.
+func waitFor(cond func() bool) {
+	for !cond() {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcadmission provides server side admission control: it limits the
+// rpcs handled concurrently per key, such as per tenant or per method, queues
+// the rpcs over the limit in bounded FIFO queues, and rejects the overflow
+// with the gRPC RESOURCE_EXHAUSTED code and a hint of when to retry.
+package drpcadmission
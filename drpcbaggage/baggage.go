@@ -0,0 +1,95 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcbaggage
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+// Error wraps errors returned by this package.
+var Error = errs.Class("drpcbaggage")
+
+// Baggage is a set of request scoped key value pairs. It must not be modified
+// once attached to a context.
+type Baggage map[string]string
+
+// contextKey is the context key for the Baggage.
+type contextKey struct{}
+
+// FromContext returns the Baggage attached to the context, or nil.
+func FromContext(ctx context.Context) Baggage {
+	b, _ := ctx.Value(contextKey{}).(Baggage)
+	return b
+}
+
+// WithBaggage returns a context with the Baggage attached, replacing any
+// Baggage already attached.
+func WithBaggage(ctx context.Context, b Baggage) context.Context {
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// With returns a context whose Baggage is the Baggage of ctx with the key set
+// to the value.
+func With(ctx context.Context, key, value string) context.Context {
+	prev := FromContext(ctx)
+	b := make(Baggage, len(prev)+1)
+	for k, v := range prev {
+		b[k] = v
+	}
+	b[key] = value
+	return WithBaggage(ctx, b)
+}
+
+// String returns the Baggage in the W3C baggage header format, with the keys
+// sorted and the values percent encoded.
+func (b Baggage) String() string {
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(url.PathEscape(b[key]))
+	}
+	return sb.String()
+}
+
+// Parse parses Baggage in the W3C baggage header format. Member properties,
+// which follow a semicolon, are ignored.
+func Parse(s string) (Baggage, error) {
+	b := make(Baggage)
+	for _, member := range strings.Split(s, ",") {
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(member, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, Error.New("invalid member: %q", member)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, Error.Wrap(err)
+		}
+		b[key] = value
+	}
+	return b, nil
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcbaggage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpctest"
+)
+
+func TestParseString(t *testing.T) {
+	b := Baggage{"tenant": "acme corp", "flag": "a,b"}
+	assert.Equal(t, b.String(), "flag=a%2Cb,tenant=acme%20corp")
+
+	parsed, err := Parse(b.String())
+	assert.NoError(t, err)
+	assert.DeepEqual(t, parsed, b)
+
+	parsed, err = Parse(" k1 = v1 ;prop=1, k2=v2,")
+	assert.NoError(t, err)
+	assert.DeepEqual(t, parsed, Baggage{"k1": "v1", "k2": "v2"})
+
+	_, err = Parse("novalue")
+	assert.Error(t, err)
+}
+
+func TestPropagation(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	received := make(chan Baggage, 1)
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		received <- FromContext(ctx)
+		return in, nil
+	})), drpcclient.WithChainUnaryInterceptor(UnaryClientInterceptor))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "hello", ""
+	callCtx := With(With(ctx, "tenant", "acme"), "flag", "on")
+	assert.NoError(t, cc.Invoke(callCtx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.DeepEqual(t, <-received, Baggage{"tenant": "acme", "flag": "on"})
+}
@@ -0,0 +1,7 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcbaggage propagates W3C Baggage, request scoped attributes such
+// as a tenant or feature flags, across drpc hops. Baggage from OpenTelemetry
+// can be carried by converting it with its String method and Parse.
+package drpcbaggage
@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcbaggage
+
+import (
+	"context"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// UnaryClientInterceptor sends the Baggage of the context to the server in the
+// drpcmetadata.Baggage key.
+func UnaryClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+	return next(attach(ctx, cc), rpc, enc, in, out, cc)
+}
+
+// StreamClientInterceptor sends the Baggage of the context to the server in
+// the drpcmetadata.Baggage key.
+func StreamClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+	return next(attach(ctx, cc), rpc, enc, cc)
+}
+
+// attach adds the Baggage of the context to its outgoing metadata.
+func attach(ctx context.Context, cc *drpcclient.ClientConn) context.Context {
+	if b := FromContext(ctx); len(b) > 0 {
+		return cc.AddMetadata(ctx, drpcmetadata.Baggage, b.String())
+	}
+	return ctx
+}
+
+// NewHandler returns a drpc.Handler that restores the Baggage sent by clients
+// onto the context of the streams passed to the handler. Malformed Baggage is
+// ignored.
+func NewHandler(handler drpc.Handler) drpc.Handler {
+	return baggageHandler{handler: handler}
+}
+
+type baggageHandler struct {
+	handler drpc.Handler
+}
+
+func (h baggageHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	value, ok := drpcmetadata.Lookup(stream.Context(), drpcmetadata.Baggage)
+	if !ok {
+		return h.handler.HandleRPC(stream, rpc)
+	}
+	b, err := Parse(value)
+	if err != nil {
+		return h.handler.HandleRPC(stream, rpc)
+	}
+	return h.handler.HandleRPC(baggageStream{
+		Stream: stream,
+		ctx:    WithBaggage(stream.Context(), b),
+	}, rpc)
+}
+
+// baggageStream is a stream whose context carries the restored Baggage.
+type baggageStream struct {
+	drpc.Stream
+	ctx context.Context
+}
+
+func (s baggageStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,441 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcbulk
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcenc"
+	"storj.io/drpc/drpcfeatures"
+	"storj.io/drpc/drpcwire"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcbulk")
+
+// DefaultChunkSize is the size of the chunks data is sent in when none is
+// configured.
+const DefaultChunkSize = 256 << 10
+
+// DefaultMinCompressSize is the size below which chunks are not compressed
+// when no MinCompressSize is configured.
+const DefaultMinCompressSize = 1 << 10
+
+// CompressionName is the name of the compression of chunks in the comma
+// separated list of the drpcfeatures.Compression feature.
+const CompressionName = "deflate"
+
+// The flags of a chunk.
+const (
+	flagCompressed = 1 << iota
+	flagChecksum
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Options configures a transfer.
+type Options struct {
+	// ChunkSize is the size of the chunks the sender sends. It defaults to
+	// DefaultChunkSize.
+	ChunkSize int
+
+	// Compress, if true, makes the sender compress every chunk that gets
+	// smaller when compressed, unless the chunk is opted out by
+	// MinCompressSize or SkipCompress, the call by WithoutCompression, or
+	// the peer by not listing CompressionName in its negotiated
+	// drpcfeatures.Compression.
+	Compress bool
+
+	// PeerFeatures are the features negotiated with the peer, such as the
+	// PeerFeatures of a drpcclient.ClientConn. If nil, the sender uses the
+	// drpcfeatures.Peer of the stream context, which handlers have. If the
+	// features of the peer are known, chunks are only compressed if their
+	// drpcfeatures.Compression lists CompressionName, and otherwise they are
+	// compressed as configured, since every receiver decompresses them.
+	PeerFeatures drpcfeatures.Set
+
+	// MinCompressSize is the size below which chunks are sent uncompressed,
+	// since compressing small chunks costs more than it saves. It defaults
+	// to DefaultMinCompressSize, and a negative value compresses chunks of
+	// every size.
+	MinCompressSize int
+
+	// SkipCompress, if set, is called with every chunk the sender would
+	// compress, and the chunk is sent uncompressed if it returns true, such
+	// as for data that is already compressed.
+	SkipCompress func(data []byte) bool
+
+	// Dictionaries are preset deflate dictionaries, such as samples of the
+	// data typically sent by the method, that improve the compression of
+	// small and highly structured chunks. The receiver offers the
+	// DictionaryID of each of its dictionaries when the transfer starts, and
+	// the sender compresses with the first of its dictionaries the receiver
+	// offered, if any, so both sides must be configured with the same
+	// dictionary for it to be used. They are agreed on per transfer rather
+	// than in the drpcfeatures handshake, since they belong to a method
+	// while the handshake happens once per conn.
+	Dictionaries [][]byte
+
+	// Checksum, if true, makes the sender add a CRC-32C checksum to every
+	// chunk, which the receiver verifies.
+	Checksum bool
+
+	// Progress, if set, is called after every chunk with the number of bytes
+	// transferred, including those skipped by resuming, and the total size.
+	Progress func(done, total int64)
+}
+
+// Send sends the size bytes of r on the stream to a receiver calling Receive,
+// starting at the offset the receiver asks for. It returns once the receiver
+// acknowledged every byte.
+func Send(stream drpc.Stream, r io.ReaderAt, size int64, opts Options) error {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var msg []byte
+	if err := stream.MsgRecv(&msg, drpcenc.Raw{}); err != nil {
+		return err
+	}
+	xs, err := readVarints(msg)
+	if err != nil {
+		return err
+	}
+	offset, offered := int64(xs[0]), xs[1:]
+	if offset > size {
+		return Error.New("resume offset %d is past the size %d", offset, size)
+	}
+
+	// the reply only names a dictionary the receiver offered, so receivers
+	// that predate dictionaries still get just the size.
+	var cw chunkWriter
+	msg = drpcwire.AppendVarint(msg[:0], uint64(size))
+	if cw.dict = pickDictionary(opts.Dictionaries, offered); cw.dict != nil {
+		msg = drpcwire.AppendVarint(msg, uint64(DictionaryID(cw.dict)))
+	}
+	if err := stream.MsgSend(&msg, drpcenc.Raw{}); err != nil {
+		return err
+	}
+
+	compress := opts.Compress && !compressionDisabled(stream.Context()) &&
+		peerDecompresses(stream.Context(), opts.PeerFeatures)
+	minCompress := opts.MinCompressSize
+	if minCompress == 0 {
+		minCompress = DefaultMinCompressSize
+	}
+
+	buf := make([]byte, chunkSize)
+	for offset < size {
+		n := int64(chunkSize)
+		if rem := size - offset; rem < n {
+			n = rem
+		}
+		if m, err := r.ReadAt(buf[:n], offset); int64(m) < n {
+			return Error.New("reading at offset %d: %v", offset, err)
+		}
+
+		chunk := buf[:n]
+		compressChunk := compress && len(chunk) >= minCompress &&
+			(opts.SkipCompress == nil || !opts.SkipCompress(chunk))
+		msg, err = cw.appendChunk(msg[:0], offset, chunk, compressChunk, opts.Checksum)
+		if err != nil {
+			return err
+		}
+		if err := stream.MsgSend(&msg, drpcenc.Raw{}); err != nil {
+			return err
+		}
+
+		offset += n
+		if opts.Progress != nil {
+			opts.Progress(offset, size)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	if err := stream.MsgRecv(&msg, drpcenc.Raw{}); err != nil {
+		return err
+	}
+	if acked, err := readVarint(msg); err != nil {
+		return err
+	} else if acked != size {
+		return Error.New("receiver acknowledged %d of %d bytes", acked, size)
+	}
+	return nil
+}
+
+// Receive receives the data sent by a sender calling Send on the stream and
+// writes it to w, asking for it from the offset, such as the number of bytes
+// a previous attempt received. It returns the total size of the data.
+func Receive(stream drpc.Stream, w io.WriterAt, offset int64, opts Options) (int64, error) {
+	msg := drpcwire.AppendVarint(nil, uint64(offset))
+	for _, dict := range opts.Dictionaries {
+		msg = drpcwire.AppendVarint(msg, uint64(DictionaryID(dict)))
+	}
+	if err := stream.MsgSend(&msg, drpcenc.Raw{}); err != nil {
+		return 0, err
+	}
+
+	if err := stream.MsgRecv(&msg, drpcenc.Raw{}); err != nil {
+		return 0, err
+	}
+	xs, err := readVarints(msg)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(xs[0])
+	if offset > size {
+		return 0, Error.New("resume offset %d is past the size %d", offset, size)
+	}
+
+	var dict []byte
+	if len(xs) > 1 {
+		if dict = pickDictionary(opts.Dictionaries, xs[1:2]); dict == nil || len(xs) > 2 {
+			return 0, Error.New("sender chose an unknown dictionary")
+		}
+	}
+
+	for {
+		if err := stream.MsgRecv(&msg, drpcenc.Raw{}); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+
+		data, err := readChunk(msg, offset, size-offset, dict)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := w.WriteAt(data, offset); err != nil {
+			return 0, Error.Wrap(err)
+		}
+
+		offset += int64(len(data))
+		if opts.Progress != nil {
+			opts.Progress(offset, size)
+		}
+	}
+	if offset != size {
+		return 0, Error.New("transfer ended after %d of %d bytes", offset, size)
+	}
+
+	msg = drpcwire.AppendVarint(msg[:0], uint64(offset))
+	if err := stream.MsgSend(&msg, drpcenc.Raw{}); err != nil {
+		return 0, err
+	}
+	return size, stream.CloseSend()
+}
+
+// SendFile sends the file at path on the stream with Send.
+func SendFile(stream drpc.Stream, path string, opts Options) (err error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, Error.Wrap(fh.Close())) }()
+
+	fi, err := fh.Stat()
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	return Send(stream, fh, fi.Size(), opts)
+}
+
+// ReceiveFile receives a file sent on the stream with Receive into the file
+// at path. If the file exists, the transfer resumes after its contents, which
+// are assumed to be the start of the data, such as from an interrupted
+// transfer.
+func ReceiveFile(stream drpc.Stream, path string, opts Options) (err error) {
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, Error.Wrap(fh.Close())) }()
+
+	fi, err := fh.Stat()
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	_, err = Receive(stream, fh, fi.Size(), opts)
+	return err
+}
+
+// noCompressionKey is the context key that disables compression.
+type noCompressionKey struct{}
+
+// WithoutCompression returns a context whose streams are sent without
+// compression even if Options.Compress is set. It is the per call option that
+// lets callers opt out calls whose data is known not to compress, such as
+// already compressed archives, without changing the Options of the method.
+func WithoutCompression(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCompressionKey{}, true)
+}
+
+// compressionDisabled returns true if the context was returned by
+// WithoutCompression.
+func compressionDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noCompressionKey{}).(bool)
+	return disabled
+}
+
+// peerDecompresses returns false if the features of the peer, or the ones it
+// negotiated for the handler of the context if peer is nil, are known and do
+// not list CompressionName as a drpcfeatures.Compression.
+func peerDecompresses(ctx context.Context, peer drpcfeatures.Set) bool {
+	if peer == nil {
+		var ok bool
+		if peer, ok = drpcfeatures.Peer(ctx); !ok {
+			return true
+		}
+	}
+	names, _ := peer.Get(drpcfeatures.Compression)
+	for _, name := range strings.Split(names, ",") {
+		if strings.TrimSpace(name) == CompressionName {
+			return true
+		}
+	}
+	return false
+}
+
+// DictionaryID returns the identifier of a dictionary in the handshake of a
+// transfer, which is the CRC-32C of its contents.
+func DictionaryID(dict []byte) uint32 {
+	return crc32.Checksum(dict, castagnoli)
+}
+
+// pickDictionary returns the first of the dictionaries whose DictionaryID is
+// in ids, or nil if there is none.
+func pickDictionary(dicts [][]byte, ids []uint64) []byte {
+	for _, dict := range dicts {
+		id := uint64(DictionaryID(dict))
+		for _, x := range ids {
+			if x == id {
+				return dict
+			}
+		}
+	}
+	return nil
+}
+
+// chunkWriter encodes chunks, reusing its compressor between them.
+type chunkWriter struct {
+	buf  bytes.Buffer
+	fw   *flate.Writer
+	dict []byte
+}
+
+// appendChunk appends the chunk of data at the offset to msg, trying to
+// compress it if compress is set and adding a checksum if checksum is set. A
+// chunk is its flags, the varint offset, the big endian CRC-32C of the data if
+// flagChecksum is set, and the data, compressed with deflate and the
+// dictionary of the transfer, if any, if flagCompressed is set.
+func (cw *chunkWriter) appendChunk(msg []byte, offset int64, data []byte, compress, checksum bool) ([]byte, error) {
+	var flags byte
+	if compress {
+		cw.buf.Reset()
+		if cw.fw == nil {
+			cw.fw, _ = flate.NewWriterDict(&cw.buf, flate.BestSpeed, cw.dict)
+		} else {
+			cw.fw.Reset(&cw.buf)
+		}
+		if _, err := cw.fw.Write(data); err != nil {
+			return nil, Error.Wrap(err)
+		}
+		if err := cw.fw.Close(); err != nil {
+			return nil, Error.Wrap(err)
+		}
+		if cw.buf.Len() < len(data) {
+			flags |= flagCompressed
+		}
+	}
+	if checksum {
+		flags |= flagChecksum
+	}
+
+	msg = append(msg, flags)
+	msg = drpcwire.AppendVarint(msg, uint64(offset))
+	if flags&flagChecksum != 0 {
+		msg = binary.BigEndian.AppendUint32(msg, crc32.Checksum(data, castagnoli))
+	}
+	if flags&flagCompressed != 0 {
+		return append(msg, cw.buf.Bytes()...), nil
+	}
+	return append(msg, data...), nil
+}
+
+// readChunk returns the data of the chunk in msg, which must be at the offset
+// and hold at most max bytes, decompressing it with the dictionary, if any.
+func readChunk(msg []byte, offset, max int64, dict []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, Error.New("empty chunk")
+	}
+	flags, rem := msg[0], msg[1:]
+
+	rem, at, ok, err := drpcwire.ReadVarint(rem)
+	if err != nil || !ok {
+		return nil, Error.New("invalid chunk offset")
+	} else if int64(at) != offset {
+		return nil, Error.New("chunk at offset %d, expected %d", at, offset)
+	}
+
+	var sum uint32
+	if flags&flagChecksum != 0 {
+		if len(rem) < 4 {
+			return nil, Error.New("invalid chunk checksum")
+		}
+		sum, rem = binary.BigEndian.Uint32(rem), rem[4:]
+	}
+
+	data := rem
+	if flags&flagCompressed != 0 {
+		data, err = io.ReadAll(io.LimitReader(flate.NewReaderDict(bytes.NewReader(rem), dict), max+1))
+		if err != nil {
+			return nil, Error.New("decompressing chunk at offset %d: %v", offset, err)
+		}
+	}
+	if int64(len(data)) > max {
+		return nil, Error.New("chunk at offset %d is past the size", offset)
+	}
+
+	if flags&flagChecksum != 0 && crc32.Checksum(data, castagnoli) != sum {
+		return nil, Error.New("checksum mismatch in chunk at offset %d", offset)
+	}
+	return data, nil
+}
+
+// readVarints reads a message holding one or more varints.
+func readVarints(msg []byte) (xs []uint64, err error) {
+	for len(msg) > 0 || len(xs) == 0 {
+		var x uint64
+		var ok bool
+		msg, x, ok, err = drpcwire.ReadVarint(msg)
+		if err != nil || !ok || (len(xs) == 0 && x > 1<<62) {
+			return nil, Error.New("invalid message")
+		}
+		xs = append(xs, x)
+	}
+	return xs, nil
+}
+
+// readVarint reads a message holding a single varint.
+func readVarint(msg []byte) (int64, error) {
+	rem, x, ok, err := drpcwire.ReadVarint(msg)
+	if err != nil || !ok || len(rem) != 0 || x > 1<<62 {
+		return 0, Error.New("invalid message")
+	}
+	return int64(x), nil
+}
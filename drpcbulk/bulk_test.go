@@ -0,0 +1,227 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcbulk
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcenc"
+	"storj.io/drpc/drpcfeatures"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+)
+
+func TestTransfer(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	data := bytes.Repeat([]byte("snapshot data "), 10000)
+	opts := Options{ChunkSize: 4096, Compress: true, Checksum: true}
+
+	conn := serve(ctx, func(stream drpc.Stream) error {
+		return Send(stream, bytes.NewReader(data), int64(len(data)), opts)
+	})
+	defer func() { _ = conn.Close() }()
+
+	// a transfer resumes from the offset the receiver asks for
+	for _, offset := range []int64{0, 5000, int64(len(data))} {
+		stream, err := conn.NewStream(ctx, "bulk", drpcenc.Raw{})
+		assert.NoError(t, err)
+
+		var progress []int64
+		out := &memFile{buf: append([]byte(nil), data[:offset]...)}
+		size, err := Receive(stream, out, offset, Options{
+			Progress: func(done, total int64) { progress = append(progress, done) },
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, size, int64(len(data)))
+		assert.That(t, bytes.Equal(out.buf, data))
+		if offset < size {
+			assert.Equal(t, progress[0], offset+4096)
+			assert.Equal(t, progress[len(progress)-1], size)
+		}
+		assert.NoError(t, stream.Close())
+	}
+}
+
+func TestTransferFile(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	data := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, 3000)
+	assert.NoError(t, os.WriteFile(src, data, 0o644))
+
+	// the receiver resumes after a partially received file
+	assert.NoError(t, os.WriteFile(dst, data[:1000], 0o644))
+
+	conn := serve(ctx, func(stream drpc.Stream) error {
+		return SendFile(stream, src, Options{ChunkSize: 1024})
+	})
+	defer func() { _ = conn.Close() }()
+
+	stream, err := conn.NewStream(ctx, "bulk", drpcenc.Raw{})
+	assert.NoError(t, err)
+	assert.NoError(t, ReceiveFile(stream, dst, Options{}))
+
+	got, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.That(t, bytes.Equal(got, data))
+}
+
+func TestChunk(t *testing.T) {
+	var cw chunkWriter
+
+	// chunks are only compressed if it makes them smaller
+	data := bytes.Repeat([]byte("a"), 1000)
+	msg, err := cw.appendChunk(nil, 0, data, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, msg[0], byte(flagCompressed))
+	assert.That(t, len(msg) < 100)
+	got, err := readChunk(msg, 0, 1000, nil)
+	assert.NoError(t, err)
+	assert.That(t, bytes.Equal(got, data))
+
+	_, err = readChunk(msg, 0, 999, nil)
+	assert.Error(t, err)
+
+	// and corrupted or misplaced chunks are rejected
+	msg, err = cw.appendChunk(nil, 10, []byte("chunk"), true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, msg[0], byte(flagChecksum))
+
+	got, err = readChunk(msg, 10, 5, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, string(got), "chunk")
+
+	msg[len(msg)-1] ^= 1
+	_, err = readChunk(msg, 10, 5, nil)
+	assert.Error(t, err)
+
+	_, err = readChunk(msg, 11, 5, nil)
+	assert.Error(t, err)
+}
+
+func TestCompressOptOut(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	data := append(append(bytes.Repeat([]byte("a"), 4096), bytes.Repeat([]byte("z"), 4096)...),
+		bytes.Repeat([]byte("a"), 100)...)
+	opts := Options{
+		ChunkSize:    4096,
+		Compress:     true,
+		SkipCompress: func(data []byte) bool { return data[0] == 'z' },
+	}
+
+	// flags returns the flags of the chunks sent by Send with the context and
+	// the features of the peer.
+	flags := func(sendCtx context.Context, peer drpcfeatures.Set) (out []byte) {
+		opts := opts
+		opts.PeerFeatures = peer
+
+		conn := serve(ctx, func(stream drpc.Stream) error {
+			return Send(ctxStream{Stream: stream, ctx: sendCtx}, bytes.NewReader(data), int64(len(data)), opts)
+		})
+		defer func() { _ = conn.Close() }()
+
+		stream, err := conn.NewStream(ctx, "bulk", drpcenc.Raw{})
+		assert.NoError(t, err)
+		defer func() { _ = stream.Close() }()
+
+		msg := []byte{0}
+		assert.NoError(t, stream.MsgSend(&msg, drpcenc.Raw{}))
+		assert.NoError(t, stream.MsgRecv(&msg, drpcenc.Raw{}))
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, stream.MsgRecv(&msg, drpcenc.Raw{}))
+			out = append(out, msg[0])
+		}
+		return out
+	}
+
+	// chunks below the threshold or skipped are sent as is.
+	assert.DeepEqual(t, flags(ctx, nil), []byte{flagCompressed, 0, 0})
+	assert.DeepEqual(t, flags(WithoutCompression(ctx), nil), []byte{0, 0, 0})
+
+	// peers that negotiated compression only get it if they listed deflate.
+	assert.DeepEqual(t, flags(ctx, drpcfeatures.Set{drpcfeatures.Compression: "zstd, deflate"}), []byte{flagCompressed, 0, 0})
+	assert.DeepEqual(t, flags(ctx, drpcfeatures.Set{drpcfeatures.Compression: "zstd"}), []byte{0, 0, 0})
+	assert.DeepEqual(t, flags(ctx, drpcfeatures.Set{}), []byte{0, 0, 0})
+}
+
+func TestDictionaries(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	dict := []byte(`{"id":0,"name":"","tags":[],"created":"2026-01-01T00:00:00Z"}`)
+	other := []byte("some unrelated dictionary")
+	data := bytes.Repeat([]byte(`{"id":7,"name":"x","tags":[],"created":"2026-01-02T00:00:00Z"}`), 4)
+
+	// a dictionary compresses small structured chunks better.
+	plain, err := (&chunkWriter{}).appendChunk(nil, 0, data, true, false)
+	assert.NoError(t, err)
+	withDict, err := (&chunkWriter{dict: dict}).appendChunk(nil, 0, data, true, false)
+	assert.NoError(t, err)
+	assert.That(t, len(withDict) < len(plain))
+
+	got, err := readChunk(withDict, 0, int64(len(data)), dict)
+	assert.NoError(t, err)
+	assert.That(t, bytes.Equal(got, data))
+
+	// the sender uses the first of its dictionaries the receiver offered, and
+	// none if they have none in common or the receiver offered none.
+	for _, dicts := range [][][]byte{{other, dict}, {other}, nil} {
+		conn := serve(ctx, func(stream drpc.Stream) error {
+			return Send(stream, bytes.NewReader(data), int64(len(data)), Options{
+				Compress: true, MinCompressSize: -1, Dictionaries: [][]byte{dict, other},
+			})
+		})
+
+		stream, err := conn.NewStream(ctx, "bulk", drpcenc.Raw{})
+		assert.NoError(t, err)
+		out := new(memFile)
+		_, err = Receive(stream, out, 0, Options{Dictionaries: dicts})
+		assert.NoError(t, err)
+		assert.That(t, bytes.Equal(out.buf, data))
+		assert.NoError(t, conn.Close())
+	}
+}
+
+// ctxStream is a stream with another context.
+type ctxStream struct {
+	drpc.Stream
+	ctx context.Context
+}
+
+func (s ctxStream) Context() context.Context { return s.ctx }
+
+// serve returns a conn to a server handling every rpc with the handler.
+func serve(ctx *drpctest.Tracker, handler func(stream drpc.Stream) error) *drpcconn.Conn {
+	srv := drpcserver.New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		return handler(stream)
+	}))
+	pc, ps := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+	return drpcconn.New(pc)
+}
+
+// memFile is an in memory io.WriterAt.
+type memFile struct{ buf []byte }
+
+func (m *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+	return copy(m.buf[off:], p), nil
+}
@@ -0,0 +1,13 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcbulk transfers large files or snapshots over drpc streams. The
+// sender splits the data into chunks, optionally compressed and checksummed,
+// and the receiver writes them at their offsets, reporting progress as it
+// goes. A receiver asks for the data from an offset, so that an interrupted
+// transfer resumes where it stopped instead of starting over.
+//
+// Chunks are compressed with deflate from the standard library, optionally
+// with preset dictionaries, rather than zstd, so that the package needs no
+// dependencies outside of the module.
+package drpcbulk
@@ -0,0 +1,136 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcchannel
+
+import (
+	"io"
+
+	"storj.io/drpc/drpcwire"
+)
+
+// Channel is a labeled channel of a Session. It is a drpc.Transport, so that
+// drpc conns and servers can run over it.
+type Channel struct {
+	s      *Session
+	id     uint64
+	label  string
+	weight float64
+
+	// guarded by s.mu
+	recv         []byte  // received and not yet read
+	consumed     int     // read since the last window update
+	credit       int     // that may be sent before the peer reads more
+	pending      []byte  // rest of the current write
+	current      float64 // weighted round robin state
+	localClosed  bool
+	remoteClosed bool
+}
+
+// Label returns the label of the channel.
+func (c *Channel) Label() string { return c.label }
+
+// sendable returns true if the channel has a chunk it may send.
+func (c *Channel) sendable() bool { return len(c.pending) > 0 && c.credit > 0 }
+
+// Read reads the data sent by the peer, and returns io.EOF once the peer
+// closed the channel and every byte was read.
+func (c *Channel) Read(p []byte) (int, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(c.recv) == 0 {
+		switch {
+		case c.localClosed:
+			return 0, Error.New("channel closed")
+		case c.remoteClosed:
+			return 0, io.EOF
+		case s.done.IsSet():
+			return 0, s.done.Err()
+		}
+		s.cond.Wait()
+	}
+
+	n := copy(p, c.recv)
+	c.recv = c.recv[n:]
+	if len(c.recv) == 0 {
+		c.recv = nil
+	}
+
+	// give the credit back once half of the window was read, so that the
+	// peer rarely waits for it without an update for every read.
+	c.consumed += n
+	if c.consumed >= s.opts.Window/2 {
+		s.control = appendFrame(s.control, kindWindow, c.id, drpcwire.AppendVarint(nil, uint64(c.consumed)))
+		c.consumed = 0
+		s.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Write sends the data to the peer in chunks interleaved with the chunks of
+// the other channels, and returns once all of it was written to the
+// transport.
+func (c *Channel) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c.pending != nil && !c.localClosed && !s.done.IsSet() {
+		s.cond.Wait()
+	}
+	if err := c.writeErr(); err != nil {
+		return 0, err
+	}
+
+	c.pending = p
+	s.cond.Broadcast()
+	for c.pending != nil && !c.localClosed && !s.done.IsSet() {
+		s.cond.Wait()
+	}
+	if c.pending != nil {
+		n := len(p) - len(c.pending)
+		c.pending = nil
+		s.cond.Broadcast()
+		if err := c.writeErr(); err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
+
+// writeErr returns why the channel cannot be written to, if it cannot. It
+// must be called with s.mu held.
+func (c *Channel) writeErr() error {
+	switch {
+	case c.localClosed:
+		return Error.New("channel closed")
+	case c.s.done.IsSet():
+		return c.s.done.Err()
+	default:
+		return nil
+	}
+}
+
+// Close closes the channel, which the peer reads as io.EOF. Writes that are in
+// progress fail.
+func (c *Channel) Close() error {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.localClosed {
+		return nil
+	}
+	c.localClosed = true
+	c.recv = nil
+	s.control = appendFrame(s.control, kindClose, c.id, nil)
+	s.forgetLocked(c)
+	s.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcchannel
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcctx"
+)
+
+// Conn is a drpc.Conn over the default channel of a client Session that opens
+// a drpc conn over another channel for every label. It implements the
+// MultiplexedConn of drpcclient, whose WithChannels routes rpcs to the
+// channels.
+type Conn struct {
+	*drpcconn.Conn
+	s *Session
+
+	mu    sync.Mutex
+	conns map[string]*drpcconn.Conn
+}
+
+// NewConn returns a Conn over a client Session on the transport.
+func NewConn(tr io.ReadWriteCloser, opts Options) *Conn {
+	s := Client(tr, opts)
+	return &Conn{
+		Conn:  drpcconn.New(s.Open("")),
+		s:     s,
+		conns: make(map[string]*drpcconn.Conn),
+	}
+}
+
+// OpenChannel returns the drpc conn over the channel with the label, opening
+// it if needed. The empty label is the default channel of the Conn itself.
+func (c *Conn) OpenChannel(label string) drpc.Conn {
+	if label == "" {
+		return c.Conn
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, ok := c.conns[label]
+	if !ok {
+		conn = drpcconn.New(c.s.Open(label))
+		c.conns[label] = conn
+	}
+	return conn
+}
+
+// Close closes the Session and every channel.
+func (c *Conn) Close() error { return c.s.Close() }
+
+// Closed returns a channel that is closed once the Session is closed.
+func (c *Conn) Closed() <-chan struct{} { return c.s.Closed() }
+
+// Serve serves every channel of a server Session on the transport with the
+// server, such as a *drpcserver.Server, until the context is canceled or the
+// transport fails.
+func Serve(ctx context.Context, srv interface {
+	ServeOne(ctx context.Context, tr drpc.Transport) error
+}, tr io.ReadWriteCloser, opts Options) error {
+	s := Server(tr, opts)
+	defer func() { _ = s.Close() }()
+
+	tracker := drpcctx.NewTracker(ctx)
+	defer tracker.Wait()
+	defer tracker.Cancel()
+
+	for {
+		ch, err := s.Accept(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		tracker.Run(func(ctx context.Context) {
+			defer func() { _ = ch.Close() }()
+			_ = srv.ServeOne(ctx, ch)
+		})
+	}
+}
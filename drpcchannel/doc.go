@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcchannel multiplexes labeled channels over one transport, each
+// carrying its own drpc conn. Writes are split into bounded chunks that are
+// interleaved across channels by their priority weights, and every channel has
+// its own flow control window, so that a stream sending a large snapshot does
+// not delay the small, latency sensitive rpcs of other channels.
+package drpcchannel
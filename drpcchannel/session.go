@@ -0,0 +1,353 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcchannel
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc/drpcsignal"
+	"storj.io/drpc/drpcwire"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcchannel")
+
+// DefaultChunkSize is the largest chunk of a write sent at once when none is
+// configured.
+const DefaultChunkSize = 16 << 10
+
+// DefaultWindow is the flow control window of a channel when none is
+// configured.
+const DefaultWindow = 1 << 20
+
+// maxLabelSize bounds the labels of the channels opened by the peer.
+const maxLabelSize = 1 << 10
+
+// The kinds of the frames sent on the transport. Every frame is the kind, the
+// varint id of the channel, the varint length of the payload, and the payload.
+const (
+	kindOpen   = 0 // payload is the label
+	kindData   = 1 // payload is data
+	kindWindow = 2 // payload is the varint number of bytes read
+	kindClose  = 3 // no payload
+)
+
+// Options configures a Session.
+type Options struct {
+	// ChunkSize is the largest chunk of a write sent before the chunks of
+	// other channels get a turn. It defaults to DefaultChunkSize.
+	ChunkSize int
+
+	// Window is how many bytes a channel may send before the peer reads them.
+	// Both ends of a Session must use the same Window. It defaults to
+	// DefaultWindow.
+	Window int
+
+	// Weights are the priority weights of the channels by label, which get
+	// chunks sent in proportion to them while several channels are writing.
+	// Channels without a weight have a weight of 1.
+	Weights map[string]int
+}
+
+// Session multiplexes channels over a transport. It is safe for concurrent
+// use.
+type Session struct {
+	tr   io.ReadWriteCloser
+	opts Options
+	done drpcsignal.Signal
+
+	mu       sync.Mutex
+	cond     sync.Cond
+	channels map[uint64]*Channel
+	nextID   uint64
+	control  []byte // frames written before any data
+	accepted []*Channel
+	acceptc  chan struct{}
+}
+
+// Client returns a Session opening channels on the transport.
+func Client(tr io.ReadWriteCloser, opts Options) *Session { return newSession(tr, opts, 1) }
+
+// Server returns a Session accepting the channels opened by a Client on the
+// transport.
+func Server(tr io.ReadWriteCloser, opts Options) *Session { return newSession(tr, opts, 2) }
+
+func newSession(tr io.ReadWriteCloser, opts Options, firstID uint64) *Session {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	s := &Session{
+		tr:       tr,
+		opts:     opts,
+		channels: make(map[uint64]*Channel),
+		nextID:   firstID,
+		acceptc:  make(chan struct{}, 1),
+	}
+	s.cond.L = &s.mu
+
+	go s.manageReader()
+	go s.manageWriter()
+
+	return s
+}
+
+// Close closes the transport and every channel.
+func (s *Session) Close() error {
+	s.fail(Error.New("session closed"))
+	return nil
+}
+
+// Closed returns a channel that is closed once the session is closed.
+func (s *Session) Closed() <-chan struct{} { return s.done.Signal() }
+
+// fail closes the session with the error.
+func (s *Session) fail(err error) {
+	if !s.done.Set(err) {
+		return
+	}
+	_ = s.tr.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// Open opens a channel with the label. If the session is closed, the channel
+// fails every read and write.
+func (s *Session) Open(label string) *Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := s.newChannelLocked(s.nextID, label)
+	s.nextID += 2
+	s.control = appendFrame(s.control, kindOpen, ch.id, []byte(label))
+	s.cond.Broadcast()
+	return ch
+}
+
+// Accept returns the next channel opened by the peer.
+func (s *Session) Accept(ctx context.Context) (*Channel, error) {
+	for {
+		s.mu.Lock()
+		if len(s.accepted) > 0 {
+			ch := s.accepted[0]
+			s.accepted = s.accepted[1:]
+			s.mu.Unlock()
+			return ch, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.done.Signal():
+			return nil, s.done.Err()
+		case <-s.acceptc:
+		}
+	}
+}
+
+// newChannelLocked registers a new channel. It must be called with s.mu held.
+func (s *Session) newChannelLocked(id uint64, label string) *Channel {
+	weight, ok := s.opts.Weights[label]
+	if !ok || weight <= 0 {
+		weight = 1
+	}
+	ch := &Channel{
+		s:      s,
+		id:     id,
+		label:  label,
+		weight: float64(weight),
+		credit: s.opts.Window,
+	}
+	s.channels[id] = ch
+	return ch
+}
+
+// manageReader reads the frames of the peer until the transport fails.
+func (s *Session) manageReader() {
+	br := bufio.NewReader(s.tr)
+	for {
+		kind, id, payload, err := readFrame(br, s.opts.Window)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		if err := s.handleFrame(kind, id, payload); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+// handleFrame applies a frame read from the peer.
+func (s *Session) handleFrame(kind byte, id uint64, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.cond.Broadcast()
+
+	ch := s.channels[id]
+	switch kind {
+	case kindOpen:
+		if ch != nil || id%2 == s.nextID%2 || len(payload) > maxLabelSize {
+			return Error.New("invalid open of channel %d", id)
+		}
+		s.accepted = append(s.accepted, s.newChannelLocked(id, string(payload)))
+		select {
+		case s.acceptc <- struct{}{}:
+		default:
+		}
+
+	case kindData:
+		if ch == nil || ch.localClosed {
+			return nil
+		}
+		if len(ch.recv)+len(payload) > s.opts.Window {
+			return Error.New("channel %d exceeded its window", id)
+		}
+		ch.recv = append(ch.recv, payload...)
+
+	case kindWindow:
+		n, _ := binary.Uvarint(payload)
+		if ch != nil {
+			ch.credit += int(n)
+		}
+
+	case kindClose:
+		if ch != nil {
+			ch.remoteClosed = true
+			s.forgetLocked(ch)
+		}
+
+	default:
+		return Error.New("unknown frame kind %d", kind)
+	}
+	return nil
+}
+
+// forgetLocked unregisters the channel once both ends closed it. It must be
+// called with s.mu held.
+func (s *Session) forgetLocked(ch *Channel) {
+	if ch.localClosed && ch.remoteClosed {
+		delete(s.channels, ch.id)
+	}
+}
+
+// manageWriter writes the control frames and the chunks of the channels until
+// the session is closed.
+func (s *Session) manageWriter() {
+	var buf []byte
+	for {
+		s.mu.Lock()
+		for !s.done.IsSet() && len(s.control) == 0 && !s.readyLocked() {
+			s.cond.Wait()
+		}
+		if s.done.IsSet() {
+			s.mu.Unlock()
+			return
+		}
+
+		var ch *Channel
+		if len(s.control) > 0 {
+			buf, s.control = append(buf[:0], s.control...), s.control[:0]
+		} else {
+			ch = s.pickLocked()
+			n := len(ch.pending)
+			if n > s.opts.ChunkSize {
+				n = s.opts.ChunkSize
+			}
+			if n > ch.credit {
+				n = ch.credit
+			}
+			buf = appendFrame(buf[:0], kindData, ch.id, ch.pending[:n])
+			ch.pending, ch.credit = ch.pending[n:], ch.credit-n
+		}
+		s.mu.Unlock()
+
+		if _, err := s.tr.Write(buf); err != nil {
+			s.fail(err)
+			return
+		}
+
+		if ch != nil {
+			s.mu.Lock()
+			if len(ch.pending) == 0 {
+				ch.pending = nil
+				s.cond.Broadcast()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// readyLocked returns true if a channel has a chunk it may send. It must be
+// called with s.mu held.
+func (s *Session) readyLocked() bool {
+	for _, ch := range s.channels {
+		if ch.sendable() {
+			return true
+		}
+	}
+	return false
+}
+
+// pickLocked picks the channel whose chunk is sent next with smooth weighted
+// round robin: every channel that may send gains its weight, and the one with
+// the most gives up the total, which interleaves the chunks of the channels in
+// proportion to their weights. It must be called with s.mu held.
+func (s *Session) pickLocked() *Channel {
+	var best *Channel
+	var total float64
+	for _, ch := range s.channels {
+		if !ch.sendable() {
+			continue
+		}
+		ch.current += ch.weight
+		total += ch.weight
+		if best == nil || ch.current > best.current {
+			best = ch
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// appendFrame appends the frame to the buffer.
+func appendFrame(buf []byte, kind byte, id uint64, payload []byte) []byte {
+	buf = append(buf, kind)
+	buf = drpcwire.AppendVarint(buf, id)
+	buf = drpcwire.AppendVarint(buf, uint64(len(payload)))
+	return append(buf, payload...)
+}
+
+// readFrame reads a frame whose payload is at most max bytes.
+func readFrame(br *bufio.Reader, max int) (kind byte, id uint64, payload []byte, err error) {
+	if kind, err = br.ReadByte(); err != nil {
+		return 0, 0, nil, err
+	}
+	if id, err = binary.ReadUvarint(br); err != nil {
+		return 0, 0, nil, err
+	}
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if size > uint64(max) {
+		return 0, 0, nil, Error.New("frame of %d bytes is too large", size)
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return 0, 0, nil, err
+	}
+	return kind, id, payload, nil
+}
@@ -0,0 +1,130 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcchannel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+)
+
+func TestConn(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	unblock := make(chan struct{})
+	srv := drpcserver.New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in []byte
+		if err := stream.MsgRecv(&in, encoding{}); err != nil {
+			return err
+		}
+		if rpc == "slow" {
+			<-unblock
+		}
+		return stream.MsgSend(&in, encoding{})
+	}))
+
+	pc, ps := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = Serve(ctx, srv, ps, Options{}) })
+	conn := NewConn(pc, Options{})
+	defer func() { _ = conn.Close() }()
+
+	invoke := func(conn drpc.Conn, rpc string, in []byte) ([]byte, error) {
+		var out []byte
+		err := conn.Invoke(ctx, rpc, encoding{}, &in, &out)
+		return out, err
+	}
+
+	// a slow rpc on one channel does not block the rpcs of the others
+	big := bytes.Repeat([]byte("x"), 4*DefaultChunkSize)
+	errs := make(chan error, 1)
+	go func() {
+		out, err := invoke(conn.OpenChannel("bulk"), "slow", big)
+		if err == nil && !bytes.Equal(out, big) {
+			err = Error.New("bad response")
+		}
+		errs <- err
+	}()
+
+	for _, label := range []string{"", "control", "control"} {
+		out, err := invoke(conn.OpenChannel(label), "fast", []byte(label))
+		assert.NoError(t, err)
+		assert.Equal(t, string(out), label)
+	}
+	assert.Equal(t, conn.OpenChannel(""), drpc.Conn(conn.Conn))
+	assert.Equal(t, conn.OpenChannel("control"), conn.OpenChannel("control"))
+
+	close(unblock)
+	assert.NoError(t, <-errs)
+}
+
+func TestSessionFlowControl(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	pc, ps := net.Pipe()
+	client := Client(pc, Options{ChunkSize: 7, Window: 64})
+	server := Server(ps, Options{ChunkSize: 7, Window: 64})
+	defer func() { _ = client.Close() }()
+	defer func() { _ = server.Close() }()
+
+	// writes much larger than the window arrive in order as they are read
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	for _, label := range []string{"a", "b"} {
+		ch := client.Open(label)
+		ctx.Run(func(ctx context.Context) {
+			_, _ = ch.Write(data)
+			_ = ch.Close()
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		ch, err := server.Accept(ctx)
+		assert.NoError(t, err)
+		got, err := io.ReadAll(ch)
+		assert.NoError(t, err)
+		assert.That(t, bytes.Equal(got, data))
+		assert.NoError(t, ch.Close())
+	}
+}
+
+func TestSessionWeights(t *testing.T) {
+	s := &Session{channels: make(map[uint64]*Channel)}
+	s.opts.Weights = map[string]int{"high": 3}
+
+	for id, label := range []string{"high", "low"} {
+		ch := s.newChannelLocked(uint64(id), label)
+		ch.pending, ch.credit = []byte("data"), 1
+	}
+
+	picked := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		picked[s.pickLocked().label]++
+	}
+	assert.Equal(t, picked["high"], 6)
+	assert.Equal(t, picked["low"], 2)
+}
+
+type encoding struct{}
+
+func (encoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return *msg.(*[]byte), nil
+}
+
+func (encoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	*msg.(*[]byte) = append([]byte(nil), buf...)
+	return nil
+}
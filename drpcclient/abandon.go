@@ -0,0 +1,160 @@
+package drpcclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// AbandonOptions configures an Abandoner. Zero values use the defaults.
+type AbandonOptions struct {
+	// Percentile of the recent latencies of a method used as the threshold
+	// after which an attempt is abandoned. It defaults to 0.95.
+	Percentile float64
+
+	// Window is the number of recent latencies kept per method. It defaults
+	// to 100.
+	Window int
+
+	// MinSamples is the number of latencies a method needs before attempts
+	// are abandoned. It defaults to 20.
+	MinSamples int
+
+	// MinThreshold is the smallest threshold used, which keeps methods that
+	// are usually very fast from being abandoned on jitter.
+	MinThreshold time.Duration
+
+	// MaxAbandons is the number of attempts of an rpc that may be abandoned.
+	// The last attempt always runs to completion. It defaults to 1.
+	MaxAbandons int
+}
+
+// Abandoner cancels unary rpcs whose backend has not responded within an
+// adaptive threshold, the configured percentile of the recent latencies of the
+// method, and retries them on a different backend of the Balancer the
+// ClientConn uses. Unlike hedging, an attempt is only started once the
+// previous one is canceled, so there is never more than one request in
+// flight. The retried rpcs must be safe to issue more than once.
+type Abandoner struct {
+	opts AbandonOptions
+
+	mu      sync.Mutex
+	methods map[string]*latencyWindow
+}
+
+// latencyWindow is a ring of the most recent latencies of a method.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+// NewAbandoner returns an Abandoner configured by opts.
+func NewAbandoner(opts AbandonOptions) *Abandoner {
+	if opts.Percentile <= 0 || opts.Percentile > 1 {
+		opts.Percentile = 0.95
+	}
+	if opts.Window <= 0 {
+		opts.Window = 100
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = 20
+	}
+	if opts.MinSamples > opts.Window {
+		opts.MinSamples = opts.Window
+	}
+	if opts.MaxAbandons <= 0 {
+		opts.MaxAbandons = 1
+	}
+	return &Abandoner{
+		opts:    opts,
+		methods: make(map[string]*latencyWindow),
+	}
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that abandons slow
+// attempts of the rpcs through it.
+func (a *Abandoner) UnaryInterceptor() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		ctx = withPeer(ctx)
+
+		for abandons := 0; ; abandons++ {
+			threshold, ok := a.Threshold(rpc)
+			if !ok || abandons >= a.opts.MaxAbandons {
+				start := time.Now()
+				err := next(ctx, rpc, enc, in, out, cc)
+				a.record(rpc, time.Since(start), err)
+				return err
+			}
+
+			start := time.Now()
+			attemptCtx, cancel := context.WithTimeout(ctx, threshold)
+			err := next(attemptCtx, rpc, enc, in, out, cc)
+			abandoned := err != nil && ctx.Err() == nil &&
+				errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+			cancel()
+
+			if !abandoned {
+				a.record(rpc, time.Since(start), err)
+				return err
+			}
+
+			if peer, _ := PeerFromContext(ctx); peer.Backend != "" {
+				ctx = avoidBackend(ctx, peer.Backend)
+			}
+		}
+	}
+}
+
+// Threshold returns the current threshold after which attempts of the rpc are
+// abandoned, and false if too few latencies have been recorded for it.
+func (a *Abandoner) Threshold(rpc string) (time.Duration, bool) {
+	a.mu.Lock()
+	w := a.methods[rpc]
+	if w == nil || len(w.samples) < a.opts.MinSamples {
+		a.mu.Unlock()
+		return 0, false
+	}
+	samples := append([]time.Duration(nil), w.samples...)
+	a.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	index := int(math.Ceil(a.opts.Percentile*float64(len(samples)))) - 1
+	if index < 0 {
+		index = 0
+	}
+
+	threshold := samples[index]
+	if threshold < a.opts.MinThreshold {
+		threshold = a.opts.MinThreshold
+	}
+	return threshold, true
+}
+
+// record adds the latency of a completed attempt to the window of the rpc.
+// Failed attempts are not recorded because errors are often returned faster
+// or slower than responses and would skew the threshold.
+func (a *Abandoner) record(rpc string, latency time.Duration, err error) {
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w := a.methods[rpc]
+	if w == nil {
+		w = &latencyWindow{samples: make([]time.Duration, 0, a.opts.Window)}
+		a.methods[rpc] = w
+	}
+	if len(w.samples) < a.opts.Window {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % a.opts.Window
+}
@@ -0,0 +1,147 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// affinityKey is the context key for the affinity key of an rpc.
+type affinityKey struct{}
+
+// WithAffinityKey returns a context whose rpcs carry the affinity key, such as
+// a user or range ID, that an AffinityPicker maps to a backend.
+func WithAffinityKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+// AffinityKey returns the affinity key set on the context by WithAffinityKey.
+func AffinityKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(affinityKey{}).(string)
+	return key, ok
+}
+
+// AffinityOptions configures an AffinityPicker.
+type AffinityOptions struct {
+	// Key extracts the affinity key of an rpc from its context. It defaults to
+	// AffinityKey.
+	Key func(ctx context.Context) (string, bool)
+
+	// VirtualNodes is the number of points each backend has on the hash ring.
+	// It defaults to DefaultVirtualNodes.
+	VirtualNodes int
+
+	// HotThreshold is the number of rpcs with the same key within a HotWindow
+	// that makes the key hot. Zero disables replication of hot keys.
+	HotThreshold int
+
+	// HotWindow is the interval over which rpcs per key are counted. It
+	// defaults to one second.
+	HotWindow time.Duration
+
+	// HotReplicas is the number of backends the rpcs of a hot key are spread
+	// across, starting at the backend that owns the key and continuing around
+	// the ring. It defaults to 2.
+	HotReplicas int
+}
+
+// AffinityPicker is a Picker that consistently sends the rpcs with the same
+// affinity key to the same backend using consistent hashing, so that backends
+// can keep the state for a key in memory. When backends join or leave, only
+// the keys owned by a leaving backend, or a proportional share of keys taken
+// by a joining backend, change owner. The rpcs of keys that become hot are
+// spread across several backends. Rpcs without a key are sent round robin.
+type AffinityPicker struct {
+	opts     AffinityOptions
+	fallback Picker
+
+	mu      sync.Mutex
+	ring    *hashRing
+	start   time.Time
+	counts  map[string]int
+	hot     map[string]bool
+	rotates map[string]int
+}
+
+// NewAffinityPicker returns an AffinityPicker configured by opts.
+func NewAffinityPicker(opts AffinityOptions) *AffinityPicker {
+	if opts.Key == nil {
+		opts.Key = AffinityKey
+	}
+	if opts.HotWindow <= 0 {
+		opts.HotWindow = time.Second
+	}
+	if opts.HotReplicas <= 0 {
+		opts.HotReplicas = 2
+	}
+	return &AffinityPicker{
+		opts:     opts,
+		fallback: RoundRobin(),
+		counts:   make(map[string]int),
+		hot:      make(map[string]bool),
+		rotates:  make(map[string]int),
+	}
+}
+
+// Pick implements Picker.
+func (p *AffinityPicker) Pick(ctx context.Context, rpc string, candidates []Backend) Backend {
+	key, ok := p.opts.Key(ctx)
+	if !ok {
+		return p.fallback.Pick(ctx, rpc, candidates)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ring := p.ringLocked(candidates)
+	replicas := 1
+	if p.hotLocked(key) {
+		replicas = p.opts.HotReplicas
+	}
+
+	owners := make([]int, 0, replicas)
+	ring.successors(hashString(key), func(index int) bool {
+		owners = append(owners, index)
+		return len(owners) < replicas
+	})
+
+	choice := 0
+	if len(owners) > 1 {
+		choice = p.rotates[key] % len(owners)
+		p.rotates[key]++
+	}
+	return ring.backends[owners[choice]]
+}
+
+// ringLocked returns the ring for the candidates, rebuilding it when their
+// membership changed. It must be called with p.mu held.
+func (p *AffinityPicker) ringLocked(candidates []Backend) *hashRing {
+	if p.ring == nil || p.ring.sig != ringSignature(candidates) {
+		p.ring = newHashRing(candidates, p.opts.VirtualNodes)
+	}
+	return p.ring
+}
+
+// hotLocked counts an rpc with the key and returns true if the key is hot in
+// the current or the previous window. It must be called with p.mu held.
+func (p *AffinityPicker) hotLocked(key string) bool {
+	if p.opts.HotThreshold <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	if now.Sub(p.start) >= p.opts.HotWindow {
+		p.hot = make(map[string]bool)
+		for key, count := range p.counts {
+			if count >= p.opts.HotThreshold {
+				p.hot[key] = true
+			}
+		}
+		p.counts = make(map[string]int)
+		p.rotates = make(map[string]int)
+		p.start = now
+	}
+
+	p.counts[key]++
+	return p.hot[key] || p.counts[key] >= p.opts.HotThreshold
+}
@@ -0,0 +1,81 @@
+package drpcclient
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ConnAttributes label a ClientConn, for example with the region, node ID or
+// purpose of the peer, so that a process with many conns can tell their
+// telemetry apart.
+type ConnAttributes map[string]string
+
+// String returns a stable human readable form of the attributes, such as
+// "node=7 region=east".
+func (a ConnAttributes) String() string {
+	pairs := make([]string, 0, len(a))
+	for name, value := range a {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// clone returns a copy of the attributes.
+func (a ConnAttributes) clone() ConnAttributes {
+	if a == nil {
+		return nil
+	}
+	out := make(ConnAttributes, len(a))
+	for name, value := range a {
+		out[name] = value
+	}
+	return out
+}
+
+// WithConnAttributes returns a DialOption that labels the ClientConn with the
+// attributes. They are returned by ClientConn.Attributes, placed on the
+// context of every rpc before any interceptor runs so that interceptors,
+// metrics and logs can read them with AttributesFromContext, and carried by
+// the ConnEvents and SlowRecords of the ClientConn. It may be passed more than
+// once to add more attributes.
+func WithConnAttributes(attrs map[string]string) DialOption {
+	return func(opt *dialOptions) {
+		if len(attrs) == 0 {
+			return
+		}
+		merged := opt.attrs.clone()
+		if merged == nil {
+			merged = make(ConnAttributes, len(attrs))
+		}
+		for name, value := range attrs {
+			merged[name] = value
+		}
+		opt.attrs = merged
+	}
+}
+
+// Attributes returns a copy of the attributes of the ClientConn.
+func (c *ClientConn) Attributes() ConnAttributes { return c.dopts.attrs.clone() }
+
+// attrsKey is the context key for the attributes of the ClientConn issuing an
+// rpc.
+type attrsKey struct{}
+
+// withAttributes returns a context carrying the attributes of the ClientConn,
+// if it has any.
+func (c *ClientConn) withAttributes(ctx context.Context) context.Context {
+	if len(c.dopts.attrs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, attrsKey{}, c.dopts.attrs)
+}
+
+// AttributesFromContext returns the attributes of the ClientConn issuing the
+// rpc whose context, or a context derived from it, is passed in, and false if
+// the ClientConn has none. The returned attributes must not be modified.
+func AttributesFromContext(ctx context.Context) (ConnAttributes, bool) {
+	attrs, ok := ctx.Value(attrsKey{}).(ConnAttributes)
+	return attrs, ok
+}
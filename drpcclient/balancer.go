@@ -0,0 +1,307 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcsignal"
+)
+
+// Backend is one of the backends a Balancer spreads rpcs across.
+type Backend struct {
+	// Addr identifies the backend. It is reported as the Backend of the Peer
+	// of the rpcs it serves.
+	Addr string
+
+	// Conn is the conn the rpcs sent to the backend are issued on.
+	Conn drpc.Conn
+
+	// Group names the set of backends the backend belongs to, which Routes
+	// send rpcs to. It is empty for the backends serving the other rpcs.
+	Group string
+}
+
+// Picker chooses the backend of each rpc issued through a Balancer. If the
+// Picker also has a method
+//
+//	Done(backend Backend)
+//
+// it is called once each rpc sent to the backend it picked finishes, which for
+// streams is when the context of the stream is done.
+type Picker interface {
+	// Pick returns one of the candidates, which is never empty, for the rpc.
+	Pick(ctx context.Context, rpc string, candidates []Backend) Backend
+}
+
+// donePicker is a Picker that is told when rpcs finish.
+type donePicker interface {
+	Picker
+	Done(backend Backend)
+}
+
+// NewPicker returns a Picker with default options for the load balancing
+// policy named like the LoadBalancingPolicy of a ServiceConfig: "round_robin"
+// or empty for RoundRobin, "ring_hash" for an AffinityPicker,
+// "bounded_load_hash" for a BoundedLoadPicker, and "weighted_round_robin" for
+// a WeightedPicker.
+func NewPicker(policy string) (Picker, error) {
+	switch policy {
+	case "", "round_robin":
+		return RoundRobin(), nil
+	case "ring_hash":
+		return NewAffinityPicker(AffinityOptions{}), nil
+	case "bounded_load_hash":
+		return NewBoundedLoadPicker(BoundedLoadOptions{}), nil
+	case "weighted_round_robin":
+		return NewWeightedPicker(WeightedOptions{}), nil
+	default:
+		return nil, drpc.Error.New("unknown load balancing policy %q", policy)
+	}
+}
+
+// RoundRobin returns a Picker that cycles through the candidates.
+func RoundRobin() Picker { return new(roundRobin) }
+
+type roundRobin struct{ next uint64 }
+
+func (r *roundRobin) Pick(ctx context.Context, rpc string, candidates []Backend) Backend {
+	n := atomic.AddUint64(&r.next, 1) - 1
+	return candidates[n%uint64(len(candidates))]
+}
+
+// Balancer spreads rpcs across a set of backends using a Picker. It is used as
+// the conn of a ClientConn by passing its Dialer to NewClientConnWithOptions,
+// so every rpc flows through the interceptors of the ClientConn before the
+// backend is picked. The Balancer does not close the conns of its backends.
+type Balancer struct {
+	mu       sync.RWMutex
+	picker   Picker
+	policy   string // the LoadBalancingPolicy the picker was made for
+	backends []Backend
+	routes   []Route
+
+	// resolveNow is set by a ResolvedBalancer to resolve its backends again.
+	resolveNow func()
+}
+
+// NewBalancer returns a Balancer that picks between the backends with the
+// picker, or with RoundRobin if picker is nil.
+func NewBalancer(picker Picker, backends ...Backend) *Balancer {
+	if picker == nil {
+		picker = RoundRobin()
+	}
+	return &Balancer{
+		picker:   picker,
+		backends: append([]Backend(nil), backends...),
+	}
+}
+
+// SetBackends replaces the backends used by subsequent rpcs. Rpcs in flight
+// keep the backend they were issued on.
+func (b *Balancer) SetBackends(backends ...Backend) {
+	backends = append([]Backend(nil), backends...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.backends = backends
+}
+
+// SetPicker replaces the Picker used by subsequent rpcs, or sets RoundRobin if
+// picker is nil, for example with the Picker NewPicker returns for the
+// LoadBalancingPolicy of a new ServiceConfig. Rpcs in flight report to the
+// Picker that picked their backend.
+func (b *Balancer) SetPicker(picker Picker) {
+	if picker == nil {
+		picker = RoundRobin()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.picker, b.policy = picker, ""
+}
+
+// SetServiceConfig applies the load balancing policy and backend groups of the
+// service config to subsequent rpcs: a LoadBalancingPolicy other than the one
+// applied last replaces the Picker with the one NewPicker returns for it, and
+// the Routes of the service config replace the routes of the Balancer. A
+// ClientConn dialing through the Balancer calls it with its ServiceConfig
+// whenever one is set.
+func (b *Balancer) SetServiceConfig(sc *ServiceConfig) error {
+	routes := sc.Routes()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sc != nil && sc.LoadBalancingPolicy != "" && sc.LoadBalancingPolicy != b.policy {
+		picker, err := NewPicker(sc.LoadBalancingPolicy)
+		if err != nil {
+			return err
+		}
+		b.picker, b.policy = picker, sc.LoadBalancingPolicy
+	}
+	b.routes = routes
+	return nil
+}
+
+// ResolveNow asks the Resolver of a ResolvedBalancer to resolve the backends
+// again. It does nothing for other Balancers.
+func (b *Balancer) ResolveNow() {
+	if b.resolveNow != nil {
+		b.resolveNow()
+	}
+}
+
+// Backends returns the current backends.
+func (b *Balancer) Backends() []Backend {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return append([]Backend(nil), b.backends...)
+}
+
+// Dialer returns a DialerFunc producing conns that issue every rpc on the
+// backend picked for it. Closing them does not close any backend.
+func (b *Balancer) Dialer() DialerFunc {
+	return func(ctx context.Context) (drpc.Conn, error) {
+		return &balancerConn{b: b}, nil
+	}
+}
+
+// pick chooses the backend for the rpc, skipping the backends the context asks
+// to avoid unless every backend is avoided, and records it as the Peer. The
+// returned func tells the Picker that picked the backend the rpc finished. It
+// is nil if that Picker is not told when rpcs finish.
+func (b *Balancer) pick(ctx context.Context, rpc string) (Backend, func(), error) {
+	b.mu.RLock()
+	picker, backends, routes := b.picker, b.backends, b.routes
+	b.mu.RUnlock()
+
+	if addr, ok := ctx.Value(targetPeerKey{}).(string); ok {
+		for _, backend := range backends {
+			if backend.Addr == addr {
+				setBackendPeer(ctx, backend)
+				return backend, nil, nil
+			}
+		}
+		return Backend{}, nil, drpc.Error.New("balancer has no backend %q", addr)
+	}
+
+	if len(backends) == 0 {
+		return Backend{}, nil, drpc.Error.New("balancer has no backends")
+	}
+	backends, err := route(routes, backends, rpc)
+	if err != nil {
+		return Backend{}, nil, err
+	}
+
+	candidates := backends
+	if avoid, _ := ctx.Value(avoidKey{}).(map[string]bool); len(avoid) > 0 {
+		candidates = make([]Backend, 0, len(backends))
+		for _, backend := range backends {
+			if !avoid[backend.Addr] {
+				candidates = append(candidates, backend)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = backends
+		}
+	}
+
+	backend := picker.Pick(ctx, rpc, candidates)
+	setBackendPeer(ctx, backend)
+
+	var done func()
+	if dp, ok := picker.(donePicker); ok {
+		done = func() { dp.Done(backend) }
+	}
+	return backend, done, nil
+}
+
+// targetPeerKey is the context key for the address of the backend an rpc is
+// pinned to.
+type targetPeerKey struct{}
+
+// WithTargetPeer returns a context that pins the rpcs issued with it through a
+// Balancer to the backend with the address, bypassing the Picker, for example
+// so that follow-up calls reach the server holding the state of an earlier
+// one. The address is typically the Backend of the Peer of that call. The rpcs
+// still flow through every interceptor, and fail if the Balancer has no such
+// backend instead of going to a different one.
+func WithTargetPeer(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, targetPeerKey{}, addr)
+}
+
+// avoidKey is the context key for the set of backend addresses to avoid.
+type avoidKey struct{}
+
+// avoidBackend returns a context that asks a Balancer to avoid the backend with
+// the address, in addition to any already avoided.
+func avoidBackend(ctx context.Context, addr string) context.Context {
+	parent, _ := ctx.Value(avoidKey{}).(map[string]bool)
+	avoid := make(map[string]bool, len(parent)+1)
+	for addr := range parent {
+		avoid[addr] = true
+	}
+	avoid[addr] = true
+	return context.WithValue(ctx, avoidKey{}, avoid)
+}
+
+// balancerConn is a drpc.Conn that issues rpcs through a Balancer.
+type balancerConn struct {
+	b      *Balancer
+	closed drpcsignal.Signal
+}
+
+func (c *balancerConn) Close() error {
+	c.closed.Set(drpc.ClosedError.New("balancer conn closed"))
+	return nil
+}
+
+func (c *balancerConn) Closed() <-chan struct{} { return c.closed.Signal() }
+
+// ResolveNow asks the Balancer to resolve its backends again.
+func (c *balancerConn) ResolveNow() { c.b.ResolveNow() }
+
+// setServiceConfig applies the service config of a ClientConn to the Balancer.
+func (c *balancerConn) setServiceConfig(sc *ServiceConfig) error { return c.b.SetServiceConfig(sc) }
+
+func (c *balancerConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if err, ok := c.closed.Get(); ok {
+		return err
+	}
+	done, err := c.b.send(ctx, rpc, func(ctx context.Context, backend Backend) error {
+		return backend.Conn.Invoke(ctx, rpc, enc, in, out)
+	})
+	if done != nil {
+		done()
+	}
+	return err
+}
+
+func (c *balancerConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	if err, ok := c.closed.Get(); ok {
+		return nil, err
+	}
+	var stream drpc.Stream
+	done, err := c.b.send(ctx, rpc, func(ctx context.Context, backend Backend) (err error) {
+		stream, err = backend.Conn.NewStream(ctx, rpc, enc)
+		return err
+	})
+	if err != nil {
+		if done != nil {
+			done()
+		}
+		return nil, err
+	}
+	if done != nil {
+		go func() {
+			<-stream.Context().Done()
+			done()
+		}()
+	}
+	return stream, nil
+}
@@ -0,0 +1,123 @@
+package drpcclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"storj.io/drpc"
+)
+
+// allocBudgets are the maximum allocations per call allowed on the hot rpc
+// path with a given number of pass-through interceptors. The conn and stream
+// used to measure them do not allocate, so the budgets only cover the
+// ClientConn itself. Raising a budget should be a deliberate decision. Any
+// interceptor costs one allocation to attach the Peer of the rpc.
+var allocBudgets = []struct {
+	interceptors int
+	unary        float64
+	stream       float64
+}{
+	{interceptors: 0, unary: 0, stream: 0},
+	{interceptors: 1, unary: 1, stream: 1},
+	{interceptors: 5, unary: 6, stream: 6},
+}
+
+// TestInterceptorAllocBudgets fails if the unary or stream path allocates more
+// than its budget in allocBudgets.
+func TestInterceptorAllocBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation counts are measured in full runs")
+	}
+
+	for _, budget := range allocBudgets {
+		cc := newBenchClientConn(t, budget.interceptors)
+		ctx := context.Background()
+
+		unary := testing.AllocsPerRun(100, func() {
+			_ = cc.Invoke(ctx, "/service/Method", nopEncoding{}, nil, nil)
+		})
+		assert.LessOrEqual(t, unary, budget.unary, "unary with %d interceptors", budget.interceptors)
+
+		stream := testing.AllocsPerRun(100, func() {
+			_, _ = cc.NewStream(ctx, "/service/Method", nopEncoding{})
+		})
+		assert.LessOrEqual(t, stream, budget.stream, "stream with %d interceptors", budget.interceptors)
+	}
+}
+
+func BenchmarkInvoke(b *testing.B) {
+	for _, budget := range allocBudgets {
+		b.Run(fmt.Sprintf("interceptors=%d", budget.interceptors), func(b *testing.B) {
+			cc := newBenchClientConn(b, budget.interceptors)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_ = cc.Invoke(ctx, "/service/Method", nopEncoding{}, nil, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkNewStream(b *testing.B) {
+	for _, budget := range allocBudgets {
+		b.Run(fmt.Sprintf("interceptors=%d", budget.interceptors), func(b *testing.B) {
+			cc := newBenchClientConn(b, budget.interceptors)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, _ = cc.NewStream(ctx, "/service/Method", nopEncoding{})
+			}
+		})
+	}
+}
+
+// newBenchClientConn returns a ClientConn over a nopConn with n pass-through
+// unary and stream interceptors.
+func newBenchClientConn(tb testing.TB, n int) *ClientConn {
+	var opts []DialOption
+	for i := 0; i < n; i++ {
+		opts = append(opts,
+			WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+				return next(ctx, rpc, enc, in, out, cc)
+			}),
+			WithChainStreamInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, next Streamer) (drpc.Stream, error) {
+				return next(ctx, rpc, enc, cc)
+			}))
+	}
+
+	conn := new(nopConn)
+	cc, err := NewClientConnWithOptions(context.Background(), func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, opts...)
+	assert.NoError(tb, err)
+	tb.Cleanup(func() { _ = cc.Close() })
+	return cc
+}
+
+// nopConn is a drpc.Conn whose rpcs do nothing and do not allocate.
+type nopConn struct {
+	mockDrpcConn
+	stream mockStream
+}
+
+func (c *nopConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	return nil
+}
+
+func (c *nopConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return &c.stream, nil
+}
+
+// nopEncoding is a drpc.Encoding for nil messages.
+type nopEncoding struct{}
+
+func (nopEncoding) Marshal(msg drpc.Message) ([]byte, error)     { return nil, nil }
+func (nopEncoding) Unmarshal(buf []byte, msg drpc.Message) error { return nil }
@@ -0,0 +1,123 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// DefaultLoadFactor is the load factor of a BoundedLoadPicker when none is
+// configured.
+const DefaultLoadFactor = 1.25
+
+// BoundedLoadOptions configures a BoundedLoadPicker.
+type BoundedLoadOptions struct {
+	// Key extracts the affinity key of an rpc from its context. It defaults to
+	// AffinityKey.
+	Key func(ctx context.Context) (string, bool)
+
+	// VirtualNodes is the number of points each backend has on the hash ring.
+	// It defaults to DefaultVirtualNodes.
+	VirtualNodes int
+
+	// LoadFactor bounds the rpcs in flight on any backend to this multiple of
+	// the average, and must be larger than 1. It defaults to
+	// DefaultLoadFactor.
+	LoadFactor float64
+}
+
+// BoundedLoadPicker is a Picker implementing consistent hashing with bounded
+// loads. Like an AffinityPicker, it sends the rpcs with the same affinity key
+// to the backend that owns the key on a consistent hash ring, but a backend
+// that already has more than LoadFactor times the average number of rpcs in
+// flight is skipped for the next one around the ring. Hot keys therefore spill
+// over to neighboring backends instead of overloading their owner, while keys
+// stay on their owner whenever it has capacity. Rpcs without a key start at a
+// rotating point on the ring. Rpcs pinned with WithTargetPeer do not count
+// towards the load.
+type BoundedLoadPicker struct {
+	opts BoundedLoadOptions
+
+	mu    sync.Mutex
+	ring  *hashRing
+	loads map[string]int
+	total int
+	next  uint64
+}
+
+// NewBoundedLoadPicker returns a BoundedLoadPicker configured by opts.
+func NewBoundedLoadPicker(opts BoundedLoadOptions) *BoundedLoadPicker {
+	if opts.Key == nil {
+		opts.Key = AffinityKey
+	}
+	if opts.LoadFactor <= 1 {
+		opts.LoadFactor = DefaultLoadFactor
+	}
+	return &BoundedLoadPicker{
+		opts:  opts,
+		loads: make(map[string]int),
+	}
+}
+
+// Pick implements Picker.
+func (p *BoundedLoadPicker) Pick(ctx context.Context, rpc string, candidates []Backend) Backend {
+	key, ok := p.opts.Key(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !ok {
+		key = strconv.FormatUint(p.next, 10)
+		p.next++
+	}
+
+	if p.ring == nil || p.ring.sig != ringSignature(candidates) {
+		p.ring = newHashRing(candidates, p.opts.VirtualNodes)
+	}
+
+	capacity := int(math.Ceil(p.opts.LoadFactor * float64(p.total+1) / float64(len(candidates))))
+	choice := -1
+	p.ring.successors(hashString(key), func(index int) bool {
+		if p.loads[p.ring.backends[index].Addr] < capacity {
+			choice = index
+			return false
+		}
+		return true
+	})
+	if choice < 0 {
+		// the capacity always leaves room on some backend, but fall back to
+		// the owner rather than fail if the loads are inconsistent.
+		p.ring.successors(hashString(key), func(index int) bool {
+			choice = index
+			return false
+		})
+	}
+
+	backend := p.ring.backends[choice]
+	p.loads[backend.Addr]++
+	p.total++
+	return backend
+}
+
+// Done implements the optional Done method of a Picker.
+func (p *BoundedLoadPicker) Done(backend Backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.loads[backend.Addr] > 0 {
+		p.loads[backend.Addr]--
+		p.total--
+	}
+	if p.loads[backend.Addr] == 0 {
+		delete(p.loads, backend.Addr)
+	}
+}
+
+// Load returns the number of rpcs in flight on the backend with the address.
+func (p *BoundedLoadPicker) Load(addr string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.loads[addr]
+}
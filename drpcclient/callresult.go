@@ -0,0 +1,126 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// CallResult describes how a unary rpc issued with InvokeDetailed went. It is
+// filled in as far as the rpc got, even if it failed.
+type CallResult struct {
+	// Header and Trailer are the metadata the server sent about the response
+	// of the last attempt, if any.
+	Header  map[string]string
+	Trailer map[string]string
+
+	// BytesSent and BytesReceived are the sizes of the encoded request and
+	// response messages of the last attempt.
+	BytesSent     int
+	BytesReceived int
+
+	// Attempts counts the times the rpc was issued on a conn, including
+	// transparent retries and the retries of the service config.
+	Attempts int
+
+	// Duration is how long the rpc took, including every attempt.
+	Duration time.Duration
+
+	// ServerTime is how long the server spent handling the last attempt, as
+	// reported in the drpcmetadata.ServerTime trailer, or zero if it did not
+	// report it.
+	ServerTime time.Duration
+
+	// QueueTime is how much of the ServerTime the last attempt waited in
+	// server side queues, as reported in the drpcmetadata.QueueTime trailer.
+	QueueTime time.Duration
+}
+
+// InvokeDetailed issues the unary rpc like Invoke and returns a CallResult
+// describing it, so that callers do not need to collect the response
+// metadata, sizes and attempts of the rpc with separate options. Waiting for
+// the trailer costs a little latency after the response arrives on conns that
+// support response metadata, such as a *drpcconn.Conn.
+func (c *ClientConn) InvokeDetailed(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) (CallResult, error) {
+	rec := new(callRecorder)
+	start := time.Now()
+	err := c.Invoke(context.WithValue(ctx, callResultKey{}, rec), rpc, enc, in, out)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	result := rec.result
+	result.Duration = time.Since(start)
+	result.ServerTime = trailerDuration(result.Trailer, drpcmetadata.ServerTime)
+	result.QueueTime = trailerDuration(result.Trailer, drpcmetadata.QueueTime)
+	return result, err
+}
+
+// trailerDuration returns the positive duration reported in the trailer with
+// the key, or zero if it is missing or invalid.
+func trailerDuration(trailer map[string]string, key drpcmetadata.Key) time.Duration {
+	value, ok := trailer[key.String()]
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// callResultKey is the context key for the *callRecorder of an rpc.
+type callResultKey struct{}
+
+// callRecorder collects the CallResult of an rpc.
+type callRecorder struct {
+	mu     sync.Mutex
+	result CallResult
+}
+
+// callRecorderFrom returns the recorder on the context, if any.
+func callRecorderFrom(ctx context.Context) *callRecorder {
+	rec, _ := ctx.Value(callResultKey{}).(*callRecorder)
+	return rec
+}
+
+// attempt records an attempt of the rpc. It returns the context and encoding
+// to issue the attempt with, and a func recording how the attempt went once it
+// is done.
+func (r *callRecorder) attempt(ctx context.Context, enc drpc.Encoding) (context.Context, drpc.Encoding, func()) {
+	r.mu.Lock()
+	r.result.Attempts++
+	r.mu.Unlock()
+
+	resp := new(drpcmetadata.Response)
+	counted := &countingEncoding{Encoding: enc}
+	return drpcmetadata.WithResponse(ctx, resp), counted, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.result.Header, r.result.Trailer = resp.Header, resp.Trailer
+		r.result.BytesSent, r.result.BytesReceived = counted.sent, counted.received
+	}
+}
+
+// countingEncoding records the sizes of the messages of an attempt.
+type countingEncoding struct {
+	drpc.Encoding
+	sent     int
+	received int
+}
+
+func (e *countingEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	data, err := e.Encoding.Marshal(msg)
+	e.sent = len(data)
+	return data, err
+}
+
+func (e *countingEncoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	e.received = len(buf)
+	return e.Encoding.Unmarshal(buf, msg)
+}
@@ -0,0 +1,47 @@
+package drpcclient
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CallStats keeps counters of the rpcs issued through an interceptor.
+type CallStats struct {
+	Calls   uint64
+	Errors  uint64
+	Latency time.Duration // total latency of all calls
+}
+
+// Record atomically records a call that took the duration d and returned err.
+func (s *CallStats) Record(d time.Duration, err error) {
+	atomic.AddUint64(&s.Calls, 1)
+	if err != nil {
+		atomic.AddUint64(&s.Errors, 1)
+	}
+	atomic.AddInt64((*int64)(&s.Latency), int64(d))
+}
+
+// AtomicClone returns a copy of the stats that is safe to use concurrently with Record.
+func (s *CallStats) AtomicClone() CallStats {
+	return CallStats{
+		Calls:   atomic.LoadUint64(&s.Calls),
+		Errors:  atomic.LoadUint64(&s.Errors),
+		Latency: time.Duration(atomic.LoadInt64((*int64)(&s.Latency))),
+	}
+}
+
+// ErrorRate returns the fraction of calls that returned an error.
+func (s CallStats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Calls)
+}
+
+// MeanLatency returns the average latency of the calls.
+func (s CallStats) MeanLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Calls)
+}
@@ -0,0 +1,240 @@
+package drpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// CallTrace is a structured record of the phases of a single unary rpc, used
+// to diagnose why one rpc was slow. It is filled in by passing the context
+// returned by WithCallTrace to ClientConn.Invoke, and is complete once Invoke
+// returns. Streams are not traced.
+type CallTrace struct {
+	mu sync.Mutex
+
+	// RPC is the name of the rpc.
+	RPC string
+
+	// Start is when the rpc was issued, and Duration how long it took.
+	Start    time.Time
+	Duration time.Duration
+
+	// FirstByte is how long after Start the first response message arrived,
+	// or zero if none did.
+	FirstByte time.Duration
+
+	// Err is the error the rpc returned, if any.
+	Err error
+
+	// Phases lists the phases of the rpc in the order they started.
+	Phases []TracePhase
+}
+
+// TracePhase is one phase of a traced rpc.
+type TracePhase struct {
+	// Name identifies the phase: "interceptor[i]" for the i'th interceptor,
+	// including everything it calls, "acquire" for obtaining the conn,
+	// including any dial, "invoke" for issuing the rpc on the conn, "marshal"
+	// for encoding the request, and "unmarshal" for decoding the response.
+	// Interceptors add their own phases with RecordPhase, such as "queue" for
+	// the time spent waiting in a drpcpriority.Limiter.
+	Name string
+
+	// Offset is how long after the start of the rpc the phase started.
+	Offset time.Duration
+
+	// Duration is how long the phase took.
+	Duration time.Duration
+
+	// Bytes is the size of the message a marshal or unmarshal phase handled.
+	Bytes int
+}
+
+// callTraceKey is the context key for the *CallTrace of an rpc.
+type callTraceKey struct{}
+
+// WithCallTrace returns a context that records the phases of the unary rpc
+// issued with it into trace. The trace should only be used for one rpc.
+func WithCallTrace(ctx context.Context, trace *CallTrace) context.Context {
+	return context.WithValue(ctx, callTraceKey{}, trace)
+}
+
+// RecordPhase records a phase with the name that started at start and ends
+// now into the trace of the rpc issued with the context, if it is traced, so
+// that interceptors outside of this package can report their own phases.
+func RecordPhase(ctx context.Context, name string, start time.Time) {
+	trace := callTraceFrom(ctx)
+	if trace == nil {
+		return
+	}
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	// keep the phases in the order they started.
+	phase := TracePhase{Name: name, Offset: start.Sub(trace.Start), Duration: time.Since(start)}
+	i := len(trace.Phases)
+	for i > 0 && trace.Phases[i-1].Offset > phase.Offset {
+		i--
+	}
+	trace.Phases = append(trace.Phases, TracePhase{})
+	copy(trace.Phases[i+1:], trace.Phases[i:])
+	trace.Phases[i] = phase
+}
+
+// callTraceFrom returns the trace on the context, if any.
+func callTraceFrom(ctx context.Context) *CallTrace {
+	trace, _ := ctx.Value(callTraceKey{}).(*CallTrace)
+	return trace
+}
+
+// begin resets the trace for a new rpc.
+func (t *CallTrace) begin(rpc string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.RPC, t.Start, t.Duration, t.FirstByte, t.Err, t.Phases = rpc, time.Now(), 0, 0, nil, nil
+}
+
+// end records the result of the rpc.
+func (t *CallTrace) end(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Duration, t.Err = time.Since(t.Start), err
+}
+
+// phase starts a phase and returns a func that ends it. It does nothing if the
+// trace is nil.
+func (t *CallTrace) phase(name string) func(bytes int) {
+	if t == nil {
+		return endNothing
+	}
+
+	t.mu.Lock()
+	start := time.Now()
+	offset := start.Sub(t.Start)
+	t.Phases = append(t.Phases, TracePhase{Name: name, Offset: offset})
+	t.mu.Unlock()
+
+	return func(bytes int) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		// RecordPhase may have inserted phases since, so search for this one.
+		for i := len(t.Phases) - 1; i >= 0; i-- {
+			if p := &t.Phases[i]; p.Name == name && p.Offset == offset {
+				p.Duration = time.Since(start)
+				p.Bytes = bytes
+				break
+			}
+		}
+		if name == "unmarshal" && t.FirstByte == 0 {
+			t.FirstByte = offset
+		}
+	}
+}
+
+// endNothing ends the phases of a nil trace.
+func endNothing(int) {}
+
+// String formats the trace as text with one line per phase.
+func (t *CallTrace) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s took %v", t.RPC, t.Duration)
+	if t.FirstByte > 0 {
+		fmt.Fprintf(&sb, " (first byte after %v)", t.FirstByte)
+	}
+	if t.Err != nil {
+		fmt.Fprintf(&sb, ": %v", t.Err)
+	}
+	for _, p := range t.Phases {
+		fmt.Fprintf(&sb, "\n  +%-12v %-12v %s", p.Offset, p.Duration, p.Name)
+		if p.Bytes > 0 {
+			fmt.Fprintf(&sb, " (%d bytes)", p.Bytes)
+		}
+	}
+	return sb.String()
+}
+
+// MarshalJSON formats the trace as JSON with durations in the format of the
+// Duration type.
+func (t *CallTrace) MarshalJSON() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type phase struct {
+		Name     string   `json:"name"`
+		Offset   Duration `json:"offset"`
+		Duration Duration `json:"duration"`
+		Bytes    int      `json:"bytes,omitempty"`
+	}
+	out := struct {
+		RPC       string    `json:"rpc"`
+		Start     time.Time `json:"start"`
+		Duration  Duration  `json:"duration"`
+		FirstByte Duration  `json:"firstByte,omitempty"`
+		Err       string    `json:"error,omitempty"`
+		Phases    []phase   `json:"phases"`
+	}{
+		RPC:       t.RPC,
+		Start:     t.Start,
+		Duration:  Duration(t.Duration),
+		FirstByte: Duration(t.FirstByte),
+	}
+	if t.Err != nil {
+		out.Err = t.Err.Error()
+	}
+	for _, p := range t.Phases {
+		out.Phases = append(out.Phases, phase{p.Name, Duration(p.Offset), Duration(p.Duration), p.Bytes})
+	}
+	return json.Marshal(out)
+}
+
+// invokeTraced issues the rpc like Invoke, recording its phases into trace.
+func (c *ClientConn) invokeTraced(ctx context.Context, trace *CallTrace, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	trace.begin(rpc)
+	enc = traceEncoding{Encoding: enc, trace: trace}
+
+	invoker := finalInvoker
+	for i := len(c.dopts.unaryInts) - 1; i >= 0; i-- {
+		next, interceptor, name := invoker, c.dopts.unaryInts[i], fmt.Sprintf("interceptor[%d]", i)
+		invoker = func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn) error {
+			defer trace.phase(name)(0)
+			return interceptor(ctx, rpc, enc, in, out, cc, next)
+		}
+	}
+
+	err := invoker(withPeer(ctx), rpc, enc, in, out, c)
+	trace.end(err)
+	return err
+}
+
+// traceEncoding records the marshal and unmarshal phases of a traced rpc.
+type traceEncoding struct {
+	drpc.Encoding
+	trace *CallTrace
+}
+
+func (e traceEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	done := e.trace.phase("marshal")
+	data, err := e.Encoding.Marshal(msg)
+	done(len(data))
+	return data, err
+}
+
+func (e traceEncoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	done := e.trace.phase("unmarshal")
+	err := e.Encoding.Unmarshal(buf, msg)
+	done(len(buf))
+	return err
+}
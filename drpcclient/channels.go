@@ -0,0 +1,94 @@
+package drpcclient
+
+import (
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// MultiplexedConn is a drpc.Conn that carries labeled logical channels over
+// its transport, each with its own flow of frames, so that rpcs on one channel
+// are not blocked behind the large messages of another. Rpcs issued on the
+// MultiplexedConn itself use its default channel. Closing it closes every
+// channel.
+type MultiplexedConn interface {
+	drpc.Conn
+
+	// OpenChannel returns the channel with the label, opening it if needed.
+	OpenChannel(label string) drpc.Conn
+}
+
+// ChannelRoute sends the rpcs matching Prefix to the channel with Label.
+type ChannelRoute struct {
+	// Prefix selects rpcs by name like the Prefix of a Route.
+	Prefix string
+
+	// Label is the label of the channel the rpcs are issued on.
+	Label string
+}
+
+// WithChannels returns a DialOption that issues the rpcs matching the routes
+// on the labeled channels of the conns the ClientConn dials, if they are
+// MultiplexedConns, for example to keep liveness rpcs on a channel apart from
+// bulk data. The route with the longest Prefix matching an rpc applies, and
+// rpcs matching no route use the default channel. Channels are opened when
+// first used on a conn, and conns that are not MultiplexedConns issue every
+// rpc themselves.
+func WithChannels(routes ...ChannelRoute) DialOption {
+	routes = append([]ChannelRoute(nil), routes...)
+	return func(opt *dialOptions) {
+		opt.channels = routes
+	}
+}
+
+// channelSet holds the channels opened on the current conn.
+type channelSet struct {
+	mu     sync.Mutex
+	parent drpc.Conn
+	opened map[string]drpc.Conn
+}
+
+// channel returns the conn the rpc is issued on: the channel of the conn the
+// routes send it to, or the conn itself.
+func (c *ClientConn) channel(conn drpc.Conn, rpc string) drpc.Conn {
+	if len(c.dopts.channels) == 0 {
+		return conn
+	}
+	mc, ok := conn.(MultiplexedConn)
+	if !ok {
+		return conn
+	}
+	label := channelLabel(c.dopts.channels, rpc)
+	if label == "" {
+		return conn
+	}
+
+	cs := &c.chans
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.parent != conn {
+		cs.parent, cs.opened = conn, make(map[string]drpc.Conn)
+	}
+	ch, ok := cs.opened[label]
+	if !ok {
+		ch = mc.OpenChannel(label)
+		cs.opened[label] = ch
+	}
+	return ch
+}
+
+// channelLabel returns the label of the route with the longest Prefix matching
+// the rpc, or empty if none does.
+func channelLabel(routes []ChannelRoute, rpc string) string {
+	var best *ChannelRoute
+	for i := range routes {
+		if (Route{Prefix: routes[i].Prefix}).matches(rpc) && (best == nil || len(routes[i].Prefix) > len(best.Prefix)) {
+			best = &routes[i]
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.Label
+}
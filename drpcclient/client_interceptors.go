@@ -34,4 +34,10 @@ type Streamer func(ctx context.Context, rpc string, enc drpc.Encoding, cc *Clien
 // The interceptor must call `streamer` to proceed with the RPC, unless it intends to short-circuit the call.
 // It should return the stream created by the streamer function or an error if the operation fails. The error should be
 // compatible with the drpcerr package.
+//
+// Canceling the context passed to NewStream cancels the stream directly on the
+// underlying conn rather than through the interceptors, so the server is
+// notified before any interceptor observes the cancellation. Interceptors that
+// wrap the returned stream see it as an error from the next MsgSend or MsgRecv,
+// or as the stream's context being done.
 type StreamClientInterceptor func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error)
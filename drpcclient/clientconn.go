@@ -2,64 +2,653 @@ package drpcclient
 
 import (
 	"context"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcctx"
+	"storj.io/drpc/drpcfeatures"
+	"storj.io/drpc/drpcsession"
+	"storj.io/drpc/drpcsignal"
 )
 
+// ErrClientConnClosed is returned by calls on a ClientConn that has been
+// closed, including calls interrupted by Close, instead of whatever error the
+// underlying conn produces. It is a drpc.ClosedError, so interceptors can
+// recognize it with errors.Is or drpc.ClosedError.Has.
+var ErrClientConnClosed = drpc.ClosedError.New("client connection closed")
+
 // DialerFunc is a function that returns a drpc.Conn or an error.
 type DialerFunc func(ctx context.Context) (drpc.Conn, error)
 
 // ClientConn represents a DRPC client connection, with support for configuring the
 // connection with dial options such as interceptors.
+//
+// A ClientConn dials its underlying conn itself and replaces it when it goes
+// idle or fails, so it no longer embeds the underlying drpc.Conn. It still
+// implements drpc.Conn, and code that used the embedded Conn field for the
+// methods of a concrete conn should use the Conn method instead.
 type ClientConn struct {
-	drpc.Conn
-	dopts dialOptions
+	dialer DialerFunc
+	dopts  dialOptions
+	rt     atomic.Pointer[runtimeOptions]
+	closed drpcsignal.Chan
+
+	mu     sync.Mutex
+	conn   drpc.Conn
+	connCh chan struct{} // closed with conn, created by Closed
+	state  State
+	active int
+	idle   drpcclock.Timer
+	peer   drpcfeatures.Set
+
+	// dialing is closed once the dial in flight, if any, finishes, and
+	// cancelDial aborts it.
+	dialing    chan struct{}
+	cancelDial context.CancelFunc
+
+	hsStats handshakeStats
+	chans   channelSet
 }
 
 // NewClientConnWithOptions creates a new ClientConn with the specified dial options
 // and dialer function. The dialer function is used to obtain the underlying drpc.Conn,
 // either from a pool or a concrete connection.
 func NewClientConnWithOptions(ctx context.Context, dialer DialerFunc, opts ...DialOption) (*ClientConn, error) {
-	conn, err := dialer(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	clientConn := &ClientConn{
-		Conn:  conn,
-		dopts: defaultDialOptions(),
+		dialer: dialer,
+		dopts:  defaultDialOptions(),
+		state:  Idle,
 	}
 	for _, opt := range opts {
 		opt(&clientConn.dopts)
 	}
 	clientConn.initInterceptors()
+	clientConn.rt.Store(&clientConn.dopts.runtime)
+
+	clientConn.mu.Lock()
+	defer clientConn.mu.Unlock()
+
+	if err := clientConn.connectLocked(ctx); err != nil {
+		return nil, err
+	}
+	clientConn.resetIdleLocked()
+
 	return clientConn, nil
 }
 
+// runtime returns the current runtime options.
+func (c *ClientConn) runtime() *runtimeOptions { return c.rt.Load() }
+
+// clock returns the clock of the ClientConn's timers.
+func (c *ClientConn) clock() drpcclock.Clock { return drpcclock.Or(c.dopts.clock) }
+
+// ServiceConfig returns the service config currently applied to calls, or nil
+// if there is none.
+func (c *ClientConn) ServiceConfig() *ServiceConfig { return c.runtime().config }
+
+// UpdateOptions applies the options to subsequent calls without redialing. Only
+// the options documented as changeable may be passed; if any other option is
+// passed, an error is returned and no option is applied. In-flight calls keep
+// the options they started with.
+func (c *ClientConn) UpdateOptions(ctx context.Context, opts ...DialOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == Shutdown {
+		return ErrClientConnClosed
+	}
+
+	if !onlyRuntime(opts) {
+		return drpc.Error.New("option cannot be changed at runtime")
+	}
+	updated := c.dopts
+	updated.runtime = *c.runtime()
+	for _, opt := range opts {
+		opt(&updated)
+	}
+	if c.conn != nil && updated.runtime.config != c.runtime().config {
+		if err := configureConn(c.conn, updated.runtime.config); err != nil {
+			return err
+		}
+	}
+
+	c.rt.Store(&updated.runtime)
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	c.resetIdleLocked()
+	return nil
+}
+
+// connectLocked dials a new underlying conn and publishes it, transitioning
+// to the Ready state. It must be called with c.mu held, which it releases
+// while dialing in the Connecting state, so that a slow dial does not block
+// Close, State or other calls, which wait on c.dialing for its result
+// instead. Close aborts the dial.
+func (c *ClientConn) connectLocked(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	dialing := make(chan struct{})
+	c.dialing, c.cancelDial = dialing, cancel
+	c.setStateLocked(Connecting)
+
+	c.mu.Unlock()
+	conn, peer, err := c.dial(ctx)
+	cancel()
+	c.mu.Lock()
+
+	c.dialing, c.cancelDial = nil, nil
+	close(dialing)
+
+	if c.state == Shutdown {
+		if conn != nil {
+			_ = conn.Close()
+		}
+		return ErrClientConnClosed
+	}
+	if err == nil {
+		if err = configureConn(conn, c.runtime().config); err != nil {
+			_ = conn.Close()
+		}
+	}
+	if err != nil {
+		c.setStateLocked(Idle)
+		return err
+	}
+	c.conn, c.connCh, c.peer = conn, nil, peer
+	c.setStateLocked(Ready)
+	return nil
+}
+
+// configureConn applies the service config to the Balancer the conn dials
+// through, if any, so that its LoadBalancingPolicy and BackendGroups take
+// effect without configuring the Balancer separately.
+func configureConn(conn drpc.Conn, sc *ServiceConfig) error {
+	if bc, ok := conn.(interface{ setServiceConfig(*ServiceConfig) error }); ok && sc != nil {
+		return bc.setServiceConfig(sc)
+	}
+	return nil
+}
+
+// dial dials a new underlying conn, negotiates features with the peer if any
+// are configured, refusing peers of another cluster and limiting the frames
+// written to the MaxFrameSize the peer advertised, and authenticates the conn
+// if a prover is configured, bounding the handshake by the handshake timeout.
+// It returns the conn and the features of its peer.
+func (c *ClientConn) dial(ctx context.Context) (drpc.Conn, drpcfeatures.Set, error) {
+	start := time.Now()
+	c.emit(ConnEvent{Type: DialStart})
+
+	dctx, hs := withHandshake(ctx, &c.dopts, &c.hsStats)
+	dctx = withSocketOptions(dctx, c.dopts.socket)
+	conn, err := c.dialer(dctx)
+	if err != nil {
+		c.emit(ConnEvent{Type: DialFailure, Err: err, Duration: time.Since(start)})
+		return nil, nil, err
+	}
+
+	local := c.dopts.features
+	if c.dopts.clusterID != "" {
+		local = local.Clone()
+		local[drpcfeatures.ClusterID] = c.dopts.clusterID
+	}
+
+	var peer drpcfeatures.Set
+	if local != nil {
+		err = hs.run(ctx, "feature negotiation", func(ctx context.Context) (err error) {
+			peer, err = drpcfeatures.Negotiate(ctx, conn, local)
+			return err
+		})
+		if err == nil {
+			err = drpcfeatures.CheckCluster(local, peer)
+		}
+		if err != nil {
+			_ = conn.Close()
+			c.emit(ConnEvent{Type: DialFailure, Err: err, Duration: time.Since(start)})
+			return nil, nil, err
+		}
+		if limiter, ok := conn.(drpcfeatures.FrameLimiter); ok {
+			drpcfeatures.LimitFrameSize(limiter, peer)
+		}
+	}
+
+	if c.dopts.session != nil {
+		err = hs.run(ctx, "session authentication", func(ctx context.Context) error {
+			return drpcsession.Authenticate(ctx, conn, c.dopts.session)
+		})
+		if err != nil {
+			_ = conn.Close()
+			c.emit(ConnEvent{Type: DialFailure, Err: err, Duration: time.Since(start)})
+			return nil, nil, err
+		}
+	}
+
+	c.emit(ConnEvent{Type: DialSuccess, Duration: time.Since(start)})
+	return conn, peer, nil
+}
+
+// PeerFeatures returns the features negotiated with the peer of the most
+// recently dialed conn. It is empty if no features were configured with
+// WithFeatures or WithClusterID or the peer does not support negotiation.
+func (c *ClientConn) PeerFeatures() drpcfeatures.Set {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.peer.Clone()
+}
+
+// ResolveNow asks the resolver behind the ClientConn's conn, such as the
+// Resolver of a ResolvedBalancer it dials through, to resolve its backends
+// again, for example after learning out of band that they changed. It does
+// nothing if the conn has no resolver or is not dialed.
+func (c *ClientConn) ResolveNow() {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if r, ok := conn.(interface{ ResolveNow() }); ok {
+		r.ResolveNow()
+	}
+}
+
+// State returns the current connectivity state of the ClientConn.
+func (c *ClientConn) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+// Close closes the ClientConn and the underlying conn, if any.
+func (c *ClientConn) Close() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == Shutdown {
+		return nil
+	}
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	if c.cancelDial != nil {
+		c.cancelDial()
+	}
+	if c.conn != nil {
+		err = c.conn.Close()
+		c.conn, c.connCh = nil, nil
+	}
+	c.setStateLocked(Shutdown)
+	c.closed.Close()
+	c.emit(ConnEvent{Type: Close, Err: err})
+	return err
+}
+
+// Closed returns a channel that is closed once the ClientConn is closed or the
+// underlying conn it currently uses is, like the Closed method of the
+// underlying conn. A ClientConn whose underlying conn closed, for example
+// because the peer went away or the ClientConn went idle, dials a new one on
+// the next rpc, after which Closed returns a new channel.
+func (c *ClientConn) Closed() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return c.closed.Get()
+	}
+	if c.connCh == nil {
+		connCh, done := make(chan struct{}), c.conn.Closed()
+		go func() {
+			select {
+			case <-done:
+			case <-c.closed.Get():
+			}
+			close(connCh)
+		}()
+		c.connCh = connCh
+	}
+	return c.connCh
+}
+
+// Conn returns the underlying conn the ClientConn currently uses, or nil if it
+// has none, such as while it is idle, for the methods of a concrete conn that
+// drpc.Conn does not have. Rpcs should be issued on the ClientConn instead, so
+// that they go through its interceptors.
+func (c *ClientConn) Conn() drpc.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.conn
+}
+
+// closedErr returns ErrClientConnClosed in place of err if the ClientConn has
+// been closed, so that calls interrupted by Close fail the same way as calls
+// started after it.
+func (c *ClientConn) closedErr(err error) error {
+	select {
+	case <-c.closed.Get():
+		return ErrClientConnClosed
+	default:
+		return err
+	}
+}
+
+// acquire returns the underlying conn, dialing a new one if the ClientConn
+// went idle or waiting for the dial in flight, or the conn the context
+// overrides it with, and marks an RPC as active so that the idle timer does
+// not fire. Every successful call must be paired with a call to release.
+func (c *ClientConn) acquire(ctx context.Context) (drpc.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	override, _ := ctx.Value(attemptConnKey{}).(drpc.Conn)
+	for c.conn == nil && override == nil {
+		if c.state == Shutdown {
+			return nil, ErrClientConnClosed
+		}
+		if dialing := c.dialing; dialing != nil {
+			c.mu.Unlock()
+			select {
+			case <-dialing:
+			case <-ctx.Done():
+				c.mu.Lock()
+				return nil, ctx.Err()
+			}
+			c.mu.Lock()
+			continue
+		}
+		if err := c.connectLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.state == Shutdown {
+		return nil, ErrClientConnClosed
+	}
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	c.active++
+	if override != nil {
+		return override, nil
+	}
+	return c.conn, nil
+}
+
+// attemptConnKey is the context key for the conn overriding the underlying
+// conn of a ClientConn.
+type attemptConnKey struct{}
+
+// withAttemptConn returns a context that issues the attempts of the rpcs
+// passed down the interceptor chain with it on the conn instead of the
+// underlying conn of the ClientConn, so that interceptors choosing the conn
+// still run the interceptors after them.
+func withAttemptConn(ctx context.Context, conn drpc.Conn) context.Context {
+	return context.WithValue(ctx, attemptConnKey{}, conn)
+}
+
+// release marks an RPC started by acquire as finished.
+func (c *ClientConn) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	c.resetIdleLocked()
+}
+
+// resetIdleLocked arms the idle timer if an idle timeout is configured and
+// there are no active RPCs. It must be called with c.mu held.
+func (c *ClientConn) resetIdleLocked() {
+	timeout := c.runtime().idleTimeout
+	if timeout <= 0 || c.active > 0 || c.state != Ready {
+		return
+	}
+	if c.idle == nil {
+		c.idle = c.clock().AfterFunc(timeout, c.enterIdle)
+	} else {
+		c.idle.Reset(timeout)
+	}
+}
+
+// enterIdle closes the underlying conn and transitions to the Idle state if
+// no RPCs have started since the idle timer was armed. Closing a pooled conn
+// returns its resources to the pool.
+func (c *ClientConn) enterIdle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active > 0 || c.state != Ready || c.conn == nil {
+		return
+	}
+	c.emit(ConnEvent{Type: Drain})
+	_ = c.conn.Close()
+	c.conn, c.connCh = nil, nil
+	c.setStateLocked(Idle)
+}
+
 // finalInvoker returns a UnaryInvoker which executes at the end in an interceptor chain.
 func finalInvoker(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn) error {
-	return cc.Conn.Invoke(ctx, rpc, enc, in, out)
+	mc := cc.runtime().config.methodConfig(rpc)
+	if mc == nil {
+		return cc.invokeOnce(ctx, rpc, enc, in, out)
+	}
+
+	buf, err := cc.dopts.bufferPool.Marshal(in, enc)
+	if err != nil {
+		return err
+	}
+	defer cc.dopts.bufferPool.Put(buf)
+
+	data := *buf
+	if mc.MaxRequestMessageBytes > 0 && len(data) > mc.MaxRequestMessageBytes {
+		return drpc.Error.New("request message too large: %d > %d", len(data), mc.MaxRequestMessageBytes)
+	}
+
+	policy := mc.RetryPolicy
+	if SkipInterceptor(ctx, RetryHint) {
+		policy = nil
+	}
+
+	enc = limitedEncoding{Encoding: enc, data: data, maxResponse: mc.MaxResponseMessageBytes}
+	return withRetries(ctx, cc.clock(), policy, func() error {
+		return cc.invokeOnce(ctx, rpc, enc, in, out)
+	})
 }
 
-func (c *ClientConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+// invokeOnce issues the rpc on the underlying conn, transparently issuing it
+// again if it failed before being sent.
+func (c *ClientConn) invokeOnce(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	ctx = c.propagateDeadline(ctx)
+	if err := c.checkMetadataSize(ctx); err != nil {
+		return err
+	}
+
+	unsent, err := c.invokeAttempt(ctx, rpc, enc, in, out)
+	if retryUnsent(ctx, unsent, err) {
+		_, err = c.invokeAttempt(ctx, rpc, enc, in, out)
+	}
+	return err
+}
+
+// invokeAttempt issues the rpc on the underlying conn once. The returned bool
+// is true if it failed before being sent.
+func (c *ClientConn) invokeAttempt(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) (unsent bool, err error) {
+	trace := callTraceFrom(ctx)
+	acquired := trace.phase("acquire")
+	conn, unsent, err := c.acquireOpen(ctx)
+	acquired(0)
+	if err != nil {
+		return unsent, err
+	}
+	defer c.release()
+	setPeer(ctx, conn)
+
+	if rec := callRecorderFrom(ctx); rec != nil {
+		var done func()
+		ctx, enc, done = rec.attempt(ctx, enc)
+		defer done()
+	}
+
+	defer trace.phase("invoke")(0)
+	if err := c.channel(conn, rpc).Invoke(ctx, rpc, enc, in, out); err != nil {
+		return UnsentError.Has(err), c.closedErr(err)
+	}
+	return false, nil
+}
+
+// Invoke issues the rpc through the configured unary interceptors.
+func (c *ClientConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) (err error) {
+	ctx = c.withAttributes(ctx)
+	ctx, task := c.startTask(ctx, rpc)
+	if task != nil {
+		defer func() { endTask(ctx, task, err) }()
+	}
+	if c.dopts.pprofLabels {
+		pprof.Do(ctx, drpcctx.PprofLabels(rpc), func(ctx context.Context) {
+			err = c.invokeEvents(ctx, rpc, enc, in, out)
+		})
+		return err
+	}
+	return c.invokeEvents(ctx, rpc, enc, in, out)
+}
+
+// invokeEvents issues the rpc, emitting its start and end to the listeners.
+func (c *ClientConn) invokeEvents(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if len(c.dopts.listeners) == 0 {
+		return c.translateError(rpc, c.invoke(ctx, rpc, enc, in, out))
+	}
+
+	start := time.Now()
+	c.emit(ConnEvent{Type: RPCStart, RPC: rpc})
+	err := c.translateError(rpc, c.invoke(ctx, rpc, enc, in, out))
+	c.emit(ConnEvent{Type: RPCEnd, RPC: rpc, Err: err, Duration: time.Since(start)})
+	return err
+}
+
+// invoke issues the rpc through the configured unary interceptors.
+func (c *ClientConn) invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	rt := c.runtime()
+	if mc := rt.config.methodConfig(rpc); mc != nil && mc.Timeout > 0 {
+		deadline := time.Now().Add(time.Duration(mc.Timeout))
+		if current, ok := ctx.Deadline(); !ok || deadline.Before(current) {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+	}
+	if rt.unaryTimeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, rt.unaryTimeout)
+			defer cancel()
+		}
+	}
+
+	if trace := callTraceFrom(ctx); trace != nil {
+		return c.invokeTraced(ctx, trace, rpc, enc, in, out)
+	}
 	if c.dopts.unaryInt != nil {
+		// only interceptors can observe the peer, so only pay for it with them.
+		ctx = withPeer(ctx)
 		return c.dopts.unaryInt(ctx, rpc, enc, in, out, c, finalInvoker)
 	}
-	return c.Conn.Invoke(ctx, rpc, enc, in, out)
+	return finalInvoker(ctx, rpc, enc, in, out, c)
 }
 
 // finalStreamer returns a Streamer which executes at the end in an interceptor chain.
 func finalStreamer(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn) (drpc.Stream, error) {
-	return cc.Conn.NewStream(ctx, rpc, enc)
+	ctx = cc.propagateDeadline(ctx)
+	if err := cc.checkMetadataSize(ctx); err != nil {
+		return nil, err
+	}
+
+	stream, unsent, err := cc.streamAttempt(ctx, rpc, enc)
+	if retryUnsent(ctx, unsent, err) {
+		stream, _, err = cc.streamAttempt(ctx, rpc, enc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cc.setFlushDelay(ctx, stream)
+	cc.startKeepalive(stream)
+
+	// the stream keeps the conn active until it is finished. without an idle
+	// timeout nothing observes the active count, so avoid the goroutine.
+	if cc.runtime().idleTimeout <= 0 {
+		cc.release()
+	} else {
+		go func() {
+			<-stream.Context().Done()
+			cc.release()
+		}()
+	}
+	return stream, nil
+}
+
+// streamAttempt opens the stream on the underlying conn once, acquiring the
+// conn until the caller releases it. The returned bool is true if it failed
+// before being sent.
+func (c *ClientConn) streamAttempt(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, bool, error) {
+	conn, unsent, err := c.acquireOpen(ctx)
+	if err != nil {
+		return nil, unsent, err
+	}
+	setPeer(ctx, conn)
+
+	stream, err := c.channel(conn, rpc).NewStream(ctx, rpc, enc)
+	if err != nil {
+		c.release()
+		return nil, UnsentError.Has(err), c.closedErr(err)
+	}
+	return stream, false, nil
+}
+
+// NewStream begins a streaming rpc through the configured stream interceptors.
+func (c *ClientConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (stream drpc.Stream, err error) {
+	ctx = c.withAttributes(ctx)
+	ctx, task := c.startTask(ctx, rpc)
+	if task != nil {
+		defer func() { endStreamTask(ctx, task, stream, err) }()
+	}
+	if c.dopts.pprofLabels {
+		pprof.Do(ctx, drpcctx.PprofLabels(rpc), func(ctx context.Context) {
+			stream, err = c.newStreamEvents(ctx, rpc, enc)
+		})
+		return stream, err
+	}
+	return c.newStreamEvents(ctx, rpc, enc)
+}
+
+// newStreamEvents begins a streaming rpc, emitting its start and end to the
+// listeners.
+func (c *ClientConn) newStreamEvents(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	if len(c.dopts.listeners) == 0 {
+		return c.newStream(ctx, rpc, enc)
+	}
+
+	start := time.Now()
+	c.emit(ConnEvent{Type: RPCStart, RPC: rpc, Stream: true})
+	stream, err := c.newStream(ctx, rpc, enc)
+	c.streamEnd(rpc, start, stream, err)
+	return stream, err
 }
 
-func (c *ClientConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+// newStream begins a streaming rpc through the configured stream interceptors.
+func (c *ClientConn) newStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	var stream drpc.Stream
+	var err error
 	if c.dopts.streamInt != nil {
-		return c.dopts.streamInt(ctx, rpc, enc, c, finalStreamer)
+		ctx = withPeer(ctx)
+		stream, err = c.dopts.streamInt(ctx, rpc, enc, c, finalStreamer)
+	} else {
+		stream, err = finalStreamer(ctx, rpc, enc, c)
 	}
-	return c.Conn.NewStream(ctx, rpc, enc)
+	return c.translateStream(rpc, stream, err)
 }
 
 func (c *ClientConn) initInterceptors() {
+	traceInterceptorRegions(&c.dopts)
 	chainUnaryClientInterceptors(c)
 	chainStreamClientInterceptors(c)
 }
@@ -1,13 +1,39 @@
 package drpcclient
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
 	"github.com/stretchr/testify/assert"
+
 	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpchttp"
+	"storj.io/drpc/drpcmetadata"
 	"storj.io/drpc/drpcpool"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpcsignal"
 	"storj.io/drpc/drpctest"
-	"testing"
-	"time"
 )
 
 // Dummy encoding, which assumes the drpc.Message is a *string.
@@ -131,70 +157,2262 @@ func TestChainStreamClientInterceptors(t *testing.T) {
 	assert.Equal(t, expected, interceptorCalls)
 }
 
-func recordUnaryInterceptor(name string, calls *[]string) UnaryClientInterceptor {
-	return func(ctx context.Context, method string, enc drpc.Encoding,
-		in, out drpc.Message, conn *ClientConn, invoker UnaryInvoker) error {
-		*calls = append(*calls, name+"_before")
-		err := invoker(ctx, method, enc, in, out, conn)
-		*calls = append(*calls, name+"_after")
-		return err
+func TestIdleTimeoutRedialsLazily(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	dials := 0
+	dialer := func(context.Context) (drpc.Conn, error) {
+		dials++
+		return &mockDrpcConn{}, nil
 	}
+
+	cc, err := NewClientConnWithOptions(ctx, dialer, WithIdleTimeout(10*time.Millisecond))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+	assert.Equal(t, Ready, cc.State())
+
+	assert.Eventually(t, func() bool { return cc.State() == Idle }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, dials)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.Equal(t, 2, dials)
+	assert.Equal(t, Ready, cc.State())
+
+	assert.NoError(t, cc.Close())
+	assert.Equal(t, Shutdown, cc.State())
+	assert.Error(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
 }
 
-func recordStreamInterceptor(name string, calls *[]string) StreamClientInterceptor {
-	return func(ctx context.Context, rpc string, enc drpc.Encoding, conn *ClientConn, next Streamer) (drpc.Stream, error) {
-		*calls = append(*calls, name+"_before")
-		stream, err := next(ctx, rpc, enc, conn)
-		if err == nil {
-			*calls = append(*calls, name+"_after")
+func TestCloseAbortsDial(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	var dials int32
+	aborted := make(chan error, 1)
+	dialer := func(ctx context.Context) (drpc.Conn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return &mockDrpcConn{}, nil
 		}
-		return stream, err
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return nil, ctx.Err()
 	}
+
+	cc, err := NewClientConnWithOptions(ctx, dialer)
+	assert.NoError(t, err)
+	cc.enterIdle()
+
+	// rpcs wait for the hung dial without holding up the rest of the conn.
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		ctx.Run(func(ctx context.Context) {
+			in, out := "foobar", ""
+			errs <- cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out)
+		})
+	}
+	assert.Eventually(t, func() bool { return cc.State() == Connecting }, time.Second, time.Millisecond)
+
+	assert.NoError(t, cc.Close())
+	assert.ErrorIs(t, <-aborted, context.Canceled)
+	assert.ErrorIs(t, <-errs, ErrClientConnClosed)
+	assert.ErrorIs(t, <-errs, ErrClientConnClosed)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dials))
+	assert.Equal(t, Shutdown, cc.State())
 }
 
-type mockDrpcConn struct{}
+func TestShadowUnaryInterceptorMirrorsRequests(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
 
-func (m *mockDrpcConn) Unblocked() <-chan struct{} {
-	return nil
+	shadow := &recordingConn{invoked: make(chan string, 1)}
+	dialer := func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}
+
+	cc, err := NewClientConnWithOptions(ctx, dialer,
+		WithChainUnaryInterceptor(ShadowUnaryInterceptor(shadow, ShadowOptions{Percent: 100})))
+	assert.NoError(t, err)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.Equal(t, "mocked response for request: foobar", out)
+
+	select {
+	case data := <-shadow.invoked:
+		assert.Equal(t, "foobar", data)
+	case <-time.After(time.Second):
+		t.Fatal("request was not mirrored")
+	}
 }
 
-func (m *mockDrpcConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
-	*out.(*string) = "mocked response for request: " + *in.(*string)
-	return nil
+func TestSplitRoutesByWeight(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	canary := &recordingConn{invoked: make(chan string, 10)}
+	split := NewSplit(&mockDrpcConn{}, canary, 0)
+	dialer := func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}
+
+	cc, err := NewClientConnWithOptions(ctx, dialer, WithChainUnaryInterceptor(split.UnaryInterceptor()))
+	assert.NoError(t, err)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	split.SetCanaryPercent(100)
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+
+	assert.Equal(t, uint64(1), split.Stats(Control).Calls)
+	assert.Equal(t, uint64(1), split.Stats(Canary).Calls)
+	assert.Len(t, canary.invoked, 1)
 }
 
-func (m *mockDrpcConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
-	return &mockStream{name: rpc}, nil
+func TestSplitRunsLaterInterceptors(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	stream := &mockStream{name: "canary"}
+	canary := &streamConn{stream: stream}
+	split := NewSplit(&mockDrpcConn{}, canary, 100)
+
+	var unary, streams int
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	},
+		WithChainUnaryInterceptor(split.UnaryInterceptor(),
+			func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+				unary++
+				return next(ctx, rpc, enc, in, out, cc)
+			}),
+		WithChainStreamInterceptor(split.StreamInterceptor(),
+			func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+				streams++
+				return streamer(ctx, rpc, enc, cc)
+			}))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	got, err := cc.NewStream(ctx, "TestStream", testEncoding{})
+	assert.NoError(t, err)
+
+	// the interceptors after the split run, and the rpcs go to the canary.
+	assert.Equal(t, 1, unary)
+	assert.Equal(t, 1, streams)
+	assert.Equal(t, drpc.Stream(stream), got)
+	assert.Equal(t, uint64(2), split.Stats(Canary).Calls)
+	assert.Equal(t, uint64(0), split.Stats(Control).Calls)
 }
 
-func (m *mockDrpcConn) Close() error {
-	return nil
+func TestUpdateOptions(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	dialer := func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}
+
+	var deadline time.Time
+	capture := func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		deadline, _ = ctx.Deadline()
+		return next(ctx, rpc, enc, in, out, cc)
+	}
+
+	cc, err := NewClientConnWithOptions(ctx, dialer, WithChainUnaryInterceptor(capture))
+	assert.NoError(t, err)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.True(t, deadline.IsZero())
+
+	assert.NoError(t, cc.UpdateOptions(ctx, WithUnaryTimeout(time.Minute), WithIdleTimeout(time.Hour)))
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.False(t, deadline.IsZero())
+
+	assert.Error(t, cc.UpdateOptions(ctx, WithChainUnaryInterceptor(capture)))
+	assert.NoError(t, cc.Close())
 }
 
-func (m *mockDrpcConn) Closed() <-chan struct{} {
-	return nil
+func TestUpdateOptionsRejectsReplacedStatic(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	dialer := func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}
+
+	var ran []string
+	intercept := func(name string) UnaryClientInterceptor {
+		return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+			ran = append(ran, name)
+			return next(ctx, rpc, enc, in, out, cc)
+		}
+	}
+	verify := func(tls.ConnectionState, PeerTarget) error { return nil }
+
+	cc, err := NewClientConnWithOptions(ctx, dialer,
+		WithChainUnaryInterceptor(intercept("old")), WithVerifyPeer(verify))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	// replacing a func valued option is rejected, and the runtime options
+	// passed with it are not applied.
+	assert.Error(t, cc.UpdateOptions(ctx, WithChainUnaryInterceptor(intercept("new")), WithUnaryTimeout(time.Minute)))
+	assert.Error(t, cc.UpdateOptions(ctx, WithVerifyPeer(verify)))
+	assert.Equal(t, time.Duration(0), cc.runtime().unaryTimeout)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.Equal(t, []string{"old"}, ran)
 }
 
-type mockStream struct {
-	name string
+func TestMethodMatcherFiltersInterceptors(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	dialer := func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}
+
+	var calls []string
+	cc, err := NewClientConnWithOptions(ctx, dialer, WithChainUnaryInterceptor(
+		MatchPrefix("/kv.KV/").Unary(recordUnaryInterceptor("prefix", &calls)),
+		ForMethods("/kv.KV/Put").Unary(recordUnaryInterceptor("exact", &calls)),
+	))
+	assert.NoError(t, err)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "/kv.KV/Get", testEncoding{}, &in, &out))
+	assert.NoError(t, cc.Invoke(ctx, "/kv.KV/Put", testEncoding{}, &in, &out))
+	assert.NoError(t, cc.Invoke(ctx, "/other.Svc/Put", testEncoding{}, &in, &out))
+
+	assert.Equal(t, []string{
+		"prefix_before", "prefix_after",
+		"prefix_before", "exact_before", "exact_after", "prefix_after",
+	}, calls)
 }
 
-func (m *mockStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
-	return nil
+func TestFlushDelay(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	stream := &delayStream{delay: -1}
+	conn := &streamConn{stream: stream}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, WithFlushDelay(time.Millisecond))
+	assert.NoError(t, err)
+
+	_, err = cc.NewStream(ctx, "TestRPC", testEncoding{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Millisecond, stream.delay)
+
+	_, err = cc.NewStream(WithCallFlushDelay(ctx, 0), "TestRPC", testEncoding{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), stream.delay)
 }
 
-func (m *mockStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
-	return nil
+func TestRecyclerReusesResponses(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	})
+	assert.NoError(t, err)
+
+	resets := 0
+	r := NewRecycler(func() *string { return new(string) }, func(s *string) {
+		resets++
+		*s = ""
+	})
+
+	in := "foo"
+	out, err := r.Invoke(ctx, cc, "TestMethod", testEncoding{}, &in)
+	assert.NoError(t, err)
+	assert.Equal(t, "mocked response for request: foo", *out)
+
+	r.Put(out)
+	assert.Equal(t, 1, resets)
+	assert.Equal(t, "", *out)
+
+	// a recycled message is only ever handed to one owner at a time.
+	a, b := r.Get(), r.Get()
+	assert.True(t, a != b)
 }
 
-func (m *mockStream) Close() error {
-	return nil
+func TestClosedClientConn(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var seen []error
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		err := next(ctx, rpc, enc, in, out, cc)
+		seen = append(seen, err)
+		return err
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, cc.Close())
+
+	in, out := "foo", ""
+	assert.ErrorIs(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out), ErrClientConnClosed)
+	_, err = cc.NewStream(ctx, "TestRPC", testEncoding{})
+	assert.ErrorIs(t, err, ErrClientConnClosed)
+	assert.ErrorIs(t, cc.UpdateOptions(ctx, WithUnaryTimeout(time.Second)), ErrClientConnClosed)
+
+	// interceptors observe the same error.
+	assert.Equal(t, []error{ErrClientConnClosed}, seen)
+	assert.True(t, drpc.ClosedError.Has(seen[0]))
 }
 
-func (m *mockStream) Context() context.Context {
-	return context.TODO()
+func TestClosedCoversCurrentConn(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var dials []*closableConn
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		dials = append(dials, &closableConn{})
+		return dials[len(dials)-1], nil
+	})
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+	assert.Equal(t, drpc.Conn(dials[0]), cc.Conn())
+
+	// the channel closes with the current underlying conn.
+	closed := cc.Closed()
+	assert.NoError(t, dials[0].Close())
+	assert.Eventually(t, func() bool { return isDone(closed) }, time.Second, time.Millisecond)
+
+	// going idle drops the underlying conn until the next rpc redials.
+	cc.enterIdle()
+	assert.Nil(t, cc.Conn())
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.Len(t, dials, 2)
+	assert.Equal(t, drpc.Conn(dials[1]), cc.Conn())
+
+	// closing the ClientConn closes the channel for the new conn too.
+	closed = cc.Closed()
+	assert.False(t, isDone(closed))
+	assert.NoError(t, cc.Close())
+	assert.Eventually(t, func() bool { return isDone(closed) }, time.Second, time.Millisecond)
+	assert.True(t, isDone(cc.Closed()))
 }
 
-func (m *mockStream) CloseSend() error {
-	return nil
+func TestErrorTranslation(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var seen []error
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &failingConn{err: drpcerr.WithCode(errors.New("db password wrong"), drpcerr.Internal)}, nil
+	}, WithErrorTranslation(
+		MapConnFailures(drpcerr.Unavailable),
+		drpcerr.Scrub("internal error", drpcerr.Internal),
+	), WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		err := next(ctx, rpc, enc, in, out, cc)
+		seen = append(seen, err)
+		return err
+	}))
+	assert.NoError(t, err)
+
+	// interceptors see the original error and callers the scrubbed one.
+	in, out := "foo", ""
+	err = cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out)
+	assert.Equal(t, "internal error", err.Error())
+	assert.Equal(t, uint64(drpcerr.Internal), drpcerr.Code(err))
+	assert.Equal(t, "db password wrong", seen[0].Error())
+
+	// the errors of streams are translated too.
+	stream, err := cc.NewStream(ctx, "TestRPC", testEncoding{})
+	assert.NoError(t, err)
+	assert.Equal(t, "internal error", stream.MsgRecv(&out, testEncoding{}).Error())
+	assert.NoError(t, stream.Close())
+
+	// conn failures are mapped to the code.
+	assert.NoError(t, cc.Close())
+	err = cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out)
+	assert.ErrorIs(t, err, ErrClientConnClosed)
+	assert.Equal(t, uint64(drpcerr.Unavailable), drpcerr.Code(err))
+}
+
+func TestFailFast(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	clock := drpcclock.NewFake(time.Now())
+	var events []string
+	prober := NewProber(ProberOptions{
+		InitialInterval: time.Second,
+		Clock:           clock,
+		OnEvent: func(ev ProbeEvent) {
+			events = append(events, fmt.Sprintf("%v %s next=%v", ev.Type, ev.Target, ev.Next))
+		},
+	})
+	defer prober.Close()
+	f := NewFailFast(FailFastOptions{Threshold: 2, Window: time.Minute, Prober: prober, Clock: clock})
+
+	dials, refused := 0, errors.New("connection refused")
+	dial := f.Dialer("node1", func(ctx context.Context) (drpc.Conn, error) {
+		dials++
+		if refused != nil {
+			return nil, refused
+		}
+		return &mockDrpcConn{}, nil
+	})
+
+	// failures too far apart are not consecutive.
+	_, err := dial(ctx)
+	assert.ErrorIs(t, err, refused)
+	clock.Advance(2 * time.Minute)
+	_, err = dial(ctx)
+	assert.ErrorIs(t, err, refused)
+	assert.False(t, f.Down("node1"))
+
+	// the second failure in the window marks the target down, after which
+	// dials fail without dialing.
+	_, err = dial(ctx)
+	assert.ErrorIs(t, err, refused)
+	assert.Equal(t, []string{"node1"}, f.DownTargets())
+	_, err = dial(ctx)
+	assert.ErrorIs(t, err, ErrTargetDown)
+	assert.Equal(t, uint64(14), drpcerr.Code(err))
+	assert.Equal(t, 3, dials)
+
+	// probes dial in the background with growing intervals until one
+	// succeeds.
+	clock.Advance(time.Second)
+	assert.Equal(t, 4, dials)
+	assert.True(t, f.Down("node1"))
+	assert.Equal(t, []string{"node1"}, prober.Probing())
+	refused = nil
+	clock.Advance(time.Second)
+	assert.Equal(t, 4, dials)
+	clock.Advance(time.Second)
+	assert.Equal(t, 5, dials)
+	assert.False(t, f.Down("node1"))
+	assert.Equal(t, []string{
+		"ProbeStart node1 next=1s",
+		"ProbeFailure node1 next=2s",
+		"ProbeSuccess node1 next=0s",
+	}, events)
+
+	conn, err := dial(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	// dials abandoned by their caller do not count.
+	refused = context.Canceled
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	for i := 0; i < 3; i++ {
+		_, err = dial(canceled)
+		assert.Error(t, err)
+	}
+	assert.False(t, f.Down("node1"))
+}
+
+func TestProber(t *testing.T) {
+	clock := drpcclock.NewFake(time.Now())
+	var nexts []time.Duration
+	p := NewProber(ProberOptions{
+		InitialInterval: time.Second,
+		MaxInterval:     3 * time.Second,
+		Clock:           clock,
+		OnEvent: func(ev ProbeEvent) {
+			if ev.Type == ProbeFailure {
+				nexts = append(nexts, ev.Next)
+			}
+		},
+	})
+
+	probes, up := 0, false
+	failing := func(ctx context.Context, target string) error { probes++; return errors.New("down") }
+	p.Start("a", failing, func() { up = true })
+	p.Start("a", failing, func() { up = true })
+	p.Start("b", failing, func() { up = true })
+
+	// the intervals grow up to the maximum.
+	clock.Advance(7 * time.Second)
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second}, nexts)
+	assert.Equal(t, 6, probes)
+
+	// stopped targets are not probed and never come up.
+	p.Stop("a")
+	p.Close()
+	p.Start("c", failing, func() { up = true })
+	clock.Advance(time.Minute)
+	assert.Equal(t, 6, probes)
+	assert.Len(t, p.Probing(), 0)
+	assert.False(t, up)
+}
+
+func TestDeadlineRecorder(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	r := NewDeadlineRecorder()
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithChainUnaryInterceptor(r.UnaryInterceptor()))
+	assert.NoError(t, err)
+
+	// calls without a deadline are not recorded.
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+	assert.Len(t, r.Usage(), 0)
+
+	callCtx, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+	assert.NoError(t, cc.Invoke(callCtx, "TestMethod", testEncoding{}, &in, &out))
+	assert.Equal(t, uint64(1), r.Usage()["TestMethod"].Calls)
+
+	// 98 calls use 10% of their deadline and 2 exceed it.
+	for i := 0; i < 98; i++ {
+		r.record("Other", 10*time.Millisecond, 100*time.Millisecond)
+	}
+	r.record("Other", 200*time.Millisecond, 100*time.Millisecond)
+	r.record("Other", 200*time.Millisecond, 100*time.Millisecond)
+
+	usage := r.Usage()["Other"]
+	assert.Equal(t, uint64(100), usage.Calls)
+	assert.Equal(t, uint64(2), usage.Exceeded)
+	assert.True(t, usage.P50 >= 0.1 && usage.P50 < 0.12)
+	assert.Equal(t, usage.P50, usage.P90)
+	assert.True(t, usage.P99 >= 2 && usage.P99 < 2.4)
+}
+
+func TestMaxMetadataSize(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithMaxMetadataSize(16), WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		return next(drpcmetadata.Add(ctx, "baggage", "0123456789"), rpc, enc, in, out, cc)
+	}))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.Error(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+
+	assert.NoError(t, cc.UpdateOptions(ctx, WithMaxMetadataSize(32)))
+	assert.NoError(t, cc.Invoke(ctx, "TestMethod", testEncoding{}, &in, &out))
+}
+
+func TestPprofLabels(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var labels []string
+	record := func(ctx context.Context) {
+		service, _ := pprof.Label(ctx, "rpc_service")
+		method, _ := pprof.Label(ctx, "rpc_method")
+		labels = append(labels, service+" "+method)
+	}
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithPprofLabels(),
+		WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+			record(ctx)
+			return next(ctx, rpc, enc, in, out, cc)
+		}),
+		WithChainStreamInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+			record(ctx)
+			return streamer(ctx, rpc, enc, cc)
+		}))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "/pkg.Service/Unary", testEncoding{}, &in, &out))
+	_, err = cc.NewStream(ctx, "/pkg.Service/Stream", testEncoding{})
+	assert.NoError(t, err)
+	assert.NoError(t, cc.Invoke(ctx, "bare", testEncoding{}, &in, &out))
+	assert.Equal(t, []string{"pkg.Service Unary", "pkg.Service Stream", " bare"}, labels)
+
+	_, ok := pprof.Label(ctx, "rpc_method")
+	assert.False(t, ok)
+}
+
+func TestExecutionTrace(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("an execution trace is already being collected")
+	}
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &cancelConn{}, nil
+	}, WithExecutionTrace(true),
+		WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+			return next(ctx, rpc, enc, in, out, cc)
+		}),
+		WithChainStreamInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+			return streamer(ctx, rpc, enc, cc)
+		}))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, trace.Start(&buf))
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "/pkg.Service/Unary", testEncoding{}, &in, &out))
+	stream, err := cc.NewStream(ctx, "/pkg.Service/Stream", testEncoding{})
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+	trace.Stop()
+
+	for _, name := range []string{"/pkg.Service/Unary", "/pkg.Service/Stream", "interceptor[0]"} {
+		assert.True(t, bytes.Contains(buf.Bytes(), []byte(name)), name)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next RPCHandler) RPCHandler {
+			return func(ctx context.Context, info *RPCInfo) (drpc.Stream, error) {
+				calls = append(calls, fmt.Sprintf("%s_%s_%v", name, info.RPC, info.Stream))
+				return next(ctx, info)
+			}
+		}
+	}
+	adapted := MiddlewareFromInterceptors(
+		recordUnaryInterceptor("unary", &calls),
+		recordStreamInterceptor("stream", &calls))
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithMiddleware(record("mw"), adapted))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, "mocked response for request: foo", out)
+
+	_, err = cc.NewStream(ctx, "Stream", testEncoding{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"mw_Unary_false", "unary_before", "unary_after",
+		"mw_Stream_true", "stream_before", "stream_after",
+	}, calls)
 }
+
+func TestHTTPTarget(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	server := httptest.NewServer(drpchttp.New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == "/service/Fail" {
+			return drpcerr.WithCode(errors.New("failed"), 5)
+		}
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		value, _ := drpcmetadata.Lookup(stream.Context(), drpcmetadata.Key{Name: "key"})
+		out := fmt.Sprintf("%s %s %s", rpc, in, value)
+		return stream.MsgSend(&out, testEncoding{})
+	})))
+	defer server.Close()
+
+	dialer, err := NewTargetDialer("http+drpc://"+server.Listener.Addr().String(), server.Client(), nil)
+	assert.NoError(t, err)
+
+	var calls []string
+	addMetadata := func(ctx context.Context, rpc string, enc drpc.Encoding,
+		in, out drpc.Message, cc *ClientConn, invoker UnaryInvoker) error {
+		return invoker(cc.AddMetadata(ctx, drpcmetadata.Key{Name: "key"}, "val ue"), rpc, enc, in, out, cc)
+	}
+	cc, err := NewClientConnWithOptions(ctx, dialer,
+		WithChainUnaryInterceptor(
+			recordUnaryInterceptor("first", &calls),
+			addMetadata,
+			recordUnaryInterceptor("second", &calls)),
+		WithMaxMetadataSize(16))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "/service/Method", testEncoding{}, &in, &out))
+	assert.Equal(t, "/service/Method foo val ue", out)
+	assert.Equal(t, []string{"first_before", "second_before", "second_after", "first_after"}, calls)
+
+	err = cc.Invoke(ctx, "/service/Fail", testEncoding{}, &in, &out)
+	assert.Error(t, err)
+	assert.Equal(t, uint64(5), drpcerr.Code(err))
+
+	big := drpcmetadata.Add(ctx, "big", "0123456789abcdef")
+	assert.Error(t, cc.Invoke(big, "/service/Method", testEncoding{}, &in, &out))
+
+	_, err = cc.NewStream(ctx, "/service/Method", testEncoding{})
+	assert.Error(t, err)
+
+	_, err = NewTargetDialer("localhost:0", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestLoopback(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	errFailed := errors.New("failed")
+	handler := drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		switch rpc {
+		case "Fail":
+			return errFailed
+		case "Stream":
+			for _, msg := range []string{"a", "b", "c"} {
+				if err := stream.MsgSend(&msg, testEncoding{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		metadata, _ := drpcmetadata.Get(stream.Context())
+		out := in + " " + metadata["key"]
+		return stream.MsgSend(&out, testEncoding{})
+	})
+
+	var calls []string
+	cc, err := NewLoopback(handler, WithChainUnaryInterceptor(recordUnaryInterceptor("int", &calls)))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(drpcmetadata.Add(ctx, "key", "value"), "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, "foo value", out)
+	assert.Equal(t, []string{"int_before", "int_after"}, calls)
+
+	var copies int
+	assert.NoError(t, cc.Invoke(ctx, "Unary", copyEncoding{copies: &copies}, &in, &out))
+	assert.Equal(t, "foo ", out)
+	assert.Equal(t, 1, copies) // the handler replies with testEncoding
+
+	assert.ErrorIs(t, cc.Invoke(ctx, "Fail", testEncoding{}, &in, &out), errFailed)
+
+	stream, err := cc.NewStream(ctx, "Stream", testEncoding{})
+	assert.NoError(t, err)
+	var got []string
+	for {
+		var msg string
+		if err := stream.MsgRecv(&msg, testEncoding{}); err != nil {
+			assert.ErrorIs(t, err, io.EOF)
+			break
+		}
+		got = append(got, msg)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+	<-stream.Context().Done()
+}
+
+// copyEncoding copies *string messages without marshaling them.
+type copyEncoding struct {
+	testEncoding
+	copies *int
+}
+
+func (e copyEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return nil, errors.New("unexpected marshal")
+}
+
+func (e copyEncoding) Copy(dst, src drpc.Message) error {
+	*e.copies++
+	*dst.(*string) = *src.(*string)
+	return nil
+}
+
+func TestSimulatedLoopback(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	handler := drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == "Stream" {
+			for _, msg := range []string{"a", "b", "c", "d"} {
+				if err := stream.MsgSend(&msg, testEncoding{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		return stream.MsgSend(&in, testEncoding{})
+	})
+	newConn := func(network Network) *ClientConn {
+		cc, err := NewSimulatedLoopback(handler, network)
+		assert.NoError(t, err)
+		t.Cleanup(func() { _ = cc.Close() })
+		return cc
+	}
+	recvAll := func(cc *ClientConn) (got []string) {
+		stream, err := cc.NewStream(ctx, "Stream", testEncoding{})
+		assert.NoError(t, err)
+		for {
+			var msg string
+			if err := stream.MsgRecv(&msg, testEncoding{}); err != nil {
+				assert.ErrorIs(t, err, io.EOF)
+				return got
+			}
+			got = append(got, msg)
+		}
+	}
+
+	// every rpc takes a round trip
+	cc := newConn(Network{Latency: FixedLatency(20 * time.Millisecond)})
+	in, out := "foo", ""
+	start := time.Now()
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, "foo", out)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, recvAll(cc))
+
+	// the request and response are each transmitted at 1000 bytes per second
+	cc = newConn(Network{Bandwidth: 1000})
+	in = strings.Repeat("x", 25)
+	start = time.Now()
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// every message is swapped with the next one
+	cc = newConn(Network{ReorderRate: 1})
+	assert.Equal(t, []string{"b", "a", "d", "c"}, recvAll(cc))
+
+	// lost requests leave the rpc to its deadline
+	cc = newConn(Network{DropRate: 1})
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.Error(t, cc.Invoke(timeout, "Unary", testEncoding{}, &in, &out))
+	assert.Empty(t, recvAll(cc))
+}
+
+func TestLatencyDist(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		d := UniformLatency(time.Millisecond, 2*time.Millisecond)(rng)
+		assert.True(t, d >= time.Millisecond && d < 2*time.Millisecond)
+		assert.GreaterOrEqual(t, NormalLatency(time.Millisecond, 10*time.Millisecond)(rng), time.Duration(0))
+		assert.GreaterOrEqual(t, LongTailLatency(time.Millisecond, time.Millisecond)(rng), time.Millisecond)
+	}
+	assert.Equal(t, time.Second, FixedLatency(time.Second)(rng))
+}
+
+func TestPeerFromContext(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var peers []Peer
+	recordPeer := func(ctx context.Context, rpc string, enc drpc.Encoding,
+		in, out drpc.Message, cc *ClientConn, invoker UnaryInvoker) error {
+		_, ok := PeerFromContext(ctx)
+		assert.False(t, ok)
+		err := invoker(ctx, rpc, enc, in, out, cc)
+		peer, ok := PeerFromContext(ctx)
+		assert.True(t, ok)
+		peers = append(peers, peer)
+		return err
+	}
+
+	cc, err := NewLoopback(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		return stream.MsgSend(&in, testEncoding{})
+	}), WithChainUnaryInterceptor(recordPeer))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+
+	split := NewSplit(&mockDrpcConn{}, &mockDrpcConn{}, 0)
+	cc2, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithChainUnaryInterceptor(recordPeer, split.UnaryInterceptor()))
+	assert.NoError(t, err)
+	assert.NoError(t, cc2.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+
+	assert.Equal(t, 2, len(peers))
+	assert.Equal(t, "loopback", peers[0].Transport)
+	assert.Equal(t, "loopback", peers[0].RemoteAddr.String())
+	assert.Equal(t, Peer{}, peers[1])
+}
+
+func TestErrorBudget(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	budget := NewErrorBudget(0.25, 4)
+	newConn := func(conn drpc.Conn) *ClientConn {
+		cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+			return conn, nil
+		}, WithChainUnaryInterceptor(budget.UnaryInterceptor()))
+		assert.NoError(t, err)
+		return cc
+	}
+
+	good := newConn(&addrConn{addr: "good"})
+	bad := newConn(&addrConn{addr: "bad", err: errors.New("failed")})
+
+	in, out := "foo", ""
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, good.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+		assert.Error(t, bad.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	}
+
+	// calls canceled by the caller do not count against the peer
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, bad.Invoke(canceled, "Unary", testEncoding{}, &in, &out))
+
+	assert.Equal(t, CallStats{Calls: 4, Errors: 4}, withoutLatency(budget.Stats("bad")))
+	assert.Equal(t, 2, len(budget.Peers()))
+	assert.False(t, budget.Exhausted("good"))
+	assert.True(t, budget.Exhausted("bad"))
+	assert.Equal(t, 1.0, budget.Remaining("good"))
+	assert.Equal(t, -3.0, budget.Remaining("bad"))
+
+	budget.Reset("bad")
+	assert.False(t, budget.Exhausted("bad"))
+	assert.Equal(t, 1, len(budget.Peers()))
+}
+
+func withoutLatency(stats CallStats) CallStats {
+	stats.Latency = 0
+	return stats
+}
+
+// addrConn is a conn with a remote address whose rpcs return err.
+type addrConn struct {
+	mockDrpcConn
+	addr string
+	err  error
+}
+
+func (c *addrConn) RemoteAddr() net.Addr { return &net.UnixAddr{Name: c.addr, Net: "unix"} }
+
+func (c *addrConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.mockDrpcConn.Invoke(ctx, rpc, enc, in, out)
+}
+
+func TestBalancer(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	a, b := &slowConn{}, &slowConn{}
+	bal := NewBalancer(nil)
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer())
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.Error(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+
+	bal.SetBackends(Backend{Addr: "a", Conn: a}, Backend{Addr: "b", Conn: b})
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&a.calls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&b.calls))
+	assert.Equal(t, 2, len(bal.Backends()))
+}
+
+func TestBalancerRoutes(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	def, heavy := &slowConn{}, &slowConn{}
+	bal := NewBalancer(nil,
+		Backend{Addr: "default", Conn: def},
+		Backend{Addr: "heavy", Conn: heavy, Group: "analytics"})
+	bal.SetRoutes(
+		Route{Prefix: "/analytics.Analytics/", Group: "analytics"},
+		Route{Prefix: "/analytics.Analytics/Ping", Group: ""},
+		Route{Prefix: "/batch.", Group: "batch"},
+	)
+	assert.Equal(t, 3, len(bal.Routes()))
+
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer())
+	assert.NoError(t, err)
+
+	invoke := func(rpc string) (string, error) {
+		ctx := withPeer(ctx)
+		in, out := "foo", ""
+		err := cc.Invoke(ctx, rpc, testEncoding{}, &in, &out)
+		peer, _ := PeerFromContext(ctx)
+		return peer.Backend, err
+	}
+
+	for rpc, want := range map[string]string{
+		"/analytics.Analytics/Scan": "heavy",
+		"/analytics.Analytics/Ping": "default",
+		"/kv.KV/Get":                "default",
+	} {
+		got, err := invoke(rpc)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, rpc)
+	}
+
+	_, err = invoke("/batch.Batch/Run")
+	assert.Error(t, err)
+
+	// pinned rpcs ignore the routes
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(WithTargetPeer(ctx, "heavy"), "/kv.KV/Get", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&heavy.calls))
+}
+
+func TestServiceConfigConfiguresBalancer(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	def, heavy := &slowConn{}, &slowConn{}
+	bal := NewBalancer(firstPicker{},
+		Backend{Addr: "default", Conn: def},
+		Backend{Addr: "other", Conn: def},
+		Backend{Addr: "heavy", Conn: heavy, Group: "analytics"})
+	sc, err := ParseServiceConfig([]byte(`{
+		"methodConfig": [{"name": [{"service": "analytics.Analytics"}], "backendGroup": "analytics"}]
+	}`))
+	assert.NoError(t, err)
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer(), WithServiceConfig(sc))
+	assert.NoError(t, err)
+
+	invoke := func(rpc string) string {
+		ctx := withPeer(ctx)
+		in, out := "foo", ""
+		assert.NoError(t, cc.Invoke(ctx, rpc, testEncoding{}, &in, &out))
+		peer, _ := PeerFromContext(ctx)
+		return peer.Backend
+	}
+
+	// the backend groups of the config route rpcs, and the picker without a
+	// policy in the config is kept.
+	assert.Equal(t, "heavy", invoke("/analytics.Analytics/Scan"))
+	assert.Equal(t, "default", invoke("/kv.KV/Get"))
+	assert.Equal(t, "default", invoke("/kv.KV/Get"))
+
+	// a new policy takes effect through the config alone.
+	sc, err = ParseServiceConfig([]byte(`{"loadBalancingPolicy": "round_robin"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, cc.UpdateOptions(ctx, WithServiceConfig(sc)))
+	assert.Equal(t, 0, len(bal.Routes()))
+	got := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		got[invoke("/kv.KV/Get")] = true
+	}
+	assert.Equal(t, map[string]bool{"default": true, "other": true}, got)
+
+	// unknown policies are rejected without applying the config.
+	assert.Error(t, cc.UpdateOptions(ctx, WithServiceConfig(&ServiceConfig{LoadBalancingPolicy: "random"})))
+	assert.Equal(t, "round_robin", cc.ServiceConfig().LoadBalancingPolicy)
+
+	// the config is applied again to the conn dialed after going idle.
+	bal.SetPicker(firstPicker{})
+	cc.enterIdle()
+	got = map[string]bool{}
+	for i := 0; i < 3; i++ {
+		got[invoke("/kv.KV/Get")] = true
+	}
+	assert.Equal(t, map[string]bool{"default": true, "other": true}, got)
+}
+
+func TestBalancerDrain(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	a, b := &slowConn{}, &slowConn{}
+	drainable := NewDrainableConn(a)
+	bal := NewBalancer(firstPicker{}, Backend{Addr: "a", Conn: drainable}, Backend{Addr: "b", Conn: b})
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer())
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&a.calls))
+
+	// rpcs picking the drained backend are sent to another one instead
+	drainable.Drain()
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	stream, err := cc.NewStream(ctx, "Stream", testEncoding{})
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&a.calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.calls))
+
+	// as are rpcs the conn refuses before sending them
+	bal.SetBackends(
+		Backend{Addr: "c", Conn: &unsentConn{}},
+		Backend{Addr: "b", Conn: b})
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&b.calls))
+
+	// with every backend draining, the rpc fails unsent
+	bal.SetBackends(Backend{Addr: "a", Conn: drainable})
+	err = cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out)
+	assert.True(t, UnsentError.Has(err))
+}
+
+// unsentConn is a mockDrpcConn refusing every rpc before sending it.
+type unsentConn struct {
+	mockDrpcConn
+	calls int32
+}
+
+func (c *unsentConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	atomic.AddInt32(&c.calls, 1)
+	return UnsentError.New("refused")
+}
+
+func TestTransparentRetry(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var dials []*closableConn
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		dials = append(dials, &closableConn{})
+		return dials[len(dials)-1], nil
+	})
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	// an rpc finding its conn closed is issued again on a new conn
+	_ = dials[0].Close()
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, 2, len(dials))
+	assert.Equal(t, Ready, cc.State())
+
+	_ = dials[1].Close()
+	stream, err := cc.NewStream(ctx, "Stream", testEncoding{})
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, 3, len(dials))
+
+	// an rpc refused before being sent is issued once more, without a policy
+	conn := &unsentConn{}
+	cc, err = NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	})
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	err = cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out)
+	assert.True(t, UnsentError.Has(err))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&conn.calls))
+}
+
+func TestAbandoner(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	slow := &slowConn{delay: 100 * time.Millisecond}
+	fast := &slowConn{}
+	bal := NewBalancer(firstPicker{}, Backend{Addr: "fast", Conn: fast})
+
+	var peers []Peer
+	recordPeer := func(ctx context.Context, rpc string, enc drpc.Encoding,
+		in, out drpc.Message, cc *ClientConn, invoker UnaryInvoker) error {
+		err := invoker(ctx, rpc, enc, in, out, cc)
+		peer, _ := PeerFromContext(ctx)
+		peers = append(peers, peer)
+		return err
+	}
+
+	ab := NewAbandoner(AbandonOptions{MinSamples: 2, MinThreshold: 10 * time.Millisecond})
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer(),
+		WithChainUnaryInterceptor(recordPeer, ab.UnaryInterceptor()))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	_, ok := ab.Threshold("Unary")
+	assert.False(t, ok)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	}
+	threshold, ok := ab.Threshold("Unary")
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Millisecond, threshold)
+
+	// the slow backend is abandoned in favor of the fast one
+	bal.SetBackends(Backend{Addr: "slow", Conn: slow}, Backend{Addr: "fast", Conn: fast})
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slow.calls))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fast.calls))
+	assert.Equal(t, "fast", peers[len(peers)-1].Backend)
+
+	// the last attempt runs to completion even if no other backend exists
+	bal.SetBackends(Backend{Addr: "slow", Conn: slow})
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&slow.calls))
+	assert.Equal(t, "slow", peers[len(peers)-1].Backend)
+}
+
+func TestWithTargetPeer(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	a, b := &slowConn{}, &slowConn{}
+	bal := NewBalancer(firstPicker{}, Backend{Addr: "a", Conn: a}, Backend{Addr: "b", Conn: b})
+
+	var calls []string
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer(),
+		WithChainUnaryInterceptor(recordUnaryInterceptor("int", &calls)))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(WithTargetPeer(ctx, "b"), "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&a.calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.calls))
+	assert.Equal(t, []string{"int_before", "int_after"}, calls)
+
+	assert.Error(t, cc.Invoke(WithTargetPeer(ctx, "c"), "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&a.calls))
+}
+
+func TestAffinityPicker(t *testing.T) {
+	ctx := context.Background()
+	backends := func(addrs ...string) (out []Backend) {
+		for _, addr := range addrs {
+			out = append(out, Backend{Addr: addr})
+		}
+		return out
+	}
+	owners := func(p Picker, candidates []Backend) map[string]string {
+		owners := make(map[string]string)
+		for i := 0; i < 200; i++ {
+			key := fmt.Sprint("key", i)
+			owners[key] = p.Pick(WithAffinityKey(ctx, key), "Unary", candidates).Addr
+		}
+		return owners
+	}
+
+	p := NewAffinityPicker(AffinityOptions{})
+	before := owners(p, backends("a", "b", "c"))
+	assert.Equal(t, before, owners(p, backends("a", "b", "c")))
+
+	// a joining backend only takes keys
+	moved := 0
+	for key, owner := range owners(p, backends("a", "b", "c", "d")) {
+		if owner != before[key] {
+			assert.Equal(t, "d", owner)
+			moved++
+		}
+	}
+	assert.True(t, moved > 0 && moved < 100, "moved %d keys", moved)
+
+	// a leaving backend only gives up its keys
+	for key, owner := range owners(p, backends("a", "c")) {
+		if before[key] != "b" {
+			assert.Equal(t, before[key], owner)
+		}
+	}
+
+	// hot keys are spread across replicas
+	hot := NewAffinityPicker(AffinityOptions{HotThreshold: 3, HotWindow: time.Hour, HotReplicas: 2})
+	seen := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		seen[hot.Pick(WithAffinityKey(ctx, "hot"), "Unary", backends("a", "b", "c")).Addr]++
+	}
+	assert.Equal(t, 2, len(seen))
+
+	// rpcs without a key are sent round robin
+	seen = make(map[string]int)
+	for i := 0; i < 3; i++ {
+		seen[p.Pick(ctx, "Unary", backends("a", "b", "c")).Addr]++
+	}
+	assert.Equal(t, 3, len(seen))
+}
+
+func TestBoundedLoadPicker(t *testing.T) {
+	ctx := WithAffinityKey(context.Background(), "hot")
+	candidates := []Backend{{Addr: "a"}, {Addr: "b"}, {Addr: "c"}}
+
+	owner := NewAffinityPicker(AffinityOptions{}).Pick(ctx, "Unary", candidates)
+	p := NewBoundedLoadPicker(BoundedLoadOptions{})
+
+	var picked []Backend
+	for i := 0; i < 9; i++ {
+		picked = append(picked, p.Pick(ctx, "Unary", candidates))
+	}
+	assert.Equal(t, owner, picked[0])
+	for _, backend := range candidates {
+		assert.LessOrEqual(t, p.Load(backend.Addr), 4)
+	}
+	assert.Less(t, p.Load(owner.Addr), 9)
+
+	for _, backend := range picked {
+		p.Done(backend)
+	}
+	assert.Equal(t, 0, p.Load(owner.Addr))
+	assert.Equal(t, owner, p.Pick(ctx, "Unary", candidates))
+
+	// the balancer reports finished rpcs to the picker
+	picker, err := NewPicker("bounded_load_hash")
+	assert.NoError(t, err)
+	bal := NewBalancer(picker, Backend{Addr: "a", Conn: &slowConn{}})
+	cc, err := NewClientConnWithOptions(ctx, bal.Dialer())
+	assert.NoError(t, err)
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, 0, picker.(*BoundedLoadPicker).Load("a"))
+
+	_, err = NewPicker("unknown")
+	assert.Error(t, err)
+}
+
+// firstPicker always picks the first candidate.
+type firstPicker struct{}
+
+func (firstPicker) Pick(ctx context.Context, rpc string, candidates []Backend) Backend {
+	return candidates[0]
+}
+
+// slowConn responds to rpcs after the delay unless the context is done first.
+type slowConn struct {
+	mockDrpcConn
+	delay time.Duration
+	calls int32
+}
+
+func (c *slowConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	atomic.AddInt32(&c.calls, 1)
+	select {
+	case <-time.After(c.delay):
+		return c.mockDrpcConn.Invoke(ctx, rpc, enc, in, out)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestEventListener(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var mu sync.Mutex
+	var events []string
+	listener := ConnEventListenerFunc(func(ev ConnEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev.Type {
+		case StateChange:
+			events = append(events, fmt.Sprintf("%v(%v->%v)", ev.Type, ev.From, ev.To))
+		case RPCStart, RPCEnd:
+			events = append(events, fmt.Sprintf("%v(%s)", ev.Type, ev.RPC))
+		default:
+			events = append(events, ev.Type.String())
+		}
+	})
+	take := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		taken := events
+		events = nil
+		return taken
+	}
+
+	_, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return nil, errors.New("dial failed")
+	}, WithEventListener(listener))
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"StateChange(Idle->Connecting)", "DialStart", "DialFailure", "StateChange(Connecting->Idle)",
+	}, take())
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithEventListener(listener), WithIdleTimeout(time.Millisecond))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	for cc.State() != Idle {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, cc.UpdateOptions(ctx, WithIdleTimeout(0)))
+	assert.Equal(t, []string{
+		"StateChange(Idle->Connecting)", "DialStart", "DialSuccess", "StateChange(Connecting->Ready)",
+		"RPCStart(Unary)", "RPCEnd(Unary)",
+		"Drain", "StateChange(Ready->Idle)",
+	}, take())
+
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.NoError(t, cc.Close())
+	assert.Equal(t, []string{
+		"RPCStart(Unary)", "StateChange(Idle->Connecting)", "DialStart", "DialSuccess",
+		"StateChange(Connecting->Ready)", "RPCEnd(Unary)",
+		"StateChange(Ready->Shutdown)", "Close",
+	}, take())
+}
+
+func TestConnAttributes(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var events []ConnAttributes
+	var seen []string
+	observe := func(ctx context.Context) {
+		attrs, ok := AttributesFromContext(ctx)
+		assert.True(t, ok)
+		seen = append(seen, attrs.String())
+	}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	},
+		WithConnAttributes(map[string]string{"region": "east", "node": "1"}),
+		WithConnAttributes(map[string]string{"node": "7", "purpose": "replication"}),
+		WithEventListener(ConnEventListenerFunc(func(ev ConnEvent) { events = append(events, ev.Attributes) })),
+		WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+			observe(ctx)
+			return next(ctx, rpc, enc, in, out, cc)
+		}),
+		WithChainStreamInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+			observe(ctx)
+			return streamer(ctx, rpc, enc, cc)
+		}))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	want := ConnAttributes{"region": "east", "node": "7", "purpose": "replication"}
+	assert.Equal(t, want, cc.Attributes())
+	assert.Equal(t, "node=7 purpose=replication region=east", want.String())
+
+	// the returned attributes are a copy
+	cc.Attributes()["region"] = "west"
+	assert.Equal(t, want, cc.Attributes())
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	_, err = cc.NewStream(ctx, "Stream", testEncoding{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{want.String(), want.String()}, seen)
+
+	assert.NotEmpty(t, events)
+	for _, attrs := range events {
+		assert.Equal(t, want, attrs)
+	}
+
+	// contexts outside of the rpcs of the ClientConn carry none
+	_, ok := AttributesFromContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestCallTrace(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var calls []string
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &echoConn{}, nil
+	}, WithChainUnaryInterceptor(
+		recordUnaryInterceptor("first", &calls),
+		recordUnaryInterceptor("second", &calls)))
+	assert.NoError(t, err)
+
+	var trace CallTrace
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(WithCallTrace(ctx, &trace), "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, []string{"first_before", "second_before", "second_after", "first_after"}, calls)
+
+	var names []string
+	for _, phase := range trace.Phases {
+		names = append(names, phase.Name)
+	}
+	assert.Equal(t, []string{"interceptor[0]", "interceptor[1]", "acquire", "invoke", "marshal", "unmarshal"}, names)
+	assert.Equal(t, "Unary", trace.RPC)
+	assert.Equal(t, 3, trace.Phases[4].Bytes)
+	assert.True(t, trace.FirstByte > 0)
+	assert.True(t, trace.Phases[0].Duration >= trace.Phases[1].Duration)
+	assert.Contains(t, trace.String(), "interceptor[1]")
+
+	data, err := json.Marshal(&trace)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"name":"marshal"`)
+}
+
+func TestInvokeDetailed(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	var calls int
+	srv := drpcserver.New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		ctx := stream.Context()
+		if err := drpcmetadata.SendHeader(ctx, map[string]string{"attempt": fmt.Sprint(calls)}); err != nil {
+			return err
+		}
+		if err := drpcmetadata.SetTrailer(ctx, map[string]string{drpcmetadata.ServerTime.String(): "5ms"}); err != nil {
+			return err
+		}
+		if calls++; calls == 1 {
+			return drpcerr.WithCode(errors.New("flaky"), 14)
+		}
+		out := in + in
+		return stream.MsgSend(&out, testEncoding{})
+	}))
+
+	sc, err := ParseServiceConfig([]byte(`{"methodConfig": [{
+		"name": [{"service": "kv.KV"}],
+		"retryPolicy": {
+			"maxAttempts": 2,
+			"initialBackoff": "1ms",
+			"maxBackoff": "1ms",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]}`))
+	assert.NoError(t, err)
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		return drpcconn.New(pc), nil
+	}, WithServiceConfig(sc))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	result, err := cc.InvokeDetailed(ctx, "/kv.KV/Get", testEncoding{}, &in, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "foofoo", out)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, map[string]string{"attempt": "1"}, result.Header)
+	assert.Equal(t, map[string]string{drpcmetadata.ServerTime.String(): "5ms"}, result.Trailer)
+	assert.Equal(t, 3, result.BytesSent)
+	assert.Equal(t, 6, result.BytesReceived)
+	assert.Equal(t, 5*time.Millisecond, result.ServerTime)
+	assert.True(t, result.Duration > 0)
+
+	// failed rpcs describe how far they got
+	calls = 0
+	result, err = cc.InvokeDetailed(WithInterceptorHints(ctx, InterceptorHints{RetryHint: HintSkip}), "/kv.KV/Get", testEncoding{}, &in, &out)
+	assert.Equal(t, uint64(14), drpcerr.Code(err))
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, map[string]string{"attempt": "0"}, result.Header)
+	assert.Equal(t, 5*time.Millisecond, result.ServerTime)
+	assert.Equal(t, 0, result.BytesReceived)
+}
+
+func TestLatencyRecorder(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	srv := drpcserver.NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		drpcmetadata.AddQueueTime(stream.Context(), 3*time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		return stream.MsgSend(&in, testEncoding{})
+	}), drpcserver.Options{ReportServerTime: true})
+
+	rec := NewLatencyRecorder()
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		return drpcconn.New(pc), nil
+	}, WithChainUnaryInterceptor(rec.UnaryInterceptor()))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "/kv.KV/Get", testEncoding{}, &in, &out))
+
+	b := rec.Breakdown()["/kv.KV/Get"]
+	assert.Equal(t, uint64(1), b.Calls)
+	assert.Equal(t, uint64(1), b.Reported)
+	assert.Equal(t, 3*time.Millisecond, b.Queue)
+	assert.True(t, b.Server >= 10*time.Millisecond)
+	assert.Equal(t, b.Latency, b.Server+b.Overhead)
+
+	// the interceptor shares the recording of InvokeDetailed
+	result, err := cc.InvokeDetailed(ctx, "/kv.KV/Get", testEncoding{}, &in, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Millisecond, result.QueueTime)
+	assert.True(t, result.ServerTime >= 10*time.Millisecond)
+	assert.Equal(t, uint64(2), rec.Breakdown()["/kv.KV/Get"].Reported)
+}
+
+// echoConn responds to unary rpcs with the marshaled request.
+type echoConn struct{ mockDrpcConn }
+
+func (*echoConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	data, err := enc.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return enc.Unmarshal(data, out)
+}
+
+func TestSlowDetector(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	d := NewSlowDetector(10*time.Millisecond, 2)
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &slowConn{delay: 50 * time.Millisecond}, nil
+	}, WithChainUnaryInterceptor(d.UnaryInterceptor()))
+	assert.NoError(t, err)
+
+	in, out := "foo", ""
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, cc.Invoke(ctx, fmt.Sprint("Slow", i), testEncoding{}, &in, &out))
+	}
+
+	records := d.Records()
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "Slow1", records[0].RPC)
+	assert.Equal(t, "Slow2", records[1].RPC)
+	assert.Equal(t, Ready, records[1].State)
+	assert.Contains(t, records[1].Stack, "TestSlowDetector")
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Contains(t, rec.Body.String(), "unary Slow2")
+}
+
+func TestSendMonitor(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	clock := drpcclock.NewFake(time.Now())
+	stalls := make(chan SendStall, 1)
+	m := NewSendMonitor(time.Second, func(stall SendStall) { stalls <- stall })
+
+	release := make(chan struct{})
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &mockDrpcConn{}, nil
+	}, WithClock(clock), WithChainStreamInterceptor(m.StreamInterceptor(),
+		func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+			stream, err := streamer(ctx, rpc, enc, cc)
+			return &blockedSendStream{Stream: stream, release: release}, err
+		}))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	stream, err := cc.NewStream(ctx, "Upload", testEncoding{})
+	assert.NoError(t, err)
+
+	// a send blocked for longer than the threshold is reported
+	in := "chunk"
+	errs := make(chan error, 1)
+	go func() { errs <- stream.MsgSend(&in, testEncoding{}) }()
+
+	clock.WaitTimers(1)
+	clock.Advance(1500 * time.Millisecond)
+	stall := <-stalls
+	assert.Equal(t, "Upload", stall.RPC)
+	assert.Equal(t, SendStats{}, stall.Stats)
+
+	close(release)
+	assert.NoError(t, <-errs)
+
+	stats, ok := StreamSendStats(stream)
+	assert.True(t, ok)
+	assert.Equal(t, SendStats{Sends: 1, Blocked: 1500 * time.Millisecond, Longest: 1500 * time.Millisecond}, stats)
+
+	// sends that return in time are only measured
+	assert.NoError(t, stream.MsgSend(&in, testEncoding{}))
+	stats, _ = StreamSendStats(stream)
+	assert.Equal(t, uint64(2), stats.Sends)
+	assert.Equal(t, 0, len(stalls))
+
+	_, ok = StreamSendStats(&mockStream{})
+	assert.False(t, ok)
+}
+
+// blockedSendStream blocks in MsgSend until release is closed.
+type blockedSendStream struct {
+	drpc.Stream
+	release chan struct{}
+}
+
+func (s *blockedSendStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	<-s.release
+	return s.Stream.MsgSend(msg, enc)
+}
+
+func TestWithClock(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	sc, err := ParseServiceConfig([]byte(`{"methodConfig": [{
+		"name": [{"service": "kv.KV"}],
+		"retryPolicy": {
+			"maxAttempts": 2,
+			"initialBackoff": "3600s",
+			"maxBackoff": "3600s",
+			"backoffMultiplier": 1,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]}`))
+	assert.NoError(t, err)
+
+	clock := drpcclock.NewFake(time.Now())
+	conn := &flakyConn{failures: 1}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, WithClock(clock), WithIdleTimeout(time.Hour), WithServiceConfig(sc))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	// the idle timer fires once the clock passes the timeout
+	clock.Advance(time.Hour - time.Nanosecond)
+	assert.Equal(t, Ready, cc.State())
+	clock.Advance(time.Nanosecond)
+	assert.Equal(t, Idle, cc.State())
+
+	// the retry waits for the backoff on the clock
+	done := make(chan error, 1)
+	go func() {
+		in, out := "foobar", ""
+		done <- cc.Invoke(ctx, "/kv.KV/Get", testEncoding{}, &in, &out)
+	}()
+	clock.WaitTimers(1)
+	clock.Advance(time.Hour)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, conn.calls)
+}
+
+func TestSchedulerInterleavings(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	started := make(chan struct{}, 1)
+	handler := drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		if rpc == "Block" {
+			started <- struct{}{}
+			<-stream.Context().Done()
+			return stream.Context().Err()
+		}
+		return stream.MsgSend(&in, testEncoding{})
+	})
+	sched := drpctest.NewScheduler()
+	newConn := func() *ClientConn {
+		cc, err := NewClientConnWithOptions(ctx, sched.Dialer(func(context.Context) (drpc.Conn, error) {
+			return &loopbackConn{handler: handler}, nil
+		}))
+		assert.NoError(t, err)
+		t.Cleanup(func() { _ = cc.Close() })
+		return cc
+	}
+	invoke := func(ctx context.Context, cc *ClientConn, rpc string) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			in, out := "foo", ""
+			done <- cc.Invoke(ctx, rpc, testEncoding{}, &in, &out)
+		}()
+		return done
+	}
+
+	// closing between acquiring the conn and invoking on it
+	cc := newConn()
+	bp := sched.Break(drpctest.BeforeInvoke)
+	done := invoke(ctx, cc, "Echo")
+	assert.NoError(t, bp.Wait(ctx))
+	assert.Equal(t, "Echo", bp.RPC())
+	assert.NoError(t, cc.Close())
+	bp.Resume()
+	assert.ErrorIs(t, <-done, ErrClientConnClosed)
+
+	// the conn only sees the cancel once it is resumed
+	cc = newConn()
+	bp = sched.Break(drpctest.OnCancel)
+	canceled, cancel := context.WithCancel(ctx)
+	done = invoke(canceled, cc, "Block")
+	<-started
+	cancel()
+	assert.NoError(t, bp.Wait(ctx))
+	select {
+	case err := <-done:
+		t.Fatalf("rpc returned before the cancel was resumed: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	bp.Resume()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestConnManager(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var dials int32
+	create := func(ctx context.Context) (*ClientConn, error) {
+		atomic.AddInt32(&dials, 1)
+		return NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+			return &mockDrpcConn{}, nil
+		})
+	}
+
+	m := NewConnManager()
+	key := ConnKey{Target: "a:1", Identity: "svc"}
+
+	var wg sync.WaitGroup
+	ccs := make([]*ClientConn, 4)
+	releases := make([]func() error, 4)
+	for i := range ccs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			ccs[i], releases[i], err = m.Get(ctx, key, create)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+	for _, cc := range ccs {
+		assert.True(t, ccs[0] == cc)
+	}
+	assert.Equal(t, 4, m.Refs(key))
+
+	// other identities and options get their own conn
+	other, releaseOther, err := m.Get(ctx, ConnKey{Target: "a:1", Identity: "admin"}, create)
+	assert.NoError(t, err)
+	assert.True(t, ccs[0] != other)
+	assert.Equal(t, 2, m.Len())
+	assert.NoError(t, releaseOther())
+
+	for _, release := range releases[1:] {
+		assert.NoError(t, release())
+		assert.NoError(t, release()) // releasing twice is harmless
+	}
+	assert.Equal(t, Ready, ccs[0].State())
+	assert.NoError(t, releases[0]())
+	assert.Equal(t, Shutdown, ccs[0].State())
+	assert.Equal(t, 0, m.Len())
+
+	// failures are not cached
+	_, _, err = m.Get(ctx, key, func(context.Context) (*ClientConn, error) {
+		return nil, errors.New("refused")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestResolvedBalancer(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	var mu sync.Mutex
+	addrs := []string{"a"}
+	resolved := make(chan time.Time, 10)
+	resolver := ResolverFunc(func(ctx context.Context) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved <- time.Now()
+		return append([]string(nil), addrs...), nil
+	})
+
+	conns := map[string]*closableConn{"a": {}, "b": {}}
+	dial := func(ctx context.Context, addr string) (drpc.Conn, error) {
+		return conns[addr], nil
+	}
+
+	const minInterval = 50 * time.Millisecond
+	rb, err := NewResolvedBalancer(ctx, nil, resolver, dial, ResolverOptions{
+		Interval:    time.Hour,
+		MinInterval: minInterval,
+	})
+	assert.NoError(t, err)
+	defer func() { _ = rb.Close() }()
+	last := <-resolved
+
+	cc, err := NewClientConnWithOptions(ctx, rb.Dialer())
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+
+	// a failure of the conn to a backend resolves again, after the min interval
+	mu.Lock()
+	addrs = []string{"b"}
+	mu.Unlock()
+	_ = conns["a"].Close()
+
+	assert.Error(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	next := <-resolved
+	assert.True(t, next.Sub(last) >= minInterval)
+	last = next
+
+	assert.Equal(t, []string{"b"}, backendAddrs(rb.Backends()))
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+
+	// ResolveNow triggers a resolution manually, also rate limited
+	for i := 0; i < 5; i++ {
+		cc.ResolveNow()
+	}
+	next = <-resolved
+	assert.True(t, next.Sub(last) >= minInterval)
+}
+
+func TestFileResolver(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	dir := t.TempDir()
+
+	addrs, err := NewStaticResolver("a", "b").Resolve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, addrs)
+
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		return path
+	}
+
+	for _, file := range []struct{ name, data string }{
+		{"list.json", `["a:1", "b:2"]`},
+		{"object.json", `{"addresses": ["a:1", "b:2"]}`},
+		{"list.yaml", "- a:1\n- b:2\n"},
+		{"object.yml", "addresses:\n  - a:1\n  - b:2\n"},
+	} {
+		addrs, err := NewFileResolver(write(file.name, file.data), FileResolverOptions{}).Resolve(ctx)
+		assert.NoError(t, err, file.name)
+		assert.Equal(t, []string{"a:1", "b:2"}, addrs, file.name)
+	}
+
+	for _, path := range []string{
+		write("empty.json", `[]`),
+		write("invalid.json", `{"addresses": 1}`),
+		filepath.Join(dir, "missing.json"),
+	} {
+		_, err := NewFileResolver(path, FileResolverOptions{}).Resolve(ctx)
+		assert.Error(t, err, path)
+	}
+
+	// a change to the file is noticed at the next poll
+	clock := drpcclock.NewFake(time.Now())
+	path := write("watched.json", `["a:1"]`)
+	r := NewFileResolver(path, FileResolverOptions{PollInterval: time.Second, Clock: clock})
+
+	changed := make(chan struct{}, 1)
+	ctx.Run(func(ctx context.Context) {
+		r.Watch(ctx, func() { changed <- struct{}{} })
+	})
+	clock.WaitTimers(1)
+
+	write("watched.json", `["a:1", "b:2"]`)
+	clock.Advance(time.Second)
+	<-changed
+
+	addrs, err = r.Resolve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:1", "b:2"}, addrs)
+}
+
+func TestPushResolver(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	conns := make(map[string]*closableConn)
+	var mu sync.Mutex
+	dial := func(ctx context.Context, addr string) (drpc.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		conns[addr] = &closableConn{}
+		return conns[addr], nil
+	}
+
+	pr := NewPushResolver("a")
+	rb, err := NewResolvedBalancer(ctx, nil, pr, dial, ResolverOptions{
+		Interval:    time.Hour,
+		MinInterval: time.Hour,
+	})
+	assert.NoError(t, err)
+	defer func() { _ = rb.Close() }()
+	assert.Equal(t, []string{"a"}, backendAddrs(rb.Backends()))
+
+	// pushed updates apply without waiting for the min interval
+	for _, addrs := range [][]string{{"a", "b"}, {"b"}} {
+		pr.Update(addrs...)
+		for !reflect.DeepEqual(backendAddrs(rb.Backends()), addrs) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, isClosed(conns["a"]))
+	assert.False(t, isClosed(conns["b"]))
+}
+
+// closableConn is a mockDrpcConn whose calls fail once it is closed.
+type closableConn struct {
+	mockDrpcConn
+	closed drpcsignal.Signal
+}
+
+func (c *closableConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if err, ok := c.closed.Get(); ok {
+		return err
+	}
+	return c.mockDrpcConn.Invoke(ctx, rpc, enc, in, out)
+}
+
+func (c *closableConn) Close() error {
+	c.closed.Set(drpc.ClosedError.New("conn closed"))
+	return nil
+}
+
+func (c *closableConn) Closed() <-chan struct{} { return c.closed.Signal() }
+
+func isDone(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func backendAddrs(backends []Backend) (addrs []string) {
+	for _, backend := range backends {
+		addrs = append(addrs, backend.Addr)
+	}
+	return addrs
+}
+
+func TestWeightedPicker(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	picker, err := NewPicker("weighted_round_robin")
+	assert.NoError(t, err)
+	assert.IsType(t, &WeightedPicker{}, picker)
+
+	clock := drpcclock.NewFake(time.Now())
+	p := NewWeightedPicker(WeightedOptions{Smoothing: 0.5, Expiration: time.Minute, Clock: clock})
+	backends := []Backend{{Addr: "a"}, {Addr: "b"}}
+	counts := func(n int) map[string]int {
+		counts := make(map[string]int)
+		for i := 0; i < n; i++ {
+			counts[p.Pick(ctx, "rpc", backends).Addr]++
+		}
+		return counts
+	}
+
+	// without reports the picks are even
+	assert.Equal(t, map[string]int{"a": 5, "b": 5}, counts(10))
+
+	// a reports a tenth of the load of b, so it gets ten times the picks
+	p.Report("a", LoadReport{Utilization: 0.09})
+	p.Report("b", LoadReport{Utilization: 0.49, QueueDepth: 5})
+	assert.Equal(t, map[string]int{"a": 100, "b": 10}, counts(110))
+
+	// reports are smoothed
+	p.Report("a", LoadReport{Utilization: 0.99})
+	load, ok := p.Load("a")
+	assert.True(t, ok)
+	assert.InDelta(t, 0.54, load, 1e-9)
+
+	// expired reports weigh like the reporting backends
+	clock.Advance(time.Minute)
+	_, ok = p.Load("a")
+	assert.False(t, ok)
+	p.Report("b", LoadReport{Utilization: 0.5})
+	assert.Equal(t, map[string]int{"a": 5, "b": 5}, counts(10))
+
+	// reports are read from a control stream until it ends
+	reports := []LoadReport{{Utilization: 0.2}, {Utilization: 0.4}}
+	err = p.Watch(ctx, "a", func() (LoadReport, error) {
+		if len(reports) == 0 {
+			return LoadReport{}, io.EOF
+		}
+		report := reports[0]
+		reports = reports[1:]
+		return report, nil
+	})
+	assert.ErrorIs(t, err, io.EOF)
+	load, _ = p.Load("a")
+	assert.InDelta(t, 0.3, load, 1e-9)
+}
+
+func TestChannels(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	conn := &channelConn{opened: map[string]int{}}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, WithChannels(
+		ChannelRoute{Prefix: "/liveness.", Label: "liveness"},
+		ChannelRoute{Prefix: "/bulk.Bulk/", Label: "bulk"},
+	))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	for rpc, want := range map[string]string{
+		"/liveness.Heartbeat/Ping": "liveness",
+		"/bulk.Bulk/Snapshot":      "bulk",
+		"/kv.KV/Get":               "",
+	} {
+		for i := 0; i < 2; i++ {
+			in, out := "foo", ""
+			assert.NoError(t, cc.Invoke(ctx, rpc, testEncoding{}, &in, &out))
+			assert.Equal(t, want, out)
+
+			stream, err := cc.NewStream(ctx, rpc, testEncoding{})
+			assert.NoError(t, err)
+			assert.Equal(t, want, stream.(*mockStream).name)
+		}
+	}
+
+	// channels are opened once per conn
+	assert.Equal(t, map[string]int{"liveness": 1, "bulk": 1}, conn.opened)
+}
+
+// channelConn is a MultiplexedConn answering rpcs with the label of the
+// channel they are issued on.
+type channelConn struct {
+	mockDrpcConn
+	label  string
+	opened map[string]int
+}
+
+func (c *channelConn) OpenChannel(label string) drpc.Conn {
+	c.opened[label]++
+	return &channelConn{label: label}
+}
+
+func (c *channelConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	*out.(*string) = c.label
+	return nil
+}
+
+func (c *channelConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return &mockStream{name: c.label}, nil
+}
+
+func recordUnaryInterceptor(name string, calls *[]string) UnaryClientInterceptor {
+	return func(ctx context.Context, method string, enc drpc.Encoding,
+		in, out drpc.Message, conn *ClientConn, invoker UnaryInvoker) error {
+		*calls = append(*calls, name+"_before")
+		err := invoker(ctx, method, enc, in, out, conn)
+		*calls = append(*calls, name+"_after")
+		return err
+	}
+}
+
+func recordStreamInterceptor(name string, calls *[]string) StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, conn *ClientConn, next Streamer) (drpc.Stream, error) {
+		*calls = append(*calls, name+"_before")
+		stream, err := next(ctx, rpc, enc, conn)
+		if err == nil {
+			*calls = append(*calls, name+"_after")
+		}
+		return stream, err
+	}
+}
+
+type mockDrpcConn struct{}
+
+func (m *mockDrpcConn) Unblocked() <-chan struct{} {
+	return nil
+}
+
+func (m *mockDrpcConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	*out.(*string) = "mocked response for request: " + *in.(*string)
+	return nil
+}
+
+func (m *mockDrpcConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return &mockStream{name: rpc}, nil
+}
+
+func (m *mockDrpcConn) Close() error {
+	return nil
+}
+
+func (m *mockDrpcConn) Closed() <-chan struct{} {
+	return nil
+}
+
+type mockStream struct {
+	name string
+}
+
+func (m *mockStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	return nil
+}
+
+func (m *mockStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	return nil
+}
+
+func (m *mockStream) Close() error {
+	return nil
+}
+
+func (m *mockStream) Context() context.Context {
+	return context.TODO()
+}
+
+func (m *mockStream) CloseSend() error {
+	return nil
+}
+
+// failingConn is a mockDrpcConn whose rpcs and stream receives fail with err.
+type failingConn struct {
+	mockDrpcConn
+	err error
+}
+
+func (f *failingConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	return f.err
+}
+
+func (f *failingConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return &failingStream{mockStream: mockStream{name: rpc}, err: f.err}, nil
+}
+
+type failingStream struct {
+	mockStream
+	err error
+}
+
+func (s *failingStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error { return s.err }
+
+// cancelConn is a mockDrpcConn whose streams have a context that is canceled
+// when they are closed.
+type cancelConn struct{ mockDrpcConn }
+
+func (*cancelConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &cancelStream{mockStream: mockStream{name: rpc}, ctx: ctx, cancel: cancel}, nil
+}
+
+type cancelStream struct {
+	mockStream
+	ctx    context.Context
+	cancel func()
+}
+
+func (s *cancelStream) Context() context.Context { return s.ctx }
+
+func (s *cancelStream) Close() error { s.cancel(); return nil }
+
+// recordingConn is a mockDrpcConn that reports the raw request of every Invoke.
+type recordingConn struct {
+	mockDrpcConn
+	invoked chan string
+}
+
+func (r *recordingConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	data, err := enc.Marshal(in)
+	if err != nil {
+		return err
+	}
+	r.invoked <- string(data)
+	return nil
+}
+
+// streamConn is a mockDrpcConn that returns the same stream for every NewStream.
+type streamConn struct {
+	mockDrpcConn
+	stream drpc.Stream
+}
+
+func (s *streamConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return s.stream, nil
+}
+
+// delayStream is a mockStream that records its flush delay.
+type delayStream struct {
+	mockStream
+	delay time.Duration
+}
+
+func (d *delayStream) SetFlushDelay(delay time.Duration) { d.delay = delay }
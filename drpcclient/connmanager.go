@@ -0,0 +1,135 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// ConnKey identifies the ClientConns a ConnManager shares. ClientConns are
+// only shared between components that agree on all of its fields.
+type ConnKey struct {
+	// Target is the address or target the ClientConn dials.
+	Target string
+
+	// Identity distinguishes the credentials of the ClientConn, such as the
+	// name of the client certificate, so that components authenticating as
+	// different identities do not share a conn.
+	Identity string
+
+	// Options is a fingerprint of the DialOptions of the ClientConn, which
+	// cannot be compared themselves, such as a version of the component's
+	// configuration.
+	Options string
+}
+
+// DefaultConnManager is a ConnManager shared by the whole process.
+var DefaultConnManager = NewConnManager()
+
+// ConnManager deduplicates ClientConns by ConnKey, so that the components of
+// a binary talking to the same peer share one conn instead of each opening
+// their own. ClientConns are reference counted and closed once the last
+// component releases them. It is safe for concurrent use.
+type ConnManager struct {
+	mu    sync.Mutex
+	conns map[ConnKey]*sharedConn
+}
+
+// sharedConn is a ClientConn of a ConnManager and its references.
+type sharedConn struct {
+	ready chan struct{} // closed once cc or err is set
+	cc    *ClientConn
+	err   error
+	refs  int
+}
+
+// NewConnManager returns an empty ConnManager.
+func NewConnManager() *ConnManager {
+	return &ConnManager{conns: make(map[ConnKey]*sharedConn)}
+}
+
+// Get returns the ClientConn for the key, calling create to make it if there
+// is none. Concurrent calls for the same key wait for a single create, and all
+// fail if it fails. The returned release func must be called once the caller
+// no longer uses the ClientConn, which is closed when every caller released
+// it. Callers must not close it themselves.
+func (m *ConnManager) Get(ctx context.Context, key ConnKey, create func(ctx context.Context) (*ClientConn, error)) (cc *ClientConn, release func() error, err error) {
+	m.mu.Lock()
+	sc, ok := m.conns[key]
+	if !ok {
+		sc = &sharedConn{ready: make(chan struct{})}
+		m.conns[key] = sc
+	}
+	sc.refs++
+	m.mu.Unlock()
+
+	if !ok {
+		sc.cc, sc.err = create(ctx)
+		if sc.err == nil && sc.cc == nil {
+			sc.err = drpc.Error.New("create returned no ClientConn for %q", key.Target)
+		}
+		if sc.err != nil {
+			// forget the failure so that later calls try again.
+			m.mu.Lock()
+			if m.conns[key] == sc {
+				delete(m.conns, key)
+			}
+			m.mu.Unlock()
+		}
+		close(sc.ready)
+	}
+
+	select {
+	case <-sc.ready:
+	case <-ctx.Done():
+		_ = m.release(key, sc)
+		return nil, nil, ctx.Err()
+	}
+	if sc.err != nil {
+		_ = m.release(key, sc)
+		return nil, nil, sc.err
+	}
+
+	var once sync.Once
+	return sc.cc, func() (err error) {
+		once.Do(func() { err = m.release(key, sc) })
+		return err
+	}, nil
+}
+
+// release drops a reference to the shared conn, closing and forgetting it once
+// there are none left.
+func (m *ConnManager) release(key ConnKey, sc *sharedConn) error {
+	m.mu.Lock()
+	sc.refs--
+	last := sc.refs == 0
+	if last && m.conns[key] == sc {
+		delete(m.conns, key)
+	}
+	m.mu.Unlock()
+
+	if last && sc.cc != nil {
+		return sc.cc.Close()
+	}
+	return nil
+}
+
+// Len returns the number of ClientConns being shared.
+func (m *ConnManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.conns)
+}
+
+// Refs returns the number of references to the ClientConn for the key.
+func (m *ConnManager) Refs(key ConnKey) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sc, ok := m.conns[key]; ok {
+		return sc.refs
+	}
+	return 0
+}
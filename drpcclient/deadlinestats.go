@@ -0,0 +1,161 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// deadlineBuckets is the number of histogram buckets. Bucket i counts calls
+// that used at most 2^((i-40)/4) of their deadline, which spans from about
+// 0.1% to 400% with roughly 19% resolution. The last bucket counts everything
+// larger.
+const deadlineBuckets = 50
+
+// deadlineBucketBound returns the largest fraction of the deadline counted by
+// bucket i.
+func deadlineBucketBound(i int) float64 {
+	return math.Exp2(float64(i-40) / 4)
+}
+
+// DeadlineUsage summarizes how much of their deadline the calls of a method
+// used, as the ratio of observed latency to the time remaining until the
+// deadline when the call started. A method whose P99 is far below 1 has a
+// timeout that could be tightened, and one whose P99 is near or above 1 has
+// calls that routinely run out of time.
+type DeadlineUsage struct {
+	// Calls is the number of calls recorded that had a deadline.
+	Calls uint64
+
+	// Exceeded is the number of calls that used all of their deadline.
+	Exceeded uint64
+
+	// P50, P90 and P99 are upper bounds of the percentiles of the fraction of
+	// the deadline used.
+	P50, P90, P99 float64
+}
+
+// DeadlineRecorder records per method how much of the context deadline unary
+// calls use, to help right-size the timeouts in a ServiceConfig. Calls without
+// a deadline are not recorded.
+type DeadlineRecorder struct {
+	mu      sync.RWMutex
+	methods map[string]*deadlineHistogram
+}
+
+// deadlineHistogram counts calls per deadlineBuckets bucket.
+type deadlineHistogram struct {
+	counts   [deadlineBuckets]uint64
+	exceeded uint64
+}
+
+// NewDeadlineRecorder returns an empty DeadlineRecorder.
+func NewDeadlineRecorder() *DeadlineRecorder {
+	return &DeadlineRecorder{methods: make(map[string]*deadlineHistogram)}
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that records the calls
+// through it. It should be placed after any interceptor that sets the deadline
+// so that it observes the deadline the rpc is issued with.
+func (r *DeadlineRecorder) UnaryInterceptor() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return next(ctx, rpc, enc, in, out, cc)
+		}
+
+		start := time.Now()
+		err := next(ctx, rpc, enc, in, out, cc)
+		r.record(rpc, time.Since(start), deadline.Sub(start))
+		return err
+	}
+}
+
+// record adds a call that took latency out of a budget to the histogram of the
+// rpc.
+func (r *DeadlineRecorder) record(rpc string, latency, budget time.Duration) {
+	h := r.histogram(rpc)
+
+	i := deadlineBuckets - 1
+	if budget > 0 {
+		used := float64(latency) / float64(budget)
+		for j := 0; j < deadlineBuckets-1; j++ {
+			if used <= deadlineBucketBound(j) {
+				i = j
+				break
+			}
+		}
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	if latency >= budget {
+		atomic.AddUint64(&h.exceeded, 1)
+	}
+}
+
+// histogram returns the histogram of the rpc, creating it if necessary.
+func (r *DeadlineRecorder) histogram(rpc string) *deadlineHistogram {
+	r.mu.RLock()
+	h := r.methods[rpc]
+	r.mu.RUnlock()
+	if h != nil {
+		return h
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if h = r.methods[rpc]; h == nil {
+		h = new(deadlineHistogram)
+		r.methods[rpc] = h
+	}
+	return h
+}
+
+// Usage returns the deadline usage of every method that has recorded calls,
+// keyed by rpc.
+func (r *DeadlineRecorder) Usage() map[string]DeadlineUsage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	usage := make(map[string]DeadlineUsage, len(r.methods))
+	for rpc, h := range r.methods {
+		usage[rpc] = h.usage()
+	}
+	return usage
+}
+
+// usage summarizes the histogram.
+func (h *deadlineHistogram) usage() DeadlineUsage {
+	var counts [deadlineBuckets]uint64
+	var calls uint64
+	for i := range counts {
+		counts[i] = atomic.LoadUint64(&h.counts[i])
+		calls += counts[i]
+	}
+
+	percentile := func(p float64) float64 {
+		target := uint64(math.Ceil(p * float64(calls)))
+		var seen uint64
+		for i, n := range counts {
+			seen += n
+			if seen >= target && i < deadlineBuckets-1 {
+				return deadlineBucketBound(i)
+			} else if seen >= target {
+				return math.Inf(1)
+			}
+		}
+		return 0
+	}
+
+	return DeadlineUsage{
+		Calls:    calls,
+		Exceeded: atomic.LoadUint64(&h.exceeded),
+		P50:      percentile(0.50),
+		P90:      percentile(0.90),
+		P99:      percentile(0.99),
+	}
+}
@@ -0,0 +1,241 @@
+package drpcclient
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+)
+
+// AddrDialerFunc is a function that returns a drpc.Conn to the given address or
+// an error.
+type AddrDialerFunc func(ctx context.Context, addr string) (drpc.Conn, error)
+
+// DefaultDialStagger is the delay between starting connection attempts to
+// successive addresses recommended by RFC 8305.
+const DefaultDialStagger = 250 * time.Millisecond
+
+// NewParallelDialer returns a DialerFunc that dials the addresses using staggered
+// parallel attempts in the style of Happy Eyeballs (RFC 8305). The addresses are
+// reordered so that IPv6 and IPv4 addresses alternate, and a new attempt is
+// started every stagger, or as soon as the previous attempt fails. The first
+// successful conn is returned, all other attempts are canceled, and any conns
+// they produce are closed. If every attempt fails, the combined errors are
+// returned. A non-positive stagger uses DefaultDialStagger.
+func NewParallelDialer(addrs []string, stagger time.Duration, dial AddrDialerFunc) DialerFunc {
+	return NewParallelDialerWithFamilies(addrs, stagger, InterleaveFamilies, dial)
+}
+
+// NewParallelDialerWithFamilies is like NewParallelDialer, but orders the
+// addresses with the FamilyPolicy instead of always alternating families, for
+// example to try IPv4 first when IPv6 is intermittently broken.
+func NewParallelDialerWithFamilies(addrs []string, stagger time.Duration, families FamilyPolicy, dial AddrDialerFunc) DialerFunc {
+	if stagger <= 0 {
+		stagger = DefaultDialStagger
+	}
+	addrs = families.Order(addrs)
+
+	return func(ctx context.Context) (drpc.Conn, error) {
+		return dialParallel(ctx, addrs, stagger, dial)
+	}
+}
+
+// dialResult is the outcome of a single connection attempt.
+type dialResult struct {
+	conn drpc.Conn
+	err  error
+}
+
+func dialParallel(ctx context.Context, addrs []string, stagger time.Duration, dial AddrDialerFunc) (drpc.Conn, error) {
+	if len(addrs) == 0 {
+		return nil, drpc.Error.New("no addresses to dial")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(addrs))
+	start := func(addr string) {
+		go func() {
+			conn, err := dial(ctx, addr)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(stagger)
+	defer timer.Stop()
+
+	var group errs.Group
+	next, pending := 0, 0
+	for {
+		if next < len(addrs) && pending == 0 {
+			start(addrs[next])
+			next, pending = next+1, pending+1
+			resetTimer(timer, stagger)
+		}
+
+		var timerC <-chan time.Time
+		if next < len(addrs) {
+			timerC = timer.C
+		}
+
+		select {
+		case <-timerC:
+			start(addrs[next])
+			next, pending = next+1, pending+1
+			timer.Reset(stagger)
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				go closeLosers(results, pending)
+				return res.conn, nil
+			}
+			group.Add(res.err)
+			if next == len(addrs) && pending == 0 {
+				return nil, group.Err()
+			}
+
+		case <-ctx.Done():
+			go closeLosers(results, pending)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// closeLosers waits for n outstanding attempts to finish and closes any conns
+// they successfully established.
+func closeLosers(results <-chan dialResult, n int) {
+	for ; n > 0; n-- {
+		if res := <-results; res.err == nil {
+			_ = res.conn.Close()
+		}
+	}
+}
+
+// resetTimer stops, drains and resets the timer so that it fires after d.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// FamilyPolicy orders and filters addresses by IP family before they are
+// dialed or used as backends.
+type FamilyPolicy int
+
+const (
+	// InterleaveFamilies alternates IPv6 and IPv4 addresses, starting with
+	// the family of the first address.
+	InterleaveFamilies FamilyPolicy = iota
+
+	// PreferIPv6 orders the IPv6 addresses before the IPv4 ones, which are
+	// only used as a fallback.
+	PreferIPv6
+
+	// PreferIPv4 orders the IPv4 addresses before the IPv6 ones, which are
+	// only used as a fallback.
+	PreferIPv4
+
+	// OnlyIPv6 drops the IPv4 addresses.
+	OnlyIPv6
+
+	// OnlyIPv4 drops the IPv6 addresses.
+	OnlyIPv4
+)
+
+// String returns a short name for the policy.
+func (p FamilyPolicy) String() string {
+	switch p {
+	case InterleaveFamilies:
+		return "interleave"
+	case PreferIPv6:
+		return "prefer ipv6"
+	case PreferIPv4:
+		return "prefer ipv4"
+	case OnlyIPv6:
+		return "only ipv6"
+	case OnlyIPv4:
+		return "only ipv4"
+	default:
+		return "unknown"
+	}
+}
+
+// Order returns the addresses ordered and filtered by the policy. The relative
+// order within a family is preserved, and addresses that are not IP literals,
+// such as host names, are kept in front of the fallback family.
+func (p FamilyPolicy) Order(addrs []string) []string {
+	if p == InterleaveFamilies {
+		return interleaveFamilies(addrs)
+	}
+
+	wantV6 := p == PreferIPv6 || p == OnlyIPv6
+	only := p == OnlyIPv6 || p == OnlyIPv4
+
+	out := make([]string, 0, len(addrs))
+	var fallback []string
+	for _, addr := range addrs {
+		if v6, ok := isIPv6(addr); ok && v6 != wantV6 {
+			fallback = append(fallback, addr)
+		} else {
+			out = append(out, addr)
+		}
+	}
+	if !only {
+		out = append(out, fallback...)
+	}
+	return out
+}
+
+// interleaveFamilies returns the addresses reordered so that IPv6 and IPv4
+// addresses alternate, starting with the family of the first address. The
+// relative order within a family is preserved, and addresses that are not IP
+// literals are kept with the family of the first address.
+func interleaveFamilies(addrs []string) []string {
+	var first, second []string
+	var firstV6 bool
+	for i, addr := range addrs {
+		v6, ok := isIPv6(addr)
+		if i == 0 {
+			firstV6 = v6
+		}
+		if ok && v6 != firstV6 {
+			second = append(second, addr)
+		} else {
+			first = append(first, addr)
+		}
+	}
+
+	out := make([]string, 0, len(addrs))
+	for len(first) > 0 || len(second) > 0 {
+		if len(first) > 0 {
+			out, first = append(out, first[0]), first[1:]
+		}
+		if len(second) > 0 {
+			out, second = append(out, second[0]), second[1:]
+		}
+	}
+	return out
+}
+
+// isIPv6 reports if the host part of addr is an IPv6 literal. The second return
+// value is false if the host is not an IP literal.
+func isIPv6(addr string) (v6, ok bool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false, false
+	}
+	return ip.To4() == nil, true
+}
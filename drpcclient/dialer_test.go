@@ -0,0 +1,383 @@
+package drpcclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcfeatures"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpcsession"
+	"storj.io/drpc/drpctest"
+)
+
+func TestParallelDialerSkipsBlackholedAddress(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	canceled := make(chan struct{})
+	dial := func(ctx context.Context, addr string) (drpc.Conn, error) {
+		if addr == "[::1]:1" {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}
+		return &mockDrpcConn{}, nil
+	}
+
+	dialer := NewParallelDialer([]string{"[::1]:1", "127.0.0.1:1"}, time.Millisecond, dial)
+	conn, err := dialer(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("losing attempt was not canceled")
+	}
+}
+
+func TestParallelDialerCombinesErrors(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	dial := func(ctx context.Context, addr string) (drpc.Conn, error) {
+		return nil, errors.New("refused: " + addr)
+	}
+
+	_, err := NewParallelDialer([]string{"a:1", "b:1"}, time.Hour, dial)(ctx)
+	assert.ErrorContains(t, err, "refused: a:1")
+	assert.ErrorContains(t, err, "refused: b:1")
+}
+
+func TestInterleaveFamilies(t *testing.T) {
+	addrs := []string{"[::1]:1", "[::2]:1", "[::3]:1", "10.0.0.1:1", "10.0.0.2:1"}
+	assert.Equal(t, []string{"[::1]:1", "10.0.0.1:1", "[::2]:1", "10.0.0.2:1", "[::3]:1"}, interleaveFamilies(addrs))
+}
+
+func TestFamilyPolicy(t *testing.T) {
+	addrs := []string{"10.0.0.1:1", "[::1]:1", "host:1", "10.0.0.2:1", "[::2]:1"}
+	for policy, want := range map[FamilyPolicy][]string{
+		InterleaveFamilies: {"10.0.0.1:1", "[::1]:1", "host:1", "[::2]:1", "10.0.0.2:1"},
+		PreferIPv6:         {"[::1]:1", "host:1", "[::2]:1", "10.0.0.1:1", "10.0.0.2:1"},
+		PreferIPv4:         {"10.0.0.1:1", "host:1", "10.0.0.2:1", "[::1]:1", "[::2]:1"},
+		OnlyIPv6:           {"[::1]:1", "host:1", "[::2]:1"},
+		OnlyIPv4:           {"10.0.0.1:1", "host:1", "10.0.0.2:1"},
+	} {
+		assert.Equal(t, want, policy.Order(addrs), policy.String())
+	}
+
+	ctx := drpctest.NewTracker(t)
+	var dialed []string
+	dial := func(ctx context.Context, addr string) (drpc.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, errors.New("refused")
+	}
+	_, err := NewParallelDialerWithFamilies(addrs, time.Hour, PreferIPv4, dial)(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, PreferIPv4.Order(addrs), dialed)
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	// a peer that accepts the connection but never answers the tls handshake
+	_, err := NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+		client, server := net.Pipe()
+		t.Cleanup(func() { _ = server.Close() })
+		return HandshakeTLS(ctx, client, &tls.Config{InsecureSkipVerify: true})
+	}, WithHandshakeTimeout(10*time.Millisecond))
+	assert.True(t, HandshakeTimeoutError.Has(err), "%v", err)
+	assert.False(t, errors.Is(err, context.DeadlineExceeded))
+
+	// a peer that never answers the feature negotiation
+	slow := &slowConn{delay: time.Hour}
+	_, err = NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return slow, nil
+	}, WithFeatures(drpcfeatures.Set{"a": ""}), WithHandshakeTimeout(10*time.Millisecond))
+	assert.True(t, HandshakeTimeoutError.Has(err), "%v", err)
+
+	// the deadline of the rpc is reported as such
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = NewClientConnWithOptions(short, func(context.Context) (drpc.Conn, error) {
+		return slow, nil
+	}, WithFeatures(drpcfeatures.Set{"a": ""}), WithHandshakeTimeout(time.Hour))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, HandshakeTimeoutError.Has(err))
+}
+
+func TestSessionAuthentication(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	mux := drpcmux.New()
+	assert.NoError(t, drpcsession.Register(mux, drpcsession.HMACChallenger(map[string][]byte{"alice": []byte("secret")})))
+	srv := drpcserver.New(drpcsession.NewHandler(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == drpcsession.RPC {
+			return mux.HandleRPC(stream, rpc)
+		}
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		out, _ := drpcsession.Identity(stream.Context())
+		return stream.MsgSend(&out, testEncoding{})
+	})))
+	dial := func(context.Context) (drpc.Conn, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		return drpcconn.New(pc), nil
+	}
+
+	cc, err := NewClientConnWithOptions(ctx, dial, WithSessionAuthentication(drpcsession.HMAC("alice", []byte("secret"))))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, "alice", out)
+
+	// a conn that fails to authenticate fails the dial
+	_, err = NewClientConnWithOptions(ctx, dial, WithSessionAuthentication(drpcsession.HMAC("alice", []byte("guess"))))
+	assert.Equal(t, uint64(16), drpcerr.Code(err))
+}
+
+func TestClusterID(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	mux := drpcmux.New()
+	assert.NoError(t, drpcfeatures.Register(mux, drpcfeatures.Set{drpcfeatures.ClusterID: "east", drpcfeatures.Keepalive: ""}))
+	srv := drpcserver.New(drpcfeatures.RequireCluster(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == drpcfeatures.RPC {
+			return mux.HandleRPC(stream, rpc)
+		}
+		var in string
+		if err := stream.MsgRecv(&in, testEncoding{}); err != nil {
+			return err
+		}
+		return stream.MsgSend(&in, testEncoding{})
+	}), "east"))
+	dial := func(context.Context) (drpc.Conn, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		return drpcconn.New(pc), nil
+	}
+
+	cc, err := NewClientConnWithOptions(ctx, dial, WithClusterID("east"), WithFeatures(drpcfeatures.Set{drpcfeatures.Keepalive: ""}))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+	assert.Equal(t, drpcfeatures.Set{drpcfeatures.ClusterID: "east", drpcfeatures.Keepalive: ""}, cc.PeerFeatures())
+
+	in, out := "foo", ""
+	assert.NoError(t, cc.Invoke(ctx, "Unary", testEncoding{}, &in, &out))
+	assert.Equal(t, "foo", out)
+
+	// a peer of another cluster fails the dial
+	_, err = NewClientConnWithOptions(ctx, dial, WithClusterID("west"))
+	assert.True(t, drpcfeatures.ClusterMismatchError.Has(err), "%v", err)
+	assert.Equal(t, uint64(9), drpcerr.Code(err))
+
+	// and so does a peer that advertises none
+	_, err = NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = drpcserver.New(drpcmux.New()).ServeOne(ctx, ps) })
+		return drpcconn.New(pc), nil
+	}, WithClusterID("east"))
+	assert.True(t, drpcfeatures.ClusterMismatchError.Has(err), "%v", err)
+
+	// a client without a cluster id is refused by the server
+	cc2, err := NewClientConnWithOptions(ctx, dial)
+	assert.NoError(t, err)
+	defer func() { _ = cc2.Close() }()
+	assert.Equal(t, uint64(9), drpcerr.Code(cc2.Invoke(ctx, "Unary", testEncoding{}, &in, &out)))
+}
+
+func TestTLSSessionResumption(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{testCertificate(t)},
+		MaxVersion:   tls.VersionTLS12,
+	}
+	var conns []drpc.Conn
+	cc, err := NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+		client, server := net.Pipe()
+		t.Cleanup(func() { _ = server.Close() })
+		go func() { _, _ = io.Copy(io.Discard, tls.Server(server, serverConfig)) }()
+
+		conn, err := HandshakeTLS(ctx, client, &tls.Config{InsecureSkipVerify: true, ServerName: "peer"})
+		if err == nil {
+			conns = append(conns, conn)
+		}
+		return conn, err
+	}, WithTLSSessionCache(tls.NewLRUClientSessionCache(0)))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	stats := cc.HandshakeStats()
+	assert.Equal(t, uint64(1), stats.Handshakes)
+	assert.Equal(t, uint64(0), stats.Resumed)
+
+	// the redial after the conn closes resumes the session
+	_ = conns[0].Close()
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	in, out := "foo", ""
+	_ = cc.Invoke(short, "Unary", testEncoding{}, &in, &out)
+
+	stats = cc.HandshakeStats()
+	assert.Equal(t, uint64(2), stats.Handshakes)
+	assert.Equal(t, uint64(1), stats.Resumed)
+	assert.Equal(t, uint64(0), stats.Failures)
+	assert.True(t, stats.MaxLatency > 0 && stats.MeanLatency() <= stats.MaxLatency)
+}
+
+func TestSPIFFEVerification(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	id, err := url.Parse("spiffe://example.org/svc")
+	assert.NoError(t, err)
+	cert := testCertificate(t, id)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	addr := tlsListener(ctx, t, cert)
+	dial := func(opts ...DialOption) (*ClientConn, error) {
+		return NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+			rawconn, err := net.Dial("tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return HandshakeTLS(ctx, rawconn, &tls.Config{RootCAs: roots, ServerName: "other"})
+		}, opts...)
+	}
+
+	// without verification the server name does not match
+	_, err = dial()
+	assert.Error(t, err)
+
+	// the SVID is verified instead of the server name and is the peer identity
+	var identity string
+	cc, err := dial(
+		WithSPIFFEVerification("example.org", "spiffe://example.org/svc"),
+		WithChainUnaryInterceptor(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, invoker UnaryInvoker) error {
+			err := invoker(ctx, rpc, enc, in, out, cc)
+			peer, _ := PeerFromContext(ctx)
+			identity = peer.Identity
+			return err
+		}),
+	)
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	in, out := "foo", ""
+	_ = cc.Invoke(short, "Unary", testEncoding{}, &in, &out)
+	assert.Equal(t, "spiffe://example.org/svc", identity)
+
+	// ids outside the trust domain or the allowed ids are refused
+	_, err = dial(WithSPIFFEVerification("other.org"))
+	assert.True(t, IdentityError.Has(err))
+	_, err = dial(WithSPIFFEVerification("example.org", "spiffe://example.org/db"))
+	assert.True(t, IdentityError.Has(err))
+}
+
+func TestVerifyPeer(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	id, err := url.Parse("spiffe://example.org/node")
+	assert.NoError(t, err)
+	cert := testCertificate(t, id)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	addr := tlsListener(ctx, t, cert)
+	dial := func(fn VerifyPeerFunc) error {
+		cc, err := NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+			rawconn, err := net.Dial("tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return HandshakeTLS(ctx, rawconn, &tls.Config{RootCAs: roots, ServerName: "peer"})
+		}, WithSPIFFEVerification("example.org"), WithVerifyPeer(fn))
+		if err == nil {
+			_ = cc.Close()
+		}
+		return err
+	}
+
+	// the hook sees the verified state and the target
+	var got PeerTarget
+	assert.NoError(t, dial(func(state tls.ConnectionState, target PeerTarget) error {
+		assert.Equal(t, "peer", state.PeerCertificates[0].DNSNames[0])
+		got = target
+		return nil
+	}))
+	assert.Equal(t, "peer", got.ServerName)
+	assert.Equal(t, addr, got.RemoteAddr.String())
+	assert.Equal(t, "spiffe://example.org/node", got.Identity)
+
+	// an error from the hook fails the handshake
+	err = dial(func(state tls.ConnectionState, target PeerTarget) error {
+		return errors.New("client certificate used as node")
+	})
+	assert.True(t, IdentityError.Has(err))
+}
+
+// tlsListener serves TLS with the certificate on a local listener until the
+// test ends, discarding what is read, and returns its address.
+func tlsListener(ctx *drpctest.Tracker, t *testing.T, cert tls.Certificate) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+	ctx.Run(func(ctx context.Context) {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(io.Discard, tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}}))
+				_ = conn.Close()
+			}()
+		}
+	})
+	return lis.Addr().String()
+}
+
+// testCertificate returns a self-signed certificate for the peer with the URIs.
+func testCertificate(t *testing.T, uris ...*url.URL) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"peer"},
+		URIs:         uris,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
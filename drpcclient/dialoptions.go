@@ -1,5 +1,17 @@
 package drpcclient
 
+import (
+	"crypto/tls"
+	"reflect"
+	"time"
+
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcenc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcfeatures"
+	"storj.io/drpc/drpcsession"
+)
+
 // dialOptions configure a NewClientConnWithOptions call. dialOptions are set by the DialOption
 // values passed to NewClientConnWithOptions.
 type dialOptions struct {
@@ -8,6 +20,66 @@ type dialOptions struct {
 
 	unaryInts  []UnaryClientInterceptor
 	streamInts []StreamClientInterceptor
+
+	features  drpcfeatures.Set
+	clusterID string
+	session   drpcsession.Prover
+
+	keepaliveInterval time.Duration
+	keepaliveTimeout  time.Duration
+
+	handshakeTimeout time.Duration
+
+	socket SocketOptions
+
+	channels []ChannelRoute
+
+	tlsSessions tls.ClientSessionCache
+	spiffe      *spiffeOptions
+	verifyPeer  VerifyPeerFunc
+
+	bufferPool *drpcenc.BufferPool
+
+	propagateDeadline bool
+	pprofLabels       bool
+	execTrace         bool
+	execTraceVerbose  bool
+
+	clock drpcclock.Clock
+
+	listeners []ConnEventListener
+	attrs     ConnAttributes
+
+	errorRules []drpcerr.Rule
+
+	runtime runtimeOptions
+}
+
+// runtimeOptions are the subset of dialOptions that may be changed after the
+// ClientConn is created with ClientConn.UpdateOptions. They are read once per
+// call so that an update applies atomically to subsequent calls.
+type runtimeOptions struct {
+	idleTimeout  time.Duration
+	peerVersion  int
+	unaryTimeout time.Duration
+	config       *ServiceConfig
+	flushDelay   time.Duration
+
+	maxMetadataSize int
+}
+
+// onlyRuntime returns true if the options set nothing but runtimeOptions,
+// which are the only options that may be changed with
+// ClientConn.UpdateOptions. The options are applied to zeroed dialOptions, so
+// that replacing a func valued option, such as an interceptor or a
+// VerifyPeerFunc, with another one is detected too.
+func onlyRuntime(opts []DialOption) bool {
+	var probe dialOptions
+	for _, opt := range opts {
+		opt(&probe)
+	}
+	probe.runtime = runtimeOptions{}
+	return reflect.ValueOf(probe).IsZero()
 }
 
 // DialOption configures how we set up the client connection.
@@ -32,3 +104,130 @@ func WithChainStreamInterceptor(ints ...StreamClientInterceptor) DialOption {
 		opt.streamInts = append(opt.streamInts, ints...)
 	}
 }
+
+// Interceptors returns the unary and stream interceptors added by the options
+// in chain order, so that tools such as drpcclienttest can exercise them.
+func Interceptors(opts ...DialOption) ([]UnaryClientInterceptor, []StreamClientInterceptor) {
+	dopts := defaultDialOptions()
+	for _, opt := range opts {
+		opt(&dopts)
+	}
+	return dopts.unaryInts, dopts.streamInts
+}
+
+// WithIdleTimeout returns a DialOption that closes the underlying conn after the
+// ClientConn has had no active RPCs for the duration d, transitioning it to the
+// Idle state. The next RPC dials a new conn using the DialerFunc. A
+// non-positive duration disables the idle timeout, which is the default. It may
+// be changed with ClientConn.UpdateOptions.
+func WithIdleTimeout(d time.Duration) DialOption {
+	return func(opt *dialOptions) {
+		opt.runtime.idleTimeout = d
+	}
+}
+
+// WithFeatures returns a DialOption that advertises the features to the peer
+// every time a conn is dialed. The features the peer also supports are
+// available from ClientConn.PeerFeatures. Peers must register the negotiation
+// rpc with drpcfeatures.Register; older peers negotiate an empty set. If both
+// advertise drpcfeatures.MaxFrameSize, the frames written on conns that
+// implement drpcfeatures.FrameLimiter, such as a *drpcconn.Conn, are limited to
+// the maximum of the peer.
+func WithFeatures(features drpcfeatures.Set) DialOption {
+	return func(opt *dialOptions) {
+		opt.features = features.Clone()
+	}
+}
+
+// WithClusterID returns a DialOption that advertises the id of the cluster or
+// deployment the client belongs to as the drpcfeatures.ClusterID feature every
+// time a conn is dialed, in addition to any WithFeatures, and fails the dial
+// with a drpcfeatures.ClusterMismatchError if the peer does not advertise the
+// same id. Peers advertise theirs by passing it to drpcfeatures.Register, and
+// refuse clients of other clusters with drpcfeatures.RequireCluster. An empty
+// id disables the check, which is the default.
+func WithClusterID(id string) DialOption {
+	return func(opt *dialOptions) {
+		opt.clusterID = id
+	}
+}
+
+// WithSessionAuthentication returns a DialOption that authenticates every conn
+// right after it is dialed by answering the challenge of the peer with the
+// prover, so that the rpcs on the conn do not carry credentials. A conn that
+// fails to authenticate fails the dial. Peers must register the authentication
+// rpc with drpcsession.Register.
+func WithSessionAuthentication(prover drpcsession.Prover) DialOption {
+	return func(opt *dialOptions) {
+		opt.session = prover
+	}
+}
+
+// WithPeerMetadataVersion returns a DialOption that gates the metadata added by
+// built-in interceptors on the drpcmetadata.Version the peers are known to run.
+// Keys introduced after that version are dropped instead of sent, which allows
+// rolling upgrades where older peers reject or mishandle unknown keys. A
+// non-positive version sends every key, which is the default. It may be
+// changed with ClientConn.UpdateOptions.
+func WithPeerMetadataVersion(version int) DialOption {
+	return func(opt *dialOptions) {
+		opt.runtime.peerVersion = version
+	}
+}
+
+// WithUnaryTimeout returns a DialOption that bounds unary RPCs issued without a
+// deadline on their context to the duration d. A non-positive duration means
+// no timeout, which is the default. It may be changed with
+// ClientConn.UpdateOptions.
+func WithUnaryTimeout(d time.Duration) DialOption {
+	return func(opt *dialOptions) {
+		opt.runtime.unaryTimeout = d
+	}
+}
+
+// WithServiceConfig returns a DialOption that applies the per method timeouts,
+// message size limits and retry policies of the service config to unary RPCs,
+// and its load balancing policy and backend groups to the Balancer the
+// ClientConn dials through, if any, with Balancer.SetServiceConfig. It may be
+// changed with ClientConn.UpdateOptions, which is how configuration
+// from a control plane takes effect.
+func WithServiceConfig(sc *ServiceConfig) DialOption {
+	return func(opt *dialOptions) {
+		opt.runtime.config = sc
+	}
+}
+
+// WithClock returns a DialOption that uses the clock for the retry backoffs,
+// idle timer, stream keepalives and resumable stream backoffs of the
+// ClientConn, so that tests can drive them with a drpcclock.Fake. Context
+// deadlines and the durations reported to interceptors and listeners still use
+// the system clock. The default is drpcclock.System.
+func WithClock(clock drpcclock.Clock) DialOption {
+	return func(opt *dialOptions) {
+		opt.clock = clock
+	}
+}
+
+// WithBufferPool returns a DialOption that marshals requests into scratch
+// buffers from the pool when a service config needs the marshaled request, for
+// example to enforce its size limits or to retry it. The pool's MaxSize caps
+// the size of the buffers kept for reuse. By default a pool shared by the whole
+// process is used.
+func WithBufferPool(pool *drpcenc.BufferPool) DialOption {
+	return func(opt *dialOptions) {
+		opt.bufferPool = pool
+	}
+}
+
+// WithPprofLabels returns a DialOption that runs every rpc, including its
+// interceptors, with the runtime/pprof labels from drpcctx.PprofLabels set on
+// the goroutine and the context, so that CPU profiles attribute time to
+// specific rpc methods. For streams the labels cover opening the stream and
+// are inherited by goroutines the ClientConn starts for it, such as for
+// keepalives, but not by sends and receives made later on the caller's
+// goroutine.
+func WithPprofLabels() DialOption {
+	return func(opt *dialOptions) {
+		opt.pprofLabels = true
+	}
+}
@@ -0,0 +1,101 @@
+package drpcclient
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+)
+
+// UnsentError is the class of errors of rpcs that failed before any of their
+// bytes were written, which are therefore always safe to issue again. Conns
+// return them for the rpcs they refuse, such as while draining, and a Balancer
+// transparently picks another backend for them.
+var UnsentError = errs.Class("rpc not sent")
+
+// DrainableConn is a drpc.Conn that can be drained, for example when its server
+// announces that it is shutting down. Once drained, new rpcs fail with an
+// UnsentError without being written while the rpcs in flight finish, and a
+// Balancer sends the new rpcs to another backend.
+type DrainableConn struct {
+	drpc.Conn
+	draining atomic.Bool
+}
+
+// NewDrainableConn returns a DrainableConn issuing rpcs on the conn.
+func NewDrainableConn(conn drpc.Conn) *DrainableConn {
+	return &DrainableConn{Conn: conn}
+}
+
+// Drain makes the conn refuse new rpcs.
+func (c *DrainableConn) Drain() { c.draining.Store(true) }
+
+// Draining returns true once the conn is drained.
+func (c *DrainableConn) Draining() bool { return c.draining.Load() }
+
+// Invoke issues the rpc unless the conn is draining.
+func (c *DrainableConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if c.Draining() {
+		return UnsentError.New("conn is draining")
+	}
+	return c.Conn.Invoke(ctx, rpc, enc, in, out)
+}
+
+// NewStream starts the stream unless the conn is draining.
+func (c *DrainableConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	if c.Draining() {
+		return nil, UnsentError.New("conn is draining")
+	}
+	return c.Conn.NewStream(ctx, rpc, enc)
+}
+
+// isDraining returns true if the conn is draining as reported by a Draining
+// method.
+func isDraining(conn drpc.Conn) bool {
+	dc, ok := conn.(interface{ Draining() bool })
+	return ok && dc.Draining()
+}
+
+// send picks a backend for the rpc and sends it with fn. While the picked
+// backend is draining or closed, or fn fails with an UnsentError, it picks
+// another backend, until every backend is avoided. Closed backends and conn
+// failures ask for the backends to be resolved again. The returned func, if
+// not nil, must be called once the rpc finishes.
+func (b *Balancer) send(ctx context.Context, rpc string, fn func(ctx context.Context, backend Backend) error) (func(), error) {
+	for {
+		backend, done, err := b.pick(ctx, rpc)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case isDraining(backend.Conn):
+			err = UnsentError.New("backend %q is draining", backend.Addr)
+		case isClosed(backend.Conn):
+			b.ResolveNow()
+			err = UnsentError.New("backend %q is closed", backend.Addr)
+		default:
+			err = fn(ctx, backend)
+		}
+
+		if err == nil || !UnsentError.Has(err) || avoided(ctx, backend.Addr) {
+			if err != nil && isConnFailure(err) {
+				b.ResolveNow()
+			}
+			return done, err
+		}
+
+		if done != nil {
+			done()
+		}
+		ctx = avoidBackend(ctx, backend.Addr)
+	}
+}
+
+// avoided returns true if the context asks to avoid the backend.
+func avoided(ctx context.Context, addr string) bool {
+	avoid, _ := ctx.Value(avoidKey{}).(map[string]bool)
+	return avoid[addr]
+}
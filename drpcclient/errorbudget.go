@@ -0,0 +1,143 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// ErrorBudget tracks the calls and errors of rpcs per remote peer, as reported
+// by PeerFromContext, against an allowed error rate, so that a balancer can
+// eject outliers and operators can spot a single misbehaving node. Peers are
+// keyed by their Balancer backend address if they have one, and by their
+// remote address otherwise. Calls canceled by the caller are not counted
+// against the peer, and calls whose peer has neither address are not
+// recorded.
+type ErrorBudget struct {
+	allowed  float64
+	minCalls uint64
+
+	mu    sync.RWMutex
+	peers map[string]*CallStats
+}
+
+// NewErrorBudget returns an ErrorBudget that allows the fraction of calls to
+// each peer, from 0 to 1, to fail. A peer is only considered to have exhausted
+// its budget once at least minCalls calls to it are recorded.
+func NewErrorBudget(allowed float64, minCalls uint64) *ErrorBudget {
+	return &ErrorBudget{
+		allowed:  allowed,
+		minCalls: minCalls,
+		peers:    make(map[string]*CallStats),
+	}
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that records the calls
+// through it.
+func (b *ErrorBudget) UnaryInterceptor() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		start := time.Now()
+		err := next(ctx, rpc, enc, in, out, cc)
+		b.record(ctx, time.Since(start), err)
+		return err
+	}
+}
+
+// StreamInterceptor returns a StreamClientInterceptor that records the opening
+// of the streams through it.
+func (b *ErrorBudget) StreamInterceptor() StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		start := time.Now()
+		stream, err := streamer(ctx, rpc, enc, cc)
+		b.record(ctx, time.Since(start), err)
+		return stream, err
+	}
+}
+
+// record adds a call to the stats of the peer of the rpc.
+func (b *ErrorBudget) record(ctx context.Context, d time.Duration, err error) {
+	if err != nil && ctx.Err() == context.Canceled {
+		return
+	}
+	peer, ok := PeerFromContext(ctx)
+	if !ok {
+		return
+	}
+	addr := peer.Backend
+	if addr == "" && peer.RemoteAddr != nil {
+		addr = peer.RemoteAddr.String()
+	}
+	if addr == "" {
+		return
+	}
+
+	b.mu.RLock()
+	stats := b.peers[addr]
+	b.mu.RUnlock()
+
+	if stats == nil {
+		b.mu.Lock()
+		if stats = b.peers[addr]; stats == nil {
+			stats = new(CallStats)
+			b.peers[addr] = stats
+		}
+		b.mu.Unlock()
+	}
+
+	stats.Record(d, err)
+}
+
+// Stats returns the stats recorded for the peer with the remote address.
+func (b *ErrorBudget) Stats(addr string) CallStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if stats := b.peers[addr]; stats != nil {
+		return stats.AtomicClone()
+	}
+	return CallStats{}
+}
+
+// Peers returns the stats recorded for every peer keyed by remote address.
+func (b *ErrorBudget) Peers() map[string]CallStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	peers := make(map[string]CallStats, len(b.peers))
+	for addr, stats := range b.peers {
+		peers[addr] = stats.AtomicClone()
+	}
+	return peers
+}
+
+// Remaining returns the fraction of the error budget of the peer with the
+// remote address that is left. It is 1 when no calls failed, 0 when the error
+// rate equals the allowed rate, and negative when the budget is overspent.
+func (b *ErrorBudget) Remaining(addr string) float64 {
+	rate := b.Stats(addr).ErrorRate()
+	if b.allowed <= 0 {
+		if rate > 0 {
+			return -1
+		}
+		return 1
+	}
+	return 1 - rate/b.allowed
+}
+
+// Exhausted returns true if the peer with the remote address has at least
+// minCalls recorded calls and an error rate above the allowed rate. Balancers
+// can eject such peers and call Reset when they are readmitted.
+func (b *ErrorBudget) Exhausted(addr string) bool {
+	stats := b.Stats(addr)
+	return stats.Calls >= b.minCalls && stats.ErrorRate() > b.allowed
+}
+
+// Reset forgets the stats recorded for the peer with the remote address.
+func (b *ErrorBudget) Reset(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.peers, addr)
+}
@@ -0,0 +1,150 @@
+package drpcclient
+
+import (
+	"time"
+
+	"storj.io/drpc"
+)
+
+// EventType identifies what a ConnEvent reports.
+type EventType int
+
+const (
+	// DialStart is sent before the ClientConn dials an underlying conn.
+	DialStart EventType = iota
+
+	// DialSuccess is sent once a dial and any feature negotiation succeeded.
+	DialSuccess
+
+	// DialFailure is sent when a dial or feature negotiation failed.
+	DialFailure
+
+	// StateChange is sent when the State of the ClientConn changes.
+	StateChange
+
+	// RPCStart is sent when an rpc is issued, before any interceptor runs.
+	RPCStart
+
+	// RPCEnd is sent when an rpc finishes. Unary rpcs finish when Invoke
+	// returns, and streams when their context is done or they fail to open.
+	RPCEnd
+
+	// Drain is sent when the ClientConn stops using its underlying conn and
+	// closes it after being idle.
+	Drain
+
+	// Close is sent when the ClientConn is closed.
+	Close
+)
+
+// String returns a human readable form of the EventType.
+func (t EventType) String() string {
+	switch t {
+	case DialStart:
+		return "DialStart"
+	case DialSuccess:
+		return "DialSuccess"
+	case DialFailure:
+		return "DialFailure"
+	case StateChange:
+		return "StateChange"
+	case RPCStart:
+		return "RPCStart"
+	case RPCEnd:
+		return "RPCEnd"
+	case Drain:
+		return "Drain"
+	case Close:
+		return "Close"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConnEvent is something that happened to a ClientConn. The fields that do
+// not apply to its Type are zero.
+type ConnEvent struct {
+	Type EventType
+	Time time.Time
+
+	// Err is the error of a DialFailure, of the unary rpc or stream that
+	// failed to open of an RPCEnd, or of closing the underlying conn for a
+	// Close.
+	Err error
+
+	// Duration is how long the dial of a DialSuccess or DialFailure, or the
+	// rpc of an RPCEnd, took.
+	Duration time.Duration
+
+	// From and To are the states of a StateChange.
+	From, To State
+
+	// RPC and Stream describe the rpc of an RPCStart or RPCEnd.
+	RPC    string
+	Stream bool
+
+	// Attributes are the attributes of the ClientConn from
+	// WithConnAttributes. They must not be modified.
+	Attributes ConnAttributes
+}
+
+// ConnEventListener receives the events of a ClientConn. OnEvent is called
+// synchronously, possibly while the ClientConn holds internal locks, so it
+// must return quickly and must not call methods of the ClientConn.
+type ConnEventListener interface {
+	OnEvent(ev ConnEvent)
+}
+
+// ConnEventListenerFunc is a function that implements ConnEventListener.
+type ConnEventListenerFunc func(ev ConnEvent)
+
+// OnEvent calls fn(ev).
+func (fn ConnEventListenerFunc) OnEvent(ev ConnEvent) { fn(ev) }
+
+// WithEventListener returns a DialOption that sends the events of the
+// ClientConn to the listener, so that embedders can build logging and alerting
+// in one place instead of across interceptors and dialers. It may be passed
+// more than once to add more listeners.
+func WithEventListener(l ConnEventListener) DialOption {
+	return func(opt *dialOptions) {
+		opt.listeners = append(opt.listeners, l)
+	}
+}
+
+// emit sends the event to every listener, setting its Time and Attributes.
+func (c *ClientConn) emit(ev ConnEvent) {
+	if len(c.dopts.listeners) == 0 {
+		return
+	}
+	ev.Time = time.Now()
+	ev.Attributes = c.dopts.attrs
+	for _, l := range c.dopts.listeners {
+		l.OnEvent(ev)
+	}
+}
+
+// setStateLocked changes the state, sending a StateChange if it differs. It
+// must be called with c.mu held.
+func (c *ClientConn) setStateLocked(state State) {
+	from := c.state
+	c.state = state
+	if from != state {
+		c.emit(ConnEvent{Type: StateChange, From: from, To: state})
+	}
+}
+
+// streamEnd sends the RPCEnd of a stream once it finishes, or immediately if
+// it failed to open.
+func (c *ClientConn) streamEnd(rpc string, start time.Time, stream drpc.Stream, err error) {
+	if len(c.dopts.listeners) == 0 {
+		return
+	}
+	if err != nil {
+		c.emit(ConnEvent{Type: RPCEnd, RPC: rpc, Stream: true, Err: err, Duration: time.Since(start)})
+		return
+	}
+	go func() {
+		<-stream.Context().Done()
+		c.emit(ConnEvent{Type: RPCEnd, RPC: rpc, Stream: true, Duration: time.Since(start)})
+	}()
+}
@@ -0,0 +1,82 @@
+package drpcclient
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+
+	"storj.io/drpc"
+)
+
+// WithExecutionTrace returns a DialOption that records every rpc as a
+// runtime/trace task named after the rpc, so that go tool trace shows the
+// lifecycle of each rpc and the goroutines that worked on it. The task of a
+// stream ends once the stream is finished. Errors are logged on the task with
+// the category "error". If verbose is set, every interceptor also runs in a
+// region named "interceptor[i]" for the i'th interceptor. Nothing is recorded
+// while no execution trace is being collected.
+func WithExecutionTrace(verbose bool) DialOption {
+	return func(opt *dialOptions) {
+		opt.execTrace = true
+		opt.execTraceVerbose = verbose
+	}
+}
+
+// startTask starts the task of an rpc if execution tracing is enabled and a
+// trace is being collected.
+func (c *ClientConn) startTask(ctx context.Context, rpc string) (context.Context, *trace.Task) {
+	if !c.dopts.execTrace || !trace.IsEnabled() {
+		return ctx, nil
+	}
+	return trace.NewTask(ctx, rpc)
+}
+
+// endTask logs the error of the rpc, if any, and ends its task.
+func endTask(ctx context.Context, task *trace.Task, err error) {
+	if err != nil {
+		trace.Log(ctx, "error", err.Error())
+	}
+	task.End()
+}
+
+// endStreamTask ends the task of a stream once the stream is finished, or
+// immediately if it failed to open.
+func endStreamTask(ctx context.Context, task *trace.Task, stream drpc.Stream, err error) {
+	if err != nil {
+		endTask(ctx, task, err)
+		return
+	}
+	go func() {
+		<-stream.Context().Done()
+		task.End()
+	}()
+}
+
+// traceInterceptorRegions wraps every interceptor in a region if verbose
+// execution tracing is enabled. The wrapped interceptors are new slices so
+// that the options passed in are not modified.
+func traceInterceptorRegions(dopts *dialOptions) {
+	if !dopts.execTraceVerbose {
+		return
+	}
+
+	unaryInts := make([]UnaryClientInterceptor, len(dopts.unaryInts))
+	for i, interceptor := range dopts.unaryInts {
+		interceptor, name := interceptor, fmt.Sprintf("interceptor[%d]", i)
+		unaryInts[i] = func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+			defer trace.StartRegion(ctx, name).End()
+			return interceptor(ctx, rpc, enc, in, out, cc, next)
+		}
+	}
+	dopts.unaryInts = unaryInts
+
+	streamInts := make([]StreamClientInterceptor, len(dopts.streamInts))
+	for i, interceptor := range dopts.streamInts {
+		interceptor, name := interceptor, fmt.Sprintf("interceptor[%d]", i)
+		streamInts[i] = func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+			defer trace.StartRegion(ctx, name).End()
+			return interceptor(ctx, rpc, enc, cc, streamer)
+		}
+	}
+	dopts.streamInts = streamInts
+}
@@ -0,0 +1,224 @@
+package drpcclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcerr"
+)
+
+// ErrTargetDown is returned, wrapped with the gRPC UNAVAILABLE code, instead
+// of dialing a target that a FailFast marked down.
+var ErrTargetDown = drpc.Error.New("target marked down")
+
+// FailFastOptions configures a FailFast. Zero values use the defaults.
+type FailFastOptions struct {
+	// Threshold is the number of consecutive dial failures after which a
+	// target is marked down. It defaults to 3.
+	Threshold int
+
+	// Window is how long after the first failure of a streak the rest of
+	// the streak must happen for them to count as consecutive. It defaults
+	// to 30 seconds.
+	Window time.Duration
+
+	// Prober probes the targets marked down in the background and marks them
+	// up again once a probe succeeds. It defaults to a Prober with default
+	// options.
+	Prober *Prober
+
+	// Probe checks a target marked down. It defaults to dialing the target
+	// like the dial that failed last and closing the conn.
+	Probe ProbeFunc
+
+	// Clock measures the window. It defaults to drpcclock.System.
+	Clock drpcclock.Clock
+}
+
+// FailFast remembers the dial failures of targets, so that after Threshold
+// consecutive failures within the Window, dials of the target fail immediately
+// with ErrTargetDown instead of every caller spending its deadline on a dead
+// node. A target marked down is probed in the background by the Prober and
+// marked up again once a probe succeeds. Dials that fail because their
+// context is done do not count. A FailFast is shared by the ClientConns of a
+// process by wrapping their dialers with Dialer or AddrDialer. It is safe for
+// concurrent use.
+type FailFast struct {
+	opts  FailFastOptions
+	clock drpcclock.Clock
+
+	mu      sync.Mutex
+	targets map[string]*targetState
+}
+
+// targetState is the failure memory of a target.
+type targetState struct {
+	failures int
+	first    time.Time
+	down     bool
+	since    time.Time
+	err      error
+	dial     DialerFunc
+}
+
+// NewFailFast returns a FailFast configured by opts.
+func NewFailFast(opts FailFastOptions) *FailFast {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 3
+	}
+	if opts.Window <= 0 {
+		opts.Window = 30 * time.Second
+	}
+	if opts.Prober == nil {
+		opts.Prober = NewProber(ProberOptions{})
+	}
+	return &FailFast{
+		opts:    opts,
+		clock:   drpcclock.Or(opts.Clock),
+		targets: make(map[string]*targetState),
+	}
+}
+
+// Dialer returns a DialerFunc that dials the target with dial unless the
+// target is marked down.
+func (f *FailFast) Dialer(target string, dial DialerFunc) DialerFunc {
+	return func(ctx context.Context) (drpc.Conn, error) {
+		if err := f.check(target); err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx)
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+		f.record(target, dial, err)
+		return conn, err
+	}
+}
+
+// AddrDialer returns an AddrDialerFunc that dials each address with dial
+// unless the address is marked down, for the backends of a ResolvedBalancer.
+func (f *FailFast) AddrDialer(dial AddrDialerFunc) AddrDialerFunc {
+	return func(ctx context.Context, addr string) (drpc.Conn, error) {
+		return f.Dialer(addr, func(ctx context.Context) (drpc.Conn, error) {
+			return dial(ctx, addr)
+		})(ctx)
+	}
+}
+
+// Down returns true if the target is marked down.
+func (f *FailFast) Down(target string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ts := f.targets[target]
+	return ts != nil && ts.down
+}
+
+// DownTargets returns the targets marked down, sorted.
+func (f *FailFast) DownTargets() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var down []string
+	for target, ts := range f.targets {
+		if ts.down {
+			down = append(down, target)
+		}
+	}
+	sort.Strings(down)
+	return down
+}
+
+// Reset forgets the failures of the target, marking it up.
+func (f *FailFast) Reset(target string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.resetLocked(target)
+}
+
+// check returns the error dials of the target fail with while it is down.
+func (f *FailFast) check(target string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ts := f.targets[target]
+	if ts == nil || !ts.down {
+		return nil
+	}
+	return drpcerr.WithCode(fmt.Errorf("%w: %q since %v: %v",
+		ErrTargetDown, target, f.clock.Now().Sub(ts.since).Round(time.Millisecond), ts.err), drpcerr.Unavailable)
+}
+
+// record remembers the result of a dial of the target, marking it down once
+// it failed Threshold times in a row.
+func (f *FailFast) record(target string, dial DialerFunc, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.resetLocked(target)
+		return
+	}
+
+	ts := f.targets[target]
+	if ts == nil {
+		ts = new(targetState)
+		f.targets[target] = ts
+	}
+	if ts.down {
+		return
+	}
+
+	now := f.clock.Now()
+	if ts.failures == 0 || now.Sub(ts.first) > f.opts.Window {
+		ts.failures, ts.first = 0, now
+	}
+	ts.failures++
+	ts.err, ts.dial = err, dial
+
+	if ts.failures >= f.opts.Threshold {
+		ts.down, ts.since = true, now
+		probe := f.opts.Probe
+		if probe == nil {
+			probe = ts.dialProbe
+		}
+		// the prober never holds its lock while calling back, so it may be
+		// started with f.mu held.
+		f.opts.Prober.Start(target, probe, func() { f.markUp(target, ts) })
+	}
+}
+
+// dialProbe dials the target like its last failed dial and closes the conn.
+func (ts *targetState) dialProbe(ctx context.Context, target string) error {
+	conn, err := ts.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// markUp forgets the failures of the target once a probe succeeded, unless it
+// was reset since it was marked down.
+func (f *FailFast) markUp(target string, ts *targetState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.targets[target] == ts {
+		delete(f.targets, target)
+	}
+}
+
+// resetLocked forgets the target and stops probing it. It must be called with
+// f.mu held.
+func (f *FailFast) resetLocked(target string) {
+	if ts := f.targets[target]; ts != nil && ts.down {
+		f.opts.Prober.Stop(target)
+	}
+	delete(f.targets, target)
+}
@@ -0,0 +1,125 @@
+package drpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+)
+
+// NewStaticResolver returns a Resolver that always returns the addresses.
+func NewStaticResolver(addrs ...string) Resolver {
+	addrs = append([]string(nil), addrs...)
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		return append([]string(nil), addrs...), nil
+	})
+}
+
+// DefaultFilePollInterval is the default FileResolverOptions.PollInterval.
+const DefaultFilePollInterval = time.Second
+
+// FileResolverOptions configure a FileResolver.
+type FileResolverOptions struct {
+	// PollInterval is how often the file is checked for changes. It defaults
+	// to DefaultFilePollInterval.
+	PollInterval time.Duration
+
+	// Clock is the clock of the PollInterval. It defaults to the system clock.
+	Clock drpcclock.Clock
+}
+
+// FileResolver is a Resolver that reads the addresses from a file, for
+// deployments without service discovery. A file named with a ".yaml" or
+// ".yml" extension is parsed as YAML, and any other as JSON. It either holds a
+// list of addresses, or an object whose "addresses" field is that list, such
+// as
+//
+//	{"addresses": ["10.0.0.1:7777", "10.0.0.2:7777"]}
+//
+// A ResolvedBalancer using it resolves again as soon as the modification time
+// or size of the file changes. A file that cannot be read or parsed, or that
+// has no addresses, fails the resolution, which keeps the current backends.
+type FileResolver struct {
+	path string
+	opts FileResolverOptions
+}
+
+// NewFileResolver returns a FileResolver reading the file at path.
+func NewFileResolver(path string, opts FileResolverOptions) *FileResolver {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultFilePollInterval
+	}
+	return &FileResolver{path: path, opts: opts}
+}
+
+// Resolve reads and parses the file.
+func (r *FileResolver) Resolve(ctx context.Context) ([]string, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, drpc.Error.Wrap(err)
+	}
+
+	unmarshal := json.Unmarshal
+	switch strings.ToLower(filepath.Ext(r.path)) {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	}
+
+	var addrs []string
+	if err := unmarshal(data, &addrs); err != nil {
+		var file struct {
+			Addresses []string `json:"addresses" yaml:"addresses"`
+		}
+		if err := unmarshal(data, &file); err != nil {
+			return nil, drpc.Error.New("invalid endpoints file %q: %v", r.path, err)
+		}
+		addrs = file.Addresses
+	}
+	if len(addrs) == 0 {
+		return nil, drpc.Error.New("no addresses in endpoints file %q", r.path)
+	}
+	return addrs, nil
+}
+
+// Watch calls changed each time the modification time or size of the file
+// changes, checking every PollInterval until the context is done.
+func (r *FileResolver) Watch(ctx context.Context, changed func()) {
+	ticker := drpcclock.Or(r.opts.Clock).NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	last := r.stat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		if cur := r.stat(); cur != last {
+			last = cur
+			changed()
+		}
+	}
+}
+
+// fileVersion identifies a version of a file.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// stat returns the version of the file, which is zero if it does not exist.
+func (r *FileResolver) stat() fileVersion {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{modTime: fi.ModTime(), size: fi.Size()}
+}
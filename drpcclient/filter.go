@@ -0,0 +1,68 @@
+package drpcclient
+
+import (
+	"context"
+	"strings"
+
+	"storj.io/drpc"
+)
+
+// MethodMatcher reports whether an interceptor applies to an rpc. It is used to
+// apply heavy interceptors, such as payload logging or validation, to a subset
+// of the rpcs on a ClientConn:
+//
+//	WithChainUnaryInterceptor(
+//	    MatchPrefix("/kv.KV/").Unary(loggingInterceptor),
+//	    ForMethods("/kv.KV/Put", "/kv.KV/Delete").Unary(validationInterceptor),
+//	)
+type MethodMatcher func(rpc string) bool
+
+// ForMethods returns a MethodMatcher that matches exactly the given rpcs.
+func ForMethods(rpcs ...string) MethodMatcher {
+	set := make(map[string]struct{}, len(rpcs))
+	for _, rpc := range rpcs {
+		set[rpc] = struct{}{}
+	}
+	return func(rpc string) bool {
+		_, ok := set[rpc]
+		return ok
+	}
+}
+
+// MatchPrefix returns a MethodMatcher that matches the rpcs starting with the
+// prefix, for example "/kv.KV/" for every method of a service.
+func MatchPrefix(prefix string) MethodMatcher {
+	return func(rpc string) bool { return strings.HasPrefix(rpc, prefix) }
+}
+
+// Not returns a MethodMatcher that matches the rpcs m does not.
+func (m MethodMatcher) Not() MethodMatcher {
+	return func(rpc string) bool { return !m(rpc) }
+}
+
+// Or returns a MethodMatcher that matches the rpcs either m or other match.
+func (m MethodMatcher) Or(other MethodMatcher) MethodMatcher {
+	return func(rpc string) bool { return m(rpc) || other(rpc) }
+}
+
+// Unary returns a UnaryClientInterceptor that runs the interceptor for the rpcs
+// m matches and calls next directly for all others.
+func (m MethodMatcher) Unary(interceptor UnaryClientInterceptor) UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		if !m(rpc) {
+			return next(ctx, rpc, enc, in, out, cc)
+		}
+		return interceptor(ctx, rpc, enc, in, out, cc, next)
+	}
+}
+
+// Stream returns a StreamClientInterceptor that runs the interceptor for the
+// rpcs m matches and calls streamer directly for all others.
+func (m MethodMatcher) Stream(interceptor StreamClientInterceptor) StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		if !m(rpc) {
+			return streamer(ctx, rpc, enc, cc)
+		}
+		return interceptor(ctx, rpc, enc, cc, streamer)
+	}
+}
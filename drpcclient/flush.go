@@ -0,0 +1,53 @@
+package drpcclient
+
+import (
+	"context"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// flushDelayStream is implemented by streams that can coalesce message sends,
+// such as the *drpcstream.Stream returned by drpcconn.
+type flushDelayStream interface {
+	SetFlushDelay(d time.Duration)
+}
+
+// WithFlushDelay returns a DialOption that buffers messages sent on streams
+// for up to the duration d before flushing them, so that streams of many small
+// messages are coalesced into fewer, larger writes at the cost of up to d of
+// added latency. A flush also happens as soon as the conn's writer buffer is
+// full, whose size is drpcmanager.Options.WriterBufferSize of the conns
+// returned by the DialerFunc, and whenever the stream receives or closes. A
+// non-positive duration flushes every message immediately, which is the
+// default. Latency critical calls can override it with WithCallFlushDelay. It
+// may be changed with ClientConn.UpdateOptions.
+func WithFlushDelay(d time.Duration) DialOption {
+	return func(opt *dialOptions) {
+		opt.runtime.flushDelay = d
+	}
+}
+
+// flushDelayKey is the context key for the per call flush delay.
+type flushDelayKey struct{}
+
+// WithCallFlushDelay returns a context that overrides the flush delay set with
+// WithFlushDelay for streams created with it. A non-positive duration flushes
+// every message immediately.
+func WithCallFlushDelay(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, flushDelayKey{}, d)
+}
+
+// setFlushDelay configures the flush delay of the stream created with ctx if
+// the stream supports it.
+func (c *ClientConn) setFlushDelay(ctx context.Context, stream drpc.Stream) {
+	fs, ok := stream.(flushDelayStream)
+	if !ok {
+		return
+	}
+	if d, ok := ctx.Value(flushDelayKey{}).(time.Duration); ok {
+		fs.SetFlushDelay(d)
+	} else if d := c.runtime().flushDelay; d > 0 {
+		fs.SetFlushDelay(d)
+	}
+}
@@ -0,0 +1,195 @@
+package drpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+)
+
+// HandshakeTimeoutError is the class of errors returned when dialing a conn
+// takes longer than the WithHandshakeTimeout after it is connected. They are
+// not context.DeadlineExceeded, so that a slow or hung peer is told apart from
+// an unreachable one and from an rpc running out of time.
+var HandshakeTimeoutError = errs.Class("handshake timeout")
+
+// WithHandshakeTimeout returns a DialOption that bounds the handshake of every
+// conn the ClientConn dials to the duration d, independently of the deadline
+// of the rpc that caused the dial and of any timeout the dialer applies to
+// connecting. The handshake starts with the TLS handshake of a conn dialed
+// with HandshakeTLS, or else with the feature negotiation of WithFeatures, and
+// ends once both are done. A non-positive duration disables the timeout,
+// which is the default.
+func WithHandshakeTimeout(d time.Duration) DialOption {
+	return func(opt *dialOptions) {
+		opt.handshakeTimeout = d
+	}
+}
+
+// WithTLSSessionCache returns a DialOption that resumes the TLS sessions of the
+// conns the ClientConn dials with HandshakeTLS from the cache, unless their
+// config has its own ClientSessionCache, so that redialing after a network
+// blip skips the full handshake. Passing the same cache, such as one from
+// tls.NewLRUClientSessionCache, to several ClientConns shares the sessions
+// between them.
+func WithTLSSessionCache(cache tls.ClientSessionCache) DialOption {
+	return func(opt *dialOptions) {
+		opt.tlsSessions = cache
+	}
+}
+
+// HandshakeStats are the metrics of the TLS handshakes of the conns a
+// ClientConn dialed with HandshakeTLS.
+type HandshakeStats struct {
+	// Handshakes counts the completed handshakes, Resumed those that resumed
+	// a session, and Failures the handshakes that failed.
+	Handshakes uint64
+	Resumed    uint64
+	Failures   uint64
+
+	// Latency is the total time the completed handshakes took, and
+	// MaxLatency the longest.
+	Latency    time.Duration
+	MaxLatency time.Duration
+}
+
+// MeanLatency returns the average time a completed handshake took.
+func (s HandshakeStats) MeanLatency() time.Duration {
+	if s.Handshakes == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Handshakes)
+}
+
+// handshakeStats collects the HandshakeStats of a ClientConn.
+type handshakeStats struct {
+	mu    sync.Mutex
+	stats HandshakeStats
+}
+
+// record adds a handshake that took the latency.
+func (s *handshakeStats) record(latency time.Duration, resumed bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.stats.Failures++
+		return
+	}
+	s.stats.Handshakes++
+	if resumed {
+		s.stats.Resumed++
+	}
+	s.stats.Latency += latency
+	if latency > s.stats.MaxLatency {
+		s.stats.MaxLatency = latency
+	}
+}
+
+// HandshakeStats returns the metrics of the TLS handshakes of the conns the
+// ClientConn dialed with HandshakeTLS.
+func (c *ClientConn) HandshakeStats() HandshakeStats {
+	c.hsStats.mu.Lock()
+	defer c.hsStats.mu.Unlock()
+
+	return c.hsStats.stats
+}
+
+// HandshakeTLS performs the TLS handshake on the connected rawconn with the
+// config and returns a drpc.Conn over it. It is meant to be called by a
+// DialerFunc after connecting, so that the handshake is bounded by the
+// WithHandshakeTimeout of the ClientConn that is dialing, resumes sessions
+// from its WithTLSSessionCache, verifies its WithSPIFFEVerification and
+// WithVerifyPeer, and is counted in its HandshakeStats. The rawconn is closed if the handshake fails.
+func HandshakeTLS(ctx context.Context, rawconn net.Conn, config *tls.Config) (drpc.Conn, error) {
+	hs := handshakeFrom(ctx)
+	if hs != nil && hs.sessions != nil && config.ClientSessionCache == nil {
+		config = config.Clone()
+		config.ClientSessionCache = hs.sessions
+	}
+	if hs != nil && hs.spiffe != nil {
+		config = hs.spiffe.config(config)
+	}
+	if hs != nil && hs.verifyPeer != nil {
+		config = verifyPeerConfig(config, rawconn, hs.spiffe != nil, hs.verifyPeer)
+	}
+
+	conn := tls.Client(rawconn, config)
+	start := time.Now()
+	err := hs.run(ctx, "tls handshake", conn.HandshakeContext)
+	if hs != nil && hs.stats != nil {
+		hs.stats.record(time.Since(start), conn.ConnectionState().DidResume, err)
+	}
+	if err != nil {
+		_ = rawconn.Close()
+		return nil, err
+	}
+	if hs != nil && hs.spiffe != nil {
+		id := spiffeID(conn.ConnectionState())
+		return &identityConn{Conn: drpcconn.New(conn), identity: id}, nil
+	}
+	return drpcconn.New(conn), nil
+}
+
+// handshake is the time budget of the handshake of a dial, along with the
+// session cache, peer verification and stats of the ClientConn. It is passed
+// to the dialer in the context.
+type handshake struct {
+	timeout  time.Duration
+	deadline time.Time // zero until the handshake starts
+
+	sessions   tls.ClientSessionCache
+	spiffe     *spiffeOptions
+	verifyPeer VerifyPeerFunc
+	stats      *handshakeStats
+}
+
+// handshakeKey is the context key of the handshake of a dial.
+type handshakeKey struct{}
+
+// withHandshake returns a context carrying a handshake configured by the dial
+// options that is recorded in the stats.
+func withHandshake(ctx context.Context, opts *dialOptions, stats *handshakeStats) (context.Context, *handshake) {
+	h := &handshake{
+		timeout:    opts.handshakeTimeout,
+		sessions:   opts.tlsSessions,
+		spiffe:     opts.spiffe,
+		verifyPeer: opts.verifyPeer,
+		stats:      stats,
+	}
+	return context.WithValue(ctx, handshakeKey{}, h), h
+}
+
+// handshakeFrom returns the handshake of the dial, if any.
+func handshakeFrom(ctx context.Context) *handshake {
+	h, _ := ctx.Value(handshakeKey{}).(*handshake)
+	return h
+}
+
+// run calls fn with a context that is done when the budget of the handshake
+// runs out, starting the budget if this is the first step of the handshake.
+// Running out returns a HandshakeTimeoutError. A nil handshake or one without
+// a timeout calls fn with ctx.
+func (h *handshake) run(ctx context.Context, step string, fn func(ctx context.Context) error) error {
+	if h == nil || h.timeout <= 0 {
+		return fn(ctx)
+	}
+	if h.deadline.IsZero() {
+		h.deadline = time.Now().Add(h.timeout)
+	}
+
+	hctx, cancel := context.WithDeadline(ctx, h.deadline)
+	defer cancel()
+
+	err := fn(hctx)
+	if err != nil && ctx.Err() == nil && hctx.Err() != nil {
+		return HandshakeTimeoutError.New("%s did not finish within %v", step, h.timeout)
+	}
+	return err
+}
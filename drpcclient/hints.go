@@ -0,0 +1,71 @@
+package drpcclient
+
+import (
+	"context"
+
+	"storj.io/drpc"
+)
+
+// HintSkip is the hint value that disables an interceptor for a call.
+const HintSkip = "skip"
+
+// RetryHint is the interceptor name consulted by the retries of the service
+// config. Setting it to HintSkip disables retries for a call.
+const RetryHint = "retry"
+
+// InterceptorHints maps interceptor names to hints that tune or disable them
+// for a single call, for example {"retry": HintSkip, "trace": "force"}.
+type InterceptorHints map[string]string
+
+type hintsKey struct{}
+
+// WithInterceptorHints returns a context carrying the hints merged over any
+// hints already on the context.
+func WithInterceptorHints(ctx context.Context, hints InterceptorHints) context.Context {
+	merged := make(InterceptorHints, len(hints))
+	if parent, ok := ctx.Value(hintsKey{}).(InterceptorHints); ok {
+		for name, hint := range parent {
+			merged[name] = hint
+		}
+	}
+	for name, hint := range hints {
+		merged[name] = hint
+	}
+	return context.WithValue(ctx, hintsKey{}, merged)
+}
+
+// InterceptorHint returns the hint for the named interceptor on the context.
+func InterceptorHint(ctx context.Context, name string) (string, bool) {
+	hints, _ := ctx.Value(hintsKey{}).(InterceptorHints)
+	hint, ok := hints[name]
+	return hint, ok
+}
+
+// SkipInterceptor returns true if the call site asked for the named
+// interceptor to be disabled.
+func SkipInterceptor(ctx context.Context, name string) bool {
+	hint, _ := InterceptorHint(ctx, name)
+	return hint == HintSkip
+}
+
+// NamedUnary returns a UnaryClientInterceptor that runs the interceptor unless
+// the call site disabled it by name with HintSkip.
+func NamedUnary(name string, interceptor UnaryClientInterceptor) UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		if SkipInterceptor(ctx, name) {
+			return next(ctx, rpc, enc, in, out, cc)
+		}
+		return interceptor(ctx, rpc, enc, in, out, cc, next)
+	}
+}
+
+// NamedStream returns a StreamClientInterceptor that runs the interceptor
+// unless the call site disabled it by name with HintSkip.
+func NamedStream(name string, interceptor StreamClientInterceptor) StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		if SkipInterceptor(ctx, name) {
+			return streamer(ctx, rpc, enc, cc)
+		}
+		return interceptor(ctx, rpc, enc, cc, streamer)
+	}
+}
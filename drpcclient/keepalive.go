@@ -0,0 +1,89 @@
+package drpcclient
+
+import (
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcfeatures"
+)
+
+// keepaliveStream is implemented by streams that support keepalives, such as
+// the *drpcstream.Stream returned by drpcconn.
+type keepaliveStream interface {
+	drpc.Stream
+	SendKeepalive() error
+	LastReceived() time.Time
+	Cancel(err error) bool
+}
+
+// keepaliveTimeout is the error a stream is canceled with when the remote did
+// not send anything within the keepalive timeout.
+var keepaliveTimeout = drpc.ClosedError.New("stream keepalive timeout")
+
+// WithStreamKeepalive returns a DialOption that sends a keepalive ping on
+// streams that have not received anything for the interval, and cancels
+// streams that have not received anything, including the answer to a ping,
+// for the timeout. This detects half-open connections under long-lived idle
+// streams such as watches. The remote must answer keepalives, so if features
+// are negotiated with WithFeatures, keepalives are only sent to peers that
+// advertise drpcfeatures.Keepalive. A non-positive interval disables
+// keepalives, which is the default.
+func WithStreamKeepalive(interval, timeout time.Duration) DialOption {
+	return func(opt *dialOptions) {
+		opt.keepaliveInterval = interval
+		opt.keepaliveTimeout = timeout
+	}
+}
+
+// startKeepalive monitors the stream with keepalives if they are configured
+// and supported by the stream and the peer.
+func (c *ClientConn) startKeepalive(stream drpc.Stream) {
+	interval, timeout := c.dopts.keepaliveInterval, c.dopts.keepaliveTimeout
+	if interval <= 0 {
+		return
+	}
+	ks, ok := stream.(keepaliveStream)
+	if !ok {
+		return
+	}
+	if c.dopts.features != nil && !c.PeerFeatures().Has(drpcfeatures.Keepalive) {
+		return
+	}
+	if timeout < interval {
+		timeout = 2 * interval
+	}
+
+	go monitorKeepalive(ks, c.clock(), interval, timeout)
+}
+
+// monitorKeepalive pings the stream when it is idle for the interval and
+// cancels it when it is idle for the timeout, until the stream is finished.
+// The stream reports when it last received in system time, so with any other
+// clock receiving is noticed at the next tick instead.
+func monitorKeepalive(ks keepaliveStream, clock drpcclock.Clock, interval, timeout time.Duration) {
+	ticker := clock.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	last, received := clock.Now(), ks.LastReceived()
+	for {
+		select {
+		case <-ks.Context().Done():
+			return
+		case now := <-ticker.C():
+			switch r := ks.LastReceived(); {
+			case clock == drpcclock.System && r.After(last):
+				last = r
+			case clock != drpcclock.System && !r.Equal(received):
+				received, last = r, now
+			}
+			switch idle := now.Sub(last); {
+			case idle >= timeout:
+				ks.Cancel(keepaliveTimeout)
+				return
+			case idle >= interval:
+				_ = ks.SendKeepalive()
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// LatencyBreakdown splits the latency of the calls of a method into the time
+// their servers reported spending on them and the overhead of the network and
+// the client.
+type LatencyBreakdown struct {
+	// Calls is the number of calls recorded, and Latency their total latency.
+	Calls   uint64
+	Latency time.Duration
+
+	// Reported is the number of calls whose server reported the time it spent
+	// on them in the drpcmetadata.ServerTime trailer, such as servers with
+	// drpcserver.Options.ReportServerTime set. Only those calls are counted
+	// by the fields below.
+	Reported uint64
+
+	// Server is the total time servers spent on the reported calls, and
+	// Queue the part of it the calls waited in server side queues.
+	Server time.Duration
+	Queue  time.Duration
+
+	// Overhead is the total latency of the reported calls that was not spent
+	// in the server, which is the time spent on the network and in the
+	// client, including the earlier attempts of retried calls.
+	Overhead time.Duration
+}
+
+// MeanLatency returns the average latency of the calls.
+func (b LatencyBreakdown) MeanLatency() time.Duration {
+	if b.Calls == 0 {
+		return 0
+	}
+	return b.Latency / time.Duration(b.Calls)
+}
+
+// MeanServer returns the average time servers spent on the reported calls.
+func (b LatencyBreakdown) MeanServer() time.Duration {
+	if b.Reported == 0 {
+		return 0
+	}
+	return b.Server / time.Duration(b.Reported)
+}
+
+// MeanOverhead returns the average network and client overhead of the
+// reported calls.
+func (b LatencyBreakdown) MeanOverhead() time.Duration {
+	if b.Reported == 0 {
+		return 0
+	}
+	return b.Overhead / time.Duration(b.Reported)
+}
+
+// LatencyRecorder records per method how the latency of unary calls splits
+// between the server and the network and client overhead, using the time
+// servers report in the trailers of their responses.
+type LatencyRecorder struct {
+	mu      sync.RWMutex
+	methods map[string]*LatencyBreakdown
+}
+
+// NewLatencyRecorder returns an empty LatencyRecorder.
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{methods: make(map[string]*LatencyBreakdown)}
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that records the calls
+// through it. Like InvokeDetailed, it waits for the trailer of every call,
+// which costs a little latency after the response arrives.
+func (r *LatencyRecorder) UnaryInterceptor() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		rec := callRecorderFrom(ctx)
+		if rec == nil {
+			rec = new(callRecorder)
+			ctx = context.WithValue(ctx, callResultKey{}, rec)
+		}
+
+		start := time.Now()
+		err := next(ctx, rpc, enc, in, out, cc)
+		latency := time.Since(start)
+
+		rec.mu.Lock()
+		trailer := rec.result.Trailer
+		rec.mu.Unlock()
+
+		r.record(rpc, latency, trailer)
+		return err
+	}
+}
+
+// record adds a call that took latency and ended with the trailer to the
+// breakdown of the rpc.
+func (r *LatencyRecorder) record(rpc string, latency time.Duration, trailer map[string]string) {
+	b := r.breakdown(rpc)
+
+	atomic.AddUint64(&b.Calls, 1)
+	atomic.AddInt64((*int64)(&b.Latency), int64(latency))
+
+	if _, ok := trailer[drpcmetadata.ServerTime.String()]; !ok {
+		return
+	}
+	server := trailerDuration(trailer, drpcmetadata.ServerTime)
+	overhead := latency - server
+	if overhead < 0 {
+		overhead = 0
+	}
+
+	atomic.AddUint64(&b.Reported, 1)
+	atomic.AddInt64((*int64)(&b.Server), int64(server))
+	atomic.AddInt64((*int64)(&b.Queue), int64(trailerDuration(trailer, drpcmetadata.QueueTime)))
+	atomic.AddInt64((*int64)(&b.Overhead), int64(overhead))
+}
+
+// breakdown returns the breakdown of the rpc, creating it if necessary.
+func (r *LatencyRecorder) breakdown(rpc string) *LatencyBreakdown {
+	r.mu.RLock()
+	b := r.methods[rpc]
+	r.mu.RUnlock()
+	if b != nil {
+		return b
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b = r.methods[rpc]; b == nil {
+		b = new(LatencyBreakdown)
+		r.methods[rpc] = b
+	}
+	return b
+}
+
+// Breakdown returns the latency breakdown of every method that has recorded
+// calls, keyed by rpc.
+func (r *LatencyRecorder) Breakdown() map[string]LatencyBreakdown {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]LatencyBreakdown, len(r.methods))
+	for rpc, b := range r.methods {
+		out[rpc] = LatencyBreakdown{
+			Calls:    atomic.LoadUint64(&b.Calls),
+			Latency:  time.Duration(atomic.LoadInt64((*int64)(&b.Latency))),
+			Reported: atomic.LoadUint64(&b.Reported),
+			Server:   time.Duration(atomic.LoadInt64((*int64)(&b.Server))),
+			Queue:    time.Duration(atomic.LoadInt64((*int64)(&b.Queue))),
+			Overhead: time.Duration(atomic.LoadInt64((*int64)(&b.Overhead))),
+		}
+	}
+	return out
+}
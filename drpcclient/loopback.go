@@ -0,0 +1,249 @@
+package drpcclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcsignal"
+)
+
+// NewLoopback returns a ClientConn whose rpcs are served in-process by the
+// handler, such as a drpcmux.Mux, so that local services can be called through
+// the same interceptors and options as remote ones. If the encoding of an rpc
+// has a method
+//
+//	Copy(dst, src drpc.Message) error
+//
+// messages are copied with it instead of being marshaled and unmarshaled.
+// Handlers see the context of the rpc, including its metadata and deadline, and
+// the errors they return are returned to the caller unchanged.
+func NewLoopback(handler drpc.Handler, opts ...DialOption) (*ClientConn, error) {
+	return NewClientConnWithOptions(context.Background(), func(context.Context) (drpc.Conn, error) {
+		return &loopbackConn{handler: handler}, nil
+	}, opts...)
+}
+
+// loopbackConn is a drpc.Conn that runs rpcs against a handler in-process.
+type loopbackConn struct {
+	handler drpc.Handler
+	closed  drpcsignal.Signal
+
+	// up and down are the links of a simulated network, if any, carrying
+	// the messages sent by the client and the server.
+	up, down *simLink
+}
+
+func (c *loopbackConn) Close() error {
+	c.closed.Set(drpc.ClosedError.New("loopback closed"))
+	return nil
+}
+
+func (c *loopbackConn) Closed() <-chan struct{} { return c.closed.Signal() }
+
+func (c *loopbackConn) RemoteAddr() net.Addr { return loopbackAddr{} }
+func (c *loopbackConn) LocalAddr() net.Addr  { return loopbackAddr{} }
+
+func (c *loopbackConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) (err error) {
+	stream, err := c.NewStream(ctx, rpc, enc)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.Close() }()
+
+	if err := stream.MsgSend(in, enc); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	return stream.MsgRecv(out, enc)
+}
+
+func (c *loopbackConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	if err, ok := c.closed.Get(); ok {
+		return nil, err
+	}
+
+	cctx, ccancel := context.WithCancel(ctx)
+	sctx, scancel := context.WithCancel(cctx)
+
+	up, down := newLoopbackPipe(c.up), newLoopbackPipe(c.down)
+	client := &loopbackStream{ctx: cctx, cancel: ccancel, send: up, recv: down}
+	server := &loopbackStream{ctx: sctx, cancel: scancel, send: down, recv: up, server: true}
+
+	go func() {
+		err := c.handler.HandleRPC(server, rpc)
+		if err == nil {
+			err = io.EOF
+		}
+		scancel()
+		down.finish(err, ccancel)
+	}()
+
+	return client, nil
+}
+
+// loopbackAddr is the address of both ends of a loopback conn.
+type loopbackAddr struct{}
+
+func (loopbackAddr) Network() string { return "loopback" }
+func (loopbackAddr) String() string  { return "loopback" }
+
+// loopbackMsg hands a sent message to the receiver, which closes done once it
+// no longer needs the sent message.
+type loopbackMsg struct {
+	recv func(msg drpc.Message, enc drpc.Encoding) error
+	done chan struct{}
+}
+
+// loopbackPipe carries the messages sent in one direction of a stream. Over a
+// simulated network, messages are sent over the link and queued once they
+// arrive instead of being handed to the receiver.
+type loopbackPipe struct {
+	msgs chan loopbackMsg
+	sent drpcsignal.Signal // set once the sender is finished sending
+
+	link    *simLink
+	mu      sync.Mutex
+	queue   []loopbackMsg
+	arrived chan struct{} // signaled when a message is queued
+}
+
+func newLoopbackPipe(link *simLink) *loopbackPipe {
+	return &loopbackPipe{
+		msgs:    make(chan loopbackMsg),
+		link:    link,
+		arrived: make(chan struct{}, 1),
+	}
+}
+
+// finish marks the sender as finished, causing receives to return err once
+// every sent message has been received, and then calls done if it is not nil.
+// Over a simulated network, the finish is sent over the link after the
+// messages.
+func (p *loopbackPipe) finish(err error, done func()) {
+	deliver := func() {
+		p.sent.Set(err)
+		if done != nil {
+			done()
+		}
+	}
+	if p.link == nil {
+		deliver()
+		return
+	}
+	p.link.send(0, false, deliver)
+}
+
+// push queues a message that arrived over the link.
+func (p *loopbackPipe) push(m loopbackMsg) {
+	p.mu.Lock()
+	p.queue = append(p.queue, m)
+	p.mu.Unlock()
+
+	select {
+	case p.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// pop dequeues a message that arrived over the link.
+func (p *loopbackPipe) pop() (m loopbackMsg, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.queue) == 0 {
+		return loopbackMsg{}, false
+	}
+	m, p.queue = p.queue[0], p.queue[1:]
+	return m, true
+}
+
+// loopbackStream is one side of an in-process stream.
+type loopbackStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	send   *loopbackPipe
+	recv   *loopbackPipe
+	server bool // the handler result finishes sends on the server side
+
+	sendClosed bool
+}
+
+func (s *loopbackStream) Context() context.Context { return s.ctx }
+
+func (s *loopbackStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	if s.sendClosed {
+		return drpc.ClosedError.New("send after CloseSend")
+	}
+	if s.send.link != nil {
+		return s.simSend(msg, enc)
+	}
+
+	m := loopbackMsg{done: make(chan struct{})}
+	if copier, ok := enc.(interface {
+		Copy(dst, src drpc.Message) error
+	}); ok {
+		m.recv = func(dst drpc.Message, _ drpc.Encoding) error { return copier.Copy(dst, msg) }
+	} else {
+		data, err := enc.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		m.recv = func(dst drpc.Message, enc drpc.Encoding) error { return enc.Unmarshal(data, dst) }
+	}
+
+	select {
+	case s.send.msgs <- m:
+		<-m.done
+		return nil
+	case <-s.ctx.Done():
+		return s.ctxErr()
+	}
+}
+
+func (s *loopbackStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if s.recv.link != nil {
+		return s.simRecv(msg, enc)
+	}
+	select {
+	case m := <-s.recv.msgs:
+		defer close(m.done)
+		return m.recv(msg, enc)
+	case <-s.recv.sent.Signal():
+		return s.recv.sent.Err()
+	case <-s.ctx.Done():
+		return s.ctxErr()
+	}
+}
+
+// ctxErr returns the error for an operation interrupted by the context being
+// done. The handler finishing cancels the client context, so the result it
+// finished with is preferred.
+func (s *loopbackStream) ctxErr() error {
+	if !s.server {
+		if err, ok := s.recv.sent.Get(); ok {
+			return err
+		}
+	}
+	return s.ctx.Err()
+}
+
+func (s *loopbackStream) CloseSend() error {
+	if !s.sendClosed {
+		s.sendClosed = true
+		if !s.server {
+			s.send.finish(io.EOF, nil)
+		}
+	}
+	return nil
+}
+
+func (s *loopbackStream) Close() error {
+	_ = s.CloseSend()
+	s.cancel()
+	return nil
+}
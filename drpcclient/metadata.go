@@ -0,0 +1,68 @@
+package drpcclient
+
+import (
+	"context"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// AddMetadata attaches the namespaced key and value to the outgoing metadata on
+// the context. Interceptors should use it for any metadata they add so that the
+// key is dropped when the peers are gated on an older metadata version with
+// WithPeerMetadataVersion.
+func (c *ClientConn) AddMetadata(ctx context.Context, key drpcmetadata.Key, value string) context.Context {
+	if !key.SupportedBy(c.runtime().peerVersion) {
+		return ctx
+	}
+	return drpcmetadata.Add(ctx, key.String(), value)
+}
+
+// WithDeadlinePropagation returns a DialOption that sends the deadline of the
+// context of every rpc in the drpcmetadata.Deadline key, so that servers with
+// drpcserver.Options.HonorDeadlines set give handlers a context that expires
+// at the same time.
+func WithDeadlinePropagation() DialOption {
+	return func(opt *dialOptions) {
+		opt.propagateDeadline = true
+	}
+}
+
+// propagateDeadline adds the deadline of the context to its outgoing metadata
+// if deadline propagation is enabled.
+func (c *ClientConn) propagateDeadline(ctx context.Context) context.Context {
+	if !c.dopts.propagateDeadline {
+		return ctx
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx
+	}
+	return c.AddMetadata(ctx, drpcmetadata.Deadline, drpcmetadata.FormatDeadline(deadline))
+}
+
+// WithMaxMetadataSize returns a DialOption that fails rpcs whose outgoing
+// metadata, counted as the total bytes of its keys and values, is larger than
+// n bytes. The limit is checked after every interceptor has run, so it catches
+// interceptors that accidentally attach huge values such as tracing baggage. A
+// non-positive n means no limit, which is the default. It may be changed with
+// ClientConn.UpdateOptions.
+func WithMaxMetadataSize(n int) DialOption {
+	return func(opt *dialOptions) {
+		opt.runtime.maxMetadataSize = n
+	}
+}
+
+// checkMetadataSize returns an error if the outgoing metadata on the context
+// is larger than the configured limit.
+func (c *ClientConn) checkMetadataSize(ctx context.Context) error {
+	limit := c.runtime().maxMetadataSize
+	if limit <= 0 {
+		return nil
+	}
+	metadata, _ := drpcmetadata.Get(ctx)
+	if size := drpcmetadata.Size(metadata); size > limit {
+		return drpc.Error.New("metadata too large: %d > %d", size, limit)
+	}
+	return nil
+}
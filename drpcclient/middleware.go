@@ -0,0 +1,102 @@
+package drpcclient
+
+import (
+	"context"
+
+	"storj.io/drpc"
+)
+
+// RPCInfo describes an rpc passing through a Middleware.
+type RPCInfo struct {
+	// RPC is the name of the rpc, like "/package.Service/Method".
+	RPC string
+
+	// Enc is the encoding of the rpc's messages.
+	Enc drpc.Encoding
+
+	// Conn is the ClientConn issuing the rpc.
+	Conn *ClientConn
+
+	// Stream is true for streaming rpcs and false for unary rpcs.
+	Stream bool
+
+	// In and Out are the request and response of a unary rpc. They are nil for
+	// streaming rpcs.
+	In, Out drpc.Message
+}
+
+// RPCHandler issues the rpc described by the info. For a streaming rpc it
+// returns the stream; for a unary rpc the stream is nil and the response is
+// unmarshaled into info.Out.
+type RPCHandler func(ctx context.Context, info *RPCInfo) (drpc.Stream, error)
+
+// Middleware wraps an RPCHandler. It is an alternative to the separate
+// UnaryClientInterceptor and StreamClientInterceptor types that lets one
+// implementation apply to both kinds of rpc, for example:
+//
+//	func logging(next RPCHandler) RPCHandler {
+//	    return func(ctx context.Context, info *RPCInfo) (drpc.Stream, error) {
+//	        log.Println("starting", info.RPC)
+//	        return next(ctx, info)
+//	    }
+//	}
+//
+// Middleware is installed on a ClientConn with WithMiddleware.
+type Middleware func(next RPCHandler) RPCHandler
+
+// WithMiddleware returns a DialOption that adds the middleware to both the
+// unary and the stream interceptor chains, in order.
+func WithMiddleware(mws ...Middleware) DialOption {
+	return func(opt *dialOptions) {
+		for _, mw := range mws {
+			opt.unaryInts = append(opt.unaryInts, mw.Unary())
+			opt.streamInts = append(opt.streamInts, mw.Stream())
+		}
+	}
+}
+
+// Unary returns a UnaryClientInterceptor that runs the middleware for unary
+// rpcs.
+func (mw Middleware) Unary() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		handler := mw(func(ctx context.Context, info *RPCInfo) (drpc.Stream, error) {
+			return nil, next(ctx, info.RPC, info.Enc, info.In, info.Out, info.Conn)
+		})
+		_, err := handler(ctx, &RPCInfo{RPC: rpc, Enc: enc, Conn: cc, In: in, Out: out})
+		return err
+	}
+}
+
+// Stream returns a StreamClientInterceptor that runs the middleware for
+// streaming rpcs.
+func (mw Middleware) Stream() StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		handler := mw(func(ctx context.Context, info *RPCInfo) (drpc.Stream, error) {
+			return streamer(ctx, info.RPC, info.Enc, info.Conn)
+		})
+		return handler(ctx, &RPCInfo{RPC: rpc, Enc: enc, Conn: cc, Stream: true})
+	}
+}
+
+// MiddlewareFromInterceptors returns a Middleware that runs the unary
+// interceptor for unary rpcs and the stream interceptor for streaming rpcs.
+// Either may be nil to pass the corresponding rpcs through unchanged.
+func MiddlewareFromInterceptors(unary UnaryClientInterceptor, stream StreamClientInterceptor) Middleware {
+	return func(next RPCHandler) RPCHandler {
+		return func(ctx context.Context, info *RPCInfo) (drpc.Stream, error) {
+			switch {
+			case info.Stream && stream != nil:
+				return stream(ctx, info.RPC, info.Enc, info.Conn, func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn) (drpc.Stream, error) {
+					return next(ctx, &RPCInfo{RPC: rpc, Enc: enc, Conn: cc, Stream: true})
+				})
+			case !info.Stream && unary != nil:
+				return nil, unary(ctx, info.RPC, info.Enc, info.In, info.Out, info.Conn, func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn) error {
+					_, err := next(ctx, &RPCInfo{RPC: rpc, Enc: enc, Conn: cc, In: in, Out: out})
+					return err
+				})
+			default:
+				return next(ctx, info)
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// LatencyDist returns a one-way latency drawn from a distribution using the
+// random source.
+type LatencyDist func(rng *rand.Rand) time.Duration
+
+// FixedLatency returns a LatencyDist that is always d.
+func FixedLatency(d time.Duration) LatencyDist {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// UniformLatency returns a LatencyDist uniformly distributed in [min, max).
+func UniformLatency(min, max time.Duration) LatencyDist {
+	return func(rng *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rng.Int63n(int64(max-min)))
+	}
+}
+
+// NormalLatency returns a LatencyDist normally distributed with the mean and
+// standard deviation, clamped to be non-negative.
+func NormalLatency(mean, stddev time.Duration) LatencyDist {
+	return func(rng *rand.Rand) time.Duration {
+		d := time.Duration(rng.NormFloat64()*float64(stddev)) + mean
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+}
+
+// LongTailLatency returns a LatencyDist that is base plus an exponentially
+// distributed delay with the mean tail, modeling the occasional slow message
+// that hedging and timeouts exist for.
+func LongTailLatency(base, tail time.Duration) LatencyDist {
+	return func(rng *rand.Rand) time.Duration {
+		return base + time.Duration(rng.ExpFloat64()*float64(tail))
+	}
+}
+
+// Network simulates the network between the ends of a loopback ClientConn.
+// Each direction of the conn is a link shared by all of its rpcs, carrying
+// every message sent as one frame. The zero value is a perfect network.
+type Network struct {
+	// Latency is the one-way latency of each frame. It defaults to none.
+	Latency LatencyDist
+
+	// Bandwidth is the bytes per second each direction carries, with
+	// senders blocking while their frames are transmitted. Zero means
+	// unlimited.
+	Bandwidth int64
+
+	// DropRate is the probability that a message is lost. Dropped messages
+	// are never received, and the rpc fails or hangs as it would if the
+	// frame was lost on a real network.
+	DropRate float64
+
+	// ReorderRate is the probability that a message is delivered after the
+	// next message sent in the same direction, or once the sender finishes
+	// if there is none. Otherwise frames are delivered in order, waiting for
+	// earlier frames delayed by latency.
+	ReorderRate float64
+
+	// Seed seeds the random choices of the network, so that runs sending
+	// the same sequence of messages make the same choices.
+	Seed int64
+}
+
+// NewSimulatedLoopback is NewLoopback over the simulated network, so that
+// tests can exercise timeouts, retries and hedging against latency, limited
+// bandwidth and lost or reordered messages without a real network. Messages
+// are always marshaled, since they are received after MsgSend returns.
+func NewSimulatedLoopback(handler drpc.Handler, network Network, opts ...DialOption) (*ClientConn, error) {
+	return NewClientConnWithOptions(context.Background(), func(context.Context) (drpc.Conn, error) {
+		return &loopbackConn{
+			handler: handler,
+			up:      newSimLink(network, network.Seed),
+			down:    newSimLink(network, network.Seed+1),
+		}, nil
+	}, opts...)
+}
+
+// simLink is one direction of a simulated network.
+type simLink struct {
+	network Network
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	free  time.Time  // when the link is done transmitting the queued frames
+	queue []*simItem // frames in delivery order
+	held  *simItem   // a reordered message waiting for the next frame
+}
+
+// simItem is a frame in flight.
+type simItem struct {
+	deliver func()
+	ready   bool
+}
+
+func newSimLink(network Network, seed int64) *simLink {
+	return &simLink{network: network, rng: rand.New(rand.NewSource(seed))}
+}
+
+// send transmits a frame of size bytes, calling deliver once it arrives. Only
+// messages may be dropped or reordered, and other frames are delivered after
+// any held message. It returns when the frame is done being transmitted.
+func (l *simLink) send(size int, message bool, deliver func()) (sent time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := time.Now()
+	if l.free.After(start) {
+		start = l.free
+	}
+	l.free = start.Add(l.transmitTime(size))
+	if message && l.rng.Float64() < l.network.DropRate {
+		return l.free
+	}
+
+	arrive := l.free
+	if l.network.Latency != nil {
+		arrive = arrive.Add(l.network.Latency(l.rng))
+	}
+	item := &simItem{deliver: deliver}
+	l.scheduleLocked(item, time.Until(arrive))
+
+	if message && l.held == nil && l.rng.Float64() < l.network.ReorderRate {
+		l.held = item
+		return l.free
+	}
+	l.queue = append(l.queue, item)
+	if l.held != nil {
+		l.queue = append(l.queue, l.held)
+		l.held = nil
+	}
+	l.releaseLocked()
+	return l.free
+}
+
+// transmitTime returns how long the link takes to transmit size bytes.
+func (l *simLink) transmitTime(size int) time.Duration {
+	if l.network.Bandwidth <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(float64(size) / float64(l.network.Bandwidth) * float64(time.Second)))
+}
+
+// scheduleLocked marks the item ready after the delay. It must be called with
+// l.mu held.
+func (l *simLink) scheduleLocked(item *simItem, delay time.Duration) {
+	if delay <= 0 {
+		item.ready = true
+		return
+	}
+	time.AfterFunc(delay, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		item.ready = true
+		l.releaseLocked()
+	})
+}
+
+// releaseLocked delivers the ready frames at the front of the queue, so that
+// no frame is delivered before the frames ahead of it. It must be called with
+// l.mu held.
+func (l *simLink) releaseLocked() {
+	for len(l.queue) > 0 && l.queue[0].ready {
+		item := l.queue[0]
+		l.queue = l.queue[1:]
+		item.deliver()
+	}
+}
+
+// simSend sends the message as a frame over the link of the pipe, waiting
+// while it is transmitted.
+func (s *loopbackStream) simSend(msg drpc.Message, enc drpc.Encoding) error {
+	data, err := enc.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p := s.send
+	m := loopbackMsg{recv: func(dst drpc.Message, enc drpc.Encoding) error { return enc.Unmarshal(data, dst) }}
+	sent := p.link.send(len(data), true, func() { p.push(m) })
+
+	if wait := time.Until(sent); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			return s.ctxErr()
+		}
+	}
+	return nil
+}
+
+// simRecv receives a message delivered over the link of the pipe. Messages
+// that arrived are received before the pipe finishing or the context being
+// done is reported.
+func (s *loopbackStream) simRecv(msg drpc.Message, enc drpc.Encoding) error {
+	p := s.recv
+	for {
+		if m, ok := p.pop(); ok {
+			return m.recv(msg, enc)
+		}
+		select {
+		case <-p.arrived:
+			continue
+		case <-p.sent.Signal():
+		case <-s.ctx.Done():
+		}
+		if m, ok := p.pop(); ok {
+			return m.recv(msg, enc)
+		}
+		if err, ok := p.sent.Get(); ok {
+			return err
+		}
+		return s.ctxErr()
+	}
+}
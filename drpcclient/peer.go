@@ -0,0 +1,131 @@
+package drpcclient
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// Peer describes the remote end that served an rpc.
+type Peer struct {
+	// RemoteAddr is the address of the remote end, if known.
+	RemoteAddr net.Addr
+
+	// LocalAddr is the address of the local end, if known.
+	LocalAddr net.Addr
+
+	// Transport is the network of the remote address, such as "tcp", "unix",
+	// "http", or "loopback", or empty if it is not known.
+	Transport string
+
+	// Backend is the address of the Balancer backend that served the rpc, or
+	// empty if the rpc was not issued through a Balancer.
+	Backend string
+
+	// Identity is the identity of the remote end verified while dialing, such
+	// as its SPIFFE ID with WithSPIFFEVerification, or empty if none was.
+	Identity string
+}
+
+// peerKey is the context key for the *peerInfo of an rpc.
+type peerKey struct{}
+
+// peerInfo holds the Peer of an rpc once the conn serving it is chosen.
+type peerInfo struct {
+	mu   sync.Mutex
+	peer Peer
+	set  bool
+}
+
+// peerContext carries a peerInfo so that attaching one allocates once.
+type peerContext struct {
+	context.Context
+	info peerInfo
+}
+
+func (c *peerContext) Value(key interface{}) interface{} {
+	if key == (peerKey{}) {
+		return &c.info
+	}
+	return c.Context.Value(key)
+}
+
+// PeerFromContext returns the Peer of the rpc whose context, or a context
+// derived from it, is passed in. The ClientConn places it on the context before
+// any interceptor runs, but it is only filled in once the conn serving the rpc
+// is chosen, so interceptors should read it after calling the next invoker or
+// streamer. The returned bool is false until then. Conns that do not expose
+// their addresses, such as pooled conns, produce an empty Peer.
+func PeerFromContext(ctx context.Context) (Peer, bool) {
+	info, _ := ctx.Value(peerKey{}).(*peerInfo)
+	if info == nil {
+		return Peer{}, false
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	return info.peer, info.set
+}
+
+// withPeer returns a context with a place to record the Peer of the rpc,
+// keeping an existing one so that nested ClientConns fill in the same Peer.
+func withPeer(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(peerKey{}).(*peerInfo); ok {
+		return ctx
+	}
+	return &peerContext{Context: ctx}
+}
+
+// setPeer records the conn as the Peer of the rpc, if the context has a place
+// for it.
+func setPeer(ctx context.Context, conn drpc.Conn) {
+	info, _ := ctx.Value(peerKey{}).(*peerInfo)
+	if info == nil {
+		return
+	}
+	info.store(peerOf(conn))
+}
+
+// setBackendPeer records the backend picked by a Balancer as the Peer of the
+// rpc, if the context has a place for it.
+func setBackendPeer(ctx context.Context, backend Backend) {
+	info, _ := ctx.Value(peerKey{}).(*peerInfo)
+	if info == nil {
+		return
+	}
+	peer := peerOf(backend.Conn)
+	peer.Backend = backend.Addr
+	info.store(peer)
+}
+
+// store records the peer.
+func (info *peerInfo) store(peer Peer) {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.peer, info.set = peer, true
+}
+
+// peerOf returns the addresses of the conn, looking for RemoteAddr and
+// LocalAddr methods on the conn itself or on its transport, and its verified
+// identity, looking for an Identity method on the conn.
+func peerOf(conn drpc.Conn) (peer Peer) {
+	if ic, ok := conn.(interface{ Identity() string }); ok {
+		peer.Identity = ic.Identity()
+	}
+
+	var src interface{} = conn
+	if tc, ok := conn.(interface{ Transport() drpc.Transport }); ok {
+		src = tc.Transport()
+	}
+	if ra, ok := src.(interface{ RemoteAddr() net.Addr }); ok {
+		peer.RemoteAddr = ra.RemoteAddr()
+	}
+	if la, ok := src.(interface{ LocalAddr() net.Addr }); ok {
+		peer.LocalAddr = la.LocalAddr()
+	}
+	if peer.RemoteAddr != nil {
+		peer.Transport = peer.RemoteAddr.Network()
+	}
+	return peer
+}
@@ -0,0 +1,258 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"storj.io/drpc/drpcclock"
+)
+
+// ProbeFunc checks if a target is healthy again, for example by dialing it or
+// by issuing a health check rpc. It returns nil if the target is healthy.
+type ProbeFunc func(ctx context.Context, target string) error
+
+// ProbeEventType identifies what a ProbeEvent reports.
+type ProbeEventType int
+
+const (
+	// ProbeStart is sent when a target starts being probed.
+	ProbeStart ProbeEventType = iota
+
+	// ProbeFailure is sent when a probe of a target failed.
+	ProbeFailure
+
+	// ProbeSuccess is sent when a probe of a target succeeded, just before
+	// the target is made eligible again.
+	ProbeSuccess
+
+	// ProbeStop is sent when a target stops being probed without a probe
+	// succeeding.
+	ProbeStop
+)
+
+// String returns a human readable form of the ProbeEventType.
+func (t ProbeEventType) String() string {
+	switch t {
+	case ProbeStart:
+		return "ProbeStart"
+	case ProbeFailure:
+		return "ProbeFailure"
+	case ProbeSuccess:
+		return "ProbeSuccess"
+	case ProbeStop:
+		return "ProbeStop"
+	default:
+		return "Unknown"
+	}
+}
+
+// ProbeEvent is something that happened to a target being probed. The fields
+// that do not apply to its Type are zero.
+type ProbeEvent struct {
+	Type   ProbeEventType
+	Time   time.Time
+	Target string
+
+	// Attempt is the number of the probe of a ProbeFailure or ProbeSuccess,
+	// starting at 1.
+	Attempt int
+
+	// Err is the error of a ProbeFailure.
+	Err error
+
+	// Duration is how long the probe of a ProbeFailure or ProbeSuccess took.
+	Duration time.Duration
+
+	// Next is how long until the next probe after a ProbeStart or
+	// ProbeFailure.
+	Next time.Duration
+}
+
+// ProberOptions configures a Prober. Zero values use the defaults.
+type ProberOptions struct {
+	// InitialInterval is the delay before the first probe of a target. It
+	// defaults to one second.
+	InitialInterval time.Duration
+
+	// MaxInterval bounds the delay between probes. It defaults to one
+	// minute.
+	MaxInterval time.Duration
+
+	// Multiplier is how much the delay grows after every failed probe. It
+	// defaults to 2.
+	Multiplier float64
+
+	// Timeout bounds each probe. It defaults to the InitialInterval.
+	Timeout time.Duration
+
+	// OnEvent, if set, is called with the events of the probed targets. It
+	// is called synchronously from the goroutine running the probes, so it
+	// must return quickly.
+	OnEvent func(ev ProbeEvent)
+
+	// Clock schedules the probes. It defaults to drpcclock.System.
+	Clock drpcclock.Clock
+}
+
+// Prober probes targets that were marked down in the background until they
+// are healthy again, with exponentially growing intervals between probes, and
+// then calls the function that makes them eligible again. A FailFast uses one
+// to bring back the targets it marked down. It is safe for concurrent use.
+type Prober struct {
+	opts  ProberOptions
+	clock drpcclock.Clock
+
+	mu      sync.Mutex
+	closed  bool
+	targets map[string]*probeState
+}
+
+// probeState is the state of a target being probed.
+type probeState struct {
+	probe   ProbeFunc
+	up      func()
+	attempt int
+	timer   drpcclock.Timer
+}
+
+// NewProber returns a Prober configured by opts.
+func NewProber(opts ProberOptions) *Prober {
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = time.Second
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = time.Minute
+	}
+	if opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = 2
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = opts.InitialInterval
+	}
+	return &Prober{
+		opts:    opts,
+		clock:   drpcclock.Or(opts.Clock),
+		targets: make(map[string]*probeState),
+	}
+}
+
+// Start probes the target with probe until a probe succeeds, and then calls
+// up. It does nothing if the target is already being probed or the Prober is
+// closed.
+func (p *Prober) Start(target string, probe ProbeFunc, up func()) {
+	p.mu.Lock()
+	if p.closed || p.targets[target] != nil {
+		p.mu.Unlock()
+		return
+	}
+	ps := &probeState{probe: probe, up: up}
+	p.targets[target] = ps
+	next := p.scheduleLocked(target, ps)
+	p.mu.Unlock()
+
+	p.emit(ProbeEvent{Type: ProbeStart, Target: target, Next: next})
+}
+
+// Stop stops probing the target without calling its up function.
+func (p *Prober) Stop(target string) {
+	p.mu.Lock()
+	ps := p.targets[target]
+	if ps != nil {
+		ps.timer.Stop()
+		delete(p.targets, target)
+	}
+	p.mu.Unlock()
+
+	if ps != nil {
+		p.emit(ProbeEvent{Type: ProbeStop, Target: target})
+	}
+}
+
+// Probing returns the targets being probed, sorted.
+func (p *Prober) Probing() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	targets := make([]string, 0, len(p.targets))
+	for target := range p.targets {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Close stops probing every target. Targets started afterwards are ignored.
+func (p *Prober) Close() {
+	p.mu.Lock()
+	p.closed = true
+	targets := p.targets
+	p.targets = make(map[string]*probeState)
+	for _, ps := range targets {
+		ps.timer.Stop()
+	}
+	p.mu.Unlock()
+
+	for target := range targets {
+		p.emit(ProbeEvent{Type: ProbeStop, Target: target})
+	}
+}
+
+// interval returns the delay before the given probe, starting at 1.
+func (p *Prober) interval(attempt int) time.Duration {
+	d := float64(p.opts.InitialInterval) * math.Pow(p.opts.Multiplier, float64(attempt-1))
+	return time.Duration(math.Min(d, float64(p.opts.MaxInterval)))
+}
+
+// scheduleLocked schedules the next probe of the target and returns how long
+// until it runs. It must be called with p.mu held.
+func (p *Prober) scheduleLocked(target string, ps *probeState) time.Duration {
+	ps.attempt++
+	next := p.interval(ps.attempt)
+	ps.timer = p.clock.AfterFunc(next, func() { p.run(target, ps) })
+	return next
+}
+
+// run probes the target once, calling its up function if the probe succeeds
+// and scheduling the next probe otherwise.
+func (p *Prober) run(target string, ps *probeState) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	start := p.clock.Now()
+	err := ps.probe(ctx, target)
+	duration := p.clock.Now().Sub(start)
+	cancel()
+
+	p.mu.Lock()
+	if p.targets[target] != ps {
+		// the target was stopped while probing.
+		p.mu.Unlock()
+		return
+	}
+	ev := ProbeEvent{Type: ProbeSuccess, Target: target, Attempt: ps.attempt, Duration: duration}
+	if err == nil {
+		delete(p.targets, target)
+	} else {
+		ev.Type, ev.Err = ProbeFailure, err
+		ev.Next = p.scheduleLocked(target, ps)
+	}
+	p.mu.Unlock()
+
+	p.emit(ev)
+	if err == nil {
+		ps.up()
+	}
+}
+
+// emit sends the event to the listener, setting its Time.
+func (p *Prober) emit(ev ProbeEvent) {
+	if p.opts.OnEvent == nil {
+		return
+	}
+	ev.Time = p.clock.Now()
+	p.opts.OnEvent(ev)
+}
@@ -0,0 +1,69 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+)
+
+// PushResolver is a Resolver whose addresses are pushed by the application
+// instead of looked up, for hosts that already track the membership of their
+// cluster, such as through gossip or node liveness. A ResolvedBalancer using
+// it applies each Update as soon as it is pushed, without waiting for the
+// MinInterval, and still resolves every Interval to redial the backends whose
+// conns closed. It is safe for concurrent use, and may feed several
+// ResolvedBalancers.
+type PushResolver struct {
+	mu       sync.Mutex
+	addrs    []string
+	watchers map[*func()]struct{}
+}
+
+// NewPushResolver returns a PushResolver with the initial addresses.
+func NewPushResolver(addrs ...string) *PushResolver {
+	return &PushResolver{
+		addrs:    append([]string(nil), addrs...),
+		watchers: make(map[*func()]struct{}),
+	}
+}
+
+// Update replaces the addresses and tells the ResolvedBalancers using the
+// PushResolver. It does not wait for them to apply the addresses.
+func (r *PushResolver) Update(addrs ...string) {
+	addrs = append([]string(nil), addrs...)
+
+	r.mu.Lock()
+	r.addrs = addrs
+	watchers := make([]func(), 0, len(r.watchers))
+	for changed := range r.watchers {
+		watchers = append(watchers, *changed)
+	}
+	r.mu.Unlock()
+
+	for _, changed := range watchers {
+		changed()
+	}
+}
+
+// Resolve returns the addresses of the most recent Update.
+func (r *PushResolver) Resolve(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.addrs...), nil
+}
+
+// Watch calls changed after every Update until the context is done, and once
+// it starts, for the Updates pushed since the addresses were last resolved.
+func (r *PushResolver) Watch(ctx context.Context, changed func()) {
+	r.mu.Lock()
+	r.watchers[&changed] = struct{}{}
+	r.mu.Unlock()
+
+	changed()
+
+	<-ctx.Done()
+
+	r.mu.Lock()
+	delete(r.watchers, &changed)
+	r.mu.Unlock()
+}
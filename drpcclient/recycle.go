@@ -0,0 +1,59 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// Recycler reuses response messages across unary rpcs so that callers issuing
+// many rpcs per second do not allocate a response for every call.
+//
+// A message returned by Get or Invoke is owned by the caller until it is
+// passed to Put. After Put the caller must not use the message or anything
+// that refers into it, such as byte slices or sub-messages it holds, because
+// it is reset and unmarshaled into by a later call.
+type Recycler[M drpc.Message] struct {
+	newMsg func() M
+	reset  func(M)
+	pool   sync.Pool
+}
+
+// NewRecycler returns a Recycler that creates messages with newMsg, such as
+// func() *pb.Response { return new(pb.Response) }, and clears them with reset,
+// such as the Reset method of a protobuf message, before they are reused. A
+// nil reset relies on the encoding's Unmarshal to overwrite every field.
+func NewRecycler[M drpc.Message](newMsg func() M, reset func(M)) *Recycler[M] {
+	return &Recycler[M]{newMsg: newMsg, reset: reset}
+}
+
+// Get returns a message from the recycler, creating one if none are free.
+func (r *Recycler[M]) Get() M {
+	if msg, ok := r.pool.Get().(M); ok {
+		return msg
+	}
+	return r.newMsg()
+}
+
+// Put resets the message and makes it available to later calls to Get. The
+// caller gives up ownership of the message.
+func (r *Recycler[M]) Put(msg M) {
+	if r.reset != nil {
+		r.reset(msg)
+	}
+	r.pool.Put(msg)
+}
+
+// Invoke issues the unary rpc on the conn and unmarshals the response into a
+// message from the recycler. On success the caller owns the returned message
+// and should pass it to Put once done with it. On error the message is
+// recycled and the zero value is returned.
+func (r *Recycler[M]) Invoke(ctx context.Context, conn drpc.Conn, rpc string, enc drpc.Encoding, in drpc.Message) (M, error) {
+	out := r.Get()
+	if err := conn.Invoke(ctx, rpc, enc, in, out); err != nil {
+		r.Put(out)
+		return *new(M), err
+	}
+	return out, nil
+}
@@ -0,0 +1,312 @@
+package drpcclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+)
+
+// Resolver looks up the addresses of the backends of a target. If the Resolver
+// also has a method
+//
+//	Watch(ctx context.Context, changed func())
+//
+// a ResolvedBalancer calls it once in its own goroutine, and resolves again
+// each time it calls changed, which it should do when the addresses may have
+// changed, until the context is done. Those resolutions are not delayed by the
+// MinInterval.
+type Resolver interface {
+	// Resolve returns the current addresses of the backends.
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// watcher is a Resolver that tells when the addresses may have changed.
+type watcher interface {
+	Resolver
+	Watch(ctx context.Context, changed func())
+}
+
+// ResolverFunc is a func that implements Resolver.
+type ResolverFunc func(ctx context.Context) ([]string, error)
+
+// Resolve calls fn.
+func (fn ResolverFunc) Resolve(ctx context.Context) ([]string, error) { return fn(ctx) }
+
+// NewDNSResolver returns a Resolver for a "host:port" target that looks up the
+// addresses of the host with resolver, or net.DefaultResolver if nil, and
+// returns each of them joined with the port.
+func NewDNSResolver(target string, resolver *net.Resolver) (Resolver, error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, drpc.Error.Wrap(err)
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		hosts, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		addrs := make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			addrs = append(addrs, net.JoinHostPort(host, port))
+		}
+		return addrs, nil
+	}), nil
+}
+
+// DefaultResolveInterval is the default ResolverOptions.Interval.
+const DefaultResolveInterval = 30 * time.Second
+
+// DefaultMinResolveInterval is the default ResolverOptions.MinInterval.
+const DefaultMinResolveInterval = time.Second
+
+// ResolverOptions configure a ResolvedBalancer.
+type ResolverOptions struct {
+	// Interval is how often the backends are resolved, standing in for the
+	// TTL of the records. It defaults to DefaultResolveInterval.
+	Interval time.Duration
+
+	// MinInterval is the shortest time between two resolutions, which rate
+	// limits the resolutions triggered by connection failures and ResolveNow.
+	// It defaults to DefaultMinResolveInterval.
+	MinInterval time.Duration
+
+	// Families orders and filters the resolved addresses by IP family, such
+	// as to drop a family that is broken in the network. It defaults to
+	// InterleaveFamilies.
+	Families FamilyPolicy
+
+	// Clock is the clock of the intervals. It defaults to the system clock.
+	Clock drpcclock.Clock
+}
+
+// ResolvedBalancer is a Balancer whose backends are the addresses returned by
+// a Resolver. The addresses are resolved again every Interval, and as soon as
+// the MinInterval allows when an rpc fails because the conn to its backend
+// went away, when a new address fails to dial, or when ResolveNow is called,
+// so that a backend becoming unreachable is noticed without waiting for the
+// next Interval. New addresses are dialed and the conns of addresses no longer
+// returned are closed, failing the rpcs still in flight on them. If resolving
+// fails, the current backends are kept.
+type ResolvedBalancer struct {
+	*Balancer
+
+	resolver Resolver
+	dial     AddrDialerFunc
+	opts     ResolverOptions
+	clock    drpcclock.Clock
+
+	trigger chan struct{}
+	changed chan struct{}
+	cancel  func()
+	wg      sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[string]drpc.Conn
+	last  time.Time
+}
+
+// NewResolvedBalancer returns a ResolvedBalancer that picks between the
+// backends returned by resolver with the picker, dialing them with dial. The
+// first resolution happens before it returns, and its error, if any, is
+// returned. It must be closed to stop resolving and close the backends.
+func NewResolvedBalancer(ctx context.Context, picker Picker, resolver Resolver, dial AddrDialerFunc, opts ResolverOptions) (*ResolvedBalancer, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultResolveInterval
+	}
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = DefaultMinResolveInterval
+	}
+
+	lctx, cancel := context.WithCancel(context.Background())
+	rb := &ResolvedBalancer{
+		Balancer: NewBalancer(picker),
+		resolver: resolver,
+		dial:     dial,
+		opts:     opts,
+		clock:    drpcclock.Or(opts.Clock),
+		trigger:  make(chan struct{}, 1),
+		changed:  make(chan struct{}, 1),
+		cancel:   cancel,
+		conns:    make(map[string]drpc.Conn),
+	}
+	rb.Balancer.resolveNow = rb.ResolveNow
+
+	if err := rb.resolve(ctx); err != nil {
+		cancel()
+		_ = rb.closeConns()
+		return nil, err
+	}
+
+	rb.wg.Add(1)
+	go rb.run(lctx)
+
+	if w, ok := resolver.(watcher); ok {
+		rb.wg.Add(1)
+		go func() {
+			defer rb.wg.Done()
+			w.Watch(lctx, rb.resolveChanged)
+		}()
+	}
+	return rb, nil
+}
+
+// ResolveNow asks for the backends to be resolved again as soon as the
+// MinInterval allows. It does not wait for the resolution.
+func (rb *ResolvedBalancer) ResolveNow() {
+	select {
+	case rb.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// resolveChanged asks for the backends to be resolved again immediately.
+func (rb *ResolvedBalancer) resolveChanged() {
+	select {
+	case rb.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops resolving and closes the conns of the backends.
+func (rb *ResolvedBalancer) Close() error {
+	rb.cancel()
+	rb.wg.Wait()
+	return rb.closeConns()
+}
+
+// run resolves the backends every Interval, when triggered, no more often than
+// the MinInterval, and when a watched Resolver reports a change, until the
+// context is done.
+func (rb *ResolvedBalancer) run(ctx context.Context) {
+	defer rb.wg.Done()
+
+	for {
+		timer := rb.clock.NewTimer(rb.opts.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		case <-rb.changed:
+			timer.Stop()
+		case <-rb.trigger:
+			timer.Stop()
+
+			rb.mu.Lock()
+			wait := rb.opts.MinInterval - rb.clock.Now().Sub(rb.last)
+			rb.mu.Unlock()
+
+			if wait > 0 {
+				timer := rb.clock.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C():
+				}
+			}
+
+			// the resolution covers the triggers that arrived while waiting.
+			select {
+			case <-rb.trigger:
+			default:
+			}
+		}
+
+		_ = rb.resolve(ctx)
+	}
+}
+
+// resolve resolves the backends, dialing the new and closed ones, closing the
+// removed ones, and updates the Balancer.
+func (rb *ResolvedBalancer) resolve(ctx context.Context) error {
+	rb.mu.Lock()
+	rb.last = rb.clock.Now()
+	rb.mu.Unlock()
+
+	addrs, err := rb.resolver.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	addrs = rb.opts.Families.Order(addrs)
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	var failed bool
+	conns := make(map[string]drpc.Conn, len(addrs))
+	backends := make([]Backend, 0, len(addrs))
+	for _, addr := range addrs {
+		if _, ok := conns[addr]; ok {
+			continue
+		}
+		conn, ok := rb.conns[addr]
+		if ok && isClosed(conn) {
+			ok = false
+		}
+		if !ok {
+			conn, err = rb.dial(ctx, addr)
+			if err != nil {
+				failed = true
+				continue
+			}
+		}
+		conns[addr] = conn
+		backends = append(backends, Backend{Addr: addr, Conn: conn})
+	}
+
+	rb.Balancer.SetBackends(backends...)
+	for addr, conn := range rb.conns {
+		if conns[addr] != conn {
+			_ = conn.Close()
+		}
+	}
+	rb.conns = conns
+
+	if failed {
+		rb.ResolveNow()
+	}
+	return nil
+}
+
+// closeConns closes the conns of the backends.
+func (rb *ResolvedBalancer) closeConns() (err error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	for _, conn := range rb.conns {
+		err = errs.Combine(err, conn.Close())
+	}
+	rb.conns = nil
+	return err
+}
+
+// isClosed returns true if the conn is known to be closed.
+func isClosed(conn drpc.Conn) bool {
+	select {
+	case <-conn.Closed():
+		return true
+	default:
+		return false
+	}
+}
+
+// isConnFailure returns true if the error is caused by the conn to a backend
+// going away or failing to connect rather than by the rpc.
+func isConnFailure(err error) bool {
+	var opErr *net.OpError
+	return drpc.ClosedError.Has(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &opErr)
+}
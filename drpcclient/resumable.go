@@ -0,0 +1,162 @@
+package drpcclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// ResumableOptions configures a ResumableStream.
+type ResumableOptions struct {
+	// Token returns the resume token carried by a received message, if any.
+	// The most recent token is sent in the drpcmetadata.ResumeToken metadata
+	// when the stream is reopened.
+	Token func(msg drpc.Message) (string, bool)
+
+	// Start sends the initial messages on every opened stream, for example
+	// the request of a server streaming rpc followed by CloseSend.
+	Start func(stream drpc.Stream) error
+
+	// Retryable reports if a receive error is a transport failure that
+	// should reopen the stream. Nil retries errors from closed connections
+	// and keepalive timeouts.
+	Retryable func(err error) bool
+
+	// MaxReopens bounds the number of consecutive reopens without receiving
+	// a message. Zero means 5.
+	MaxReopens int
+
+	// Backoff is the delay before each reopen. Zero means no delay.
+	Backoff time.Duration
+}
+
+// ResumableStream is a receive side stream that hides transport failures by
+// reopening the rpc with the last resume token the server supplied, which the
+// server reads with drpcmetadata.Lookup to continue where it left off. It is
+// meant for server streaming rpcs such as watches and changefeeds.
+type ResumableStream struct {
+	ctx  context.Context
+	cc   *ClientConn
+	rpc  string
+	enc  drpc.Encoding
+	opts ResumableOptions
+
+	mu     sync.Mutex
+	stream drpc.Stream
+	token  string
+}
+
+// NewResumableStream opens the rpc on the ClientConn and calls opts.Start on it.
+func NewResumableStream(ctx context.Context, cc *ClientConn, rpc string, enc drpc.Encoding, opts ResumableOptions) (*ResumableStream, error) {
+	if opts.MaxReopens == 0 {
+		opts.MaxReopens = 5
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = defaultResumeRetryable
+	}
+
+	rs := &ResumableStream{ctx: ctx, cc: cc, rpc: rpc, enc: enc, opts: opts}
+	stream, err := rs.open()
+	if err != nil {
+		return nil, err
+	}
+	rs.stream = stream
+	return rs, nil
+}
+
+// defaultResumeRetryable retries errors caused by the connection going away.
+func defaultResumeRetryable(err error) bool {
+	return drpc.ClosedError.Has(err) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// open opens a new stream carrying the current resume token and starts it.
+func (rs *ResumableStream) open() (drpc.Stream, error) {
+	return openResumed(rs.ctx, rs.cc, rs.rpc, rs.enc, rs.token, rs.opts.Start)
+}
+
+// openResumed opens the rpc on the ClientConn carrying the resume token, if
+// any, and calls start on it.
+func openResumed(ctx context.Context, cc *ClientConn, rpc string, enc drpc.Encoding, token string, start func(drpc.Stream) error) (drpc.Stream, error) {
+	if token != "" {
+		ctx = cc.AddMetadata(ctx, drpcmetadata.ResumeToken, token)
+	}
+
+	stream, err := cc.NewStream(ctx, rpc, enc)
+	if err != nil {
+		return nil, err
+	}
+	if start != nil {
+		if err := start(stream); err != nil {
+			_ = stream.Close()
+			return nil, err
+		}
+	}
+	return stream, nil
+}
+
+// Token returns the most recent resume token received.
+func (rs *ResumableStream) Token() string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.token
+}
+
+// MsgRecv receives a message, reopening the stream with the last resume token
+// if the receive fails with a retryable error.
+func (rs *ResumableStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for reopens := 0; ; reopens++ {
+		err := rs.stream.MsgRecv(msg, enc)
+		if err == nil {
+			if token, ok := rs.opts.Token(msg); ok {
+				rs.token = token
+			}
+			return nil
+		}
+		if rs.ctx.Err() != nil || reopens >= rs.opts.MaxReopens || !rs.opts.Retryable(err) {
+			return err
+		}
+
+		_ = rs.stream.Close()
+		if err := rs.sleep(); err != nil {
+			return err
+		}
+		stream, err := rs.open()
+		if err != nil {
+			return err
+		}
+		rs.stream = stream
+	}
+}
+
+// sleep waits for the backoff or the context to be done.
+func (rs *ResumableStream) sleep() error {
+	if rs.opts.Backoff <= 0 {
+		return nil
+	}
+	timer := rs.cc.clock().NewTimer(rs.opts.Backoff)
+	defer timer.Stop()
+
+	select {
+	case <-rs.ctx.Done():
+		return rs.ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// Close closes the current underlying stream.
+func (rs *ResumableStream) Close() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.stream.Close()
+}
@@ -0,0 +1,179 @@
+package drpcclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpctest"
+)
+
+func TestResumableStreamReopensWithToken(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	conn := &resumeConn{}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	})
+	assert.NoError(t, err)
+
+	rs, err := NewResumableStream(ctx, cc, "/kv.KV/Watch", testEncoding{}, ResumableOptions{
+		Token: func(msg drpc.Message) (string, bool) { return *msg.(*string), true },
+	})
+	assert.NoError(t, err)
+
+	var msg string
+	assert.NoError(t, rs.MsgRecv(&msg, testEncoding{}))
+	assert.Equal(t, "1", msg)
+	assert.NoError(t, rs.MsgRecv(&msg, testEncoding{}))
+	assert.Equal(t, "2", msg)
+
+	assert.Equal(t, []string{"", "1"}, conn.tokens)
+	assert.Equal(t, "2", rs.Token())
+}
+
+// resumeConn opens streams that deliver one message, numbered from the resume
+// token, before failing as if the connection was lost.
+type resumeConn struct {
+	mockDrpcConn
+	tokens []string
+}
+
+func (r *resumeConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	token, _ := drpcmetadata.Lookup(ctx, drpcmetadata.ResumeToken)
+	r.tokens = append(r.tokens, token)
+	next := "1"
+	if token != "" {
+		next = string(token[0] + 1)
+	}
+	return &resumeStream{msg: next}, nil
+}
+
+type resumeStream struct {
+	mockStream
+	msg  string
+	sent bool
+}
+
+func (r *resumeStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if r.sent {
+		return drpc.ClosedError.New("connection lost")
+	}
+	r.sent = true
+	*msg.(*string) = r.msg
+	return nil
+}
+
+func TestWatchReconnectsWithToken(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	conn := &resumeConn{}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	})
+	assert.NoError(t, err)
+
+	var liveness []bool
+	w := NewWatch(ctx, cc, "/kv.KV/Watch", testEncoding{}, WatchOptions{
+		NewMessage:     func() drpc.Message { return new(string) },
+		Token:          func(msg drpc.Message) (string, bool) { return *msg.(*string), true },
+		InitialBackoff: time.Millisecond,
+		OnLiveness:     func(live bool) { liveness = append(liveness, live) },
+	})
+
+	var types []WatchEventType
+	var msgs []string
+	for ev := range w.Events() {
+		types = append(types, ev.Type)
+		switch ev.Type {
+		case WatchMessage:
+			msgs = append(msgs, *ev.Msg.(*string))
+		case WatchDisconnected:
+			assert.True(t, drpc.ClosedError.Has(ev.Err))
+			assert.Equal(t, 1, ev.Attempt)
+			assert.Equal(t, time.Millisecond, ev.Next)
+		}
+		if len(msgs) == 3 {
+			break
+		}
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []WatchEventType{
+		WatchConnected, WatchMessage, WatchDisconnected,
+		WatchConnected, WatchMessage, WatchDisconnected,
+		WatchConnected, WatchMessage,
+	}, types)
+	assert.Equal(t, []string{"1", "2", "3"}, msgs)
+	assert.Equal(t, []string{"", "1", "2"}, conn.tokens)
+	assert.Equal(t, "3", w.Token())
+	assert.Equal(t, []bool{true, false, true, false, true, false}, liveness)
+	assert.ErrorIs(t, w.Err(), context.Canceled)
+}
+
+func TestWatchStops(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return &stallConn{}, nil
+	})
+	assert.NoError(t, err)
+
+	// streams that stall go stale until the attempts run out.
+	w := NewWatch(ctx, cc, "/kv.KV/Watch", testEncoding{}, WatchOptions{
+		NewMessage:     func() drpc.Message { return new(string) },
+		InitialBackoff: time.Millisecond,
+		Liveness:       5 * time.Millisecond,
+		MaxAttempts:    2,
+	})
+	var disconnects int
+	for ev := range w.Events() {
+		if ev.Type == WatchDisconnected {
+			disconnects++
+			assert.True(t, WatchStaleError.Has(ev.Err))
+		}
+	}
+	assert.Equal(t, 1, disconnects)
+	assert.True(t, WatchStaleError.Has(w.Err()))
+
+	// errors that are not retryable stop the watch right away.
+	w = NewWatch(ctx, cc, "/kv.KV/Watch", testEncoding{}, WatchOptions{
+		NewMessage: func() drpc.Message { return new(string) },
+		Start:      func(drpc.Stream) error { return errors.New("rejected") },
+		Retryable:  func(error) bool { return false },
+	})
+	for range w.Events() {
+	}
+	assert.EqualError(t, w.Err(), "rejected")
+}
+
+// stallConn opens streams whose receives block until they are closed.
+type stallConn struct{ mockDrpcConn }
+
+func (*stallConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return &stallStream{closed: make(chan struct{})}, nil
+}
+
+type stallStream struct {
+	mockStream
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (s *stallStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	<-s.closed
+	return drpc.ClosedError.New("stream closed")
+}
+
+func (s *stallStream) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
@@ -0,0 +1,69 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcerr"
+)
+
+// retryable returns true if the policy allows retrying the error.
+func (rp *RetryPolicy) retryable(err error) bool {
+	code := drpcerr.Code(err)
+	for _, s := range rp.RetryableStatusCodes {
+		if n, ok := parseStatusCode(s); ok && n == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the randomized delay before the given retry, starting at 1.
+func (rp *RetryPolicy) backoff(retry int) time.Duration {
+	limit := float64(rp.InitialBackoff) * math.Pow(rp.BackoffMultiplier, float64(retry-1))
+	limit = math.Min(limit, float64(rp.MaxBackoff))
+	return time.Duration(rand.Float64() * limit)
+}
+
+// withRetries calls fn until it succeeds, returns an error the policy does not
+// retry, the attempts are exhausted, or the context is done, waiting for the
+// backoffs with the clock. A nil policy calls fn once.
+func withRetries(ctx context.Context, clock drpcclock.Clock, rp *RetryPolicy, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || rp == nil || attempt >= rp.MaxAttempts || !rp.retryable(err) {
+			return err
+		}
+
+		timer := clock.NewTimer(rp.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C():
+		}
+	}
+}
+
+// limitedEncoding is a drpc.Encoding that sends an already marshaled request
+// and rejects responses larger than a limit before unmarshaling them.
+type limitedEncoding struct {
+	drpc.Encoding
+	data        []byte
+	maxResponse int
+}
+
+func (l limitedEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return l.data, nil
+}
+
+func (l limitedEncoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	if l.maxResponse > 0 && len(buf) > l.maxResponse {
+		return drpc.Error.New("response message too large: %d > %d", len(buf), l.maxResponse)
+	}
+	return l.Encoding.Unmarshal(buf, msg)
+}
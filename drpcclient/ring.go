@@ -0,0 +1,100 @@
+package drpcclient
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultVirtualNodes is the number of points each backend has on a consistent
+// hash ring when none is configured.
+const DefaultVirtualNodes = 100
+
+// hashRing is a consistent hash ring of backends, each placed at a number of
+// virtual nodes so that keys spread evenly and only the keys of a backend that
+// leaves, or a share of keys for a backend that joins, change owner.
+type hashRing struct {
+	sig      string    // identifies the backends the ring was built for
+	backends []Backend // owners of the points
+	points   []uint64  // sorted hashes of the virtual nodes
+	owners   []int     // index into backends of the owner of each point
+}
+
+// ringSignature returns a string that changes whenever the membership of the
+// backends does.
+func ringSignature(backends []Backend) string {
+	var sb strings.Builder
+	for _, backend := range backends {
+		sb.WriteString(backend.Addr)
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// newHashRing builds a ring of the backends with vnodes points each.
+func newHashRing(backends []Backend, vnodes int) *hashRing {
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+
+	type point struct {
+		hash  uint64
+		owner int
+	}
+	points := make([]point, 0, len(backends)*vnodes)
+	for i, backend := range backends {
+		for v := 0; v < vnodes; v++ {
+			points = append(points, point{hash: hashString(backend.Addr + "#" + strconv.Itoa(v)), owner: i})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].hash < points[j].hash })
+
+	r := &hashRing{
+		sig:      ringSignature(backends),
+		backends: backends,
+		points:   make([]uint64, len(points)),
+		owners:   make([]int, len(points)),
+	}
+	for i, p := range points {
+		r.points[i], r.owners[i] = p.hash, p.owner
+	}
+	return r
+}
+
+// successors calls fn with the distinct backends found walking clockwise from
+// the hash until fn returns false or every backend has been visited.
+func (r *hashRing) successors(hash uint64, fn func(index int) bool) {
+	if len(r.points) == 0 {
+		return
+	}
+
+	seen := make([]bool, len(r.backends))
+	visited := 0
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	for i := 0; i < len(r.points) && visited < len(r.backends); i++ {
+		owner := r.owners[(start+i)%len(r.points)]
+		if seen[owner] {
+			continue
+		}
+		seen[owner] = true
+		visited++
+		if !fn(owner) {
+			return
+		}
+	}
+}
+
+// hashString hashes s to a point on the ring. FNV alone spreads similar
+// strings poorly, so its result is mixed with the splitmix64 finalizer.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,106 @@
+package drpcclient
+
+import (
+	"strings"
+
+	"storj.io/drpc"
+)
+
+// Route sends the rpcs matching Prefix to the backends of a Balancer in Group.
+type Route struct {
+	// Prefix selects rpcs by name. A Prefix ending with "/" or "." matches
+	// every rpc it is a prefix of, such as "/analytics.Analytics/" for every
+	// method of a service, "/analytics." for every service of a package, or
+	// "/" for every rpc. Any other Prefix matches the rpc with exactly that
+	// name, such as "/analytics.Analytics/Scan".
+	Prefix string
+
+	// Group is the Group of the backends the rpcs are sent to.
+	Group string
+}
+
+// matches returns true if the route applies to the rpc.
+func (r Route) matches(rpc string) bool {
+	if strings.HasSuffix(r.Prefix, "/") || strings.HasSuffix(r.Prefix, ".") {
+		return strings.HasPrefix(rpc, r.Prefix)
+	}
+	return rpc == r.Prefix
+}
+
+// SetRoutes replaces the routes of the Balancer, which send the rpcs matching
+// them only to the backends in their Group, for example to keep heavy
+// analytical rpcs on a dedicated set of backends. The route with the longest
+// Prefix matching an rpc applies, and the rpc fails if no backend is in its
+// Group. Rpcs matching no route are sent to the backends without a Group, or
+// to every backend if all of them have one. Rpcs pinned with WithTargetPeer
+// ignore the routes.
+func (b *Balancer) SetRoutes(routes ...Route) {
+	routes = append([]Route(nil), routes...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.routes = routes
+}
+
+// Routes returns the current routes.
+func (b *Balancer) Routes() []Route {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return append([]Route(nil), b.routes...)
+}
+
+// route returns the backends the routes allow for the rpc.
+func route(routes []Route, backends []Backend, rpc string) ([]Backend, error) {
+	var best *Route
+	for i := range routes {
+		if routes[i].matches(rpc) && (best == nil || len(routes[i].Prefix) > len(best.Prefix)) {
+			best = &routes[i]
+		}
+	}
+
+	group := ""
+	if best != nil {
+		group = best.Group
+	}
+
+	var grouped []Backend
+	for _, backend := range backends {
+		if backend.Group == group {
+			grouped = append(grouped, backend)
+		}
+	}
+	switch {
+	case len(grouped) > 0:
+		return grouped, nil
+	case best != nil:
+		return nil, drpc.Error.New("balancer has no backends in group %q for %q", group, rpc)
+	default:
+		return backends, nil
+	}
+}
+
+// Routes returns the routes sending the rpcs selected by each MethodConfig with
+// a BackendGroup to that group, for Balancer.SetRoutes.
+func (sc *ServiceConfig) Routes() (routes []Route) {
+	if sc == nil {
+		return nil
+	}
+	for _, mc := range sc.MethodConfig {
+		if mc.BackendGroup == "" {
+			continue
+		}
+		for _, name := range mc.Name {
+			prefix := "/"
+			switch {
+			case name.Service != "" && name.Method != "":
+				prefix = "/" + name.Service + "/" + name.Method
+			case name.Service != "":
+				prefix = "/" + name.Service + "/"
+			}
+			routes = append(routes, Route{Prefix: prefix, Group: mc.BackendGroup})
+		}
+	}
+	return routes
+}
@@ -0,0 +1,117 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+)
+
+// SendStats are the send metrics of a stream opened through a SendMonitor.
+type SendStats struct {
+	// Sends is the number of MsgSend calls that returned.
+	Sends uint64
+
+	// Blocked is the total time spent in MsgSend, which is mostly time spent
+	// waiting for the transport when the remote reads slower than the stream
+	// sends.
+	Blocked time.Duration
+
+	// Longest is the longest time spent in a single MsgSend.
+	Longest time.Duration
+}
+
+// SendStall describes a MsgSend that was still blocked after the threshold of
+// a SendMonitor.
+type SendStall struct {
+	// RPC is the name of the rpc of the stream.
+	RPC string
+
+	// Start is when the stalled MsgSend was called.
+	Start time.Time
+
+	// Stats are the send metrics of the stream before the stalled MsgSend.
+	Stats SendStats
+}
+
+// SendMonitor measures the time the streams opened through its interceptor
+// spend blocked in MsgSend, and calls a callback when a MsgSend stalls for
+// longer than a threshold, so that slow receivers holding resources can be
+// detected.
+type SendMonitor struct {
+	threshold time.Duration
+	onStall   func(SendStall)
+}
+
+// NewSendMonitor returns a SendMonitor that calls onStall from its own
+// goroutine when a MsgSend is blocked for longer than threshold. A
+// non-positive threshold or nil onStall only measures sends.
+func NewSendMonitor(threshold time.Duration, onStall func(SendStall)) *SendMonitor {
+	return &SendMonitor{threshold: threshold, onStall: onStall}
+}
+
+// StreamInterceptor returns a StreamClientInterceptor that monitors the sends
+// of the streams it opens. Their metrics are available from
+// StreamSendStats.
+func (m *SendMonitor) StreamInterceptor() StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		stream, err := streamer(ctx, rpc, enc, cc)
+		if err != nil {
+			return nil, err
+		}
+		return &sendStream{Stream: stream, m: m, rpc: rpc, clock: cc.clock()}, nil
+	}
+}
+
+// StreamSendStats returns the send metrics of a stream returned by the
+// interceptor of a SendMonitor, and false if the stream was not.
+func StreamSendStats(stream drpc.Stream) (SendStats, bool) {
+	ss, ok := stream.(*sendStream)
+	if !ok {
+		return SendStats{}, false
+	}
+	return ss.sendStats(), true
+}
+
+// sendStream measures the time spent in MsgSend.
+type sendStream struct {
+	drpc.Stream
+	m     *SendMonitor
+	rpc   string
+	clock drpcclock.Clock
+
+	mu    sync.Mutex
+	stats SendStats
+}
+
+func (s *sendStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	start := s.clock.Now()
+	if s.m.threshold > 0 && s.m.onStall != nil {
+		stall := SendStall{RPC: s.rpc, Start: start, Stats: s.sendStats()}
+		timer := s.clock.AfterFunc(s.m.threshold, func() { s.m.onStall(stall) })
+		defer timer.Stop()
+	}
+
+	err := s.Stream.MsgSend(msg, enc)
+	blocked := s.clock.Now().Sub(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Sends++
+	s.stats.Blocked += blocked
+	if blocked > s.stats.Longest {
+		s.stats.Longest = blocked
+	}
+	return err
+}
+
+// sendStats returns a copy of the metrics of the stream.
+func (s *sendStream) sendStats() SendStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
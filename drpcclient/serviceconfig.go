@@ -0,0 +1,181 @@
+package drpcclient
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+)
+
+// ServiceConfig is a gRPC style service config document that moves per method
+// policy out of code and into configuration.
+type ServiceConfig struct {
+	// LoadBalancingPolicy names the policy a Balancer uses to pick between
+	// backends, as accepted by NewPicker. Empty keeps the current Picker.
+	LoadBalancingPolicy string `json:"loadBalancingPolicy,omitempty"`
+
+	// MethodConfig lists the per method policies.
+	MethodConfig []MethodConfig `json:"methodConfig,omitempty"`
+}
+
+// MethodName selects the rpcs a MethodConfig applies to. An empty Method
+// selects every method of the Service, and an empty Service selects every rpc.
+type MethodName struct {
+	Service string `json:"service,omitempty"`
+	Method  string `json:"method,omitempty"`
+}
+
+// MethodConfig is the policy applied to the rpcs selected by Name.
+type MethodConfig struct {
+	Name []MethodName `json:"name"`
+
+	// Timeout bounds the rpc unless the context has an earlier deadline.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// MaxRequestMessageBytes is the maximum size of a marshaled request.
+	// Zero means unlimited.
+	MaxRequestMessageBytes int `json:"maxRequestMessageBytes,omitempty"`
+
+	// MaxResponseMessageBytes is the maximum size of a marshaled response.
+	// Zero means unlimited.
+	MaxResponseMessageBytes int `json:"maxResponseMessageBytes,omitempty"`
+
+	// RetryPolicy controls retries of failed unary rpcs. Nil means no retries.
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// BackendGroup is the Group of the backends of a Balancer the rpcs are
+	// sent to once the config is set on it with SetServiceConfig.
+	BackendGroup string `json:"backendGroup,omitempty"`
+}
+
+// RetryPolicy controls retries of failed unary rpcs. The delay before retry n
+// is a random duration between zero and min(InitialBackoff *
+// BackoffMultiplier^(n-1), MaxBackoff).
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first.
+	MaxAttempts int `json:"maxAttempts"`
+
+	InitialBackoff    Duration `json:"initialBackoff"`
+	MaxBackoff        Duration `json:"maxBackoff"`
+	BackoffMultiplier float64  `json:"backoffMultiplier"`
+
+	// RetryableStatusCodes are the error codes, as reported by drpcerr.Code,
+	// that are retried. They may be gRPC status code names like
+	// "UNAVAILABLE" or decimal numbers.
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// Duration is a time.Duration that is encoded in JSON the way gRPC service
+// configs encode them, as a decimal number of seconds with an "s" suffix like
+// "1.5s". Any string accepted by time.ParseDuration is also accepted.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(time.Duration(d).Seconds(), 'f', -1, 64) + "s")
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return drpc.Error.Wrap(err)
+	}
+	if secs, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64); err == nil && strings.HasSuffix(s, "s") {
+		*d = Duration(secs * float64(time.Second))
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return drpc.Error.New("invalid duration: %q", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ParseServiceConfig parses and validates a JSON service config document.
+func ParseServiceConfig(data []byte) (*ServiceConfig, error) {
+	var sc ServiceConfig
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, drpc.Error.Wrap(err)
+	}
+	for _, mc := range sc.MethodConfig {
+		if rp := mc.RetryPolicy; rp != nil {
+			if rp.MaxAttempts < 2 || rp.InitialBackoff <= 0 || rp.MaxBackoff <= 0 || rp.BackoffMultiplier <= 0 {
+				return nil, drpc.Error.New("invalid retry policy for %v", mc.Name)
+			}
+			for _, code := range rp.RetryableStatusCodes {
+				if _, ok := parseStatusCode(code); !ok {
+					return nil, drpc.Error.New("invalid retryable status code: %q", code)
+				}
+			}
+		}
+	}
+	return &sc, nil
+}
+
+// methodConfig returns the most specific MethodConfig for the rpc, which has
+// the form "/package.Service/Method", or nil if none applies.
+func (sc *ServiceConfig) methodConfig(rpc string) *MethodConfig {
+	if sc == nil {
+		return nil
+	}
+
+	service, method := rpc, ""
+	if i := strings.LastIndexByte(rpc, '/'); i >= 0 {
+		service, method = strings.TrimPrefix(rpc[:i], "/"), rpc[i+1:]
+	}
+
+	var best *MethodConfig
+	bestScore := 0
+	for i := range sc.MethodConfig {
+		mc := &sc.MethodConfig[i]
+		for _, name := range mc.Name {
+			score := 0
+			switch {
+			case name.Service == service && name.Method == method:
+				score = 3
+			case name.Service == service && name.Method == "":
+				score = 2
+			case name.Service == "" && name.Method == "":
+				score = 1
+			}
+			if score > bestScore {
+				best, bestScore = mc, score
+			}
+		}
+	}
+	return best
+}
+
+// grpcCodes maps gRPC status code names to their numeric values.
+var grpcCodes = map[string]uint64{
+	"CANCELLED":           drpcerr.Canceled,
+	"UNKNOWN":             drpcerr.Unknown,
+	"INVALID_ARGUMENT":    drpcerr.InvalidArgument,
+	"DEADLINE_EXCEEDED":   drpcerr.DeadlineExceeded,
+	"NOT_FOUND":           drpcerr.NotFound,
+	"ALREADY_EXISTS":      drpcerr.AlreadyExists,
+	"PERMISSION_DENIED":   drpcerr.PermissionDenied,
+	"RESOURCE_EXHAUSTED":  drpcerr.ResourceExhausted,
+	"FAILED_PRECONDITION": drpcerr.FailedPrecondition,
+	"ABORTED":             drpcerr.Aborted,
+	"OUT_OF_RANGE":        drpcerr.OutOfRange,
+	"UNIMPLEMENTED":       drpcerr.Unimplemented,
+	"INTERNAL":            drpcerr.Internal,
+	"UNAVAILABLE":         drpcerr.Unavailable,
+	"DATA_LOSS":           drpcerr.DataLoss,
+	"UNAUTHENTICATED":     drpcerr.Unauthenticated,
+}
+
+// parseStatusCode parses a gRPC status code name or a decimal code.
+func parseStatusCode(code string) (uint64, bool) {
+	if n, ok := grpcCodes[strings.ToUpper(code)]; ok {
+		return n, true
+	}
+	n, err := strconv.ParseUint(code, 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,101 @@
+package drpcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+func TestParseServiceConfig(t *testing.T) {
+	sc, err := ParseServiceConfig([]byte(`{
+		"loadBalancingPolicy": "round_robin",
+		"methodConfig": [
+			{"name": [{}], "timeout": "10s"},
+			{"name": [{"service": "kv.KV"}], "timeout": "1.5s"},
+			{"name": [{"service": "kv.KV", "method": "Get"}], "maxRequestMessageBytes": 4}
+		]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "round_robin", sc.LoadBalancingPolicy)
+
+	assert.Equal(t, 4, sc.methodConfig("/kv.KV/Get").MaxRequestMessageBytes)
+	assert.Equal(t, Duration(1500*time.Millisecond), sc.methodConfig("/kv.KV/Put").Timeout)
+	assert.Equal(t, Duration(10*time.Second), sc.methodConfig("/other.Svc/Put").Timeout)
+
+	_, err = ParseServiceConfig([]byte(`{"methodConfig": [{"name": [{}], "retryPolicy": {"maxAttempts": 1}}]}`))
+	assert.Error(t, err)
+}
+
+func TestServiceConfigRoutes(t *testing.T) {
+	sc, err := ParseServiceConfig([]byte(`{"methodConfig": [
+		{"name": [{}], "timeout": "10s"},
+		{"name": [{"service": "analytics.Analytics"}], "backendGroup": "analytics"},
+		{"name": [{"service": "kv.KV", "method": "Scan"}, {}], "backendGroup": "scans"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Route{
+		{Prefix: "/analytics.Analytics/", Group: "analytics"},
+		{Prefix: "/kv.KV/Scan", Group: "scans"},
+		{Prefix: "/", Group: "scans"},
+	}, sc.Routes())
+}
+
+func TestServiceConfigRetriesAndLimits(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	sc, err := ParseServiceConfig([]byte(`{"methodConfig": [{
+		"name": [{"service": "kv.KV"}],
+		"maxRequestMessageBytes": 8,
+		"retryPolicy": {
+			"maxAttempts": 3,
+			"initialBackoff": "1ms",
+			"maxBackoff": "1ms",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]}`))
+	assert.NoError(t, err)
+
+	conn := &flakyConn{failures: 2}
+	cc, err := NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, WithServiceConfig(sc))
+	assert.NoError(t, err)
+
+	in, out := "foobar", ""
+	assert.NoError(t, cc.Invoke(ctx, "/kv.KV/Get", testEncoding{}, &in, &out))
+	assert.Equal(t, 3, conn.calls)
+
+	conn.calls = 0
+	noRetry := WithInterceptorHints(ctx, InterceptorHints{RetryHint: HintSkip})
+	assert.Error(t, cc.Invoke(noRetry, "/kv.KV/Get", testEncoding{}, &in, &out))
+	assert.Equal(t, 1, conn.calls)
+	conn.calls = 3
+
+	in = "too large request"
+	assert.Error(t, cc.Invoke(ctx, "/kv.KV/Get", testEncoding{}, &in, &out))
+	assert.Equal(t, 3, conn.calls)
+}
+
+// flakyConn is a mockDrpcConn that fails the first failures Invokes with an
+// UNAVAILABLE error code.
+type flakyConn struct {
+	mockDrpcConn
+	failures int
+	calls    int
+}
+
+func (f *flakyConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return drpcerr.WithCode(errors.New("unavailable"), 14)
+	}
+	return f.mockDrpcConn.Invoke(ctx, rpc, enc, in, out)
+}
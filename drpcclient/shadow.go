@@ -0,0 +1,80 @@
+package drpcclient
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcenc"
+)
+
+// ShadowOptions configures ShadowUnaryInterceptor.
+type ShadowOptions struct {
+	// Percent is the percentage of unary requests, from 0 to 100, that are
+	// mirrored to the shadow conn.
+	Percent float64
+
+	// Timeout bounds how long a mirrored request may run. Zero means no
+	// timeout beyond the lifetime of the shadow conn.
+	Timeout time.Duration
+
+	// MaxInFlight bounds the number of mirrored requests running at once.
+	// Requests selected for mirroring while at the bound are not mirrored.
+	// Zero means unlimited.
+	MaxInFlight int
+}
+
+// ShadowUnaryInterceptor returns a UnaryClientInterceptor that asynchronously
+// mirrors a percentage of unary requests to the shadow conn, for example a
+// ClientConn to a new server build that is being validated against production
+// traffic. The request is marshaled before the primary rpc is issued so the
+// caller is free to reuse it. Responses and errors from the shadow conn are
+// ignored, and the mirrored request keeps the metadata of the original but
+// not its cancellation.
+func ShadowUnaryInterceptor(shadow drpc.Conn, opts ShadowOptions) UnaryClientInterceptor {
+	var inFlight int64
+
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		if opts.Percent <= 0 || rand.Float64()*100 >= opts.Percent {
+			return next(ctx, rpc, enc, in, out, cc)
+		}
+
+		if n := atomic.AddInt64(&inFlight, 1); opts.MaxInFlight > 0 && n > int64(opts.MaxInFlight) {
+			atomic.AddInt64(&inFlight, -1)
+		} else if data, err := drpcenc.MarshalAppend(in, enc, nil); err != nil {
+			atomic.AddInt64(&inFlight, -1)
+		} else {
+			go mirror(detach(ctx), shadow, rpc, data, opts.Timeout, &inFlight)
+		}
+
+		return next(ctx, rpc, enc, in, out, cc)
+	}
+}
+
+// mirror issues the already marshaled request on the shadow conn and discards
+// the result.
+func mirror(ctx context.Context, shadow drpc.Conn, rpc string, data []byte, timeout time.Duration, inFlight *int64) {
+	defer atomic.AddInt64(inFlight, -1)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var discard []byte
+	_ = shadow.Invoke(ctx, rpc, drpcenc.Raw{}, &data, &discard)
+}
+
+// detachedContext carries the values of a parent context without its deadline
+// or cancellation.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// detach returns a context with the values of ctx that is never canceled.
+func detach(ctx context.Context) context.Context { return detachedContext{ctx} }
@@ -0,0 +1,160 @@
+package drpcclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// slowStackDepth is the number of frames of the calling goroutine captured for
+// each rpc.
+const slowStackDepth = 32
+
+// SlowRecord describes an rpc that was still in flight when it exceeded the
+// threshold of a SlowDetector.
+type SlowRecord struct {
+	// RPC is the name of the rpc, and Stream is true if it was the opening of
+	// a stream.
+	RPC    string
+	Stream bool
+
+	// Start is when the rpc was issued.
+	Start time.Time
+
+	// State is the state of the ClientConn when the threshold was exceeded.
+	State State
+
+	// Attributes are the attributes of the ClientConn from
+	// WithConnAttributes.
+	Attributes ConnAttributes
+
+	// Stack is the stack of the goroutine that issued the rpc, starting at
+	// the interceptor that called the SlowDetector.
+	Stack string
+}
+
+// SlowDetector records the rpcs that are still in flight after a threshold,
+// along with the stack of the goroutine that issued them and the state of the
+// ClientConn, into a ring buffer. It helps diagnose hangs that never return an
+// error. The stack is captured when the rpc is issued, which costs a small
+// allocation per rpc, but only symbolized for slow rpcs. The buffer can be
+// served on a debug endpoint since SlowDetector is an http.Handler.
+type SlowDetector struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	records []SlowRecord
+	next    int
+	full    bool
+}
+
+// NewSlowDetector returns a SlowDetector that records rpcs in flight for
+// longer than threshold, keeping the most recent size of them.
+func NewSlowDetector(threshold time.Duration, size int) *SlowDetector {
+	if size <= 0 {
+		size = 1
+	}
+	return &SlowDetector{
+		threshold: threshold,
+		records:   make([]SlowRecord, size),
+	}
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that watches the unary
+// rpcs through it.
+func (d *SlowDetector) UnaryInterceptor() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		defer d.watch(cc, rpc, false).Stop()
+		return next(ctx, rpc, enc, in, out, cc)
+	}
+}
+
+// StreamInterceptor returns a StreamClientInterceptor that watches the opening
+// of the streams through it. Streams that stay open are not considered slow.
+func (d *SlowDetector) StreamInterceptor() StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		defer d.watch(cc, rpc, true).Stop()
+		return streamer(ctx, rpc, enc, cc)
+	}
+}
+
+// watch captures the stack of the caller and returns a timer that records the
+// rpc if it is not stopped within the threshold.
+func (d *SlowDetector) watch(cc *ClientConn, rpc string, stream bool) *time.Timer {
+	pcs := make([]uintptr, slowStackDepth)
+	pcs = pcs[:runtime.Callers(3, pcs)] // skip runtime.Callers, watch, and the interceptor
+	start := time.Now()
+
+	return time.AfterFunc(d.threshold, func() {
+		d.add(SlowRecord{
+			RPC:        rpc,
+			Stream:     stream,
+			Start:      start,
+			State:      cc.State(),
+			Attributes: cc.Attributes(),
+			Stack:      formatStack(pcs),
+		})
+	})
+}
+
+// add appends the record to the ring buffer.
+func (d *SlowDetector) add(record SlowRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.records[d.next] = record
+	d.next = (d.next + 1) % len(d.records)
+	if d.next == 0 {
+		d.full = true
+	}
+}
+
+// Records returns the recorded slow rpcs, oldest first.
+func (d *SlowDetector) Records() []SlowRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.full {
+		return append([]SlowRecord(nil), d.records[:d.next]...)
+	}
+	return append(append([]SlowRecord(nil), d.records[d.next:]...), d.records[:d.next]...)
+}
+
+// ServeHTTP writes the recorded slow rpcs as text, newest first.
+func (d *SlowDetector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	records := d.Records()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintf(w, "%d rpcs in flight for longer than %v\n", len(records), d.threshold)
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		kind := "unary"
+		if r.Stream {
+			kind = "stream"
+		}
+		conn := r.State.String()
+		if len(r.Attributes) > 0 {
+			conn += " (" + r.Attributes.String() + ")"
+		}
+		_, _ = fmt.Fprintf(w, "\n%s %s started %s, conn %s\n%s",
+			kind, r.RPC, r.Start.Format(time.RFC3339Nano), conn, r.Stack)
+	}
+}
+
+// formatStack symbolizes the program counters like a goroutine dump does.
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			return sb.String()
+		}
+	}
+}
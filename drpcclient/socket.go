@@ -0,0 +1,117 @@
+package drpcclient
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// SocketOptions tune the kernel behavior of the sockets dialed by DialSocket.
+// Zero fields keep the defaults of the system and of the net package. Options
+// marked as Linux only fail the dial on other systems.
+type SocketOptions struct {
+	// Nagle enables Nagle's algorithm, which the net package disables by
+	// setting TCP_NODELAY, trading latency for fewer small packets.
+	Nagle bool
+
+	// KeepAliveIdle is how long the conn is idle before TCP keepalive probes
+	// are sent, and KeepAliveInterval the time between probes, which
+	// defaults to KeepAliveIdle. KeepAliveCount is how many unanswered probes
+	// close the conn (Linux only). A negative KeepAliveIdle disables TCP
+	// keepalives.
+	KeepAliveIdle     time.Duration
+	KeepAliveInterval time.Duration
+	KeepAliveCount    int
+
+	// SendBuffer and ReceiveBuffer are the sizes of the kernel buffers of the
+	// socket in bytes.
+	SendBuffer    int
+	ReceiveBuffer int
+
+	// UserTimeout is how long sent data may remain unacknowledged before the
+	// conn is closed, bounding how long writes to a dead peer hang (Linux
+	// only).
+	UserTimeout time.Duration
+
+	// DSCP is the Differentiated Services Code Point, between 1 and 63, that
+	// marks the packets of the conn for prioritization by the network (Linux
+	// only).
+	DSCP int
+
+	// ReusePort sets SO_REUSEPORT so that several sockets may bind the same
+	// local address (Linux only).
+	ReusePort bool
+}
+
+// WithSocketOptions returns a DialOption that applies the socket options to
+// the conns the ClientConn dials with DialSocket, so that operators can tune
+// them per ClientConn without writing their own dialer.
+func WithSocketOptions(opts SocketOptions) DialOption {
+	return func(opt *dialOptions) {
+		opt.socket = opts
+	}
+}
+
+// socketKey is the context key of the SocketOptions of a dial.
+type socketKey struct{}
+
+// withSocketOptions returns a context carrying the socket options if any are
+// set.
+func withSocketOptions(ctx context.Context, opts SocketOptions) context.Context {
+	if opts == (SocketOptions{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, socketKey{}, opts)
+}
+
+// DialSocket dials the address on the network, like net.Dialer.DialContext,
+// applying the WithSocketOptions of the ClientConn that is dialing. It is
+// meant to be called by a DialerFunc, whose context carries the options.
+func DialSocket(ctx context.Context, network, addr string) (net.Conn, error) {
+	opts, _ := ctx.Value(socketKey{}).(SocketOptions)
+
+	d := net.Dialer{KeepAlive: opts.KeepAliveIdle}
+	if opts.DSCP != 0 || opts.ReusePort {
+		d.Control = opts.control
+	}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.apply(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// apply sets the options on the dialed conn.
+func (o SocketOptions) apply(conn net.Conn) error {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if o.Nagle {
+		if err := tcp.SetNoDelay(false); err != nil {
+			return err
+		}
+	}
+	if o.SendBuffer > 0 {
+		if err := tcp.SetWriteBuffer(o.SendBuffer); err != nil {
+			return err
+		}
+	}
+	if o.ReceiveBuffer > 0 {
+		if err := tcp.SetReadBuffer(o.ReceiveBuffer); err != nil {
+			return err
+		}
+	}
+	if o.KeepAliveInterval > 0 || o.KeepAliveCount > 0 || o.UserTimeout > 0 {
+		raw, err := tcp.SyscallConn()
+		if err != nil {
+			return err
+		}
+		return o.tune(raw)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package drpcclient
+
+import (
+	"strings"
+	"syscall"
+
+	"storj.io/drpc"
+)
+
+// the values of options missing from the syscall package of some
+// architectures.
+const (
+	soReusePort    = 0xf
+	tcpUserTimeout = 0x12
+)
+
+// control sets the options that must be set before the socket connects.
+func (o SocketOptions) control(network, address string, c syscall.RawConn) error {
+	if o.DSCP < 0 || o.DSCP > 63 {
+		return drpc.Error.New("invalid dscp %d", o.DSCP)
+	}
+	return rawControl(c, func(fd int) error {
+		if o.ReusePort {
+			if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, soReusePort, 1); err != nil {
+				return err
+			}
+		}
+		if o.DSCP != 0 {
+			if strings.HasSuffix(network, "6") {
+				return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, o.DSCP<<2)
+			}
+			return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TOS, o.DSCP<<2)
+		}
+		return nil
+	})
+}
+
+// tune sets the options that the net package does not expose on the
+// connected socket.
+func (o SocketOptions) tune(c syscall.RawConn) error {
+	return rawControl(c, func(fd int) error {
+		if o.KeepAliveInterval > 0 {
+			secs := int((o.KeepAliveInterval + 999e6) / 1e9)
+			if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, secs); err != nil {
+				return err
+			}
+		}
+		if o.KeepAliveCount > 0 {
+			if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, o.KeepAliveCount); err != nil {
+				return err
+			}
+		}
+		if o.UserTimeout > 0 {
+			millis := int(o.UserTimeout.Milliseconds())
+			if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpUserTimeout, millis); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rawControl calls fn with the file descriptor of the socket.
+func rawControl(c syscall.RawConn, fn func(fd int) error) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) { err = fn(int(fd)) }); cerr != nil {
+		return cerr
+	}
+	return err
+}
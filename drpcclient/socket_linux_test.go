@@ -0,0 +1,81 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package drpcclient
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpctest"
+)
+
+func TestWithSocketOptions(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = lis.Close() }()
+	ctx.Run(func(ctx context.Context) {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+		}
+	})
+
+	var rawconn net.Conn
+	cc, err := NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+		rawconn, err = DialSocket(ctx, "tcp", lis.Addr().String())
+		if err != nil {
+			return nil, err
+		}
+		return drpcconn.New(rawconn), nil
+	}, WithSocketOptions(SocketOptions{
+		KeepAliveIdle:     time.Minute,
+		KeepAliveInterval: 10 * time.Second,
+		KeepAliveCount:    3,
+		UserTimeout:       5 * time.Second,
+		DSCP:              46,
+		ReusePort:         true,
+	}))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	raw, err := rawconn.(*net.TCPConn).SyscallConn()
+	assert.NoError(t, err)
+	assert.NoError(t, raw.Control(func(fd uintptr) {
+		for _, opt := range []struct{ level, name, want int }{
+			{syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, 60},
+			{syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, 10},
+			{syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, 3},
+			{syscall.IPPROTO_TCP, tcpUserTimeout, 5000},
+			{syscall.IPPROTO_IP, syscall.IP_TOS, 46 << 2},
+			{syscall.SOL_SOCKET, soReusePort, 1},
+		} {
+			got, err := syscall.GetsockoptInt(int(fd), opt.level, opt.name)
+			assert.NoError(t, err)
+			assert.Equal(t, opt.want, got, opt.name)
+		}
+	}))
+
+	// dials outside a ClientConn use the defaults
+	conn, err := DialSocket(ctx, "tcp", lis.Addr().String())
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	_, err = NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+		_, err := DialSocket(ctx, "tcp", lis.Addr().String())
+		return nil, err
+	}, WithSocketOptions(SocketOptions{DSCP: 64}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,21 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+// +build !linux mips mipsle mips64 mips64le
+
+package drpcclient
+
+import (
+	"runtime"
+	"syscall"
+
+	"storj.io/drpc"
+)
+
+// control fails because the options set before connecting are not supported.
+func (o SocketOptions) control(network, address string, c syscall.RawConn) error {
+	return drpc.Error.New("dscp and reuse port socket options are not supported on %s", runtime.GOOS)
+}
+
+// tune fails because the options it sets are not supported.
+func (o SocketOptions) tune(c syscall.RawConn) error {
+	return drpc.Error.New("keepalive interval, keepalive count and user timeout socket options are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,103 @@
+package drpcclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc/drpcconn"
+)
+
+// IdentityError is the class of errors returned when the TLS handshake of a
+// dialed conn fails because the identity of the peer is not allowed.
+var IdentityError = errs.Class("peer identity")
+
+// spiffeOptions configure the verification of peer SVIDs.
+type spiffeOptions struct {
+	trustDomain string
+	allowed     map[string]bool
+}
+
+// WithSPIFFEVerification returns a DialOption that verifies the X.509 SVID of
+// the peer of every conn the ClientConn dials with HandshakeTLS, as issued by
+// SPIRE in mesh deployments. The certificate chain is verified against the
+// RootCAs of the tls.Config, which hold the trust bundle of the trust domain,
+// instead of the server name, and its leaf must have a single SPIFFE ID in the
+// trust domain, which must be one of the allowed IDs unless there are none.
+// Otherwise the handshake fails with an IdentityError. The verified ID is the
+// Identity of the Peer of the rpcs issued on the conn.
+func WithSPIFFEVerification(trustDomain string, allowedIDs ...string) DialOption {
+	allowed := make(map[string]bool, len(allowedIDs))
+	for _, id := range allowedIDs {
+		allowed[id] = true
+	}
+	return func(opt *dialOptions) {
+		opt.spiffe = &spiffeOptions{trustDomain: trustDomain, allowed: allowed}
+	}
+}
+
+// config returns a copy of the config verifying the SVID of the peer.
+func (o *spiffeOptions) config(config *tls.Config) *tls.Config {
+	config = config.Clone()
+	roots, verify := config.RootCAs, config.VerifyConnection
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if _, err := o.verify(cs, roots); err != nil {
+			return err
+		}
+		if verify != nil {
+			return verify(cs)
+		}
+		return nil
+	}
+	return config
+}
+
+// verify verifies the chain of the peer against the roots and returns the
+// SPIFFE ID of its leaf.
+func (o *spiffeOptions) verify(cs tls.ConnectionState, roots *x509.CertPool) (string, error) {
+	if len(cs.PeerCertificates) == 0 {
+		return "", IdentityError.New("peer sent no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return "", IdentityError.Wrap(err)
+	}
+
+	if len(leaf.URIs) != 1 || leaf.URIs[0].Scheme != "spiffe" {
+		return "", IdentityError.New("peer certificate has no single spiffe id")
+	}
+	id := leaf.URIs[0]
+	if id.Host != o.trustDomain {
+		return "", IdentityError.New("peer %q is not in trust domain %q", id, o.trustDomain)
+	}
+	if len(o.allowed) > 0 && !o.allowed[id.String()] {
+		return "", IdentityError.New("peer %q is not allowed", id)
+	}
+	return id.String(), nil
+}
+
+// spiffeID returns the SPIFFE ID of the leaf of a verified peer.
+func spiffeID(cs tls.ConnectionState) string {
+	return cs.PeerCertificates[0].URIs[0].String()
+}
+
+// identityConn is a conn whose peer identity was verified.
+type identityConn struct {
+	*drpcconn.Conn
+	identity string
+}
+
+// Identity returns the verified identity of the peer.
+func (c *identityConn) Identity() string { return c.identity }
@@ -0,0 +1,103 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"storj.io/drpc"
+)
+
+// Arm identifies one of the two backends of a Split.
+type Arm int
+
+const (
+	// Control is the arm that receives the traffic not sent to the canary.
+	Control Arm = iota
+
+	// Canary is the arm that receives the weighted share of traffic.
+	Canary
+)
+
+// String returns a human readable form of the Arm.
+func (a Arm) String() string {
+	switch a {
+	case Control:
+		return "control"
+	case Canary:
+		return "canary"
+	default:
+		return "unknown"
+	}
+}
+
+// Split routes rpcs between a control and a canary backend by weight, keeping
+// per arm stats so that canary analysis can compare error rates and latency.
+// Its interceptors pass every rpc down the rest of the chain to be issued on
+// the conn of the chosen arm instead of the conn of the ClientConn they are
+// installed on.
+type Split struct {
+	conns  [2]drpc.Conn
+	weight uint64 // math.Float64bits of the canary percentage
+	stats  [2]CallStats
+}
+
+// NewSplit returns a Split that sends percent of the rpcs, from 0 to 100, to
+// the canary conn and the rest to the control conn.
+func NewSplit(control, canary drpc.Conn, percent float64) *Split {
+	s := &Split{conns: [2]drpc.Conn{control, canary}}
+	s.SetCanaryPercent(percent)
+	return s
+}
+
+// SetCanaryPercent atomically changes the percentage of rpcs sent to the canary
+// for subsequent calls. Values are clamped to be between 0 and 100.
+func (s *Split) SetCanaryPercent(percent float64) {
+	percent = math.Max(0, math.Min(100, percent))
+	atomic.StoreUint64(&s.weight, math.Float64bits(percent))
+}
+
+// CanaryPercent returns the percentage of rpcs sent to the canary.
+func (s *Split) CanaryPercent() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.weight))
+}
+
+// Stats returns the stats collected for the arm.
+func (s *Split) Stats(arm Arm) CallStats {
+	return s.stats[arm].AtomicClone()
+}
+
+// pick chooses the arm for the next rpc.
+func (s *Split) pick() Arm {
+	if rand.Float64()*100 < s.CanaryPercent() {
+		return Canary
+	}
+	return Control
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that issues each unary rpc
+// on the conn of the chosen arm.
+func (s *Split) UnaryInterceptor() UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *ClientConn, next UnaryInvoker) error {
+		arm := s.pick()
+		start := time.Now()
+		err := next(withAttemptConn(ctx, s.conns[arm]), rpc, enc, in, out, cc)
+		s.stats[arm].Record(time.Since(start), err)
+		return err
+	}
+}
+
+// StreamInterceptor returns a StreamClientInterceptor that opens each stream on
+// the conn of the chosen arm. Only the opening of the stream is recorded in the
+// stats.
+func (s *Split) StreamInterceptor() StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *ClientConn, streamer Streamer) (drpc.Stream, error) {
+		arm := s.pick()
+		start := time.Now()
+		stream, err := streamer(withAttemptConn(ctx, s.conns[arm]), rpc, enc, cc)
+		s.stats[arm].Record(time.Since(start), err)
+		return stream, err
+	}
+}
@@ -0,0 +1,37 @@
+package drpcclient
+
+// State is the connectivity state of a ClientConn.
+type State int
+
+const (
+	// Ready means the ClientConn has an underlying conn and can issue RPCs
+	// on it without dialing.
+	Ready State = iota
+
+	// Idle means the ClientConn released its underlying conn after a period
+	// without RPCs. The next RPC dials a new conn.
+	Idle
+
+	// Shutdown means the ClientConn has been closed.
+	Shutdown
+
+	// Connecting means the ClientConn is dialing an underlying conn. RPCs
+	// issued meanwhile wait for the dial.
+	Connecting
+)
+
+// String returns a human readable form of the State.
+func (s State) String() string {
+	switch s {
+	case Ready:
+		return "Ready"
+	case Idle:
+		return "Idle"
+	case Shutdown:
+		return "Shutdown"
+	case Connecting:
+		return "Connecting"
+	default:
+		return "Unknown"
+	}
+}
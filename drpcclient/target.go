@@ -0,0 +1,71 @@
+package drpcclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpchttp"
+)
+
+// NewTargetDialer returns a DialerFunc for the target. A target with the
+// "http+drpc" or "https+drpc" scheme, like "https+drpc://example.com/rpc",
+// issues unitary RPCs over HTTP to a drpchttp handler hosted at the URL with
+// the "+drpc" dropped from the scheme, using client, or http.DefaultClient if
+// client is nil. Interceptors, metadata and the other options of a ClientConn
+// work the same over HTTP, but streams fail to start.
+//
+// A target like "unix:///run/agent.sock" or "unix:agent.sock" dials the unix
+// domain socket at the absolute or relative path, which drpcserver.ListenUnix
+// can serve. The socket is dialed again on every call, so a ClientConn that
+// redials after going idle or after its conn is closed connects to whichever
+// process is listening at the path at that time. Windows supports unix domain
+// sockets as well; named pipes require passing a dialer for them as dial.
+//
+// Any other target is passed to dial as an address, and an error is returned
+// if dial is nil.
+func NewTargetDialer(target string, client *http.Client, dial AddrDialerFunc) (DialerFunc, error) {
+	if path, ok := unixPath(target); ok {
+		if path == "" {
+			return nil, drpc.Error.New("missing socket path in target %q", target)
+		}
+		return func(ctx context.Context) (drpc.Conn, error) {
+			var d net.Dialer
+			rawconn, err := d.DialContext(ctx, "unix", path)
+			if err != nil {
+				return nil, err
+			}
+			return drpcconn.New(rawconn), nil
+		}, nil
+	}
+
+	for _, scheme := range []string{"http", "https"} {
+		if rest := strings.TrimPrefix(target, scheme+"+drpc://"); rest != target {
+			base := scheme + "://" + rest
+			return func(ctx context.Context) (drpc.Conn, error) {
+				return drpchttp.NewConn(client, base), nil
+			}, nil
+		}
+	}
+
+	if dial == nil {
+		return nil, drpc.Error.New("no dialer for target %q", target)
+	}
+	return func(ctx context.Context) (drpc.Conn, error) {
+		return dial(ctx, target)
+	}, nil
+}
+
+// unixPath returns the socket path of a unix target.
+func unixPath(target string) (string, bool) {
+	if path := strings.TrimPrefix(target, "unix://"); path != target {
+		return path, true
+	}
+	if path := strings.TrimPrefix(target, "unix:"); path != target {
+		return path, true
+	}
+	return "", false
+}
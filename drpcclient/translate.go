@@ -0,0 +1,78 @@
+package drpcclient
+
+import (
+	"errors"
+	"io"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+)
+
+// WithErrorTranslation returns a DialOption that rewrites the errors of rpcs
+// with the rules before they are returned to callers, for example to map
+// transport failures to the gRPC UNAVAILABLE code with
+// MapConnFailures(drpcerr.Unavailable) or to scrub internal details with
+// drpcerr.Scrub. The rules apply after every interceptor, so interceptors see
+// the original errors. For streams, the rules also apply to the errors of
+// MsgSend, MsgRecv, CloseSend and Close, except io.EOF. The first rule that
+// applies to an error wins.
+func WithErrorTranslation(rules ...drpcerr.Rule) DialOption {
+	return func(opt *dialOptions) {
+		opt.errorRules = append(opt.errorRules, rules...)
+	}
+}
+
+// MapConnFailures returns a drpcerr.Rule that associates the code with errors
+// caused by the conn to the server going away or failing to connect, rather
+// than by the rpc, such as resets and closed conns.
+func MapConnFailures(code uint64) drpcerr.Rule {
+	return drpcerr.Match(func(err error) bool {
+		return drpcerr.Code(err) == 0 && isConnFailure(err)
+	}, code)
+}
+
+// translateError rewrites the error of the rpc with the configured rules.
+func (c *ClientConn) translateError(rpc string, err error) error {
+	if len(c.dopts.errorRules) == 0 {
+		return err
+	}
+	return drpcerr.Translate(rpc, err, c.dopts.errorRules)
+}
+
+// translateStream rewrites the error of opening the stream and wraps the
+// stream so that the errors of its methods are rewritten too.
+func (c *ClientConn) translateStream(rpc string, stream drpc.Stream, err error) (drpc.Stream, error) {
+	if len(c.dopts.errorRules) == 0 {
+		return stream, err
+	}
+	if err != nil {
+		return nil, c.translateError(rpc, err)
+	}
+	return &translateStream{Stream: stream, rpc: rpc, rules: c.dopts.errorRules}, nil
+}
+
+// translateStream rewrites the errors of a stream.
+type translateStream struct {
+	drpc.Stream
+	rpc   string
+	rules []drpcerr.Rule
+}
+
+func (s *translateStream) translate(err error) error {
+	if errors.Is(err, io.EOF) {
+		return err
+	}
+	return drpcerr.Translate(s.rpc, err, s.rules)
+}
+
+func (s *translateStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	return s.translate(s.Stream.MsgSend(msg, enc))
+}
+
+func (s *translateStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	return s.translate(s.Stream.MsgRecv(msg, enc))
+}
+
+func (s *translateStream) CloseSend() error { return s.translate(s.Stream.CloseSend()) }
+
+func (s *translateStream) Close() error { return s.translate(s.Stream.Close()) }
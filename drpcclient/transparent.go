@@ -0,0 +1,48 @@
+package drpcclient
+
+import (
+	"context"
+
+	"storj.io/drpc"
+)
+
+// retryUnsent returns true if an attempt that failed with err should be made
+// again transparently, which is when it failed before any of the rpc was sent,
+// such as when dialing failed or the conn was found closed, since issuing it
+// again is then always safe. Rpcs get one such retry regardless of their
+// retry policy.
+func retryUnsent(ctx context.Context, unsent bool, err error) bool {
+	return err != nil && unsent && ctx.Err() == nil
+}
+
+// acquireOpen is like acquire, but returns an error for conns that are already
+// closed after forgetting them, so that the next attempt dials a new conn. The
+// returned bool is true if the error happened before the rpc was sent.
+func (c *ClientConn) acquireOpen(ctx context.Context) (drpc.Conn, bool, error) {
+	conn, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err != ErrClientConnClosed && ctx.Err() == nil, err
+	}
+	if isClosed(conn) {
+		c.release()
+		c.forget(conn)
+		return nil, true, UnsentError.New("conn closed before the rpc was sent")
+	}
+	return conn, false, nil
+}
+
+// forget drops the closed conn so that the next rpc dials a new one.
+func (c *ClientConn) forget(conn drpc.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != conn || c.state != Ready {
+		return
+	}
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	c.emit(ConnEvent{Type: Drain})
+	c.conn, c.connCh = nil, nil
+	c.setStateLocked(Idle)
+}
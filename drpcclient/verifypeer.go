@@ -0,0 +1,57 @@
+package drpcclient
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// PeerTarget describes the peer a conn was dialed to, for a VerifyPeerFunc.
+type PeerTarget struct {
+	// ServerName is the name the tls.Config expects the peer to have, which
+	// is not verified if InsecureSkipVerify is set.
+	ServerName string
+
+	// RemoteAddr is the address of the dialed peer.
+	RemoteAddr net.Addr
+
+	// Identity is the SPIFFE ID verified by WithSPIFFEVerification, or empty.
+	Identity string
+}
+
+// VerifyPeerFunc verifies the peer of a conn during its TLS handshake, with the
+// full connection state of the handshake. Returning an error aborts the
+// handshake.
+type VerifyPeerFunc func(state tls.ConnectionState, target PeerTarget) error
+
+// WithVerifyPeer returns a DialOption that calls fn to verify the peer of every
+// conn the ClientConn dials with HandshakeTLS, after the verification of the
+// tls.Config and of WithSPIFFEVerification succeeded, so that clusters with
+// their own certificate conventions, such as node and client certificates or
+// tenant scoped SANs, can check them without replacing the dialer. The
+// handshake fails with an IdentityError wrapping the error fn returns.
+func WithVerifyPeer(fn VerifyPeerFunc) DialOption {
+	return func(opt *dialOptions) { opt.verifyPeer = fn }
+}
+
+// verifyPeerConfig returns a copy of the config that calls fn once the
+// verification of the config succeeded.
+func verifyPeerConfig(config *tls.Config, rawconn net.Conn, spiffe bool, fn VerifyPeerFunc) *tls.Config {
+	config = config.Clone()
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		target := PeerTarget{ServerName: config.ServerName, RemoteAddr: rawconn.RemoteAddr()}
+		if spiffe {
+			target.Identity = spiffeID(cs)
+		}
+		if err := fn(cs, target); err != nil {
+			return IdentityError.Wrap(err)
+		}
+		return nil
+	}
+	return config
+}
@@ -0,0 +1,366 @@
+package drpcclient
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+)
+
+// WatchStaleError is the class of errors a Watch reopens its stream with when
+// the stream received nothing within the Liveness timeout.
+var WatchStaleError = errs.Class("watch stale")
+
+// WatchEventType identifies what a WatchEvent reports.
+type WatchEventType int
+
+const (
+	// WatchConnected is sent when a stream of the watch is opened.
+	WatchConnected WatchEventType = iota
+
+	// WatchMessage is sent with every message received.
+	WatchMessage
+
+	// WatchDisconnected is sent when a stream of the watch failed and is
+	// about to be reopened.
+	WatchDisconnected
+)
+
+// String returns a human readable form of the WatchEventType.
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchConnected:
+		return "WatchConnected"
+	case WatchMessage:
+		return "WatchMessage"
+	case WatchDisconnected:
+		return "WatchDisconnected"
+	default:
+		return "Unknown"
+	}
+}
+
+// WatchEvent is something that happened to a Watch. The fields that do not
+// apply to its Type are zero.
+type WatchEvent struct {
+	Type WatchEventType
+	Time time.Time
+
+	// Msg is the message of a WatchMessage, allocated by NewMessage.
+	Msg drpc.Message
+
+	// Token is the resume token a WatchConnected stream was opened with.
+	Token string
+
+	// Attempt is the number of consecutive failed streams before a
+	// WatchConnected, or including this one for a WatchDisconnected.
+	Attempt int
+
+	// Err is the error a WatchDisconnected stream failed with.
+	Err error
+
+	// Next is how long until the stream is reopened after a
+	// WatchDisconnected.
+	Next time.Duration
+}
+
+// WatchOptions configures a Watch. Zero values use the defaults.
+type WatchOptions struct {
+	// NewMessage allocates the message each response is received into. It
+	// is required.
+	NewMessage func() drpc.Message
+
+	// Token returns the resume token carried by a received message, if any.
+	// The most recent token is sent in the drpcmetadata.ResumeToken metadata
+	// when the stream is reopened.
+	Token func(msg drpc.Message) (string, bool)
+
+	// ResumeFrom is the resume token the first stream is opened with, for
+	// example one persisted by a previous process.
+	ResumeFrom string
+
+	// Start sends the initial messages on every opened stream, for example
+	// the request of a server streaming rpc followed by CloseSend.
+	Start func(stream drpc.Stream) error
+
+	// Retryable reports if an error should reopen the stream instead of
+	// stopping the watch. Nil reopens the stream after every error,
+	// including the server ending it.
+	Retryable func(err error) bool
+
+	// MaxAttempts bounds the number of consecutive streams that fail
+	// without receiving a message before the watch stops. Zero retries
+	// forever.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first reopen after a failure.
+	// It defaults to 100 milliseconds.
+	InitialBackoff time.Duration
+
+	// MaxBackoff bounds the delay between reopens. It defaults to 30
+	// seconds.
+	MaxBackoff time.Duration
+
+	// Multiplier is how much the delay grows after every consecutive
+	// failure. It defaults to 2.
+	Multiplier float64
+
+	// Liveness, if positive, reopens a stream that received nothing for
+	// this long with WatchStaleError, for servers that send periodic
+	// heartbeats.
+	Liveness time.Duration
+
+	// OnLiveness, if set, is called with true when the watch receives a
+	// message after being down, and with false when a stream that received
+	// messages fails or goes stale. It is called synchronously from the
+	// goroutine running the watch, so it must return quickly.
+	OnLiveness func(live bool)
+
+	// Buffer is the capacity of the events channel. The watch stops
+	// receiving while the channel is full.
+	Buffer int
+
+	// Clock schedules the backoff and liveness timeouts. It defaults to the
+	// clock of the ClientConn.
+	Clock drpcclock.Clock
+}
+
+// Watch keeps a server streaming rpc, such as the watch of a configuration,
+// open in the background. Failed streams are reopened with exponential
+// backoff and the last resume token the server supplied, which the server
+// reads with drpcmetadata.Lookup to continue where it left off. What happens
+// is delivered on the Events channel.
+type Watch struct {
+	cc     *ClientConn
+	rpc    string
+	enc    drpc.Encoding
+	opts   WatchOptions
+	clock  drpcclock.Clock
+	ctx    context.Context
+	cancel func()
+	events chan WatchEvent
+	done   chan struct{}
+
+	mu    sync.Mutex
+	token string
+	err   error
+	live  bool
+}
+
+// NewWatch starts watching the rpc on the ClientConn until the context is
+// done, Close is called, or the watch stops because of an error that is not
+// retryable.
+func NewWatch(ctx context.Context, cc *ClientConn, rpc string, enc drpc.Encoding, opts WatchOptions) *Watch {
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.MaxBackoff < opts.InitialBackoff {
+		opts.MaxBackoff = opts.InitialBackoff
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = 2
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = cc.clock()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watch{
+		cc:     cc,
+		rpc:    rpc,
+		enc:    enc,
+		opts:   opts,
+		clock:  clock,
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan WatchEvent, opts.Buffer),
+		done:   make(chan struct{}),
+		token:  opts.ResumeFrom,
+	}
+	go w.run()
+	return w
+}
+
+// Events returns the channel the events of the watch are delivered on. It is
+// closed once the watch stops, after which Err returns why.
+func (w *Watch) Events() <-chan WatchEvent { return w.events }
+
+// Token returns the most recent resume token received.
+func (w *Watch) Token() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.token
+}
+
+// Live returns true if the current stream of the watch has received a
+// message and not failed since.
+func (w *Watch) Live() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.live
+}
+
+// Err returns the error the watch stopped with, or nil while it runs. It is
+// the error of the context if the context is done or Close was called.
+func (w *Watch) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// Close stops the watch and waits for it to stop.
+func (w *Watch) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
+}
+
+// run keeps the rpc open until the watch stops.
+func (w *Watch) run() {
+	var err error
+	defer func() {
+		w.mu.Lock()
+		w.err = err
+		w.mu.Unlock()
+
+		w.cancel()
+		close(w.events)
+		close(w.done)
+	}()
+
+	for attempt := 0; ; {
+		token := w.Token()
+		var stream drpc.Stream
+		stream, err = openResumed(w.ctx, w.cc, w.rpc, w.enc, token, w.opts.Start)
+		if err == nil {
+			if !w.emit(WatchEvent{Type: WatchConnected, Token: token, Attempt: attempt}) {
+				_ = stream.Close()
+				err = w.ctx.Err()
+				return
+			}
+			var received bool
+			received, err = w.recv(stream)
+			_ = stream.Close()
+			if received {
+				attempt = 0
+			}
+			w.setLive(false)
+		}
+
+		if w.ctx.Err() != nil {
+			err = w.ctx.Err()
+			return
+		}
+		if w.opts.Retryable != nil && !w.opts.Retryable(err) {
+			return
+		}
+		attempt++
+		if w.opts.MaxAttempts > 0 && attempt >= w.opts.MaxAttempts {
+			return
+		}
+
+		next := w.backoff(attempt)
+		if !w.emit(WatchEvent{Type: WatchDisconnected, Attempt: attempt, Err: err, Next: next}) || !w.sleep(next) {
+			err = w.ctx.Err()
+			return
+		}
+	}
+}
+
+// recv delivers the messages of the stream until it fails, returning if any
+// message was received.
+func (w *Watch) recv(stream drpc.Stream) (received bool, err error) {
+	var stale int32
+	var timer drpcclock.Timer
+	if w.opts.Liveness > 0 {
+		timer = w.clock.AfterFunc(w.opts.Liveness, func() {
+			atomic.StoreInt32(&stale, 1)
+			_ = stream.Close()
+		})
+		defer timer.Stop()
+	}
+
+	for {
+		msg := w.opts.NewMessage()
+		if err := stream.MsgRecv(msg, w.enc); err != nil {
+			if atomic.LoadInt32(&stale) == 1 {
+				err = WatchStaleError.Wrap(err)
+			}
+			return received, err
+		}
+		if timer != nil {
+			timer.Reset(w.opts.Liveness)
+		}
+		received = true
+
+		if w.opts.Token != nil {
+			if token, ok := w.opts.Token(msg); ok {
+				w.mu.Lock()
+				w.token = token
+				w.mu.Unlock()
+			}
+		}
+		w.setLive(true)
+		if !w.emit(WatchEvent{Type: WatchMessage, Msg: msg}) {
+			return received, w.ctx.Err()
+		}
+	}
+}
+
+// setLive records whether the watch is live, calling OnLiveness if it
+// changed.
+func (w *Watch) setLive(live bool) {
+	w.mu.Lock()
+	changed := w.live != live
+	w.live = live
+	w.mu.Unlock()
+
+	if changed && w.opts.OnLiveness != nil {
+		w.opts.OnLiveness(live)
+	}
+}
+
+// backoff returns the delay before reopening after the given consecutive
+// failure, starting at 1.
+func (w *Watch) backoff(attempt int) time.Duration {
+	d := float64(w.opts.InitialBackoff) * math.Pow(w.opts.Multiplier, float64(attempt-1))
+	return time.Duration(math.Min(d, float64(w.opts.MaxBackoff)))
+}
+
+// sleep waits for the duration, returning false if the watch stopped first.
+func (w *Watch) sleep(d time.Duration) bool {
+	timer := w.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+// emit delivers the event, setting its Time, returning false if the watch
+// stopped first.
+func (w *Watch) emit(ev WatchEvent) bool {
+	ev.Time = w.clock.Now()
+	select {
+	case w.events <- ev:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
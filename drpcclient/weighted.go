@@ -0,0 +1,212 @@
+package drpcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"storj.io/drpc/drpcclock"
+)
+
+// DefaultLoadSmoothing is the smoothing factor of a WeightedPicker when none is
+// configured.
+const DefaultLoadSmoothing = 0.3
+
+// DefaultLoadExpiration is how long the load reported by a backend is used by
+// a WeightedPicker when none is configured.
+const DefaultLoadExpiration = 3 * time.Minute
+
+// DefaultQueueCost is the utilization a queued request counts as in a
+// WeightedPicker when none is configured.
+const DefaultQueueCost = 0.1
+
+// LoadReport is the load a backend reports about itself.
+type LoadReport struct {
+	// Utilization is the fraction of its capacity the backend uses, such as
+	// its CPU utilization, where 1 is fully used.
+	Utilization float64
+
+	// QueueDepth is the number of requests waiting to be served.
+	QueueDepth int
+}
+
+// WeightedOptions configures a WeightedPicker.
+type WeightedOptions struct {
+	// Smoothing is the factor of the exponentially weighted moving average
+	// of the reported loads, between 0 and 1, where larger values follow new
+	// reports more closely. It defaults to DefaultLoadSmoothing.
+	Smoothing float64
+
+	// QueueCost is the utilization one queued request counts as. It defaults
+	// to DefaultQueueCost.
+	QueueCost float64
+
+	// Expiration is how long a report is used after it is received, so that a
+	// backend that stops reporting is not weighted by stale load forever. It
+	// defaults to DefaultLoadExpiration.
+	Expiration time.Duration
+
+	// Clock is the clock of the Expiration. It defaults to the system clock.
+	Clock drpcclock.Clock
+}
+
+// WeightedPicker is a Picker doing weighted round robin over the candidates,
+// weighting each backend by the inverse of the load it reports, smoothed with
+// an exponentially weighted moving average, so that lightly loaded backends
+// receive proportionally more rpcs. Backends without a current report are
+// weighted like the average reporting backend, and the picks are spread
+// evenly when none reports.
+//
+// DRPC responses have no trailers to carry load reports, so they are passed
+// to Report, typically from a control stream the application opens to each
+// backend, which Watch can consume.
+type WeightedPicker struct {
+	opts  WeightedOptions
+	clock drpcclock.Clock
+
+	mu      sync.Mutex
+	loads   map[string]*backendLoad
+	current map[string]float64
+}
+
+// backendLoad is the smoothed load of a backend.
+type backendLoad struct {
+	load    float64
+	updated time.Time
+}
+
+// minLoad bounds the weight of idle backends.
+const minLoad = 0.01
+
+// NewWeightedPicker returns a WeightedPicker configured by opts.
+func NewWeightedPicker(opts WeightedOptions) *WeightedPicker {
+	if opts.Smoothing <= 0 || opts.Smoothing > 1 {
+		opts.Smoothing = DefaultLoadSmoothing
+	}
+	if opts.QueueCost <= 0 {
+		opts.QueueCost = DefaultQueueCost
+	}
+	if opts.Expiration <= 0 {
+		opts.Expiration = DefaultLoadExpiration
+	}
+	return &WeightedPicker{
+		opts:    opts,
+		clock:   drpcclock.Or(opts.Clock),
+		loads:   make(map[string]*backendLoad),
+		current: make(map[string]float64),
+	}
+}
+
+// Report records the load reported by the backend with the address.
+func (p *WeightedPicker) Report(addr string, report LoadReport) {
+	load := report.Utilization + p.opts.QueueCost*float64(report.QueueDepth)
+	if load < 0 {
+		load = 0
+	}
+	now := p.clock.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bl, ok := p.loads[addr]
+	if !ok || now.Sub(bl.updated) >= p.opts.Expiration {
+		p.loads[addr] = &backendLoad{load: load, updated: now}
+		return
+	}
+	bl.load += p.opts.Smoothing * (load - bl.load)
+	bl.updated = now
+}
+
+// Watch reports the loads returned by recv for the backend with the address
+// until recv fails, such as when the control stream it reads ends, and returns
+// the error.
+func (p *WeightedPicker) Watch(ctx context.Context, addr string, recv func() (LoadReport, error)) error {
+	for {
+		report, err := recv()
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.Report(addr, report)
+	}
+}
+
+// Load returns the smoothed load of the backend with the address, and false if
+// it has no current report.
+func (p *WeightedPicker) Load(addr string) (float64, bool) {
+	now := p.clock.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bl, ok := p.loads[addr]
+	if !ok || now.Sub(bl.updated) >= p.opts.Expiration {
+		return 0, false
+	}
+	return bl.load, true
+}
+
+// Pick implements Picker.
+func (p *WeightedPicker) Pick(ctx context.Context, rpc string, candidates []Backend) Backend {
+	now := p.clock.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	weights := make([]float64, len(candidates))
+	var sum float64
+	var reported int
+	for i, backend := range candidates {
+		if bl, ok := p.loads[backend.Addr]; ok && now.Sub(bl.updated) < p.opts.Expiration {
+			weights[i] = 1 / (minLoad + bl.load)
+			sum += weights[i]
+			reported++
+		}
+	}
+	fallback := 1.0
+	if reported > 0 {
+		fallback = sum / float64(reported)
+	}
+
+	// smooth weighted round robin: every candidate gains its weight, and the
+	// one with the most gives up the total, which interleaves the picks.
+	var total float64
+	choice := 0
+	for i, backend := range candidates {
+		if weights[i] == 0 {
+			weights[i] = fallback
+		}
+		total += weights[i]
+		p.current[backend.Addr] += weights[i]
+		if p.current[backend.Addr] > p.current[candidates[choice].Addr] {
+			choice = i
+		}
+	}
+	p.current[candidates[choice].Addr] -= total
+
+	if len(p.current) > 2*len(candidates) {
+		p.pruneLocked(now, candidates)
+	}
+	return candidates[choice]
+}
+
+// pruneLocked forgets the round robin state of the backends that are not
+// candidates and the expired loads. It must be called with p.mu held.
+func (p *WeightedPicker) pruneLocked(now time.Time, candidates []Backend) {
+	keep := make(map[string]bool, len(candidates))
+	for _, backend := range candidates {
+		keep[backend.Addr] = true
+	}
+	for addr := range p.current {
+		if !keep[addr] {
+			delete(p.current, addr)
+		}
+	}
+	for addr, bl := range p.loads {
+		if now.Sub(bl.updated) >= p.opts.Expiration {
+			delete(p.loads, addr)
+		}
+	}
+}
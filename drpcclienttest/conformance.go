@@ -0,0 +1,418 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclienttest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpctest"
+)
+
+// contextKey is the key of the value placed in the caller's context to check
+// that interceptors propagate it.
+type contextKey struct{}
+
+// CheckUnaryInterceptor runs subtests that verify the interceptor keeps the
+// contract of a unary client interceptor:
+//
+//   - "once": it calls the invoker at most once per rpc,
+//   - "context": the context the invoker sees carries the caller's values and
+//     is canceled when the caller's context is,
+//   - "error": errors from the invoker are returned so that errors.Is still
+//     matches them, and
+//   - "goroutines": no goroutines it started outlive the rpcs.
+//
+// The goroutine check counts every goroutine in the process, so it must not
+// run in parallel with other tests.
+func CheckUnaryInterceptor(t *testing.T, interceptor drpcclient.UnaryClientInterceptor) {
+	opt := drpcclient.WithChainUnaryInterceptor(interceptor)
+	invoke := func(ctx context.Context, cc *drpcclient.ClientConn) error {
+		in, out := "request", ""
+		return cc.Invoke(ctx, "/drpcclienttest/Unary", drpctest.StringEncoding{}, &in, &out)
+	}
+
+	t.Run("once", func(t *testing.T) {
+		for _, fail := range []error{nil, errScripted} {
+			fail := fail
+			conn := &Conn{InvokeFunc: func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+				return fail
+			}}
+			cc := newClientConn(t, conn, opt)
+			within(t, "rpc", func() { _ = invoke(context.Background(), cc) })
+			if calls := len(conn.Calls()); calls > 1 {
+				t.Fatalf("invoker called %d times when it returned %v", calls, fail)
+			}
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		seen := make(chan context.Context, 1)
+		conn := &Conn{InvokeFunc: func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+			select {
+			case seen <- ctx:
+			default:
+			}
+			return blockUntilDone(ctx)
+		}}
+		checkContext(t, conn, opt, seen, func(ctx context.Context, cc *drpcclient.ClientConn) {
+			_ = invoke(ctx, cc)
+		})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		conn := &Conn{InvokeFunc: func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+			return errScripted
+		}}
+		cc := newClientConn(t, conn, opt)
+		var err error
+		within(t, "rpc", func() { err = invoke(context.Background(), cc) })
+		if !errors.Is(err, errScripted) {
+			t.Fatalf("expected the invoker's error, got %v", err)
+		}
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		checkGoroutines(t, func(t *testing.T) {
+			cc := newClientConn(t, new(Conn), opt)
+			within(t, "rpc", func() { _ = invoke(context.Background(), cc) })
+
+			conn := &Conn{InvokeFunc: func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+				return blockUntilDone(ctx)
+			}}
+			cc = newClientConn(t, conn, opt)
+			for _, sc := range scenarios {
+				if sc.ctx == nil {
+					continue
+				}
+				ctx, cancel := sc.ctx()
+				within(t, sc.name+" rpc", func() { _ = invoke(ctx, cc) })
+				cancel()
+			}
+		})
+	})
+}
+
+// CheckStreamInterceptor runs subtests that verify the interceptor keeps the
+// contract of a stream client interceptor:
+//
+//   - "once": it calls the streamer at most once per stream,
+//   - "context": the context the streamer sees carries the caller's values
+//     and is canceled when the caller's context is,
+//   - "error": errors from the streamer are returned so that errors.Is still
+//     matches them,
+//   - "wrap": if it wraps the stream, every message and error still reaches
+//     the caller and the conn, and closing it closes the conn's stream, and
+//   - "goroutines": no goroutines it started outlive the streams.
+//
+// The goroutine check counts every goroutine in the process, so it must not
+// run in parallel with other tests.
+func CheckStreamInterceptor(t *testing.T, interceptor drpcclient.StreamClientInterceptor) {
+	opt := drpcclient.WithChainStreamInterceptor(interceptor)
+	open := func(ctx context.Context, cc *drpcclient.ClientConn) (drpc.Stream, error) {
+		return cc.NewStream(ctx, "/drpcclienttest/Stream", drpctest.StringEncoding{})
+	}
+
+	t.Run("once", func(t *testing.T) {
+		for _, fail := range []error{nil, errScripted} {
+			fail := fail
+			conn := &Conn{NewStreamFunc: func(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+				if fail != nil {
+					return nil, fail
+				}
+				return NewStream(ctx), nil
+			}}
+			cc := newClientConn(t, conn, opt)
+			within(t, "stream", func() {
+				if stream, err := open(context.Background(), cc); err == nil {
+					_ = stream.Close()
+				}
+			})
+			if calls := len(conn.Calls()); calls > 1 {
+				t.Fatalf("streamer called %d times when it returned %v", calls, fail)
+			}
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		seen := make(chan context.Context, 1)
+		conn := &Conn{NewStreamFunc: func(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+			select {
+			case seen <- ctx:
+			default:
+			}
+			return NewStream(ctx), nil
+		}}
+		checkContext(t, conn, opt, seen, func(ctx context.Context, cc *drpcclient.ClientConn) {
+			if stream, err := open(ctx, cc); err == nil {
+				_ = stream.MsgRecv(new(string), drpctest.StringEncoding{})
+			}
+		})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		conn := &Conn{NewStreamFunc: func(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+			return nil, errScripted
+		}}
+		cc := newClientConn(t, conn, opt)
+		var err error
+		within(t, "stream", func() { _, err = open(context.Background(), cc) })
+		if !errors.Is(err, errScripted) {
+			t.Fatalf("expected the streamer's error, got %v", err)
+		}
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		inner := newRecordingStream(context.Background(), "first", "second")
+		conn := &Conn{NewStreamFunc: func(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+			return inner, nil
+		}}
+		ctx := context.WithValue(context.Background(), contextKey{}, "value")
+		cc := newClientConn(t, conn, opt)
+		within(t, "stream", func() { exchange(t, ctx, cc, open) })
+
+		sent, closeSend, closed := inner.state()
+		if len(sent) != 2 || sent[0] != "a" || sent[1] != "b" {
+			t.Fatalf("expected the conn to receive [a b], got %q", sent)
+		}
+		if !closeSend {
+			t.Fatal("CloseSend did not reach the conn's stream")
+		}
+		if !closed {
+			t.Fatal("Close did not reach the conn's stream")
+		}
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		checkGoroutines(t, func(t *testing.T) {
+			cc := newClientConn(t, new(Conn), opt)
+			for _, sc := range scenarios {
+				if sc.ctx == nil {
+					continue
+				}
+				ctx, cancel := sc.ctx()
+				within(t, sc.name+" stream", func() {
+					if stream, err := open(ctx, cc); err == nil {
+						_ = stream.MsgRecv(new(string), drpctest.StringEncoding{})
+					}
+				})
+				cancel()
+			}
+			within(t, "stream", func() {
+				if stream, err := open(context.Background(), cc); err == nil {
+					_ = stream.Close()
+				}
+			})
+		})
+	})
+}
+
+// exchange opens a stream with open, sends and receives the messages the
+// wrap check expects on it, and closes it, failing the test at the first
+// step that does not behave like the conn's stream.
+func exchange(t *testing.T, ctx context.Context, cc *drpcclient.ClientConn,
+	open func(ctx context.Context, cc *drpcclient.ClientConn) (drpc.Stream, error)) {
+
+	stream, err := open(ctx, cc)
+	if err != nil {
+		t.Errorf("opening stream: %v", err)
+		return
+	}
+	if stream.Context() == nil {
+		t.Error("stream has a nil context")
+	}
+
+	for _, msg := range []string{"a", "b"} {
+		msg := msg
+		if err := stream.MsgSend(&msg, drpctest.StringEncoding{}); err != nil {
+			t.Errorf("sending %q: %v", msg, err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Errorf("closing send: %v", err)
+	}
+	for _, want := range []string{"first", "second"} {
+		var got string
+		if err := stream.MsgRecv(&got, drpctest.StringEncoding{}); err != nil {
+			t.Errorf("receiving %q: %v", want, err)
+		} else if got != want {
+			t.Errorf("expected to receive %q, got %q", want, got)
+		}
+	}
+	if err := stream.MsgRecv(new(string), drpctest.StringEncoding{}); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF after the last message, got %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("closing stream: %v", err)
+	}
+}
+
+// newClientConn returns a ClientConn over the conn with the option that is
+// closed when the test ends.
+func newClientConn(t *testing.T, conn *Conn, opt drpcclient.DialOption) *drpcclient.ClientConn {
+	cc, err := drpcclient.NewClientConnWithOptions(context.Background(), func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, opt)
+	if err != nil {
+		t.Fatalf("creating client conn: %v", err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return cc
+}
+
+// within runs fn and fails the test if it does not return in time. Since fn
+// runs on another goroutine, it must report failures with t.Error.
+func within(t *testing.T, what string, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(hangTimeout):
+		t.Fatalf("%s did not return within %v", what, hangTimeout)
+	}
+}
+
+// checkContext issues an rpc with call on a context carrying a value and
+// checks that the context the conn receives on seen carries the value and is
+// canceled along with the caller's.
+func checkContext(t *testing.T, conn *Conn, opt drpcclient.DialOption, seen <-chan context.Context,
+	call func(ctx context.Context, cc *drpcclient.ClientConn)) {
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "value"))
+	defer cancel()
+
+	cc := newClientConn(t, conn, opt)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		call(ctx, cc)
+	}()
+
+	var got context.Context
+	select {
+	case got = <-seen:
+	case <-done:
+		t.Skip("interceptor did not reach the conn")
+	case <-time.After(hangTimeout):
+		t.Fatalf("rpc did not reach the conn within %v", hangTimeout)
+	}
+
+	if value, _ := got.Value(contextKey{}).(string); value != "value" {
+		t.Fatalf("expected the caller's context value, got %q", value)
+	}
+
+	cancel()
+	select {
+	case <-got.Done():
+	case <-time.After(hangTimeout):
+		t.Fatalf("context was not canceled within %v of the caller's", hangTimeout)
+	}
+	select {
+	case <-done:
+	case <-time.After(hangTimeout):
+		t.Fatalf("rpc did not return within %v of being canceled", hangTimeout)
+	}
+}
+
+// checkGoroutines runs fn in a subtest and fails the test if there are more goroutines
+// than before once it returns and they have had time to exit.
+func checkGoroutines(t *testing.T, fn func(t *testing.T)) {
+	before := runtime.NumGoroutine()
+
+	// client conns close in a cleanup, so running fn as a subtest closes
+	// them before counting.
+	if !t.Run("run", fn) {
+		return
+	}
+
+	deadline := time.Now().Add(hangTimeout)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Fatalf("%d goroutines leaked:\n%s",
+				runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// recordingStream is a drpc.Stream that records what is sent on it and
+// receives a fixed sequence of messages followed by io.EOF.
+type recordingStream struct {
+	ctx    context.Context
+	cancel func()
+
+	mu        sync.Mutex
+	recv      []string
+	sent      []string
+	closeSend bool
+	closed    bool
+}
+
+func newRecordingStream(ctx context.Context, recv ...string) *recordingStream {
+	ctx, cancel := context.WithCancel(ctx)
+	return &recordingStream{ctx: ctx, cancel: cancel, recv: recv}
+}
+
+func (s *recordingStream) Context() context.Context { return s.ctx }
+
+func (s *recordingStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	buf, err := enc.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, string(buf))
+	return nil
+}
+
+func (s *recordingStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.recv) == 0 {
+		return io.EOF
+	}
+	next := s.recv[0]
+	s.recv = s.recv[1:]
+	return enc.Unmarshal([]byte(next), msg)
+}
+
+func (s *recordingStream) CloseSend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeSend = true
+	return nil
+}
+
+func (s *recordingStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.cancel()
+	return nil
+}
+
+func (s *recordingStream) state() (sent []string, closeSend, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.sent...), s.closeSend, s.closed
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclienttest
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// Conn is a drpc.Conn whose rpcs are answered by its functions. A nil
+// InvokeFunc answers every unary rpc successfully without touching the
+// response, and a nil NewStreamFunc opens streams with NewStream.
+type Conn struct {
+	InvokeFunc    func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error
+	NewStreamFunc func(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error)
+
+	mu     sync.Mutex
+	calls  []string
+	once   sync.Once
+	closed chan struct{}
+}
+
+// Calls returns the names of the rpcs issued on the conn, in order.
+func (c *Conn) Calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.calls...)
+}
+
+// Close closes the conn. It may be called more than once.
+func (c *Conn) Close() error {
+	c.once.Do(c.init)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	return nil
+}
+
+// Closed returns a channel that is closed once the conn is closed.
+func (c *Conn) Closed() <-chan struct{} {
+	c.once.Do(c.init)
+	return c.closed
+}
+
+func (c *Conn) init() { c.closed = make(chan struct{}) }
+
+// Invoke records the rpc and answers it with InvokeFunc.
+func (c *Conn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	c.record(rpc)
+	if c.InvokeFunc == nil {
+		return nil
+	}
+	return c.InvokeFunc(ctx, rpc, enc, in, out)
+}
+
+// NewStream records the rpc and answers it with NewStreamFunc.
+func (c *Conn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	c.record(rpc)
+	if c.NewStreamFunc == nil {
+		return NewStream(ctx), nil
+	}
+	return c.NewStreamFunc(ctx, rpc, enc)
+}
+
+func (c *Conn) record(rpc string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls = append(c.calls, rpc)
+}
+
+// NewStream returns a drpc.Stream that accepts every message sent on it, never
+// receives one, and whose context is canceled when it is closed.
+func NewStream(ctx context.Context) drpc.Stream {
+	ctx, cancel := context.WithCancel(ctx)
+	return &stream{ctx: ctx, cancel: cancel}
+}
+
+type stream struct {
+	ctx    context.Context
+	cancel func()
+}
+
+func (s *stream) Context() context.Context                  { return s.ctx }
+func (s *stream) MsgSend(drpc.Message, drpc.Encoding) error { return s.ctx.Err() }
+func (s *stream) CloseSend() error                          { return nil }
+func (s *stream) Close() error                              { s.cancel(); return nil }
+
+func (s *stream) MsgRecv(drpc.Message, drpc.Encoding) error {
+	<-s.ctx.Done()
+	return s.ctx.Err()
+}
@@ -0,0 +1,13 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcclienttest helps test drpcclient interceptors. Conn is a
+// scripted drpc.Conn, and RunInterceptorTests checks every interceptor added
+// by a set of DialOptions against success, error, panic, cancellation and
+// deadline scenarios, so that teams writing custom middleware get broad
+// coverage with a single call. CheckUnaryInterceptor and
+// CheckStreamInterceptor go further and verify the contract an interceptor
+// must keep, so middleware authors can certify their interceptors. PipeDialer
+// and NewPipeClientConn connect a ClientConn to a drpcserver over net.Pipe, for
+// tests of middleware with a client and a server half.
+package drpcclienttest
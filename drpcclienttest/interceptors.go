@@ -0,0 +1,225 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclienttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+// hangTimeout is how long a scenario may run before it is considered hung.
+const hangTimeout = 5 * time.Second
+
+// scriptedCode is the error code of the error returned by the conn in the
+// error scenario.
+const scriptedCode = drpcerr.Internal
+
+// errScripted is the error returned by the conn in the error scenario.
+var errScripted = drpcerr.WithCode(errors.New("scripted failure"), scriptedCode)
+
+// RunInterceptorTests runs subtests for every interceptor added by the
+// options. Each interceptor is installed alone on a ClientConn over a Conn,
+// and the rpcs it issues use *string messages with an encoding that copies
+// them. The subtests check that the interceptor
+//
+//   - returns the response when the conn succeeds,
+//   - returns an error with the same drpcerr code when the conn fails,
+//   - either returns an error or panics when the conn panics,
+//   - returns context.Canceled when the rpc is canceled, and
+//   - returns context.DeadlineExceeded when the deadline passes,
+//
+// and that it never hangs. The subtests are named after the kind and index of
+// the interceptor and the scenario, such as "unary[0]/canceled".
+func RunInterceptorTests(t *testing.T, opts ...drpcclient.DialOption) {
+	unary, stream := drpcclient.Interceptors(opts...)
+	for i, interceptor := range unary {
+		interceptor := interceptor
+		t.Run(fmt.Sprintf("unary[%d]", i), func(t *testing.T) {
+			runUnary(t, drpcclient.WithChainUnaryInterceptor(interceptor))
+		})
+	}
+	for i, interceptor := range stream {
+		interceptor := interceptor
+		t.Run(fmt.Sprintf("stream[%d]", i), func(t *testing.T) {
+			runStream(t, drpcclient.WithChainStreamInterceptor(interceptor))
+		})
+	}
+}
+
+// scenario is the behavior of the conn and the context of the rpc for one
+// subtest.
+type scenario struct {
+	name  string
+	ctx   func() (context.Context, func())
+	fail  func(ctx context.Context) error
+	check func(t *testing.T, err error, panicked interface{})
+}
+
+// scenarios are the scenarios run against every interceptor.
+var scenarios = []scenario{
+	{
+		name: "success",
+		fail: func(ctx context.Context) error { return nil },
+		check: func(t *testing.T, err error, panicked interface{}) {
+			if panicked != nil || err != nil {
+				t.Fatalf("expected success, got error %v and panic %v", err, panicked)
+			}
+		},
+	},
+	{
+		name: "error",
+		fail: func(ctx context.Context) error { return errScripted },
+		check: func(t *testing.T, err error, panicked interface{}) {
+			if panicked != nil || err == nil {
+				t.Fatalf("expected the error, got error %v and panic %v", err, panicked)
+			}
+			if code := drpcerr.Code(err); code != scriptedCode {
+				t.Fatalf("expected code %d, got %d from %v", scriptedCode, code, err)
+			}
+		},
+	},
+	{
+		name: "panic",
+		fail: func(ctx context.Context) error { panic("scripted panic") },
+		check: func(t *testing.T, err error, panicked interface{}) {
+			if panicked == nil && err == nil {
+				t.Fatal("expected an error or panic, got success")
+			}
+		},
+	},
+	{
+		name: "canceled",
+		ctx: func() (context.Context, func()) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		},
+		fail: blockUntilDone,
+		check: func(t *testing.T, err error, panicked interface{}) {
+			if panicked != nil || !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got error %v and panic %v", err, panicked)
+			}
+		},
+	},
+	{
+		name: "deadline",
+		ctx: func() (context.Context, func()) {
+			return context.WithTimeout(context.Background(), 10*time.Millisecond)
+		},
+		fail: blockUntilDone,
+		check: func(t *testing.T, err error, panicked interface{}) {
+			if panicked != nil || !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected context.DeadlineExceeded, got error %v and panic %v", err, panicked)
+			}
+		},
+	},
+}
+
+// blockUntilDone waits for the context like a conn waiting on a slow server.
+func blockUntilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func runUnary(t *testing.T, opt drpcclient.DialOption) {
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			conn := &Conn{InvokeFunc: func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+				if err := sc.fail(ctx); err != nil {
+					return err
+				}
+				*out.(*string) = "response"
+				return nil
+			}}
+
+			var out string
+			err, panicked := run(t, sc, conn, opt, func(ctx context.Context, cc *drpcclient.ClientConn) error {
+				in := "request"
+				return cc.Invoke(ctx, "/drpcclienttest/Unary", drpctest.StringEncoding{}, &in, &out)
+			})
+			sc.check(t, err, panicked)
+			if sc.name == "success" && out != "response" {
+				t.Fatalf("expected the response, got %q", out)
+			}
+		})
+	}
+}
+
+func runStream(t *testing.T, opt drpcclient.DialOption) {
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			conn := &Conn{NewStreamFunc: func(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+				if err := sc.fail(ctx); err != nil {
+					return nil, err
+				}
+				return NewStream(ctx), nil
+			}}
+
+			err, panicked := run(t, sc, conn, opt, func(ctx context.Context, cc *drpcclient.ClientConn) error {
+				stream, err := cc.NewStream(ctx, "/drpcclienttest/Stream", drpctest.StringEncoding{})
+				if err != nil {
+					return err
+				}
+				if stream == nil {
+					return errors.New("nil stream without an error")
+				}
+				return stream.Close()
+			})
+			sc.check(t, err, panicked)
+		})
+	}
+}
+
+// run issues the rpc on a ClientConn over the conn with the scenario's
+// context, returning its error or what it panicked with, and fails the test
+// if it hangs.
+func run(t *testing.T, sc scenario, conn *Conn, opt drpcclient.DialOption,
+	call func(ctx context.Context, cc *drpcclient.ClientConn) error) (err error, panicked interface{}) {
+
+	ctx, cancel := context.Background(), func() {}
+	if sc.ctx != nil {
+		ctx, cancel = sc.ctx()
+	}
+	defer cancel()
+
+	cc, err := drpcclient.NewClientConnWithOptions(context.Background(), func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, opt)
+	if err != nil {
+		t.Fatalf("creating client conn: %v", err)
+	}
+	defer func() { _ = cc.Close() }()
+
+	type result struct {
+		err      error
+		panicked interface{}
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		defer func() {
+			res.panicked = recover()
+			done <- res
+		}()
+		res.err = call(ctx, cc)
+	}()
+
+	select {
+	case res := <-done:
+		return res.err, res.panicked
+	case <-time.After(hangTimeout):
+		t.Fatalf("%s: rpc did not return within %v", sc.name, hangTimeout)
+		return nil, nil
+	}
+}
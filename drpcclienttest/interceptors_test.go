@@ -0,0 +1,75 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclienttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcbaggage"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcidempotency"
+	"storj.io/drpc/drpcpriority"
+)
+
+func TestRunInterceptorTests(t *testing.T) {
+	limiter := drpcpriority.NewLimiter(4)
+
+	RunInterceptorTests(t,
+		drpcclient.WithChainUnaryInterceptor(
+			func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+				return next(ctx, rpc, enc, in, out, cc)
+			},
+			drpcbaggage.UnaryClientInterceptor,
+			drpcidempotency.UnaryClientInterceptor,
+			limiter.UnaryClientInterceptor(),
+		),
+		drpcclient.WithChainStreamInterceptor(
+			drpcpriority.StreamClientInterceptor,
+			limiter.StreamClientInterceptor(),
+		),
+	)
+
+	// streams release their slot asynchronously once their context is done.
+	for limiter.InFlight() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConn(t *testing.T) {
+	conn := new(Conn)
+	in, out := "in", ""
+	assert.NoError(t, conn.Invoke(context.Background(), "a", nil, &in, &out))
+
+	stream, err := conn.NewStream(context.Background(), "b", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, stream.MsgRecv(&out, nil), context.Canceled)
+
+	assert.DeepEqual(t, conn.Calls(), []string{"a", "b"})
+
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, conn.Close())
+	<-conn.Closed()
+}
+
+func TestCheckInterceptors(t *testing.T) {
+	CheckUnaryInterceptor(t, drpcbaggage.UnaryClientInterceptor)
+	CheckStreamInterceptor(t, drpcpriority.StreamClientInterceptor)
+
+	// a stream interceptor that wraps the stream it returns.
+	CheckStreamInterceptor(t, func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, streamer drpcclient.Streamer) (drpc.Stream, error) {
+		stream, err := streamer(ctx, rpc, enc, cc)
+		if err != nil {
+			return nil, err
+		}
+		return wrappedStream{stream}, nil
+	})
+}
+
+type wrappedStream struct{ drpc.Stream }
@@ -0,0 +1,64 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclienttest
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+)
+
+// PipeDialer dials conns to a drpcserver.Server serving a handler, each over
+// its own net.Pipe, so that client and server middleware can be tested
+// together without a network. The server side of every conn runs on the
+// Tracker.
+type PipeDialer struct {
+	tracker *drpctest.Tracker
+	server  *drpcserver.Server
+
+	mu    sync.Mutex
+	conns []drpc.Conn
+}
+
+// NewPipeDialer returns a PipeDialer for the handler.
+func NewPipeDialer(tracker *drpctest.Tracker, handler drpc.Handler) *PipeDialer {
+	return &PipeDialer{
+		tracker: tracker,
+		server:  drpcserver.New(handler),
+	}
+}
+
+// Dial returns a conn to the server over a new net.Pipe. It has the signature
+// of a drpcclient.DialerFunc.
+func (d *PipeDialer) Dial(ctx context.Context) (drpc.Conn, error) {
+	pc, ps := net.Pipe()
+	d.tracker.Run(func(ctx context.Context) { _ = d.server.ServeOne(ctx, ps) })
+	conn := drpcconn.New(pc)
+
+	d.mu.Lock()
+	d.conns = append(d.conns, conn)
+	d.mu.Unlock()
+
+	return conn, nil
+}
+
+// Conns returns the conns dialed so far, in order.
+func (d *PipeDialer) Conns() []drpc.Conn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]drpc.Conn(nil), d.conns...)
+}
+
+// NewPipeClientConn returns a ClientConn with the options whose conns are
+// dialed by a PipeDialer for the handler.
+func NewPipeClientConn(tracker *drpctest.Tracker, handler drpc.Handler, opts ...drpcclient.DialOption) (*drpcclient.ClientConn, error) {
+	return drpcclient.NewClientConnWithOptions(tracker, NewPipeDialer(tracker, handler).Dial, opts...)
+}
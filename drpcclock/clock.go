@@ -0,0 +1,85 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclock
+
+import "time"
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer returns a Timer that sends the time on its channel once d
+	// has passed.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker returns a Ticker that sends the time on its channel every
+	// d, dropping ticks for slow receivers.
+	NewTicker(d time.Duration) Ticker
+
+	// AfterFunc returns a Timer that calls f once d has passed. Its channel
+	// is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event, like a *time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, returning false if it already
+	// fired or was stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after d, returning false if it
+	// already fired or was stopped.
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a periodic event, like a *time.Ticker.
+type Ticker interface {
+	// C returns the channel the ticks are sent on.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// System is the Clock of the time package.
+var System Clock = systemClock{}
+
+// Or returns the clock, or System if it is nil.
+func Or(clock Clock) Clock {
+	if clock == nil {
+		return System
+	}
+	return clock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time        { return t.t.C }
+func (t systemTimer) Stop() bool                 { return t.t.Stop() }
+func (t systemTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
@@ -0,0 +1,7 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcclock provides a clock interface for time-based behavior, such
+// as backoffs, idle timers, keepalives and expirations, and a fake clock that
+// tests advance by hand.
+package drpcclock
@@ -0,0 +1,189 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when it is advanced, so that tests of
+// time-based behavior run instantly and deterministically. It is safe for
+// concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{} // closed when timers are added
+}
+
+// NewFake returns a Fake clock starting at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the current time of the clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// NewTimer returns a Timer that fires once the clock is advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0, nil)
+}
+
+// NewTicker returns a Ticker that ticks every time the clock is advanced by d.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("drpcclock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.add(d, d, nil)}
+}
+
+// AfterFunc returns a Timer that calls f from Advance once the clock is
+// advanced by d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, 0, fn)
+}
+
+// Timers returns the number of timers and tickers waiting to fire.
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.timers)
+}
+
+// WaitTimers blocks until at least n timers and tickers are waiting to fire,
+// so that tests can advance the clock once the code under test armed its
+// timers in another goroutine.
+func (f *Fake) WaitTimers(n int) {
+	for {
+		f.mu.Lock()
+		count, changed := len(f.timers), f.changed
+		f.mu.Unlock()
+
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// Advance moves the clock forward by d, firing the timers that come due in
+// order of their times. Functions of AfterFunc timers are called before
+// Advance returns.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		t := f.nextLocked(end)
+		if t == nil {
+			break
+		}
+		f.now = t.when
+		f.removeLocked(t)
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+			f.insertLocked(t)
+		}
+
+		if t.fn != nil {
+			f.mu.Unlock()
+			t.fn()
+			f.mu.Lock()
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+// nextLocked returns the earliest timer due by end, if any. It must be called
+// with f.mu held.
+func (f *Fake) nextLocked(end time.Time) *fakeTimer {
+	if len(f.timers) == 0 || f.timers[0].when.After(end) {
+		return nil
+	}
+	return f.timers[0]
+}
+
+func (f *Fake) add(d, period time.Duration, fn func()) *fakeTimer {
+	t := &fakeTimer{f: f, period: period, fn: fn}
+	if fn == nil {
+		t.c = make(chan time.Time, 1)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t.when = f.now.Add(d)
+	f.insertLocked(t)
+	return t
+}
+
+// insertLocked adds the timer after the timers due at or before it. It must
+// be called with f.mu held.
+func (f *Fake) insertLocked(t *fakeTimer) {
+	i := sort.Search(len(f.timers), func(i int) bool { return f.timers[i].when.After(t.when) })
+	f.timers = append(f.timers, nil)
+	copy(f.timers[i+1:], f.timers[i:])
+	f.timers[i] = t
+
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// removeLocked removes the timer, returning false if it was not waiting. It
+// must be called with f.mu held.
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer is a Timer or Ticker of a Fake clock.
+type fakeTimer struct {
+	f      *Fake
+	c      chan time.Time
+	fn     func()
+	period time.Duration
+	when   time.Time // guarded by f.mu
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	return t.f.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	active := t.f.removeLocked(t)
+	t.when = t.f.now.Add(d)
+	t.f.insertLocked(t)
+	return active
+}
+
+// fakeTicker is a Ticker of a Fake clock.
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
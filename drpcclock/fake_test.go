@@ -0,0 +1,67 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcclock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewFake(start)
+
+	var fired []string
+	timer := clock.NewTimer(2 * time.Second)
+	ticker := clock.NewTicker(time.Second)
+	clock.AfterFunc(1500*time.Millisecond, func() {
+		fired = append(fired, "func at "+clock.Now().Sub(start).String())
+	})
+	stopped := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+	assert.Equal(t, clock.Timers(), 3)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, (<-ticker.C()).Sub(start), time.Second)
+	assert.Equal(t, len(timer.C()), 0)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, (<-timer.C()).Sub(start), 2*time.Second)
+	assert.Equal(t, (<-ticker.C()).Sub(start), 2*time.Second)
+	assert.Equal(t, fired, []string{"func at 1.5s"})
+	assert.Equal(t, clock.Now().Sub(start), 2*time.Second)
+
+	// fired timers can be reset, and unread ticks are dropped.
+	assert.False(t, timer.Reset(time.Second))
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, (<-timer.C()).Sub(start), 3*time.Second)
+	assert.Equal(t, len(ticker.C()), 1)
+
+	ticker.Stop()
+	assert.Equal(t, clock.Timers(), 0)
+}
+
+func TestFakeWaitTimers(t *testing.T) {
+	clock := NewFake(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-clock.NewTimer(time.Minute).C()
+	}()
+
+	clock.WaitTimers(1)
+	clock.Advance(time.Minute)
+	<-done
+}
+
+func TestOr(t *testing.T) {
+	assert.Equal(t, Or(nil), System)
+
+	clock := NewFake(time.Now())
+	assert.Equal(t, Or(clock), Clock(clock))
+}
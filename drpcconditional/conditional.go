@@ -0,0 +1,144 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcconditional
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcconditional")
+
+// Any is the expected version that matches every current version, for calls
+// that require the resource to exist without caring which version it is at.
+const Any = "*"
+
+// currentPrefix precedes the quoted current version in the message of
+// mismatches.
+const currentPrefix = "current version "
+
+// ifMatchKey is the context key for the expected version of a call.
+type ifMatchKey struct{}
+
+// WithIfMatch returns a context whose rpcs expect the resource to be at the
+// version.
+func WithIfMatch(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, version)
+}
+
+// IfMatch returns the expected version set on the context by WithIfMatch.
+func IfMatch(ctx context.Context) (string, bool) {
+	version, ok := ctx.Value(ifMatchKey{}).(string)
+	return version, ok
+}
+
+// UnaryClientInterceptor sends the expected version of the context, if any, in
+// the drpcmetadata.IfMatch key.
+func UnaryClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+	return next(attach(ctx, cc), rpc, enc, in, out, cc)
+}
+
+// StreamClientInterceptor sends the expected version of the context, if any,
+// in the drpcmetadata.IfMatch key.
+func StreamClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+	return next(attach(ctx, cc), rpc, enc, cc)
+}
+
+// attach adds the expected version of the context to its outgoing metadata.
+func attach(ctx context.Context, cc *drpcclient.ClientConn) context.Context {
+	if version, ok := IfMatch(ctx); ok {
+		return cc.AddMetadata(ctx, drpcmetadata.IfMatch, version)
+	}
+	return ctx
+}
+
+// Expected returns the version the client of the rpc with the context expects,
+// if it sent one.
+func Expected(ctx context.Context) (string, bool) {
+	return drpcmetadata.Lookup(ctx, drpcmetadata.IfMatch)
+}
+
+// Check returns nil if the client of the rpc with the context expects the
+// current version, expects Any version of an existing resource, or sent no
+// expectation. Otherwise it returns the error of Mismatch. An empty current
+// version means the resource does not exist.
+func Check(ctx context.Context, current string) error {
+	expected, ok := Expected(ctx)
+	if !ok || expected == current || (expected == Any && current != "") {
+		return nil
+	}
+	return Mismatch(current)
+}
+
+// Mismatch returns an error with the gRPC FAILED_PRECONDITION code whose
+// message carries the current version, for handlers that check expectations
+// themselves.
+func Mismatch(current string) error {
+	return drpcerr.WithCode(Error.New("precondition failed, %s%s", currentPrefix, strconv.Quote(current)), drpcerr.FailedPrecondition)
+}
+
+// CurrentVersion returns the current version carried by the error of an rpc
+// rejected by Check or with Mismatch. It works on the errors returned to
+// clients, which only keep the message and code of the error.
+func CurrentVersion(err error) (string, bool) {
+	if !drpcerr.HasCode(err, drpcerr.FailedPrecondition) {
+		return "", false
+	}
+	msg := err.Error()
+	i := strings.Index(msg, currentPrefix)
+	if i < 0 {
+		return "", false
+	}
+	quoted, err := strconv.QuotedPrefix(msg[i+len(currentPrefix):])
+	if err != nil {
+		return "", false
+	}
+	current, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", false
+	}
+	return current, true
+}
+
+// VersionFunc returns the current version of the resource an rpc with the
+// context operates on, or the empty string if it does not exist.
+type VersionFunc func(ctx context.Context, rpc string) (string, error)
+
+// NewHandler returns a drpc.Handler that rejects rpcs whose expected version
+// does not match the one returned by versions, as Check does, before passing
+// them to the handler. Rpcs without an expectation are passed to the handler
+// without calling versions. The check is only advisory if the version can
+// change before the handler runs, so handlers that update the resource should
+// also Check the version atomically with the update.
+func NewHandler(handler drpc.Handler, versions VersionFunc) drpc.Handler {
+	return conditionalHandler{handler: handler, versions: versions}
+}
+
+type conditionalHandler struct {
+	handler  drpc.Handler
+	versions VersionFunc
+}
+
+func (h conditionalHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	ctx := stream.Context()
+	if _, ok := Expected(ctx); ok {
+		current, err := h.versions(ctx, rpc)
+		if err != nil {
+			return err
+		}
+		if err := Check(ctx, current); err != nil {
+			return err
+		}
+	}
+	return h.handler.HandleRPC(stream, rpc)
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcconditional
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+func TestConditionalCalls(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	// the resource is a counter whose version is its value.
+	counter := 1
+	version := func(ctx context.Context, rpc string) (string, error) {
+		return strconv.Itoa(counter), nil
+	}
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		counter++
+		return strconv.Itoa(counter), nil
+	}), version), drpcclient.WithChainUnaryInterceptor(UnaryClientInterceptor))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	// calls expecting the current version succeed
+	in, out := "incr", ""
+	assert.NoError(t, cc.Invoke(WithIfMatch(ctx, "1"), "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "2")
+
+	// stale expectations fail with the current version
+	err = cc.Invoke(WithIfMatch(ctx, "1"), "rpc", drpctest.StringEncoding{}, &in, &out)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.FailedPrecondition)
+	current, ok := CurrentVersion(err)
+	assert.That(t, ok)
+	assert.Equal(t, current, "2")
+
+	// calls without an expectation and with Any always succeed
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.NoError(t, cc.Invoke(WithIfMatch(ctx, Any), "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, counter, 4)
+}
+
+func TestCurrentVersion(t *testing.T) {
+	current, ok := CurrentVersion(Mismatch(`v"3 current version 4`))
+	assert.That(t, ok)
+	assert.Equal(t, current, `v"3 current version 4`)
+
+	_, ok = CurrentVersion(Error.New("current version \"1\""))
+	assert.False(t, ok)
+}
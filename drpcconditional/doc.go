@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcconditional standardizes conditional requests for optimistic
+// concurrency. Clients send the version of a resource they expect in the
+// drpcmetadata.IfMatch key, and servers reject calls whose expectation does
+// not match the current version with the gRPC FAILED_PRECONDITION code and
+// the current version, so that clients can refresh and try again.
+package drpcconditional
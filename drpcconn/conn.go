@@ -27,14 +27,19 @@ type Options struct {
 	// CollectStats controls whether the server should collect stats on the
 	// rpcs it creates.
 	CollectStats bool
+
+	// BufferPool provides the scratch buffers that Invoke marshals requests
+	// into. Its MaxSize caps the size of the buffers kept for reuse. If nil, a
+	// pool shared by the whole process is used.
+	BufferPool *drpcenc.BufferPool
 }
 
 // Conn is a drpc client connection.
 type Conn struct {
 	tr   drpc.Transport
 	man  *drpcmanager.Manager
+	pool *drpcenc.BufferPool
 	mu   sync.Mutex
-	wbuf []byte
 
 	stats map[string]*drpcstats.Stats
 }
@@ -48,7 +53,8 @@ func New(tr drpc.Transport) *Conn { return NewWithOptions(tr, Options{}) }
 // The Options control details of how the conn operates.
 func NewWithOptions(tr drpc.Transport, opts Options) *Conn {
 	c := &Conn{
-		tr: tr,
+		tr:   tr,
+		pool: opts.BufferPool,
 	}
 
 	if opts.CollectStats {
@@ -97,11 +103,17 @@ func (c *Conn) Closed() <-chan struct{} { return c.man.Closed() }
 // be called concurrently with Invoke or NewStream.
 func (c *Conn) Unblocked() <-chan struct{} { return c.man.Unblocked() }
 
+// LimitFrameSize limits the frames written by rpcs started after it is called
+// to at most n bytes of data. A non-positive n removes the limit.
+func (c *Conn) LimitFrameSize(n int) { c.man.LimitFrameSize(n) }
+
 // Close closes the connection.
 func (c *Conn) Close() (err error) { return c.man.Close() }
 
 // Invoke issues the rpc on the transport serializing in, waits for a response, and
-// deserializes it into out. Only one Invoke or Stream may be open at a time.
+// deserializes it into out. Only one Invoke or Stream may be open at a time. If
+// the context records the response metadata with drpcmetadata.WithResponse,
+// Invoke also waits for the end of the rpc so that the trailer is complete.
 func (c *Conn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) (err error) {
 	var metadata []byte
 	if md, ok := drpcmetadata.Get(ctx); ok {
@@ -117,21 +129,25 @@ func (c *Conn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, ou
 	}
 	defer func() { err = errs.Combine(err, stream.Close()) }()
 
-	// we have to protect c.wbuf here even though the manager only allows one
-	// stream at a time because the stream may async close allowing another
-	// concurrent call to Invoke to proceed.
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.wbuf, err = drpcenc.MarshalAppend(in, enc, c.wbuf[:0])
+	// the buffer comes from a pool rather than being owned by the conn because
+	// the stream may async close allowing another concurrent call to Invoke to
+	// proceed. the stream copies the data when writing it.
+	buf, err := c.pool.Marshal(in, enc)
 	if err != nil {
 		return err
 	}
+	defer c.pool.Put(buf)
 
-	if err := c.doInvoke(stream, enc, rpc, c.wbuf, metadata, out); err != nil {
-		return err
+	err = c.doInvoke(stream, enc, rpc, *buf, metadata, out)
+	if resp, ok := drpcmetadata.ResponseFrom(ctx); ok {
+		if err == nil {
+			// the server sends the trailer right before it ends the rpc,
+			// which it does right after the response, so wait for the end.
+			_, _ = stream.RawRecv()
+		}
+		resp.Header, resp.Trailer = stream.Header(), stream.Trailer()
 	}
-	return nil
+	return err
 }
 
 func (c *Conn) doInvoke(stream *drpcstream.Stream, enc drpc.Encoding, rpc string, data []byte, metadata []byte, out drpc.Message) (err error) {
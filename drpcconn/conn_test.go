@@ -12,6 +12,7 @@ import (
 	"github.com/zeebo/assert"
 
 	"storj.io/drpc"
+	"storj.io/drpc/drpcmetadata"
 	"storj.io/drpc/drpctest"
 	"storj.io/drpc/drpcwire"
 )
@@ -84,3 +85,45 @@ func TestConn_InvokeFlushesSendClose(t *testing.T) {
 		t.Fatal("took too long for conn to be closed")
 	}
 }
+
+func TestConn_InvokeRecordsResponse(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	pc, ps := net.Pipe()
+	defer func() { assert.NoError(t, pc.Close()) }()
+	defer func() { assert.NoError(t, ps.Close()) }()
+
+	ctx.Run(func(ctx context.Context) {
+		wr := drpcwire.NewWriter(ps, 64)
+		rd := drpcwire.NewReader(ps)
+
+		_, _ = rd.ReadPacket()    // Invoke
+		_, _ = rd.ReadPacket()    // Message
+		pkt, _ := rd.ReadPacket() // CloseSend
+
+		header, _ := drpcmetadata.Encode(nil, map[string]string{"h": "1"})
+		trailer, _ := drpcmetadata.Encode(nil, map[string]string{"t": "2"})
+		for i, p := range []drpcwire.Packet{
+			{Kind: drpcwire.KindHeader, Data: header, Control: true},
+			{Kind: drpcwire.KindMessage, Data: []byte("qux")},
+			{Kind: drpcwire.KindTrailer, Data: trailer, Control: true},
+			{Kind: drpcwire.KindCloseSend},
+		} {
+			p.ID = drpcwire.ID{Stream: pkt.ID.Stream, Message: uint64(i + 1)}
+			_ = wr.WritePacket(p)
+		}
+		_ = wr.Flush()
+
+		_, _ = rd.ReadPacket() // Close
+	})
+
+	conn := New(pc)
+
+	var resp drpcmetadata.Response
+	in, out := "baz", ""
+	assert.NoError(t, conn.Invoke(drpcmetadata.WithResponse(ctx, &resp), "/com.example.Foo/Bar", testEncoding{}, &in, &out))
+	assert.Equal(t, out, "qux")
+	assert.DeepEqual(t, resp.Header, map[string]string{"h": "1"})
+	assert.DeepEqual(t, resp.Trailer, map[string]string{"t": "2"})
+}
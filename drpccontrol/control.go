@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpccontrol
+
+import (
+	"context"
+	"encoding/json"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+)
+
+// RPC is the name of the long-polling rpc served by control planes.
+const RPC = "/drpc.Control/Watch"
+
+// Request asks the control plane for the configuration of a target.
+type Request struct {
+	// Target names the service the configuration is for.
+	Target string `json:"target"`
+
+	// Version is the version of the configuration the client already has. The
+	// control plane responds once it has a different version.
+	Version string `json:"version,omitempty"`
+}
+
+// Config is the configuration the control plane has for a target.
+type Config struct {
+	// Version identifies this configuration.
+	Version string `json:"version"`
+
+	// Endpoints are the addresses of the backends for the target. They are
+	// applied by EndpointsUpdater.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// ServiceConfig is a JSON service config document as accepted by
+	// drpcclient.ParseServiceConfig.
+	ServiceConfig json.RawMessage `json:"serviceConfig,omitempty"`
+}
+
+// Encoding is the drpc.Encoding used for the control plane messages. It
+// encodes them as JSON.
+type Encoding struct{}
+
+// Marshal encodes msg as JSON.
+func (Encoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// Unmarshal decodes the JSON in buf into msg.
+func (Encoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	return json.Unmarshal(buf, msg)
+}
+
+// ServiceConfigUpdater returns a callback for Watcher that applies the service
+// config of every Config to the ClientConn. Configs without a service config
+// leave the current one in place.
+func ServiceConfigUpdater(cc *drpcclient.ClientConn) func(context.Context, Config) error {
+	return func(ctx context.Context, cfg Config) error {
+		if len(cfg.ServiceConfig) == 0 {
+			return nil
+		}
+		sc, err := drpcclient.ParseServiceConfig(cfg.ServiceConfig)
+		if err != nil {
+			return err
+		}
+		return cc.UpdateOptions(ctx, drpcclient.WithServiceConfig(sc))
+	}
+}
+
+// BalancerUpdater returns a callback for Watcher that applies the service
+// config of every Config to the Balancer with SetServiceConfig, routing the
+// rpcs of every MethodConfig with a BackendGroup to that group and replacing
+// the Picker when the LoadBalancingPolicy changes. Configs without a service
+// config leave the current routes and Picker in place.
+func BalancerUpdater(b *drpcclient.Balancer) func(context.Context, Config) error {
+	return func(ctx context.Context, cfg Config) error {
+		if len(cfg.ServiceConfig) == 0 {
+			return nil
+		}
+		sc, err := drpcclient.ParseServiceConfig(cfg.ServiceConfig)
+		if err != nil {
+			return err
+		}
+		return b.SetServiceConfig(sc)
+	}
+}
+
+// EndpointsUpdater returns a callback for Watcher that pushes the endpoints of
+// every Config to the PushResolver, so that the ResolvedBalancers using it
+// dial the backends the control plane lists. Configs without endpoints leave
+// the current addresses in place.
+func EndpointsUpdater(r *drpcclient.PushResolver) func(context.Context, Config) error {
+	return func(ctx context.Context, cfg Config) error {
+		if len(cfg.Endpoints) == 0 {
+			return nil
+		}
+		r.Update(cfg.Endpoints...)
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpccontrol
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+)
+
+func TestWatcherAppliesServiceConfig(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	srv := NewServer()
+	mux := drpcmux.New()
+	assert.NoError(t, srv.Register(mux))
+
+	pc, ps := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = drpcserver.New(mux).ServeOne(ctx, ps) })
+
+	cc, err := drpcclient.NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return drpcconn.New(pc), nil
+	})
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	srv.Set("kv", Config{
+		Version:       "1",
+		ServiceConfig: []byte(`{"loadBalancingPolicy": "round_robin"}`),
+	})
+
+	updated := make(chan Config, 1)
+	apply := ServiceConfigUpdater(cc)
+	w := &Watcher{
+		Conn:   cc,
+		Target: "kv",
+		OnUpdate: func(ctx context.Context, cfg Config) error {
+			err := apply(ctx, cfg)
+			updated <- cfg
+			return err
+		},
+	}
+	ctx.Run(func(ctx context.Context) { _ = w.Run(ctx) })
+
+	select {
+	case cfg := <-updated:
+		assert.Equal(t, cfg.Version, "1")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update received")
+	}
+	assert.Equal(t, cc.ServiceConfig().LoadBalancingPolicy, "round_robin")
+}
+
+func TestWatcherBacksOff(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	clock := drpcclock.NewFake(time.Now())
+	conn := &countConn{err: drpc.Error.New("unavailable")}
+	errs := make(chan error, 10)
+	w := &Watcher{
+		Conn:     conn,
+		Target:   "kv",
+		OnUpdate: func(context.Context, Config) error { return nil },
+		OnError:  func(err error) { errs <- err },
+		Backoff:  time.Minute,
+		Clock:    clock,
+	}
+	ctx.Run(func(ctx context.Context) { _ = w.Run(ctx) })
+
+	assert.Error(t, <-errs)
+	clock.WaitTimers(1)
+	assert.Equal(t, atomic.LoadInt32(&conn.calls), int32(1))
+
+	// the control plane is polled again only once the backoff passed.
+	clock.Advance(time.Minute - time.Second)
+	assert.Equal(t, atomic.LoadInt32(&conn.calls), int32(1))
+	clock.Advance(time.Second)
+	assert.Error(t, <-errs)
+	assert.Equal(t, atomic.LoadInt32(&conn.calls), int32(2))
+}
+
+func TestBalancerUpdater(t *testing.T) {
+	ctx := context.Background()
+	a, b := &countConn{}, &countConn{}
+	bal := drpcclient.NewBalancer(firstPicker{},
+		drpcclient.Backend{Addr: "a", Conn: a},
+		drpcclient.Backend{Addr: "b", Conn: b})
+	conn, err := bal.Dialer()(ctx)
+	assert.NoError(t, err)
+	update := BalancerUpdater(bal)
+
+	assert.NoError(t, update(ctx, Config{Version: "1", ServiceConfig: []byte(`{
+		"loadBalancingPolicy": "round_robin",
+		"methodConfig": [{"name": [{"service": "analytics.Analytics"}], "backendGroup": "analytics"}]
+	}`)}))
+	assert.DeepEqual(t, bal.Routes(), []drpcclient.Route{{Prefix: "/analytics.Analytics/", Group: "analytics"}})
+
+	// the round robin picker replaced the one always picking the first backend.
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, conn.Invoke(ctx, "/kv.KV/Get", Encoding{}, nil, nil))
+	}
+	assert.Equal(t, atomic.LoadInt32(&a.calls), int32(2))
+	assert.Equal(t, atomic.LoadInt32(&b.calls), int32(2))
+
+	// configs without a service config keep the routes, and unknown policies
+	// are rejected.
+	assert.NoError(t, update(ctx, Config{Version: "2"}))
+	assert.Equal(t, len(bal.Routes()), 1)
+	assert.Error(t, update(ctx, Config{Version: "3", ServiceConfig: []byte(`{"loadBalancingPolicy": "random"}`)}))
+}
+
+func TestEndpointsUpdater(t *testing.T) {
+	ctx := context.Background()
+	r := drpcclient.NewPushResolver("a:1")
+	update := EndpointsUpdater(r)
+
+	assert.NoError(t, update(ctx, Config{Version: "1", Endpoints: []string{"b:1", "c:1"}}))
+	addrs, err := r.Resolve(ctx)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, addrs, []string{"b:1", "c:1"})
+
+	// configs without endpoints keep the current addresses.
+	assert.NoError(t, update(ctx, Config{Version: "2"}))
+	addrs, err = r.Resolve(ctx)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, addrs, []string{"b:1", "c:1"})
+}
+
+// countConn is a drpc.Conn counting its unary rpcs, which fail with err.
+type countConn struct {
+	calls int32
+	err   error
+}
+
+func (c *countConn) Close() error               { return nil }
+func (c *countConn) Closed() <-chan struct{}    { return nil }
+func (c *countConn) Unblocked() <-chan struct{} { return nil }
+
+func (c *countConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	atomic.AddInt32(&c.calls, 1)
+	return c.err
+}
+
+func (c *countConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return nil, c.err
+}
+
+// firstPicker is a drpcclient.Picker always picking the first candidate.
+type firstPicker struct{}
+
+func (firstPicker) Pick(ctx context.Context, rpc string, candidates []drpcclient.Backend) drpcclient.Backend {
+	return candidates[0]
+}
@@ -0,0 +1,15 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpccontrol consumes endpoint and routing configuration from a control
+// plane over a long-polling drpc API so that traffic policy can be managed
+// centrally.
+//
+// A control plane serves the Watch rpc, for example with a Server registered on
+// a drpcmux.Mux. Clients run a Watcher that long-polls the control plane for a
+// target and hands every new Config to a callback, such as one returned by
+// ServiceConfigUpdater, which applies it to a drpcclient.ClientConn, by
+// BalancerUpdater, which applies its routes and load balancing policy to a
+// drpcclient.Balancer, or by EndpointsUpdater, which pushes its endpoints to a
+// drpcclient.PushResolver.
+package drpccontrol
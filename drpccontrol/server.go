@@ -0,0 +1,78 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpccontrol
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// Server is a minimal in-process control plane that serves the Watch rpc for
+// the configurations set on it.
+type Server struct {
+	mu      sync.Mutex
+	configs map[string]Config
+	changed chan struct{}
+}
+
+// NewServer returns an empty Server.
+func NewServer() *Server {
+	return &Server{
+		configs: make(map[string]Config),
+		changed: make(chan struct{}),
+	}
+}
+
+// Set replaces the configuration for the target, waking any watchers.
+func (s *Server) Set(target string, cfg Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.configs[target] = cfg
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Watch responds with the configuration for the target once its version
+// differs from the version in the request.
+func (s *Server) Watch(ctx context.Context, req *Request) (*Config, error) {
+	for {
+		s.mu.Lock()
+		cfg, ok := s.configs[req.Target]
+		changed := s.changed
+		s.mu.Unlock()
+
+		if ok && cfg.Version != req.Version {
+			return &cfg, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Register registers the Watch rpc of the Server on the mux.
+func (s *Server) Register(mux drpc.Mux) error {
+	return mux.Register(s, description{})
+}
+
+// description describes the Watch rpc to a drpc.Mux.
+type description struct{}
+
+func (description) NumMethods() int { return 1 }
+
+func (description) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	if n != 0 {
+		return "", nil, nil, nil, false
+	}
+	return RPC, Encoding{},
+		func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+			return srv.(*Server).Watch(ctx, in1.(*Request))
+		}, (*Server).Watch, true
+}
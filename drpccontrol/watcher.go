@@ -0,0 +1,79 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpccontrol
+
+import (
+	"context"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+)
+
+// Watcher long-polls a control plane for the configuration of a target.
+type Watcher struct {
+	// Conn is the connection to the control plane.
+	Conn drpc.Conn
+
+	// Target names the service to watch.
+	Target string
+
+	// OnUpdate is called with every new Config. If it returns an error the
+	// Config is not acknowledged and is delivered again on the next poll.
+	OnUpdate func(context.Context, Config) error
+
+	// OnError is called with errors from the control plane or OnUpdate. It
+	// may be nil.
+	OnError func(error)
+
+	// Backoff is the delay before polling again after an error. Zero means
+	// one second.
+	Backoff time.Duration
+
+	// Clock is the clock of the Backoff. It defaults to the system clock.
+	Clock drpcclock.Clock
+}
+
+// Run polls the control plane until the context is canceled, returning the
+// context error.
+func (w *Watcher) Run(ctx context.Context) error {
+	backoff := w.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var version string
+	for {
+		cfg, err := w.poll(ctx, version)
+		if err == nil {
+			err = w.OnUpdate(ctx, cfg)
+			if err == nil {
+				version = cfg.Version
+				continue
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if w.OnError != nil {
+			w.OnError(err)
+		}
+
+		timer := drpcclock.Or(w.Clock).NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// poll waits for a Config with a version different from version.
+func (w *Watcher) poll(ctx context.Context, version string) (cfg Config, err error) {
+	req := Request{Target: w.Target, Version: version}
+	err = w.Conn.Invoke(ctx, RPC, Encoding{}, &req, &cfg)
+	return cfg, err
+}
@@ -0,0 +1,25 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcctx
+
+import (
+	"context"
+)
+
+// FrameLimiterKey is used to store the frame limiter of a conn with the
+// context.
+type FrameLimiterKey struct{}
+
+// WithFrameLimiter associates the frame limiter of a conn, such as its
+// *drpcmanager.Manager, as a value on the context.
+func WithFrameLimiter(ctx context.Context, limiter interface{ LimitFrameSize(n int) }) context.Context {
+	return context.WithValue(ctx, FrameLimiterKey{}, limiter)
+}
+
+// FrameLimiter returns the frame limiter associated with the context and a
+// bool if it existed.
+func FrameLimiter(ctx context.Context) (interface{ LimitFrameSize(n int) }, bool) {
+	limiter, ok := ctx.Value(FrameLimiterKey{}).(interface{ LimitFrameSize(n int) })
+	return limiter, ok
+}
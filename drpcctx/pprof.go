@@ -0,0 +1,21 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcctx
+
+import (
+	"runtime/pprof"
+	"strings"
+)
+
+// PprofLabels returns the runtime/pprof labels that attribute work to the rpc,
+// which has the form "/package.Service/Method": rpc_service is
+// "package.Service" and rpc_method is "Method". An rpc without a slash is
+// used as the method with an empty service.
+func PprofLabels(rpc string) pprof.LabelSet {
+	service, method := "", rpc
+	if i := strings.LastIndexByte(rpc, '/'); i >= 0 {
+		service, method = strings.TrimPrefix(rpc[:i], "/"), rpc[i+1:]
+	}
+	return pprof.Labels("rpc_service", service, "rpc_method", method)
+}
@@ -0,0 +1,34 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcctx
+
+import (
+	"context"
+)
+
+// ResponseKey is used to store the ResponseWriter of a stream with the
+// context.
+type ResponseKey struct{}
+
+// ResponseWriter sends metadata about the response of an rpc to the client,
+// such as a *drpcstream.Stream does.
+type ResponseWriter interface {
+	// SendHeader sends the metadata as the header of the response.
+	SendHeader(metadata map[string]string) error
+
+	// SetTrailer adds the metadata to the trailer sent when the rpc ends.
+	SetTrailer(metadata map[string]string)
+}
+
+// WithResponse associates the ResponseWriter as a value on the context.
+func WithResponse(ctx context.Context, rw ResponseWriter) context.Context {
+	return context.WithValue(ctx, ResponseKey{}, rw)
+}
+
+// Response returns the ResponseWriter associated with the context and a bool
+// if it existed.
+func Response(ctx context.Context) (ResponseWriter, bool) {
+	rw, ok := ctx.Value(ResponseKey{}).(ResponseWriter)
+	return rw, ok
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcecho
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// DefaultSizes are the payload sizes benchmarked when none are passed to
+// Benchmark.
+var DefaultSizes = []int{16, 2 << 10, 1 << 20}
+
+// Benchmark runs a sub-benchmark of every kind of rpc at every payload size
+// against the client, which must be served by Server or GRPCServer. The unary
+// benchmarks measure one rpc per operation, and the stream benchmarks measure
+// one message (or one round trip for Bidi) per operation on a single stream.
+// Every stream is finished before the next benchmark starts, since canceling
+// a drpc stream closes its conn. Clients created with NewDRPCEchoClientAdapter
+// and NewGRPCEchoClientAdapter can be passed to compare drpc against gRPC on
+// the same network.
+func Benchmark(b *testing.B, client RPCEchoClient, sizes ...int) {
+	if len(sizes) == 0 {
+		sizes = DefaultSizes
+	}
+
+	for _, bench := range []struct {
+		name string
+		fn   func(b *testing.B, client RPCEchoClient, size int)
+	}{
+		{"Unary", benchmarkUnary},
+		{"ClientStream", benchmarkClientStream},
+		{"ServerStream", benchmarkServerStream},
+		{"Bidi", benchmarkBidi},
+	} {
+		bench := bench
+		b.Run(bench.name, func(b *testing.B) {
+			for _, size := range sizes {
+				size := size
+				b.Run(sizeName(size), func(b *testing.B) {
+					b.SetBytes(int64(size))
+					b.ReportAllocs()
+					bench.fn(b, client, size)
+				})
+			}
+		})
+	}
+}
+
+func benchmarkUnary(b *testing.B, client RPCEchoClient, size int) {
+	ctx := context.Background()
+	req := &Request{Payload: make([]byte, size)}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		resp, err := client.Unary(ctx, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		checkSize(b, resp, size)
+	}
+}
+
+func benchmarkClientStream(b *testing.B, client RPCEchoClient, size int) {
+	ctx := context.Background()
+	stream, err := client.ClientStream(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req := &Request{Payload: make([]byte, size)}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := stream.Send(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		b.Fatal(err)
+	}
+	checkSize(b, resp, size)
+}
+
+func benchmarkServerStream(b *testing.B, client RPCEchoClient, size int) {
+	ctx := context.Background()
+	b.ResetTimer()
+
+	stream, err := client.ServerStream(ctx, &Request{ResponseSize: int64(size), Count: int64(b.N)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		resp, err := stream.Recv()
+		if err != nil {
+			b.Fatal(err)
+		}
+		checkSize(b, resp, size)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		b.Fatalf("stream did not end after %d responses: %v", b.N, err)
+	}
+}
+
+func benchmarkBidi(b *testing.B, client RPCEchoClient, size int) {
+	ctx := context.Background()
+	stream, err := client.Bidi(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req := &Request{Payload: make([]byte, size)}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := stream.Send(req); err != nil {
+			b.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			b.Fatal(err)
+		}
+		checkSize(b, resp, size)
+	}
+
+	b.StopTimer()
+	if err := stream.CloseSend(); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		b.Fatalf("stream did not end cleanly: %v", err)
+	}
+}
+
+func checkSize(b *testing.B, resp *Response, size int) {
+	if len(resp.GetPayload()) != size {
+		b.Fatalf("response has %d bytes, expected %d", len(resp.GetPayload()), size)
+	}
+}
+
+// sizeName formats a payload size for the name of a sub-benchmark.
+func sizeName(size int) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMiB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKiB", size>>10)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcecho is a reference echo service with drpc and gRPC stubs and a
+// benchmark harness for comparing the two. It is used by the perf CI of this
+// repository and can be used to compare drpc against gRPC in any environment.
+package drpcecho
+
+//go:generate protoc --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. --go-drpc_out=paths=source_relative:. echo.proto
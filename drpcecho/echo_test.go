@@ -0,0 +1,130 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcecho
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/zeebo/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+)
+
+func TestEcho(t *testing.T) {
+	both(t, func(t *testing.T, client RPCEchoClient) {
+		ctx := drpctest.NewTracker(t)
+		defer ctx.Close()
+
+		resp, err := client.Unary(ctx, &Request{Payload: []byte("hello")})
+		assert.NoError(t, err)
+		assert.Equal(t, string(resp.Payload), "hello")
+
+		resp, err = client.Unary(ctx, &Request{Payload: []byte("hello"), ResponseSize: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, len(resp.Payload), 10)
+
+		cs, err := client.ClientStream(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, cs.Send(&Request{Payload: []byte("first")}))
+		assert.NoError(t, cs.Send(&Request{Payload: []byte("last")}))
+		resp, err = cs.CloseAndRecv()
+		assert.NoError(t, err)
+		assert.Equal(t, string(resp.Payload), "last")
+
+		ss, err := client.ServerStream(ctx, &Request{ResponseSize: 3, Count: 4})
+		assert.NoError(t, err)
+		for i := 0; i < 4; i++ {
+			resp, err := ss.Recv()
+			assert.NoError(t, err)
+			assert.Equal(t, len(resp.Payload), 3)
+		}
+		_, err = ss.Recv()
+		assert.That(t, errors.Is(err, io.EOF))
+
+		bs, err := client.Bidi(ctx)
+		assert.NoError(t, err)
+		for _, msg := range []string{"a", "bb", "ccc"} {
+			assert.NoError(t, bs.Send(&Request{Payload: []byte(msg)}))
+			resp, err := bs.Recv()
+			assert.NoError(t, err)
+			assert.Equal(t, string(resp.Payload), msg)
+		}
+		assert.NoError(t, bs.CloseSend())
+		_, err = bs.Recv()
+		assert.That(t, errors.Is(err, io.EOF))
+	})
+}
+
+func TestSizeName(t *testing.T) {
+	assert.Equal(t, sizeName(0), "0B")
+	assert.Equal(t, sizeName(16), "16B")
+	assert.Equal(t, sizeName(1500), "1500B")
+	assert.Equal(t, sizeName(2<<10), "2KiB")
+	assert.Equal(t, sizeName(1<<20), "1MiB")
+}
+
+func BenchmarkDRPC(b *testing.B) { Benchmark(b, dialDRPC(b)) }
+
+func BenchmarkGRPC(b *testing.B) { Benchmark(b, dialGRPC(b)) }
+
+func both(t *testing.T, fn func(t *testing.T, client RPCEchoClient)) {
+	t.Run("DRPC", func(t *testing.T) { fn(t, dialDRPC(t)) })
+	t.Run("GRPC", func(t *testing.T) { fn(t, dialGRPC(t)) })
+}
+
+// dialDRPC serves the echo service over drpc on a loopback port and returns a
+// client of it that is closed when the test finishes.
+func dialDRPC(t testing.TB) RPCEchoClient {
+	ctx := drpctest.NewTracker(t)
+	t.Cleanup(ctx.Close)
+
+	mux := drpcmux.New()
+	assert.NoError(t, DRPCRegisterEcho(mux, Server{}))
+
+	lis := listen(t)
+	ctx.Run(func(ctx context.Context) { _ = drpcserver.New(mux).Serve(ctx, lis) })
+
+	raw, err := net.Dial("tcp", lis.Addr().String())
+	assert.NoError(t, err)
+	conn := drpcconn.New(raw)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewDRPCEchoClientAdapter(conn)
+}
+
+// dialGRPC serves the echo service over gRPC on a loopback port and returns a
+// client of it that is closed when the test finishes.
+func dialGRPC(t testing.TB) RPCEchoClient {
+	ctx := drpctest.NewTracker(t)
+	t.Cleanup(ctx.Close)
+
+	srv := grpc.NewServer()
+	RegisterEchoServer(srv, GRPCServer{})
+
+	lis := listen(t)
+	ctx.Run(func(context.Context) { _ = srv.Serve(lis) })
+	t.Cleanup(srv.Stop)
+
+	cc, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+
+	return NewGRPCEchoClientAdapter(cc)
+}
+
+func listen(t testing.TB) net.Listener {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	return lis
+}
@@ -0,0 +1,29 @@
+module storj.io/drpc/drpcecho
+
+go 1.19
+
+require (
+	github.com/cockroachdb/errors v1.11.3
+	github.com/zeebo/assert v1.3.0
+	google.golang.org/grpc v1.64.0
+	google.golang.org/protobuf v1.34.1
+	storj.io/drpc v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/cockroachdb/logtags v0.0.0-20230118201751-21c54148d20b // indirect
+	github.com/cockroachdb/redact v1.1.5 // indirect
+	github.com/getsentry/sentry-go v0.27.0 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	github.com/zeebo/errs v1.2.2 // indirect
+	golang.org/x/net v0.23.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
+
+replace storj.io/drpc => ..
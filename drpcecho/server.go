@@ -0,0 +1,111 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcecho
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Respond returns the response to the request: a payload of ResponseSize
+// zero bytes if it is positive, and otherwise the payload of the request.
+func Respond(req *Request) *Response {
+	if size := req.GetResponseSize(); size > 0 {
+		return &Response{Payload: make([]byte, size)}
+	}
+	return &Response{Payload: req.GetPayload()}
+}
+
+// Server implements the Echo service for drpc.
+type Server struct{}
+
+var _ DRPCEchoServer = Server{}
+
+// Unary responds to the request.
+func (Server) Unary(ctx context.Context, req *Request) (*Response, error) {
+	return Respond(req), nil
+}
+
+// ClientStream responds to the last request once the client closes its side.
+func (Server) ClientStream(stream DRPCEcho_ClientStreamStream) error {
+	return clientStream(stream)
+}
+
+// ServerStream responds to the request count times.
+func (Server) ServerStream(req *Request, stream DRPCEcho_ServerStreamStream) error {
+	return serverStream(req, stream)
+}
+
+// Bidi responds to every request until the client closes its side.
+func (Server) Bidi(stream DRPCEcho_BidiStream) error {
+	return bidi(stream)
+}
+
+// GRPCServer implements the Echo service for gRPC with the same behavior as
+// Server.
+type GRPCServer struct {
+	UnimplementedEchoServer
+}
+
+var _ EchoServer = GRPCServer{}
+
+// Unary responds to the request.
+func (GRPCServer) Unary(ctx context.Context, req *Request) (*Response, error) {
+	return Respond(req), nil
+}
+
+// ClientStream responds to the last request once the client closes its side.
+func (GRPCServer) ClientStream(stream Echo_ClientStreamServer) error {
+	return clientStream(stream)
+}
+
+// ServerStream responds to the request count times.
+func (GRPCServer) ServerStream(req *Request, stream Echo_ServerStreamServer) error {
+	return serverStream(req, stream)
+}
+
+// Bidi responds to every request until the client closes its side.
+func (GRPCServer) Bidi(stream Echo_BidiServer) error {
+	return bidi(stream)
+}
+
+func clientStream(stream RPCEcho_ClientStreamStream) error {
+	last := new(Request)
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(Respond(last))
+		} else if err != nil {
+			return err
+		}
+		last = req
+	}
+}
+
+func serverStream(req *Request, stream RPCEcho_ServerStreamStream) error {
+	// the same response is sent every time so that the benchmarks measure
+	// the transport rather than allocating payloads.
+	resp := Respond(req)
+	for i := int64(0); i < req.GetCount(); i++ {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bidi(stream RPCEcho_BidiStream) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.Send(Respond(req)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcenc
+
+import (
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// DefaultMaxPooledBufferSize is the capacity above which buffers are not
+// returned to a BufferPool that does not set MaxSize.
+const DefaultMaxPooledBufferSize = 1 << 20
+
+// defaultPool is used by a nil *BufferPool.
+var defaultPool BufferPool
+
+// BufferPool is a sync.Pool backed pool of scratch buffers for marshaling
+// messages. The zero value is ready to use, and a nil *BufferPool uses a pool
+// shared by the whole process.
+type BufferPool struct {
+	// MaxSize is the largest capacity of a buffer that is returned to the
+	// pool. Larger buffers are dropped so that an occasional large message
+	// does not keep its memory alive. Zero means DefaultMaxPooledBufferSize,
+	// and a negative value disables pooling.
+	MaxSize int
+
+	pool sync.Pool
+}
+
+// Get returns an empty buffer from the pool. It should be returned with Put
+// once nothing refers to its contents anymore.
+func (p *BufferPool) Get() *[]byte {
+	if p == nil {
+		p = &defaultPool
+	}
+	if buf, ok := p.pool.Get().(*[]byte); ok {
+		*buf = (*buf)[:0]
+		return buf
+	}
+	return new([]byte)
+}
+
+// Put returns the buffer to the pool unless it is larger than MaxSize.
+func (p *BufferPool) Put(buf *[]byte) {
+	if p == nil {
+		p = &defaultPool
+	}
+	max := p.MaxSize
+	if max == 0 {
+		max = DefaultMaxPooledBufferSize
+	}
+	if buf == nil || cap(*buf) > max {
+		return
+	}
+	p.pool.Put(buf)
+}
+
+// Marshal marshals msg with enc into a buffer from the pool, using
+// MarshalAppend when enc supports it. The buffer should be returned with Put
+// once the data is no longer used.
+func (p *BufferPool) Marshal(msg drpc.Message, enc drpc.Encoding) (buf *[]byte, err error) {
+	buf = p.Get()
+	*buf, err = MarshalAppend(msg, enc, *buf)
+	if err != nil {
+		p.Put(buf)
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcenc
+
+import (
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := &BufferPool{MaxSize: 16}
+
+	buf, err := pool.Marshal([]byte("hello"), byteEncoding{})
+	assert.NoError(t, err)
+	assert.Equal(t, string(*buf), "hello")
+	pool.Put(buf)
+
+	// buffers from the pool are always empty.
+	assert.Equal(t, len(*pool.Get()), 0)
+
+	// buffers larger than MaxSize are dropped.
+	big := make([]byte, 0, 32)
+	pool.Put(&big)
+	for i := 0; i < 10; i++ {
+		assert.That(t, cap(*pool.Get()) <= 16)
+	}
+
+	// a nil pool uses the shared default.
+	var shared *BufferPool
+	buf, err = shared.Marshal([]byte("world"), byteEncoding{})
+	assert.NoError(t, err)
+	assert.Equal(t, string(*buf), "world")
+	shared.Put(buf)
+}
+
+type byteEncoding struct{}
+
+func (byteEncoding) Marshal(msg drpc.Message) ([]byte, error) { return msg.([]byte), nil }
+func (byteEncoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	*msg.(*[]byte) = append((*msg.(*[]byte))[:0], buf...)
+	return nil
+}
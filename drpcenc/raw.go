@@ -0,0 +1,23 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcenc
+
+import "storj.io/drpc"
+
+// Raw is a drpc.Encoding for *[]byte messages that are already marshaled, for
+// code that forwards or stores messages without knowing their type. Marshal
+// returns the bytes unchanged, and Unmarshal copies the bytes into the message,
+// reusing its capacity.
+type Raw struct{}
+
+// Marshal returns the bytes of the *[]byte message.
+func (Raw) Marshal(msg drpc.Message) ([]byte, error) {
+	return *msg.(*[]byte), nil
+}
+
+// Unmarshal copies buf into the *[]byte message.
+func (Raw) Unmarshal(buf []byte, msg drpc.Message) error {
+	*msg.(*[]byte) = append((*msg.(*[]byte))[:0], buf...)
+	return nil
+}
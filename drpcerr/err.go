@@ -5,10 +5,63 @@ package drpcerr
 
 import "unsafe"
 
+// These codes match the gRPC status codes of the same name so that they
+// keep their meaning when errors cross into gRPC or HTTP.
 const (
+	// Canceled is the code for operations canceled by the caller.
+	Canceled = 1
+
+	// Unknown is the code for errors that have no better code.
+	Unknown = 2
+
+	// InvalidArgument is the code for requests that are malformed no matter
+	// the state of the server.
+	InvalidArgument = 3
+
+	// DeadlineExceeded is the code for operations that ran out of time.
+	DeadlineExceeded = 4
+
+	// NotFound is the code for requests for entities that do not exist.
+	NotFound = 5
+
+	// AlreadyExists is the code for requests to create entities that exist.
+	AlreadyExists = 6
+
+	// PermissionDenied is the code for callers that are not allowed to make
+	// the request.
+	PermissionDenied = 7
+
+	// ResourceExhausted is the code for requests rejected because of a limit
+	// or quota, such as by admission control.
+	ResourceExhausted = 8
+
+	// FailedPrecondition is the code for requests the state of the server
+	// does not allow, such as conditional requests whose condition fails.
+	FailedPrecondition = 9
+
+	// Aborted is the code for operations aborted because of a conflict with
+	// a concurrent operation.
+	Aborted = 10
+
+	// OutOfRange is the code for requests past the end of a valid range.
+	OutOfRange = 11
+
 	// Unimplemented is the code used by the generated unimplemented
 	// servers when returning errors.
 	Unimplemented = 12
+
+	// Internal is the code for broken invariants, such as panics.
+	Internal = 13
+
+	// Unavailable is the code for transient failures the request may be
+	// retried after.
+	Unavailable = 14
+
+	// DataLoss is the code for unrecoverable loss or corruption of data.
+	DataLoss = 15
+
+	// Unauthenticated is the code for requests without valid credentials.
+	Unauthenticated = 16
 )
 
 // Code returns the error code associated with the error or 0 if none is.
@@ -33,6 +86,12 @@ func Code(err error) uint64 {
 	return 0
 }
 
+// HasCode returns true if the error is associated with the code. A nil error
+// has no code.
+func HasCode(err error, code uint64) bool {
+	return err != nil && Code(err) == code
+}
+
 // shallowEqual returns true if the two errors are equal without comparing
 // their values. It may return false even if the errors are equal, but if
 // returns true, then the errors are equal.
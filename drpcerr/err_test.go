@@ -34,6 +34,14 @@ func TestCode(t *testing.T) {
 	assert.Equal(t, Code(opaque{WithCode(errors.New("test"), 5)}), 0)
 }
 
+func TestHasCode(t *testing.T) {
+	err := errs.Wrap(WithCode(errors.New("test"), Unauthenticated))
+	assert.That(t, HasCode(err, Unauthenticated))
+	assert.That(t, !HasCode(err, PermissionDenied))
+	assert.That(t, !HasCode(nil, 0))
+	assert.That(t, !HasCode(errors.New("test"), Unauthenticated))
+}
+
 type cycle struct{}
 
 func (s cycle) Error() string { return "cycle" }
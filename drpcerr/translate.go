@@ -0,0 +1,70 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcerr
+
+import "errors"
+
+// Rule rewrites the error of an rpc. It returns the rewritten error and true
+// if it applies to the error, and false otherwise.
+type Rule func(rpc string, err error) (error, bool)
+
+// Translate returns the error rewritten by the first rule that applies to it,
+// or the error unchanged if none does. A nil error is never rewritten.
+func Translate(rpc string, err error, rules []Rule) error {
+	if err == nil {
+		return nil
+	}
+	for _, rule := range rules {
+		if rewritten, ok := rule(rpc, err); ok {
+			return rewritten
+		}
+	}
+	return err
+}
+
+// MapCode returns a Rule that changes the code of errors with the code from
+// to the code to, keeping their messages.
+func MapCode(from, to uint64) Rule {
+	return func(rpc string, err error) (error, bool) {
+		if Code(err) != from {
+			return nil, false
+		}
+		return WithCode(err, to), true
+	}
+}
+
+// Match returns a Rule that associates the code with the errors the match
+// function returns true for, keeping their messages.
+func Match(match func(err error) bool, code uint64) Rule {
+	return func(rpc string, err error) (error, bool) {
+		if !match(err) {
+			return nil, false
+		}
+		return WithCode(err, code), true
+	}
+}
+
+// Scrub returns a Rule that replaces the message of errors with any of the
+// codes, or of every error if no codes are given, with the message. The code
+// is kept, but the original error cannot be unwrapped from the result, so that
+// internal details do not reach callers.
+func Scrub(message string, codes ...uint64) Rule {
+	return func(rpc string, err error) (error, bool) {
+		code := Code(err)
+		if len(codes) > 0 && !containsCode(codes, code) {
+			return nil, false
+		}
+		return WithCode(errors.New(message), code), true
+	}
+}
+
+// containsCode returns true if the code is one of the codes.
+func containsCode(codes []uint64, code uint64) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
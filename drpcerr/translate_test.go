@@ -0,0 +1,43 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcerr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zeebo/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	reset := errors.New("connection reset")
+	rules := []Rule{
+		Match(func(err error) bool { return errors.Is(err, reset) }, 14),
+		MapCode(2, 13),
+		Scrub("internal error", 13),
+	}
+
+	// nil errors are never rewritten
+	assert.Nil(t, Translate("rpc", nil, rules))
+
+	// the first rule that applies wins
+	err := Translate("rpc", reset, rules)
+	assert.Equal(t, Code(err), 14)
+	assert.That(t, errors.Is(err, reset))
+
+	err = Translate("rpc", WithCode(errors.New("boom"), 2), rules)
+	assert.Equal(t, Code(err), 13)
+	assert.Equal(t, err.Error(), "boom")
+
+	// scrubbed errors keep their code but not their details
+	secret := errors.New("db password wrong")
+	err = Translate("rpc", WithCode(secret, 13), rules)
+	assert.Equal(t, Code(err), 13)
+	assert.Equal(t, err.Error(), "internal error")
+	assert.False(t, errors.Is(err, secret))
+
+	// errors no rule applies to are unchanged
+	other := WithCode(errors.New("not found"), 5)
+	assert.Equal(t, Translate("rpc", other, rules), other)
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcfeatures
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpccache"
+	"storj.io/drpc/drpcerr"
+)
+
+// ClusterMismatchError is the class of errors returned when the peer belongs
+// to a different cluster or deployment than the ClusterID configured locally.
+var ClusterMismatchError = errs.Class("cluster mismatch")
+
+// CheckCluster returns a ClusterMismatchError with the gRPC FAILED_PRECONDITION
+// code if local has a ClusterID and the peer did not advertise the same one.
+// Peers that advertise no ClusterID, including those that do not implement
+// negotiation, are a mismatch, so that a misconfigured endpoint is refused
+// instead of silently serving the traffic of another cluster.
+func CheckCluster(local, peer Set) error {
+	want, ok := local.Get(ClusterID)
+	if !ok {
+		return nil
+	}
+	got, ok := peer.Get(ClusterID)
+	if !ok {
+		return mismatch("local cluster %q, peer advertised none", want)
+	}
+	if got != want {
+		return mismatch("local cluster %q, peer cluster %q", want, got)
+	}
+	return nil
+}
+
+// mismatch returns a ClusterMismatchError with the gRPC FAILED_PRECONDITION
+// code.
+func mismatch(format string, args ...interface{}) error {
+	return ClusterMismatchError.Wrap(drpcerr.WithCode(fmt.Errorf(format, args...), drpcerr.FailedPrecondition))
+}
+
+// peerKey is the drpccache key for the features advertised by the client of a
+// conn.
+type peerKey struct{}
+
+// Peer returns the features the client of the conn of the rpc whose handler
+// was passed the context advertised, and false if it did not negotiate.
+func Peer(ctx context.Context) (Set, bool) {
+	cache := drpccache.FromContext(ctx)
+	if cache == nil {
+		return nil, false
+	}
+	peer, ok := cache.Load(peerKey{}).(Set)
+	return peer.Clone(), ok
+}
+
+// RequireCluster returns a drpc.Handler that rejects the rpcs on conns whose
+// client did not advertise the cluster id as its ClusterID with the error of
+// CheckCluster, except for the negotiation rpc itself, which the handler must
+// serve, such as with a mux passed to Register with the same ClusterID.
+func RequireCluster(handler drpc.Handler, id string) drpc.Handler {
+	return clusterHandler{handler: handler, local: Set{ClusterID: id}}
+}
+
+type clusterHandler struct {
+	handler drpc.Handler
+	local   Set
+}
+
+func (h clusterHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	if rpc != RPC {
+		peer, _ := Peer(stream.Context())
+		if err := CheckCluster(h.local, peer); err != nil {
+			return err
+		}
+	}
+	return h.handler.HandleRPC(stream, rpc)
+}
@@ -0,0 +1,6 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcfeatures negotiates optional protocol features between clients
+// and servers so that new wire features can roll out across mixed versions.
+package drpcfeatures
@@ -0,0 +1,153 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcfeatures
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// RPC is the name of the rpc used to exchange feature sets.
+const RPC = "/drpc.Features/Negotiate"
+
+// Well known feature names. Values are feature specific, for example the list
+// of supported codecs, the maximum frame size in bytes, the id of the cluster
+// or deployment the peer belongs to or the drpcmetadata.Version it implements.
+const (
+	Compression     = "compression"
+	MaxFrameSize    = "max-frame-size"
+	Keepalive       = "keepalive"
+	ClusterID       = "cluster-id"
+	MetadataVersion = "metadata-version"
+)
+
+// Set is a set of advertised features mapping feature names to values.
+type Set map[string]string
+
+// Has returns true if the feature is in the set.
+func (s Set) Has(name string) bool {
+	_, ok := s[name]
+	return ok
+}
+
+// Get returns the value of the feature and whether it is in the set.
+func (s Set) Get(name string) (string, bool) {
+	value, ok := s[name]
+	return value, ok
+}
+
+// Intersect returns the features in s that are also in other, using the values
+// from s.
+func (s Set) Intersect(other Set) Set {
+	out := make(Set)
+	for name, value := range s {
+		if other.Has(name) {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// Clone returns a copy of the set.
+func (s Set) Clone() Set {
+	out := make(Set, len(s))
+	for name, value := range s {
+		out[name] = value
+	}
+	return out
+}
+
+// String returns a stable human readable form of the set.
+func (s Set) String() string {
+	names := make([]string, 0, len(s))
+	for name, value := range s {
+		names = append(names, name+"="+value)
+	}
+	sort.Strings(names)
+	return "{" + strings.Join(names, ", ") + "}"
+}
+
+// FrameLimiter is implemented by conns whose written frames can be limited,
+// such as a *drpcconn.Conn or a *drpcmanager.Manager.
+type FrameLimiter interface {
+	LimitFrameSize(n int)
+}
+
+// LimitFrameSize limits the frames written through the limiter to the
+// MaxFrameSize the peer advertised in the set, which is the most data a frame
+// may carry in bytes, and returns true if it did.
+func LimitFrameSize(limiter FrameLimiter, peer Set) bool {
+	value, ok := peer.Get(MaxFrameSize)
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return false
+	}
+	limiter.LimitFrameSize(n)
+	return true
+}
+
+// Negotiate sends the local features to the peer over conn and returns the
+// features the peer advertised that are also in local, using the values the
+// peer advertised. Peers that do not implement negotiation, which respond
+// that the rpc is unknown or unimplemented, cause an empty set to be returned
+// so that callers degrade to the baseline protocol. Other errors, such as a
+// broken conn or the cancellation of ctx, are returned.
+func Negotiate(ctx context.Context, conn drpc.Conn, local Set) (Set, error) {
+	var remote Set
+	if err := conn.Invoke(ctx, RPC, Encoding{}, &local, &remote); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if unknownRPC(err) {
+			return Set{}, nil
+		}
+		return nil, err
+	}
+	return remote.Intersect(local), nil
+}
+
+// unknownRPC returns true if the error is a response to an rpc the peer does
+// not serve, such as the error of a drpcmux.Mux the rpc is not registered on
+// or of a generated unimplemented server.
+func unknownRPC(err error) bool {
+	return drpcerr.HasCode(err, drpcerr.Unimplemented) || strings.Contains(err.Error(), "unknown rpc")
+}
+
+// Encoding is the drpc.Encoding used for *Set messages.
+type Encoding struct{}
+
+// Marshal encodes the *Set in msg.
+func (Encoding) Marshal(msg drpc.Message) ([]byte, error) {
+	set, ok := msg.(*Set)
+	if !ok {
+		return nil, drpc.InternalError.New("invalid feature set type: %T", msg)
+	}
+	return drpcmetadata.Encode(nil, *set)
+}
+
+// Unmarshal decodes buf into the *Set in msg.
+func (Encoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	set, ok := msg.(*Set)
+	if !ok {
+		return drpc.InternalError.New("invalid feature set type: %T", msg)
+	}
+	decoded, err := drpcmetadata.Decode(buf)
+	if err != nil {
+		return err
+	}
+	*set = Set(decoded)
+	if *set == nil {
+		*set = Set{}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcfeatures
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcenc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmanager"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+	"storj.io/drpc/drpcwire"
+)
+
+func TestNegotiate(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	for _, register := range []bool{true, false} {
+		mux := drpcmux.New()
+		if register {
+			assert.NoError(t, Register(mux, Set{Compression: "zstd", Keepalive: ""}))
+		}
+
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = drpcserver.New(mux).ServeOne(ctx, ps) })
+
+		conn := drpcconn.New(pc)
+		peer, err := Negotiate(ctx, conn, Set{Compression: "gzip,zstd", MaxFrameSize: "1024"})
+		assert.NoError(t, err)
+		assert.NoError(t, conn.Close())
+
+		if register {
+			assert.DeepEqual(t, peer, Set{Compression: "zstd"})
+		} else {
+			assert.DeepEqual(t, peer, Set{})
+		}
+	}
+}
+
+func TestNegotiateErrors(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	negotiate := func(err error) (Set, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) {
+			_ = drpcserver.New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+				return err
+			})).ServeOne(ctx, ps)
+		})
+		conn := drpcconn.New(pc)
+		defer func() { _ = conn.Close() }()
+		return Negotiate(ctx, conn, Set{Keepalive: ""})
+	}
+
+	// peers without negotiation degrade to an empty set.
+	peer, err := negotiate(drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented))
+	assert.NoError(t, err)
+	assert.DeepEqual(t, peer, Set{})
+
+	// other failures are reported.
+	_, err = negotiate(errors.New("overloaded"))
+	assert.Error(t, err)
+}
+
+func TestMaxFrameSize(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	mux := drpcmux.New()
+	assert.NoError(t, Register(mux, Set{MaxFrameSize: "1024"}))
+	srv := drpcserver.NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == RPC {
+			return mux.HandleRPC(stream, rpc)
+		}
+		var in []byte
+		if err := stream.MsgRecv(&in, drpcenc.Raw{}); err != nil {
+			return err
+		}
+		return stream.MsgSend(&in, drpcenc.Raw{})
+	}), drpcserver.Options{
+		Manager: drpcmanager.Options{Reader: drpcwire.ReaderOptions{MaximumFrameSize: 1024}},
+	})
+
+	dial := func() *drpcconn.Conn {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		return drpcconn.NewWithOptions(pc, drpcconn.Options{
+			Manager: drpcmanager.Options{Reader: drpcwire.ReaderOptions{MaximumFrameSize: 512}},
+		})
+	}
+	echo := func(conn *drpcconn.Conn) error {
+		in, out := make([]byte, 4096), []byte(nil)
+		return conn.Invoke(ctx, "echo", drpcenc.Raw{}, &in, &out)
+	}
+
+	// after negotiation both ends write frames the other accepts
+	conn := dial()
+	peer, err := Negotiate(ctx, conn, Set{MaxFrameSize: "512"})
+	assert.NoError(t, err)
+	assert.DeepEqual(t, peer, Set{MaxFrameSize: "1024"})
+	assert.That(t, LimitFrameSize(conn, peer))
+	assert.NoError(t, echo(conn))
+	assert.NoError(t, conn.Close())
+
+	// without it the server rejects the default frame size
+	conn = dial()
+	assert.Error(t, echo(conn))
+	assert.NoError(t, conn.Close())
+
+	assert.That(t, !LimitFrameSize(conn, Set{}))
+	assert.That(t, !LimitFrameSize(conn, Set{MaxFrameSize: "jumbo"}))
+}
+
+func TestCluster(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	assert.NoError(t, CheckCluster(Set{}, Set{ClusterID: "east"}))
+	assert.NoError(t, CheckCluster(Set{ClusterID: "east"}, Set{ClusterID: "east"}))
+	assert.That(t, ClusterMismatchError.Has(CheckCluster(Set{ClusterID: "east"}, Set{ClusterID: "west"})))
+	assert.Equal(t, drpcerr.Code(CheckCluster(Set{ClusterID: "east"}, Set{})), drpcerr.FailedPrecondition)
+
+	mux := drpcmux.New()
+	assert.NoError(t, Register(mux, Set{ClusterID: "east"}))
+	srv := drpcserver.New(RequireCluster(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == RPC {
+			return mux.HandleRPC(stream, rpc)
+		}
+		var in []byte
+		if err := stream.MsgRecv(&in, drpcenc.Raw{}); err != nil {
+			return err
+		}
+		peer, ok := Peer(stream.Context())
+		assert.That(t, ok)
+		in = []byte(peer[ClusterID])
+		return stream.MsgSend(&in, drpcenc.Raw{})
+	}), "east"))
+
+	call := func(local Set) (string, error) {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		conn := drpcconn.New(pc)
+		defer func() { _ = conn.Close() }()
+
+		if local != nil {
+			peer, err := Negotiate(ctx, conn, local)
+			assert.NoError(t, err)
+			assert.Equal(t, peer[ClusterID], "east")
+		}
+		in, out := []byte("hi"), []byte(nil)
+		err := conn.Invoke(ctx, "echo", drpcenc.Raw{}, &in, &out)
+		return string(out), err
+	}
+
+	out, err := call(Set{ClusterID: "east"})
+	assert.NoError(t, err)
+	assert.Equal(t, out, "east")
+
+	// clients of another cluster or that did not negotiate are refused
+	_, err = call(Set{ClusterID: "west"})
+	assert.Equal(t, drpcerr.Code(err), drpcerr.FailedPrecondition)
+	_, err = call(nil)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.FailedPrecondition)
+}
+
+func TestMetadataVersion(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	mux := drpcmux.New()
+	assert.NoError(t, Register(mux, Set{MetadataVersion: strconv.Itoa(drpcmetadata.Version)}))
+	srv := drpcserver.New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == RPC {
+			return mux.HandleRPC(stream, rpc)
+		}
+		var in []byte
+		if err := stream.MsgRecv(&in, drpcenc.Raw{}); err != nil {
+			return err
+		}
+		metadata, _ := drpcmetadata.Get(stream.Context())
+		keys := make([]string, 0, len(metadata))
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := []byte(strings.Join(keys, ","))
+		return stream.MsgSend(&out, drpcenc.Raw{})
+	}))
+
+	call := func(version int) string {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		conn := drpcconn.New(pc)
+		defer func() { _ = conn.Close() }()
+
+		_, err := Negotiate(ctx, conn, Set{MetadataVersion: strconv.Itoa(version)})
+		assert.NoError(t, err)
+
+		callCtx := drpcmetadata.Add(ctx, "drpc-future", "x")
+		callCtx = drpcmetadata.Add(callCtx, drpcmetadata.Baggage.String(), "k=v")
+		callCtx = drpcmetadata.Add(callCtx, "app", "y")
+		in, out := []byte("hi"), []byte(nil)
+		assert.NoError(t, conn.Invoke(callCtx, "echo", drpcenc.Raw{}, &in, &out))
+		return string(out)
+	}
+
+	// keys of a newer client the server does not know are dropped.
+	assert.Equal(t, call(drpcmetadata.Version), "app,drpc-baggage,drpc-future")
+	assert.Equal(t, call(drpcmetadata.Version+1), "app,drpc-baggage")
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcfeatures
+
+import (
+	"context"
+	"strconv"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpccache"
+	"storj.io/drpc/drpcctx"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// Register registers the negotiation rpc on the mux so that clients can learn
+// the features the server supports. If the features include MaxFrameSize, the
+// frames written to clients that advertise it are limited to their maximum.
+// The features each client advertised are recorded in the drpccache of its
+// conn and returned by Peer, and the MetadataVersion it advertised is recorded
+// with drpcmetadata.SetPeerVersion so that servers drop the metadata keys of
+// newer clients they do not know.
+func Register(mux drpc.Mux, features Set) error {
+	return mux.Register(&server{features: features.Clone()}, description{})
+}
+
+// server answers negotiation requests with its configured features.
+type server struct {
+	features Set
+}
+
+func (s *server) Negotiate(ctx context.Context, in *Set) (*Set, error) {
+	if limiter, ok := drpcctx.FrameLimiter(ctx); ok && s.features.Has(MaxFrameSize) {
+		LimitFrameSize(limiter, *in)
+	}
+	if cache := drpccache.FromContext(ctx); cache != nil {
+		cache.Store(peerKey{}, in.Clone())
+	}
+	if value, ok := in.Get(MetadataVersion); ok {
+		if version, err := strconv.Atoi(value); err == nil {
+			drpcmetadata.SetPeerVersion(ctx, version)
+		}
+	}
+	out := s.features.Clone()
+	return &out, nil
+}
+
+// description describes the negotiation rpc to a drpc.Mux.
+type description struct{}
+
+func (description) NumMethods() int { return 1 }
+
+func (description) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	if n != 0 {
+		return "", nil, nil, nil, false
+	}
+	return RPC, Encoding{},
+		func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+			return srv.(*server).Negotiate(ctx, in1.(*Set))
+		}, (*server).Negotiate, true
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpchttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpcsignal"
+)
+
+// Conn is a drpc.Conn that issues unitary RPCs as requests to a handler
+// returned by New, using the "application/proto" content type and sending
+// any metadata on the context in X-Drpc-Metadata headers. Streams are not
+// supported. Unlike other drpc.Conns, a Conn may be used concurrently.
+type Conn struct {
+	client *http.Client
+	base   string
+	closed drpcsignal.Signal
+}
+
+// NewConn returns a Conn that sends RPCs with the client to the handler
+// hosted at the base URL, such that the RPC "/service.Server/Method" is sent
+// to base + "/service.Server/Method". If client is nil, http.DefaultClient is
+// used.
+func NewConn(client *http.Client, base string) *Conn {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Conn{
+		client: client,
+		base:   strings.TrimSuffix(base, "/"),
+	}
+}
+
+// Close closes the Conn and any idle connections held by its client.
+func (c *Conn) Close() error {
+	if c.closed.Set(drpc.ClosedError.New("conn closed")) {
+		c.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Closed returns a channel that is closed once the Conn is closed.
+func (c *Conn) Closed() <-chan struct{} { return c.closed.Signal() }
+
+// RemoteAddr returns the address of the handler, whose network is the scheme
+// of the base URL, like "http" or "https", and whose string form is its host.
+func (c *Conn) RemoteAddr() net.Addr {
+	u, err := url.Parse(c.base)
+	if err != nil {
+		return urlAddr{network: "http", host: c.base}
+	}
+	return urlAddr{network: u.Scheme, host: u.Host}
+}
+
+// urlAddr is a net.Addr for a URL.
+type urlAddr struct{ network, host string }
+
+func (a urlAddr) Network() string { return a.network }
+func (a urlAddr) String() string  { return a.host }
+
+// NewStream always returns an error because streams are not supported.
+func (c *Conn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	return nil, errs.New("drpchttp: streams are not supported")
+}
+
+// Invoke issues the unitary RPC to the handler.
+func (c *Conn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	if err, ok := c.closed.Get(); ok {
+		return err
+	}
+
+	data, err := enc.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+rpc, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/proto")
+	if metadata, ok := drpcmetadata.Get(ctx); ok {
+		for key, value := range metadata {
+			req.Header.Add("X-Drpc-Metadata", escape(key)+"="+escape(value))
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return err
+	} else if len(body) > maxSize {
+		return errs.New("drpchttp: response exceeds maximum size of %d bytes", maxSize)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp.StatusCode, body)
+	}
+	return enc.Unmarshal(body, out)
+}
+
+// responseError builds an error from the body of a failed response, restoring
+// the drpcerr code if the handler sent one.
+func responseError(status int, body []byte) error {
+	var resp struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Msg == "" {
+		return errs.New("drpchttp: unexpected status %d", status)
+	}
+
+	err := errs.New("%s", resp.Msg)
+	var code uint64
+	if _, serr := fmt.Sscanf(resp.Code, "drpcerr(%d)", &code); serr == nil {
+		return drpcerr.WithCode(err, code)
+	}
+	return err
+}
+
+// escape is the inverse of unescape, percent encoding every byte that is not
+// unreserved in a URL so that the result is safe to send in a header.
+func escape(s string) string {
+	var t strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			_ = t.WriteByte(c)
+		default:
+			_, _ = fmt.Fprintf(&t, "%%%02X", c)
+		}
+	}
+	return t.String()
+}
+
+var _ drpc.Conn = (*Conn)(nil)
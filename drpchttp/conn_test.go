@@ -0,0 +1,37 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpchttp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcenc"
+)
+
+func TestConnRejectsOversizedResponse(t *testing.T) {
+	var size int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte{'a'}, size))
+	}))
+	defer srv.Close()
+
+	conn := NewConn(srv.Client(), srv.URL)
+	defer func() { _ = conn.Close() }()
+
+	size = maxSize
+	var out []byte
+	assert.NoError(t, conn.Invoke(context.Background(), "/service/Method", drpcenc.Raw{}, new([]byte), &out))
+	assert.Equal(t, len(out), maxSize)
+
+	size = maxSize + 1
+	err := conn.Invoke(context.Background(), "/service/Method", drpcenc.Raw{}, new([]byte), &out)
+	assert.Error(t, err)
+	assert.That(t, bytes.Contains([]byte(err.Error()), []byte("exceeds maximum size")))
+}
@@ -62,3 +62,14 @@ func TestBuildContext(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+func TestEscapeRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "plain", "a=b%c", "spaces and\nnewlines", "\x00\xff"} {
+		ctx, err := buildContext(context.Background(), []string{escape(s) + "=" + escape(s)})
+		assert.NoError(t, err)
+
+		metadata, ok := drpcmetadata.Get(ctx)
+		assert.That(t, ok)
+		assert.Equal(t, metadata[s], s)
+	}
+}
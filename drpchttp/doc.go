@@ -1,5 +1,6 @@
 // Copyright (C) 2021 Storj Labs, Inc.
 // See LICENSE for copying information.
 
-// Package drpchttp implements a net/http handler for unitary RPCs.
+// Package drpchttp implements a net/http handler for unitary RPCs, and a
+// drpc.Conn that issues unitary RPCs to it.
 package drpchttp
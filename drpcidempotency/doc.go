@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcidempotency makes retries of non-idempotent unary rpcs safe.
+// Clients attach an idempotency key that stays the same across the retries of
+// a logical call, and servers record the response of the first execution in a
+// Store and send it again for repeated calls with the same key instead of
+// executing them again.
+package drpcidempotency
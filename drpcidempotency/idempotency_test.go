@@ -0,0 +1,154 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcidempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpctest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour)
+
+	_, done, err := store.Begin(ctx, "k")
+	assert.NoError(t, err)
+	assert.That(t, !done)
+
+	_, _, err = store.Begin(ctx, "k")
+	assert.Equal(t, err, ErrInProgress)
+
+	assert.NoError(t, store.Complete(ctx, "k", []byte("resp")))
+	response, done, err := store.Begin(ctx, "k")
+	assert.NoError(t, err)
+	assert.That(t, done)
+	assert.Equal(t, string(response), "resp")
+
+	_, _, err = store.Begin(ctx, "aborted")
+	assert.NoError(t, err)
+	assert.NoError(t, store.Abort(ctx, "aborted"))
+	_, done, err = store.Begin(ctx, "aborted")
+	assert.NoError(t, err)
+	assert.That(t, !done)
+
+	expiring := NewMemoryStore(time.Nanosecond)
+	_, _, _ = expiring.Begin(ctx, "k")
+	time.Sleep(time.Millisecond)
+	_, done, err = expiring.Begin(ctx, "other")
+	assert.NoError(t, err)
+	assert.That(t, !done)
+	assert.Equal(t, expiring.Len(), 1)
+}
+
+func TestIdempotentCalls(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	executions := 0
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		executions++
+		if in == "fail" {
+			return "", errors.New("failed")
+		}
+		return fmt.Sprint(in, executions), nil
+	}), NewMemoryStore(time.Minute), func(rpc string) bool { return rpc != "stream" }),
+		drpcclient.WithChainUnaryInterceptor(UnaryClientInterceptor))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	// repeated calls with the same key execute once and get the same response
+	keyed := WithKey(ctx, NewKey())
+	in, out := "create", ""
+	assert.NoError(t, cc.Invoke(keyed, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "create1")
+	out = ""
+	assert.NoError(t, cc.Invoke(keyed, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "create1")
+	assert.Equal(t, executions, 1)
+
+	// the same key with another request is rejected
+	other := "delete"
+	err = cc.Invoke(keyed, "rpc", drpctest.StringEncoding{}, &other, &out)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.InvalidArgument)
+	assert.Equal(t, executions, 1)
+
+	// the same key on another rpc is a different call
+	assert.NoError(t, cc.Invoke(keyed, "other", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "create2")
+
+	// calls without a key get a fresh one each time
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, executions, 4)
+
+	// rpcs that are not unary are never replayed
+	assert.NoError(t, cc.Invoke(keyed, "stream", drpctest.StringEncoding{}, &in, &out))
+	assert.NoError(t, cc.Invoke(keyed, "stream", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, executions, 6)
+
+	// failures are not recorded, so retries execute again
+	keyed = WithKey(ctx, NewKey())
+	in = "fail"
+	assert.Error(t, cc.Invoke(keyed, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Error(t, cc.Invoke(keyed, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, executions, 8)
+}
+
+func TestInProgressWrapped(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		return in, nil
+	}), wrappingStore{NewMemoryStore(time.Minute)}, func(string) bool { return true }))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	keyed := cc.AddMetadata(ctx, drpcmetadata.IdempotencyKey, "k")
+	in, out := "a", ""
+	err = cc.Invoke(keyed, "rpc", drpctest.StringEncoding{}, &in, &out)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Aborted)
+}
+
+// wrappingStore is a Store whose keys are always in progress, with
+// ErrInProgress wrapped like a store backed by another system might.
+type wrappingStore struct{ *MemoryStore }
+
+func (s wrappingStore) Begin(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, fmt.Errorf("shared store: %w", ErrInProgress)
+}
+
+func TestUnaryRPCs(t *testing.T) {
+	unary := UnaryRPCs(description{})
+	assert.That(t, unary("/svc/Unary"))
+	assert.That(t, !unary("/svc/Stream"))
+	assert.That(t, !unary("/svc/Unknown"))
+}
+
+// description describes a unary and a server streaming method.
+type description struct{}
+
+func (description) NumMethods() int { return 2 }
+
+func (description) Method(i int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	switch i {
+	case 0:
+		return "/svc/Unary", nil, nil, func(interface{}, context.Context, *string) (*string, error) { return nil, nil }, true
+	case 1:
+		return "/svc/Stream", nil, nil, func(interface{}, *string, drpc.Stream) error { return nil }, true
+	}
+	return "", nil, nil, nil, false
+}
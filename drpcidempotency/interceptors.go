@@ -0,0 +1,168 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcidempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcenc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// keyKey is the context key for the idempotency key of a call.
+type keyKey struct{}
+
+// WithKey returns a context whose unary rpcs use the idempotency key. Callers
+// that retry a logical call themselves should set the key once and reuse the
+// context for every attempt.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// Key returns the idempotency key set on the context by WithKey.
+func Key(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyKey{}).(string)
+	return key, ok
+}
+
+// NewKey returns a random idempotency key.
+func NewKey() string {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// UnaryClientInterceptor sends the idempotency key of the context in the
+// drpcmetadata.IdempotencyKey key, minting a new one with NewKey if it has
+// none. The retries configured by a service config happen after interceptors
+// run, so they share the key.
+func UnaryClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+	key, ok := Key(ctx)
+	if !ok {
+		key = NewKey()
+	}
+	return next(cc.AddMetadata(ctx, drpcmetadata.IdempotencyKey, key), rpc, enc, in, out, cc)
+}
+
+// UnaryRPCs returns a function reporting if an rpc is one of the unary rpcs
+// of the descriptions, such as those registered with a drpcmux.Mux, for use
+// with NewHandler.
+func UnaryRPCs(descs ...drpc.Description) func(rpc string) bool {
+	unary := make(map[string]bool)
+	for _, desc := range descs {
+		for i := 0; i < desc.NumMethods(); i++ {
+			rpc, _, _, method, ok := desc.Method(i)
+			// like drpcmux, unary methods are those returning a response and
+			// an error.
+			if ok && reflect.TypeOf(method).NumOut() == 2 {
+				unary[rpc] = true
+			}
+		}
+	}
+	return func(rpc string) bool { return unary[rpc] }
+}
+
+// NewHandler returns a drpc.Handler that executes each unary rpc with an
+// idempotency key at most once per key and rpc name, as recorded in the
+// store. Repeated rpcs are sent the recorded response without executing the
+// handler, and rpcs that repeat one still in progress fail with the gRPC
+// ABORTED code. The store records a SHA-256 hash of the request in front of
+// the response, and repeated rpcs whose request differs fail with the gRPC
+// INVALID_ARGUMENT code instead of being sent the response of another
+// request. Failed executions are not recorded, so that retries execute again.
+// Rpcs without a key, and rpcs for which unary returns false, such as
+// streaming rpcs, are passed to the handler unchanged.
+//
+// The keys are only as trustworthy as the clients sending them. Deployments
+// with untrusted clients should scope them, for example with a handler that
+// authenticates the client and prefixes the key with its identity.
+func NewHandler(handler drpc.Handler, store Store, unary func(rpc string) bool) drpc.Handler {
+	return idempotentHandler{handler: handler, store: store, unary: unary}
+}
+
+type idempotentHandler struct {
+	handler drpc.Handler
+	store   Store
+	unary   func(rpc string) bool
+}
+
+func (h idempotentHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	key, ok := drpcmetadata.Lookup(stream.Context(), drpcmetadata.IdempotencyKey)
+	if !ok || !h.unary(rpc) {
+		return h.handler.HandleRPC(stream, rpc)
+	}
+	key = rpc + "\x00" + key
+	ctx := stream.Context()
+
+	// the request is received first so that repeated rpcs can be checked
+	// against the request they repeat.
+	var request []byte
+	if err := stream.MsgRecv(&request, drpcenc.Raw{}); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(request)
+
+	recorded, done, err := h.store.Begin(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrInProgress) {
+			return drpcerr.WithCode(err, drpcerr.Aborted)
+		}
+		return err
+	}
+	if done {
+		if len(recorded) < len(sum) || !bytes.Equal(recorded[:len(sum)], sum[:]) {
+			return drpcerr.WithCode(Error.New("idempotency key reused with a different request"), drpcerr.InvalidArgument)
+		}
+		response := recorded[len(sum):]
+		return stream.MsgSend(&response, drpcenc.Raw{})
+	}
+
+	rs := &recordStream{Stream: stream, request: request}
+	if err := h.handler.HandleRPC(rs, rpc); err != nil || !rs.sent {
+		_ = h.store.Abort(ctx, key)
+		return err
+	}
+	return h.store.Complete(ctx, key, append(sum[:], rs.response...))
+}
+
+// recordStream hands the already received request to the handler and records
+// the first response sent on it.
+type recordStream struct {
+	drpc.Stream
+	request  []byte
+	received bool
+	sent     bool
+	response []byte
+}
+
+func (s *recordStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if s.received {
+		return s.Stream.MsgRecv(msg, enc)
+	}
+	s.received = true
+	return enc.Unmarshal(s.request, msg)
+}
+func (s *recordStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	if s.sent {
+		return s.Stream.MsgSend(msg, enc)
+	}
+	data, err := enc.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := s.Stream.MsgSend(&data, drpcenc.Raw{}); err != nil {
+		return err
+	}
+	s.sent, s.response = true, data
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcidempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcidempotency")
+
+// ErrInProgress is returned by a Store when another execution with the same
+// key has begun but not yet completed.
+var ErrInProgress = Error.New("call with the same idempotency key in progress")
+
+// Store records the executions of calls by their key. Implementations backed
+// by shared storage deduplicate calls across server instances.
+type Store interface {
+	// Begin reserves the key for an execution. If an execution with the key
+	// already completed, it returns its response with done true. If one is in
+	// progress, it returns ErrInProgress.
+	Begin(ctx context.Context, key string) (response []byte, done bool, err error)
+
+	// Complete records the response of the execution that reserved the key.
+	Complete(ctx context.Context, key string, response []byte) error
+
+	// Abort releases the key after a failed execution so that a retry
+	// executes the call again.
+	Abort(ctx context.Context, key string) error
+}
+
+// MemoryStore is a Store that keeps the executions in memory for a time to
+// live after they begin.
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	swept   time.Time
+}
+
+type memoryEntry struct {
+	done     bool
+	response []byte
+	expires  time.Time
+}
+
+// NewMemoryStore returns a MemoryStore that forgets executions ttl after they
+// begin, which should be longer than the clients keep retrying.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:     ttl,
+		entries: make(map[string]*memoryEntry),
+		swept:   time.Now(),
+	}
+}
+
+// Begin implements Store.
+func (s *MemoryStore) Begin(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweepLocked(now)
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		if !entry.done {
+			return nil, false, ErrInProgress
+		}
+		return entry.response, true, nil
+	}
+	s.entries[key] = &memoryEntry{expires: now.Add(s.ttl)}
+	return nil, false, nil
+}
+
+// Complete implements Store.
+func (s *MemoryStore) Complete(ctx context.Context, key string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		entry.done = true
+		entry.response = response
+	}
+	return nil
+}
+
+// Abort implements Store.
+func (s *MemoryStore) Abort(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Len returns the number of executions the store remembers.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// sweepLocked removes the expired entries at most once per ttl. It must be
+// called with s.mu held.
+func (s *MemoryStore) sweepLocked(now time.Time) {
+	if now.Sub(s.swept) < s.ttl {
+		return
+	}
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.swept = now
+}
@@ -0,0 +1,7 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcjwt verifies JWT bearer tokens sent in the metadata of rpcs
+// against the keys of a JWKS endpoint, which are cached and refreshed, and
+// passes the validated claims to handlers for their authorization decisions.
+package drpcjwt
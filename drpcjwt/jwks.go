@@ -0,0 +1,237 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"storj.io/drpc/drpcclock"
+)
+
+// DefaultRefreshInterval is how long a JWKS uses the keys it fetched when none
+// is configured.
+const DefaultRefreshInterval = 15 * time.Minute
+
+// DefaultMinRefreshInterval is how long a JWKS waits between fetches when none
+// is configured.
+const DefaultMinRefreshInterval = 30 * time.Second
+
+// maxJWKSSize bounds the size of the fetched key sets.
+const maxJWKSSize = 1 << 20
+
+// Keys returns the public keys that sign tokens.
+type Keys interface {
+	// Key returns the public key with the key ID.
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// JWKSOptions configures a JWKS.
+type JWKSOptions struct {
+	// Client fetches the key set. It defaults to http.DefaultClient.
+	Client *http.Client
+
+	// RefreshInterval is how long the fetched keys are used before they are
+	// fetched again. It defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval is how long to wait after a fetch before fetching
+	// again, so that tokens with made up key IDs or a failing endpoint do not
+	// cause a fetch per rpc. It defaults to DefaultMinRefreshInterval.
+	MinRefreshInterval time.Duration
+
+	// Clock is the clock of the intervals. It defaults to the system clock.
+	Clock drpcclock.Clock
+}
+
+// JWKS is the cached key set of a JWKS endpoint. The keys are fetched when
+// first needed, again once they are older than the RefreshInterval, and again
+// when a token is signed by an unknown key, such as after the issuer rotated
+// its keys, but at most once per MinRefreshInterval. If a fetch fails, the
+// keys fetched before keep being used. It is safe for concurrent use.
+type JWKS struct {
+	url   string
+	opts  JWKSOptions
+	clock drpcclock.Clock
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time // when keys were fetched
+	attempted time.Time // when a fetch was last attempted
+}
+
+// NewJWKS returns a JWKS fetching the key set at the url.
+func NewJWKS(url string, opts JWKSOptions) *JWKS {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultRefreshInterval
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = DefaultMinRefreshInterval
+	}
+	return &JWKS{
+		url:   url,
+		opts:  opts,
+		clock: drpcclock.Or(opts.Clock),
+	}
+}
+
+// Key implements Keys.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.clock.Now()
+	key, ok := j.keys[kid]
+	stale := j.keys == nil || now.Sub(j.fetched) >= j.opts.RefreshInterval
+	allowed := j.attempted.IsZero() || now.Sub(j.attempted) >= j.opts.MinRefreshInterval
+
+	if (stale || !ok) && allowed {
+		j.attempted = now
+		keys, err := j.fetch(ctx)
+		switch {
+		case err == nil:
+			j.keys, j.fetched = keys, now
+			key, ok = keys[kid]
+		case j.keys == nil:
+			return nil, err
+		}
+	}
+
+	switch {
+	case j.keys == nil:
+		return nil, Error.New("key set %q is unavailable", j.url)
+	case !ok:
+		return nil, Error.New("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// fetch returns the keys of the key set at the url.
+func (j *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	resp, err := j.opts.Client.Do(req)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, Error.New("fetching %q: %s", j.url, resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// jwk is a JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the public key described by the jwk.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, Error.New("key %q has invalid exponent", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, Error.New("key %q has unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, Error.New("key %q is not on its curve", k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, Error.New("key %q has unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, Error.New("key %q is malformed", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, Error.New("key %q has unsupported type %q", k.Kid, k.Kty)
+	}
+}
+
+// decodeInt decodes a base64url encoded big endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(buf) == 0 {
+		return nil, Error.New("malformed key parameter")
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
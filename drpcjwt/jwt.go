@@ -0,0 +1,296 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcjwt")
+
+// MetadataKey is the metadata key carrying the token as "Bearer <token>", as
+// with gRPC.
+const MetadataKey = "authorization"
+
+// DefaultLeeway is the clock skew tolerated when checking the times of a
+// token when none is configured.
+const DefaultLeeway = time.Minute
+
+// Options configures a Verifier.
+type Options struct {
+	// Issuer is the required "iss" claim, unless it is empty.
+	Issuer string
+
+	// Audience must be one of the "aud" claims, unless it is empty.
+	Audience string
+
+	// Leeway is the clock skew tolerated between the issuer and the server
+	// when checking the "exp" and "nbf" claims. It defaults to DefaultLeeway.
+	Leeway time.Duration
+
+	// Clock is the clock the times are checked against. It defaults to the
+	// system clock.
+	Clock drpcclock.Clock
+}
+
+// Verifier verifies tokens signed by the keys.
+type Verifier struct {
+	keys  Keys
+	opts  Options
+	clock drpcclock.Clock
+}
+
+// NewVerifier returns a Verifier of the tokens signed by the keys.
+func NewVerifier(keys Keys, opts Options) *Verifier {
+	if opts.Leeway <= 0 {
+		opts.Leeway = DefaultLeeway
+	}
+	return &Verifier{keys: keys, opts: opts, clock: drpcclock.Or(opts.Clock)}
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]interface{}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Issuer returns the "iss" claim.
+func (c Claims) Issuer() string {
+	iss, _ := c["iss"].(string)
+	return iss
+}
+
+// Audience returns the "aud" claim, which is either a string or a list.
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		auds := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+		return auds
+	default:
+		return nil
+	}
+}
+
+// Time returns the claim holding a NumericDate, such as "exp", and false if
+// it is missing or not a number.
+func (c Claims) Time(name string) (time.Time, bool) {
+	n, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+// Verify returns the claims of the token if it is signed by one of the keys,
+// is not expired or not yet valid, and has the Issuer and Audience of the
+// options. Tokens without an "exp" claim are rejected.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, Error.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, Error.New("malformed signature")
+	}
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.check(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// check checks the registered claims against the options.
+func (v *Verifier) check(claims Claims) error {
+	now := v.clock.Now()
+
+	exp, ok := claims.Time("exp")
+	if !ok {
+		return Error.New("token has no expiration")
+	}
+	if !now.Before(exp.Add(v.opts.Leeway)) {
+		return Error.New("token expired at %v", exp)
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Before(nbf.Add(-v.opts.Leeway)) {
+		return Error.New("token not valid before %v", nbf)
+	}
+
+	if v.opts.Issuer != "" && claims.Issuer() != v.opts.Issuer {
+		return Error.New("token issuer %q is not %q", claims.Issuer(), v.opts.Issuer)
+	}
+	if v.opts.Audience != "" {
+		for _, aud := range claims.Audience() {
+			if aud == v.opts.Audience {
+				return nil
+			}
+		}
+		return Error.New("token audience does not include %q", v.opts.Audience)
+	}
+	return nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment into v, keeping
+// numbers as json.Number.
+func decodeSegment(seg string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return Error.New("malformed token segment")
+	}
+	dec := json.NewDecoder(strings.NewReader(string(buf)))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return Error.New("malformed token segment: %v", err)
+	}
+	return nil
+}
+
+// verifySignature verifies the signature of the signing input with the key
+// for the algorithm, which must match the type of the key.
+func verifySignature(alg string, key crypto.PublicKey, input string, sig []byte) error {
+	var hash crypto.Hash
+	var bits int // of the curve of ES algorithms
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash, bits = crypto.SHA256, 256
+	case "RS384", "PS384", "ES384":
+		hash, bits = crypto.SHA384, 384
+	case "RS512", "PS512", "ES512":
+		hash, bits = crypto.SHA512, 521
+	case "EdDSA":
+	default:
+		return Error.New("unsupported algorithm %q", alg)
+	}
+
+	var digest []byte
+	if hash != 0 {
+		h := hash.New()
+		_, _ = h.Write([]byte(input))
+		digest = h.Sum(nil)
+	}
+
+	valid := false
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(key, hash, digest, sig) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(key, hash, digest, sig, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (bits + 7) / 8
+		if alg[:2] == "ES" && key.Curve.Params().BitSize == bits && len(sig) == 2*size {
+			r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(key, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		valid = alg == "EdDSA" && ed25519.Verify(key, []byte(input), sig)
+	}
+	if !valid {
+		return Error.New("invalid signature")
+	}
+	return nil
+}
+
+// claimsKey is the context key for the claims of an rpc.
+type claimsKey struct{}
+
+// ClaimsFromContext returns the verified claims of the token of the rpc whose
+// handler was passed the context.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// NewHandler returns a drpc.Handler that verifies the bearer token in the
+// MetadataKey of every rpc with the verifier, and rejects the rpcs without a
+// valid one with the gRPC UNAUTHENTICATED code.
+func NewHandler(handler drpc.Handler, verifier *Verifier) drpc.Handler {
+	return jwtHandler{handler: handler, verifier: verifier}
+}
+
+type jwtHandler struct {
+	handler  drpc.Handler
+	verifier *Verifier
+}
+
+func (h jwtHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	ctx := stream.Context()
+	metadata, _ := drpcmetadata.Get(ctx)
+	value, ok := metadata[MetadataKey]
+	if !ok {
+		return drpcerr.WithCode(Error.New("missing token"), drpcerr.Unauthenticated)
+	}
+	token := strings.TrimPrefix(value, "Bearer ")
+	if token == value {
+		return drpcerr.WithCode(Error.New("authorization is not a bearer token"), drpcerr.Unauthenticated)
+	}
+	claims, err := h.verifier.Verify(ctx, token)
+	if err != nil {
+		return drpcerr.WithCode(err, drpcerr.Unauthenticated)
+	}
+	return h.handler.HandleRPC(claimsStream{
+		Stream: stream,
+		ctx:    context.WithValue(ctx, claimsKey{}, claims),
+	}, rpc)
+}
+
+// claimsStream is a stream whose context carries the claims.
+type claimsStream struct {
+	drpc.Stream
+	ctx context.Context
+}
+
+func (s claimsStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,224 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpctest"
+)
+
+func TestVerifier(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	set := &testKeySet{}
+	set.set(map[string]interface{}{"ec": &ecKey.PublicKey, "rsa": &rsaKey.PublicKey})
+	srv := httptest.NewServer(set)
+	defer srv.Close()
+
+	clock := drpcclock.NewFake(time.Unix(1e9, 0))
+	keys := NewJWKS(srv.URL, JWKSOptions{Clock: clock})
+	v := NewVerifier(keys, Options{Issuer: "issuer", Audience: "drpc", Clock: clock})
+	ctx := context.Background()
+
+	claims := map[string]interface{}{
+		"sub": "alice",
+		"iss": "issuer",
+		"aud": []string{"other", "drpc"},
+		"exp": clock.Now().Add(time.Hour).Unix(),
+	}
+
+	for _, tok := range []string{
+		testToken(t, "ES256", "ec", ecKey, claims),
+		testToken(t, "RS256", "rsa", rsaKey, claims),
+		testToken(t, "PS256", "rsa", rsaKey, claims),
+	} {
+		got, err := v.Verify(ctx, tok)
+		assert.NoError(t, err)
+		assert.Equal(t, got.Subject(), "alice")
+		assert.DeepEqual(t, got.Audience(), []string{"other", "drpc"})
+	}
+	assert.Equal(t, set.fetches.Load(), int32(1))
+
+	// mismatched algorithms, tampered tokens, and bad claims are rejected
+	_, err = v.Verify(ctx, testToken(t, "RS256", "ec", ecKey, claims))
+	assert.Error(t, err)
+	tok := testToken(t, "ES256", "ec", ecKey, claims)
+	_, err = v.Verify(ctx, tok[:len(tok)-4]+"AAAA")
+	assert.Error(t, err)
+	for name, value := range map[string]interface{}{"iss": "other", "aud": "other", "exp": nil} {
+		bad := copyClaims(claims)
+		bad[name] = value
+		if value == nil {
+			delete(bad, name)
+		}
+		_, err = v.Verify(ctx, testToken(t, "ES256", "ec", ecKey, bad))
+		assert.Error(t, err)
+	}
+
+	// expiry is checked with the leeway
+	clock.Advance(time.Hour + DefaultLeeway/2)
+	_, err = v.Verify(ctx, tok)
+	assert.NoError(t, err)
+	clock.Advance(DefaultLeeway)
+	_, err = v.Verify(ctx, tok)
+	assert.Error(t, err)
+
+	// a rotated key is fetched once, and unknown keys are not fetched again
+	// before the MinRefreshInterval
+	fetches := set.fetches.Load()
+	set.set(map[string]interface{}{"ec": &ecKey.PublicKey, "ed": edPub})
+	claims["exp"] = clock.Now().Add(time.Hour).Unix()
+	_, err = v.Verify(ctx, testToken(t, "EdDSA", "ed", edKey, claims))
+	assert.NoError(t, err)
+	_, err = v.Verify(ctx, testToken(t, "EdDSA", "missing", edKey, claims))
+	assert.Error(t, err)
+	assert.Equal(t, set.fetches.Load(), fetches+1)
+
+	// failed fetches keep the known keys, and are not retried for every token
+	set.fail.Store(true)
+	clock.Advance(DefaultRefreshInterval)
+	claims["exp"] = clock.Now().Add(time.Hour).Unix()
+	for i := 0; i < 2; i++ {
+		_, err = v.Verify(ctx, testToken(t, "ES256", "ec", ecKey, claims))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, set.fetches.Load(), fetches+2)
+}
+
+func TestHandler(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	set := &testKeySet{}
+	set.set(map[string]interface{}{"ec": &key.PublicKey})
+	hs := httptest.NewServer(set)
+	defer hs.Close()
+
+	received := make(chan string, 1)
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		claims, _ := ClaimsFromContext(ctx)
+		received <- claims.Subject()
+		return in, nil
+	}), NewVerifier(NewJWKS(hs.URL, JWKSOptions{}), Options{})))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	tok := testToken(t, "ES256", "ec", key, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	in, out := "hello", ""
+	assert.NoError(t, cc.Invoke(drpcmetadata.Add(ctx, MetadataKey, "Bearer "+tok), "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, <-received, "alice")
+
+	err = cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out)
+	assert.Error(t, err)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Unauthenticated)
+}
+
+// testToken returns a token of the claims signed by the key.
+func testToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		assert.NoError(t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case *rsa.PrivateKey:
+		if alg == "PS256" {
+			sig, err = rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], nil)
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		}
+		assert.NoError(t, err)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, []byte(input))
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func copyClaims(claims map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(claims))
+	for k, v := range claims {
+		out[k] = v
+	}
+	return out
+}
+
+// testKeySet serves a JWKS endpoint.
+type testKeySet struct {
+	mu      sync.Mutex
+	keys    []map[string]string
+	fetches atomic.Int32
+	fail    atomic.Bool
+}
+
+func (s *testKeySet) set(keys map[string]interface{}) {
+	enc := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+
+	var jwks []map[string]string
+	for kid, key := range keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			jwks = append(jwks, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": enc(key.X), "y": enc(key.Y)})
+		case *rsa.PublicKey:
+			jwks = append(jwks, map[string]string{"kty": "RSA", "kid": kid, "n": enc(key.N), "e": enc(big.NewInt(int64(key.E)))})
+		case ed25519.PublicKey:
+			jwks = append(jwks, map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": base64.RawURLEncoding.EncodeToString(key)})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = jwks
+}
+
+func (s *testKeySet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.fetches.Add(1)
+	if s.fail.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+}
@@ -0,0 +1,10 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpclog logs the messages of rpcs through client interceptors and a
+// server handler, redacting sensitive values so that keys and personal data
+// never land in logs while the structure of the messages remains visible.
+// Protobuf fields are redacted if they carry the debug_redact field option or
+// match a custom predicate, and other messages redact themselves by
+// implementing Redactor.
+package drpclog
@@ -0,0 +1,189 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpclog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+)
+
+// Direction is the direction a logged message travels in.
+type Direction int
+
+const (
+	// Request is a message sent by the client to the server.
+	Request Direction = iota
+
+	// Response is a message sent by the server to the client.
+	Response
+)
+
+// String returns "request" or "response".
+func (d Direction) String() string {
+	if d == Request {
+		return "request"
+	}
+	return "response"
+}
+
+// Entry is a logged message of an rpc, or the error the rpc failed with.
+type Entry struct {
+	// RPC is the name of the rpc.
+	RPC string
+
+	// Direction is the direction of the message.
+	Direction Direction
+
+	// Message is the message formatted with its sensitive values redacted.
+	// It is empty for errors.
+	Message string
+
+	// Err is the error the rpc failed with, if any.
+	Err error
+}
+
+// Options configures a Logger.
+type Options struct {
+	// Log is called with every entry. It defaults to printing the entry with
+	// the log package.
+	Log func(ctx context.Context, entry Entry)
+
+	// Redact returns true if the value of the protobuf field is sensitive.
+	// Fields carrying the debug_redact option are always redacted.
+	Redact func(fd protoreflect.FieldDescriptor) bool
+}
+
+// Logger logs the messages of the rpcs through its interceptors and handlers
+// with their sensitive values redacted. Wrapping its interceptors with a
+// drpcsample.Sampler limits the logging to a fraction of the rpcs.
+type Logger struct {
+	log    func(ctx context.Context, entry Entry)
+	format formatter
+}
+
+// New returns a Logger configured by opts.
+func New(opts Options) *Logger {
+	l := &Logger{log: opts.Log, format: formatter{sensitive: DebugRedact}}
+	if l.log == nil {
+		l.log = printEntry
+	}
+	if redact := opts.Redact; redact != nil {
+		l.format.sensitive = func(fd protoreflect.FieldDescriptor) bool {
+			return DebugRedact(fd) || redact(fd)
+		}
+	}
+	return l
+}
+
+// printEntry prints the entry with the log package, along with the attributes
+// of the ClientConn of client rpcs.
+func printEntry(ctx context.Context, entry Entry) {
+	rpc := entry.RPC
+	if attrs, ok := drpcclient.AttributesFromContext(ctx); ok {
+		rpc += " [" + attrs.String() + "]"
+	}
+	if entry.Err != nil {
+		log.Printf("drpc %s %s: %v", rpc, entry.Direction, entry.Err)
+		return
+	}
+	log.Printf("drpc %s %s: %s", rpc, entry.Direction, entry.Message)
+}
+
+// Format returns the message as text with its sensitive values redacted
+// according to the Options of the Logger.
+func (l *Logger) Format(msg drpc.Message) string {
+	return l.format.format(msg)
+}
+
+// message logs the message of the rpc.
+func (l *Logger) message(ctx context.Context, rpc string, dir Direction, msg drpc.Message) {
+	l.log(ctx, Entry{RPC: rpc, Direction: dir, Message: l.format.format(msg)})
+}
+
+// failure logs the error of the rpc, if any. Ends of streams are not errors.
+func (l *Logger) failure(ctx context.Context, rpc string, dir Direction, err error) {
+	if err != nil && !errors.Is(err, io.EOF) {
+		l.log(ctx, Entry{RPC: rpc, Direction: dir, Err: err})
+	}
+}
+
+// UnaryInterceptor returns a UnaryClientInterceptor that logs the request and
+// the response or error of the unary rpcs through it.
+func (l *Logger) UnaryInterceptor() drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		l.message(ctx, rpc, Request, in)
+		err := next(ctx, rpc, enc, in, out, cc)
+		if err != nil {
+			l.failure(ctx, rpc, Response, err)
+		} else {
+			l.message(ctx, rpc, Response, out)
+		}
+		return err
+	}
+}
+
+// StreamInterceptor returns a StreamClientInterceptor that logs the messages
+// sent and received on the streams through it, and the errors they fail with.
+func (l *Logger) StreamInterceptor() drpcclient.StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, streamer drpcclient.Streamer) (drpc.Stream, error) {
+		stream, err := streamer(ctx, rpc, enc, cc)
+		if err != nil {
+			l.failure(ctx, rpc, Response, err)
+			return nil, err
+		}
+		return &logStream{Stream: stream, l: l, rpc: rpc, send: Request, recv: Response}, nil
+	}
+}
+
+// NewHandler returns a drpc.Handler that logs the messages received and sent
+// by the handler, and the error it returns.
+func (l *Logger) NewHandler(handler drpc.Handler) drpc.Handler {
+	return logHandler{handler: handler, l: l}
+}
+
+type logHandler struct {
+	handler drpc.Handler
+	l       *Logger
+}
+
+func (h logHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	err := h.handler.HandleRPC(&logStream{Stream: stream, l: h.l, rpc: rpc, send: Response, recv: Request}, rpc)
+	h.l.failure(stream.Context(), rpc, Response, err)
+	return err
+}
+
+// logStream logs the messages sent and received on a stream.
+type logStream struct {
+	drpc.Stream
+	l          *Logger
+	rpc        string
+	send, recv Direction
+}
+
+func (s *logStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	err := s.Stream.MsgSend(msg, enc)
+	if err != nil {
+		s.l.failure(s.Context(), s.rpc, s.send, err)
+	} else {
+		s.l.message(s.Context(), s.rpc, s.send, msg)
+	}
+	return err
+}
+
+func (s *logStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	err := s.Stream.MsgRecv(msg, enc)
+	if err != nil {
+		s.l.failure(s.Context(), s.rpc, s.recv, err)
+	} else {
+		s.l.message(s.Context(), s.rpc, s.recv, msg)
+	}
+	return err
+}
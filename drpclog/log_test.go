@@ -0,0 +1,110 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpclog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zeebo/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+)
+
+// newLogin returns a message with a password field carrying the debug_redact
+// option, set in the unknown fields as older runtimes do not know it.
+func newLogin(t *testing.T) *dynamicpb.Message {
+	redact := new(descriptorpb.FieldOptions)
+	raw := protowire.AppendTag(nil, debugRedactField, protowire.VarintType)
+	redact.ProtoReflect().SetUnknown(protowire.AppendVarint(raw, 1))
+
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+	}
+	password := field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+	password.Options = redact
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("login.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Login"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("user", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+				password,
+				field("email", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+				field("scopes", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		}},
+	}, nil)
+	assert.NoError(t, err)
+
+	msg := dynamicpb.NewMessage(fd.Messages().Get(0))
+	set := func(name, value string) {
+		msg.Set(msg.Descriptor().Fields().ByName(protoreflect.Name(name)), protoreflect.ValueOfString(value))
+	}
+	set("user", "bert")
+	set("password", "hunter2")
+	set("email", "bert@example.com")
+	scopes := msg.Mutable(msg.Descriptor().Fields().ByName("scopes")).List()
+	scopes.Append(protoreflect.ValueOfString("read"))
+	scopes.Append(protoreflect.ValueOfString("write"))
+	return msg
+}
+
+func TestFormat(t *testing.T) {
+	login := newLogin(t)
+	assert.Equal(t, Format(login), `{user:"bert" password:[REDACTED] email:"bert@example.com" scopes:["read" "write"]}`)
+
+	// custom predicates redact more fields.
+	l := New(Options{Redact: func(fd protoreflect.FieldDescriptor) bool { return fd.Name() == "email" }})
+	assert.Equal(t, l.Format(login), `{user:"bert" password:[REDACTED] email:[REDACTED] scopes:["read" "write"]}`)
+
+	// messages redact themselves, and unknown ones only show their type.
+	assert.Equal(t, Format(token{"secret"}), `"tok_..."`)
+	assert.Equal(t, Format(&struct{ Key string }{"secret"}), "<*struct { Key string }>")
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	var entries []Entry
+	l := New(Options{Log: func(ctx context.Context, entry Entry) { entries = append(entries, entry) }})
+	interceptor := l.UnaryInterceptor()
+
+	login := newLogin(t)
+	assert.NoError(t, interceptor(context.Background(), "/Login", nil, login, token{"secret"}, nil,
+		func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn) error {
+			return nil
+		}))
+	failed := errors.New("denied")
+	assert.Equal(t, interceptor(context.Background(), "/Login", nil, login, nil, nil,
+		func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn) error {
+			return failed
+		}), failed)
+
+	assert.Equal(t, len(entries), 4)
+	assert.Equal(t, entries[0].Direction, Request)
+	assert.Equal(t, entries[0].Message, Format(login))
+	assert.Equal(t, entries[1], Entry{RPC: "/Login", Direction: Response, Message: `"tok_..."`})
+	assert.Equal(t, entries[3], Entry{RPC: "/Login", Direction: Response, Err: failed})
+}
+
+// token is a message that redacts itself.
+type token struct{ value string }
+
+func (t token) Redact() interface{} { return "tok_..." }
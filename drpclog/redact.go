@@ -0,0 +1,195 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpclog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"storj.io/drpc"
+)
+
+// Placeholder replaces the values of redacted fields.
+const Placeholder = "[REDACTED]"
+
+// debugRedactField is the number of the debug_redact field of the
+// google.protobuf.FieldOptions message.
+const debugRedactField = 16
+
+// Redactor is implemented by messages that redact themselves. Redact returns
+// the value to log in place of the message, which is formatted like a message
+// itself, so it may be a protobuf message with its sensitive fields cleared.
+type Redactor interface {
+	Redact() interface{}
+}
+
+// DebugRedact returns true if the field carries the debug_redact field
+// option, as in
+//
+//	string password = 1 [debug_redact = true];
+//
+// It recognizes the option even if the linked protobuf runtime predates it.
+func DebugRedact(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(protoreflect.ProtoMessage)
+	if !ok || opts == nil {
+		return false
+	}
+	m := opts.ProtoReflect()
+	if !m.IsValid() {
+		return false
+	}
+	if field := m.Descriptor().Fields().ByNumber(debugRedactField); field != nil {
+		return m.Get(field).Bool()
+	}
+
+	// the runtime does not know the option, so look for it in the unknown
+	// fields, where the last occurrence wins.
+	redact, b := false, m.GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+		if num == debugRedactField && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return false
+			}
+			redact, b = v != 0, b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+	}
+	return redact
+}
+
+// formatter formats messages with their sensitive values redacted.
+type formatter struct {
+	sensitive func(fd protoreflect.FieldDescriptor) bool
+}
+
+// format returns the message as text with its sensitive values redacted.
+// Messages that are neither protobuf messages nor Redactors are formatted as
+// their type alone, since their sensitive values cannot be found.
+func (f formatter) format(msg interface{}) string {
+	for i := 0; i < 8; i++ {
+		r, ok := msg.(Redactor)
+		if !ok {
+			break
+		}
+		msg = r.Redact()
+	}
+
+	switch m := msg.(type) {
+	case nil:
+		return "<nil>"
+	case protoreflect.ProtoMessage:
+		return string(f.appendMessage(nil, m.ProtoReflect()))
+	case string:
+		return strconv.Quote(m)
+	default:
+		return fmt.Sprintf("<%T>", msg)
+	}
+}
+
+// appendMessage appends the populated fields of the message in the order
+// they are declared.
+func (f formatter) appendMessage(b []byte, m protoreflect.Message) []byte {
+	b = append(b, '{')
+	fields := m.Descriptor().Fields()
+	first := true
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		if !first {
+			b = append(b, ' ')
+		}
+		first = false
+
+		b = append(b, fd.Name()...)
+		b = append(b, ':')
+		if f.sensitive(fd) {
+			b = append(b, Placeholder...)
+			continue
+		}
+
+		v := m.Get(fd)
+		switch {
+		case fd.IsList():
+			list := v.List()
+			b = append(b, '[')
+			for j := 0; j < list.Len(); j++ {
+				if j > 0 {
+					b = append(b, ' ')
+				}
+				b = f.appendValue(b, fd, list.Get(j))
+			}
+			b = append(b, ']')
+
+		case fd.IsMap():
+			b = f.appendMap(b, fd, v.Map())
+
+		default:
+			b = f.appendValue(b, fd, v)
+		}
+	}
+	return append(b, '}')
+}
+
+// appendMap appends the entries of the map sorted by key.
+func (f formatter) appendMap(b []byte, fd protoreflect.FieldDescriptor, m protoreflect.Map) []byte {
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	b = append(b, '{')
+	for i, key := range keys {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = f.appendValue(b, fd.MapKey(), key.Value())
+		b = append(b, ':')
+		b = f.appendValue(b, fd.MapValue(), m.Get(key))
+	}
+	return append(b, '}')
+}
+
+// appendValue appends a single value of the field.
+func (f formatter) appendValue(b []byte, fd protoreflect.FieldDescriptor, v protoreflect.Value) []byte {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return f.appendMessage(b, v.Message())
+	case protoreflect.StringKind:
+		return strconv.AppendQuote(b, v.String())
+	case protoreflect.BytesKind:
+		return strconv.AppendQuote(b, string(v.Bytes()))
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return append(b, ev.Name()...)
+		}
+		return strconv.AppendInt(b, int64(v.Enum()), 10)
+	default:
+		return append(b, v.String()...)
+	}
+}
+
+// Format returns the message as text with the values of the protobuf fields
+// carrying the debug_redact option replaced by the Placeholder.
+func Format(msg drpc.Message) string {
+	return formatter{sensitive: DebugRedact}.format(msg)
+}
@@ -10,6 +10,8 @@ import (
 	"io"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -52,6 +54,14 @@ type Options struct {
 	// no timeout is used.
 	InactivityTimeout time.Duration
 
+	// ParkIdleReader, if true, stops reading from the transport while no
+	// stream is active instead of keeping a goroutine blocked reading from
+	// it, and resumes reading when the next stream starts. It is meant for
+	// processes holding many mostly idle client conns, where it cuts one
+	// goroutine and its read buffers per conn. While parked, the manager does
+	// not notice the remote closing the transport until the next stream.
+	ParkIdleReader bool
+
 	// Internal contains options that are for internal use only.
 	Internal drpcopts.Manager
 }
@@ -72,6 +82,13 @@ type Manager struct {
 	pdone   drpcsignal.Chan      // signals when a packets buffers can be reused
 	sfin    chan struct{}        // shared signal for stream finished
 	streams chan streamInfo      // channel to signal that a stream should start
+	flimit  atomic.Int64         // largest frame the remote accepts, if positive
+
+	rmu   sync.Mutex // protects rpark and rwait
+	rpark bool       // set while the reader is parked
+	rwait bool       // set while a server stream waits for an invoke
+
+	rcancel context.CancelFunc // cancels the context of memory reservations
 
 	sigs struct {
 		term   drpcsignal.Signal // set when the manager should start terminating
@@ -94,12 +111,19 @@ func New(tr drpc.Transport) *Manager {
 // NewWithOptions returns a new manager for the transport. It uses the provided
 // options to manage details of how it uses it.
 func NewWithOptions(tr drpc.Transport, opts Options) *Manager {
+	// memory reservations of the reader are abandoned once the manager is
+	// terminated.
+	rctx, rcancel := context.WithCancel(context.Background())
+	drpcopts.SetReaderContext(&opts.Reader.Internal, rctx)
+
 	m := &Manager{
 		tr:   tr,
 		wr:   drpcwire.NewWriter(tr, opts.WriterBufferSize),
 		rd:   drpcwire.NewReaderWithOptions(tr, opts.Reader),
 		opts: opts,
 
+		rcancel: rcancel,
+
 		pkts:    make(chan drpcwire.Packet),
 		sfin:    make(chan struct{}, 1),
 		streams: make(chan streamInfo),
@@ -198,8 +222,48 @@ func (m *Manager) waitForPreviousStream(ctx context.Context) (err error) {
 func (m *Manager) terminate(err error) {
 	if m.sigs.term.Set(err) {
 		m.log("TERM", func() string { return fmt.Sprint(err) })
+		m.rcancel()
 		m.sigs.tport.Set(m.tr.Close())
 		m.sbuf.Close()
+
+		// a parked reader will never read again, so it is done.
+		m.rmu.Lock()
+		if m.rpark {
+			m.sigs.read.Set(nil)
+		}
+		m.rmu.Unlock()
+	}
+}
+
+// parkReader returns true if the reader should park because ParkIdleReader is
+// set and no stream needs it, marking it as parked.
+func (m *Manager) parkReader() bool {
+	if !m.opts.ParkIdleReader {
+		return false
+	}
+
+	m.rmu.Lock()
+	defer m.rmu.Unlock()
+
+	if m.sigs.term.IsSet() || m.rwait {
+		return false
+	} else if curr := m.sbuf.Get(); curr != nil && !curr.IsTerminated() {
+		return false
+	}
+	m.rpark = true
+	m.rd.Release()
+	return true
+}
+
+// unparkReader starts reading from the transport again if the reader is
+// parked.
+func (m *Manager) unparkReader() {
+	m.rmu.Lock()
+	defer m.rmu.Unlock()
+
+	if m.rpark && !m.sigs.term.IsSet() {
+		m.rpark = false
+		go m.manageReader()
 	}
 }
 
@@ -210,15 +274,27 @@ func (m *Manager) terminate(err error) {
 // manageReader is always reading a packet and dispatching it to the appropriate
 // stream or queue. It sets the read signal when it exits so that one can wait
 // to ensure that no one is reading on the reader. It sets the term signal if
-// there is any error reading packets.
+// there is any error reading packets. It exits without setting the read signal
+// if it parks, and is started again when it is unparked.
 func (m *Manager) manageReader() {
-	defer m.sigs.read.Set(nil)
+	parked := false
+	defer func() {
+		if !parked {
+			m.rd.Release()
+			m.sigs.read.Set(nil)
+		}
+	}()
 
 	var pkt drpcwire.Packet
 	var err error
 	var run int
 
 	for !m.sigs.term.IsSet() {
+		if m.parkReader() {
+			parked = true
+			return
+		}
+
 		// if we have a run of "small" packets, drop the buffer to release
 		// memory so that a burst of large packets does not cause eternally
 		// large heap usage.
@@ -294,6 +370,12 @@ func (m *Manager) manageReader() {
 // newStream creates a stream value with the appropriate configuration for this manager.
 func (m *Manager) newStream(ctx context.Context, sid uint64, kind, rpc string) (*drpcstream.Stream, error) {
 	opts := m.opts.Stream
+	if limit := int(m.flimit.Load()); limit > 0 {
+		switch n := opts.SplitSize; {
+		case n < 0, n > limit, n == 0 && limit < drpcwire.DefaultSplitSize:
+			opts.SplitSize = limit
+		}
+	}
 	drpcopts.SetStreamKind(&opts.Internal, kind)
 	drpcopts.SetStreamRPC(&opts.Internal, rpc)
 	if cb := drpcopts.GetManagerStatsCB(&m.opts.Internal); cb != nil {
@@ -404,6 +486,14 @@ func (m *Manager) Unblocked() <-chan struct{} {
 	return closedCh
 }
 
+// LimitFrameSize limits the frames written by streams created after it is
+// called to at most n bytes of data, such as to the maximum frame size the
+// remote advertised. It only ever lowers the configured split size. A
+// non-positive n removes the limit.
+func (m *Manager) LimitFrameSize(n int) {
+	m.flimit.Store(int64(n))
+}
+
 // Close closes the transport the manager is using.
 func (m *Manager) Close() error {
 	m.terminate(managerClosed.New("Close called"))
@@ -421,7 +511,11 @@ func (m *Manager) NewClientStream(ctx context.Context, rpc string) (stream *drpc
 		return nil, err
 	}
 
-	return m.newStream(ctx, m.sbuf.Get().ID()+1, "cli", rpc)
+	stream, err = m.newStream(ctx, m.sbuf.Get().ID()+1, "cli", rpc)
+	if err == nil {
+		m.unparkReader()
+	}
+	return stream, err
 }
 
 // NewServerStream starts a stream on the managed transport for use by a server.
@@ -437,6 +531,17 @@ func (m *Manager) NewServerStream(ctx context.Context) (stream *drpcstream.Strea
 		}
 	}()
 
+	// keep the reader running until the invoke arrives.
+	m.rmu.Lock()
+	m.rwait = true
+	m.rmu.Unlock()
+	defer func() {
+		m.rmu.Lock()
+		m.rwait = false
+		m.rmu.Unlock()
+	}()
+	m.unparkReader()
+
 	var meta map[string]string
 	var metaID uint64
 	var timeoutCh <-chan time.Time
@@ -87,6 +87,106 @@ func TestUnblocked_NoCancel(t *testing.T) {
 	ctx.Wait()
 }
 
+func TestParkIdleReader(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	cconn, sconn := net.Pipe()
+	defer func() { _ = sconn.Close() }()
+
+	cman := NewWithOptions(cconn, Options{ParkIdleReader: true})
+	sman := New(sconn)
+	defer func() { _ = sman.Close() }()
+
+	parked := func() bool {
+		for i := 0; i < 1000; i++ {
+			cman.rmu.Lock()
+			rpark := cman.rpark
+			cman.rmu.Unlock()
+			if rpark {
+				return true
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return false
+	}
+
+	handled := make(chan struct{})
+	ctx.Run(func(ctx context.Context) {
+		for {
+			stream, _, err := sman.NewServerStream(ctx)
+			if err != nil {
+				return
+			}
+			pkt, err := stream.RawRecv()
+			assert.NoError(t, err)
+			assert.NoError(t, stream.RawWrite(drpcwire.KindMessage, pkt))
+			assert.NoError(t, stream.Close())
+			handled <- struct{}{}
+		}
+	})
+
+	// the reader is parked until a stream starts, and parks again once the
+	// remote closes it
+	assert.That(t, parked())
+	for i := 0; i < 2; i++ {
+		stream, err := cman.NewClientStream(ctx, "rpc")
+		assert.NoError(t, err)
+		assert.NoError(t, stream.RawWrite(drpcwire.KindInvoke, []byte("rpc")))
+		assert.NoError(t, stream.RawWrite(drpcwire.KindMessage, []byte("message")))
+		assert.NoError(t, stream.RawFlush())
+
+		pkt, err := stream.RawRecv()
+		assert.NoError(t, err)
+		assert.Equal(t, string(pkt), "message")
+		_, err = stream.RawRecv()
+		assert.That(t, errors.Is(err, io.EOF))
+		<-handled
+		assert.That(t, parked())
+	}
+
+	// closing does not wait for a parked reader
+	assert.NoError(t, cman.Close())
+}
+
+func TestMemoryAccount(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	cconn, sconn := net.Pipe()
+	defer func() { _ = cconn.Close() }()
+
+	// the server cannot reserve any memory, so its reader blocks
+	mem := make(blockingAccount)
+	sman := NewWithOptions(sconn, Options{Reader: drpcwire.ReaderOptions{Memory: mem}})
+	cman := New(cconn)
+	defer func() { _ = cman.Close() }()
+
+	ctx.Run(func(ctx context.Context) {
+		stream, err := cman.NewClientStream(ctx, "rpc")
+		if err != nil {
+			return
+		}
+		_ = stream.RawWrite(drpcwire.KindInvoke, []byte("rpc"))
+		_ = stream.RawFlush()
+	})
+
+	<-mem
+
+	// until the manager is closed
+	assert.NoError(t, sman.Close())
+}
+
+type blockingAccount chan struct{}
+
+func (b blockingAccount) Reserve(ctx context.Context, n int) error {
+	b <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b blockingAccount) Release(n int) {}
+
 func TestUnblocked_SoftCancel(t *testing.T) {
 	run := func(t *testing.T, softCancel bool) {
 		ctx := drpctest.NewTracker(t)
@@ -0,0 +1,119 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmetadata
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+)
+
+// BinarySuffix marks the metadata keys whose values are binary. Following the
+// gRPC convention, their values are encoded with a BinaryCodec so that they
+// survive transports that only carry text, such as HTTP headers.
+const BinarySuffix = "-bin"
+
+// BinaryCodec converts binary metadata values to and from their string form.
+// The encodings of package encoding/base64 implement it.
+type BinaryCodec interface {
+	EncodeToString(src []byte) string
+	DecodeString(s string) ([]byte, error)
+}
+
+// Base64 is the default BinaryCodec. It encodes without padding, like gRPC,
+// and decodes values with or without padding.
+var Base64 BinaryCodec = base64Codec{}
+
+// Raw is a BinaryCodec that stores the bytes unchanged. It is the most
+// compact choice when every hop carries the metadata in drpc packets, which
+// are binary safe.
+var Raw BinaryCodec = rawCodec{}
+
+// BinaryKey returns the key with the BinarySuffix appended if it is missing.
+func BinaryKey(key string) string {
+	if strings.HasSuffix(key, BinarySuffix) {
+		return key
+	}
+	return key + BinarySuffix
+}
+
+// AddBinary associates the binary value with the key on the context, encoding
+// it with the codec. The BinarySuffix is appended to the key if it is missing.
+// A nil codec means Base64.
+func AddBinary(ctx context.Context, codec BinaryCodec, key string, value []byte) context.Context {
+	return Add(ctx, BinaryKey(key), orBase64(codec).EncodeToString(value))
+}
+
+// GetBinary returns the binary value associated with the key on the context,
+// decoding it with the codec. The BinarySuffix is appended to the key if it is
+// missing. A nil codec means Base64.
+func GetBinary(ctx context.Context, codec BinaryCodec, key string) (value []byte, ok bool, err error) {
+	metadata, _ := Get(ctx)
+	encoded, ok := metadata[BinaryKey(key)]
+	if !ok {
+		return nil, false, nil
+	}
+	value, err = orBase64(codec).DecodeString(encoded)
+	if err != nil {
+		return nil, false, errs.Wrap(err)
+	}
+	return value, true, nil
+}
+
+// AddMessage marshals the message with the encoding and associates it with
+// the binary key on the context, so that interceptors can attach structured
+// values such as proto encoded claims.
+func AddMessage(ctx context.Context, codec BinaryCodec, key string, enc drpc.Encoding, msg drpc.Message) (context.Context, error) {
+	data, err := enc.Marshal(msg)
+	if err != nil {
+		return ctx, errs.Wrap(err)
+	}
+	return AddBinary(ctx, codec, key, data), nil
+}
+
+// GetMessage unmarshals the value associated with the binary key on the
+// context into the message with the encoding. It returns false if the key is
+// absent.
+func GetMessage(ctx context.Context, codec BinaryCodec, key string, enc drpc.Encoding, msg drpc.Message) (bool, error) {
+	data, ok, err := GetBinary(ctx, codec, key)
+	if !ok || err != nil {
+		return false, err
+	}
+	if err := enc.Unmarshal(data, msg); err != nil {
+		return false, errs.Wrap(err)
+	}
+	return true, nil
+}
+
+// orBase64 returns the codec, or Base64 if it is nil.
+func orBase64(codec BinaryCodec) BinaryCodec {
+	if codec == nil {
+		return Base64
+	}
+	return codec
+}
+
+// base64Codec encodes standard base64 without padding and decodes either.
+type base64Codec struct{}
+
+func (base64Codec) EncodeToString(src []byte) string {
+	return base64.RawStdEncoding.EncodeToString(src)
+}
+
+func (base64Codec) DecodeString(s string) ([]byte, error) {
+	if strings.HasSuffix(s, "=") {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// rawCodec stores binary values unchanged.
+type rawCodec struct{}
+
+func (rawCodec) EncodeToString(src []byte) string      { return string(src) }
+func (rawCodec) DecodeString(s string) ([]byte, error) { return []byte(s), nil }
@@ -0,0 +1,29 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmetadata
+
+import (
+	"strconv"
+	"time"
+)
+
+// Deadline carries the deadline of the caller's context so that servers can
+// abandon work the client no longer waits for. The value is formatted with
+// FormatDeadline.
+var Deadline = Key{Name: "deadline", Since: 2}
+
+// FormatDeadline formats the deadline as decimal nanoseconds since the Unix
+// epoch.
+func FormatDeadline(deadline time.Time) string {
+	return strconv.FormatInt(deadline.UnixNano(), 10)
+}
+
+// ParseDeadline parses a deadline formatted by FormatDeadline.
+func ParseDeadline(value string) (time.Time, error) {
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}
@@ -66,3 +66,12 @@ func Get(ctx context.Context) (map[string]string, bool) {
 	metadata, ok := ctx.Value(metadataKey{}).(map[string]string)
 	return metadata, ok
 }
+
+// Size returns the total number of bytes in the keys and values of the
+// metadata.
+func Size(metadata map[string]string) (n int) {
+	for key, value := range metadata {
+		n += len(key) + len(value)
+	}
+	return n
+}
@@ -6,8 +6,12 @@ package drpcmetadata
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpccache"
+	"storj.io/drpc/drpcenc"
 )
 
 func TestAddGet(t *testing.T) {
@@ -45,6 +49,11 @@ func TestAddGet(t *testing.T) {
 	}
 }
 
+func TestSize(t *testing.T) {
+	assert.Equal(t, Size(nil), 0)
+	assert.Equal(t, Size(map[string]string{"foo": "bar", "a": ""}), 7)
+}
+
 func TestEncode(t *testing.T) {
 	t.Run("Empty Metadata", func(t *testing.T) {
 		var metadata map[string]string
@@ -77,3 +86,107 @@ func TestDecode(t *testing.T) {
 		assert.DeepEqual(t, metadata, map[string]string{"test": "a"})
 	})
 }
+
+func TestNamespacedKeys(t *testing.T) {
+	deadline := Key{Name: "deadline", Since: 1}
+	baggage := Key{Name: "baggage", Since: 2}
+
+	assert.Equal(t, deadline.String(), "drpc-deadline")
+	assert.That(t, baggage.SupportedBy(0))
+	assert.That(t, baggage.SupportedBy(2))
+	assert.That(t, !baggage.SupportedBy(1))
+
+	ctx := Add(context.Background(), deadline.String(), "10ms")
+	{
+		value, ok := Lookup(ctx, deadline)
+		assert.That(t, ok)
+		assert.Equal(t, value, "10ms")
+	}
+	{
+		_, ok := Lookup(ctx, baggage)
+		assert.That(t, !ok)
+	}
+
+	assert.DeepEqual(t, DropUnknown(map[string]string{
+		"drpc-deadline": "10ms",
+		"drpc-future":   "x",
+		"app":           "y",
+	}, deadline), map[string]string{
+		"drpc-deadline": "10ms",
+		"app":           "y",
+	})
+}
+
+func TestDropNewer(t *testing.T) {
+	ctx := drpccache.WithContext(context.Background(), drpccache.New())
+	ctx = Add(ctx, Deadline.String(), "10ms")
+	ctx = Add(ctx, "drpc-future", "x")
+	ctx = Add(ctx, "app", "y")
+
+	// peers of the same or an older version are passed through.
+	for _, version := range []int{0, Version} {
+		SetPeerVersion(ctx, version)
+		assert.Equal(t, DropNewer(ctx), ctx)
+	}
+
+	SetPeerVersion(ctx, Version+1)
+	metadata, _ := Get(DropNewer(ctx))
+	assert.DeepEqual(t, metadata, map[string]string{
+		"drpc-deadline": "10ms",
+		"app":           "y",
+	})
+
+	// the metadata of the original context is left alone.
+	metadata, _ = Get(ctx)
+	assert.Equal(t, metadata["drpc-future"], "x")
+}
+
+func TestDeadline(t *testing.T) {
+	deadline := time.Unix(1700000000, 123456789)
+
+	parsed, err := ParseDeadline(FormatDeadline(deadline))
+	assert.NoError(t, err)
+	assert.That(t, parsed.Equal(deadline))
+
+	_, err = ParseDeadline("soon")
+	assert.Error(t, err)
+}
+
+func TestBinary(t *testing.T) {
+	value := []byte{0, 1, 0xfe, 0xff}
+
+	for _, codec := range []BinaryCodec{nil, Base64, Raw} {
+		ctx := AddBinary(context.Background(), codec, "token", value)
+
+		got, ok, err := GetBinary(ctx, codec, "token-bin")
+		assert.NoError(t, err)
+		assert.That(t, ok)
+		assert.DeepEqual(t, got, value)
+	}
+
+	ctx := AddBinary(context.Background(), nil, "token", value)
+	metadata, _ := Get(ctx)
+	assert.Equal(t, metadata["token-bin"], "AAH+/w")
+
+	// padded values from other implementations are accepted
+	ctx = Add(context.Background(), "token-bin", "AAH+/w==")
+	got, ok, err := GetBinary(ctx, nil, "token")
+	assert.NoError(t, err)
+	assert.That(t, ok)
+	assert.DeepEqual(t, got, value)
+
+	_, ok, err = GetBinary(context.Background(), nil, "token")
+	assert.NoError(t, err)
+	assert.That(t, !ok)
+
+	_, _, err = GetBinary(Add(context.Background(), "token-bin", "!"), nil, "token")
+	assert.Error(t, err)
+
+	ctx, err = AddMessage(context.Background(), nil, "claims", drpcenc.Raw{}, &value)
+	assert.NoError(t, err)
+	var out []byte
+	ok, err = GetMessage(ctx, nil, "claims", drpcenc.Raw{}, &out)
+	assert.NoError(t, err)
+	assert.That(t, ok)
+	assert.DeepEqual(t, out, value)
+}
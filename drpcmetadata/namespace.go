@@ -0,0 +1,156 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmetadata
+
+import (
+	"context"
+	"strings"
+
+	"storj.io/drpc/drpccache"
+)
+
+// InterceptorPrefix namespaces the metadata keys added by built-in interceptors
+// so that they cannot collide with application metadata.
+const InterceptorPrefix = "drpc-"
+
+// Version is the interceptor metadata version implemented by this build. It is
+// incremented whenever a built-in interceptor starts sending a new Key.
+const Version = 10
+
+// ResumeToken carries the resume token of a reopened resumable stream so that
+// the server can continue from where the previous stream left off.
+var ResumeToken = Key{Name: "resume-token", Since: 1}
+
+// Baggage carries the W3C Baggage of the caller, as formatted by
+// drpcbaggage, so that request scoped attributes flow across hops.
+var Baggage = Key{Name: "baggage", Since: 3}
+
+// Signature carries the request signature added by drpcsign so that servers
+// can verify the authenticity of each request.
+var Signature = Key{Name: "signature", Since: 4}
+
+// IdempotencyKey carries the key that identifies a logical unary call across
+// its retries, so that servers using drpcidempotency execute it only once.
+var IdempotencyKey = Key{Name: "idempotency-key", Since: 5}
+
+// Priority carries the priority of a call, as formatted by drpcpriority, so
+// that servers shed low priority work first when overloaded.
+var Priority = Key{Name: "priority", Since: 6}
+
+// Negotiate carries the SPNEGO token added by drpcspnego so that servers can
+// establish the Kerberos security context of the conn.
+var Negotiate = Key{Name: "negotiate", Since: 7}
+
+// IfMatch carries the version of a resource a call expects, as sent by
+// drpcconditional, so that servers reject the call if the resource changed.
+var IfMatch = Key{Name: "if-match", Since: 8}
+
+// ServerTime is sent by servers in the trailer of a response, formatted by
+// time.Duration.String, with how long they spent handling the rpc.
+var ServerTime = Key{Name: "server-time", Since: 9}
+
+// QueueTime is sent by servers in the trailer of a response, formatted by
+// time.Duration.String, with how much of the ServerTime the rpc spent waiting
+// in queues before its handler ran.
+var QueueTime = Key{Name: "queue-time", Since: 10}
+
+// known are the keys known to this build.
+var known = []Key{
+	ResumeToken, Deadline, Baggage, Signature, IdempotencyKey,
+	Priority, Negotiate, IfMatch, ServerTime, QueueTime,
+}
+
+// Key is a metadata key added by a built-in interceptor.
+type Key struct {
+	// Name is the key without the InterceptorPrefix.
+	Name string
+
+	// Since is the Version that introduced the key. Peers running an older
+	// Version do not understand it, so clients gated on that version drop it.
+	Since int
+}
+
+// String returns the namespaced form of the key that is sent on the wire.
+func (k Key) String() string { return InterceptorPrefix + k.Name }
+
+// SupportedBy returns true if a peer running the given Version understands the
+// key. A non-positive version means the peer version is unknown and every key
+// is assumed to be supported.
+func (k Key) SupportedBy(version int) bool {
+	return version <= 0 || k.Since <= version
+}
+
+// Lookup returns the value associated with the key on the context. Absence is
+// not an error so that handlers tolerate peers that run older builds.
+func Lookup(ctx context.Context, k Key) (string, bool) {
+	metadata, ok := Get(ctx)
+	if !ok {
+		return "", false
+	}
+	value, ok := metadata[k.String()]
+	return value, ok
+}
+
+// DropUnknown returns a copy of the metadata without the namespaced keys that
+// are not in known. Keys outside of the InterceptorPrefix namespace belong to
+// the application and are always kept.
+func DropUnknown(metadata map[string]string, known ...Key) map[string]string {
+	out := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if strings.HasPrefix(key, InterceptorPrefix) && !isKnown(key, known) {
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// isKnown returns true if key is the namespaced form of one of the known keys.
+func isKnown(key string, known []Key) bool {
+	for _, k := range known {
+		if k.String() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// peerVersionKey is the drpccache key for the Version negotiated by the client
+// of a conn.
+type peerVersionKey struct{}
+
+// SetPeerVersion records the Version the client of the conn of the rpc whose
+// handler was passed the context negotiated, such as with the
+// drpcfeatures.MetadataVersion feature, in the drpccache of the conn. It does
+// nothing if the context has no drpccache.
+func SetPeerVersion(ctx context.Context, version int) {
+	if cache := drpccache.FromContext(ctx); cache != nil {
+		cache.Store(peerVersionKey{}, version)
+	}
+}
+
+// PeerVersion returns the Version recorded with SetPeerVersion for the conn of
+// the rpc whose handler was passed the context, or zero if none was.
+func PeerVersion(ctx context.Context) int {
+	if cache := drpccache.FromContext(ctx); cache != nil {
+		version, _ := cache.Load(peerVersionKey{}).(int)
+		return version
+	}
+	return 0
+}
+
+// DropNewer returns the context with the namespaced keys this build does not
+// know removed from its metadata if the client of its conn negotiated a newer
+// Version, so that handlers do not act on keys added after this build during
+// a rolling upgrade. Otherwise the context is returned unchanged.
+func DropNewer(ctx context.Context) context.Context {
+	if PeerVersion(ctx) <= Version {
+		return ctx
+	}
+	metadata, ok := Get(ctx)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, metadataKey{}, DropUnknown(metadata, known...))
+}
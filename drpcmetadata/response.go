@@ -0,0 +1,62 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmetadata
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc/drpcctx"
+)
+
+// SendHeader sends the metadata to the client as the header of the response of
+// the rpc whose handler was passed the context. It returns an error if the
+// context does not belong to an rpc served by a drpc server.
+func SendHeader(ctx context.Context, metadata map[string]string) error {
+	rw, ok := drpcctx.Response(ctx)
+	if !ok {
+		return errs.New("context has no response")
+	}
+	return rw.SendHeader(metadata)
+}
+
+// SetTrailer adds the metadata to the trailer of the response of the rpc whose
+// handler was passed the context, which is sent to the client when the rpc
+// ends. It returns an error if the context does not belong to an rpc served by
+// a drpc server.
+func SetTrailer(ctx context.Context, metadata map[string]string) error {
+	rw, ok := drpcctx.Response(ctx)
+	if !ok {
+		return errs.New("context has no response")
+	}
+	rw.SetTrailer(metadata)
+	return nil
+}
+
+// Response is the metadata a server sent about the response of an rpc.
+type Response struct {
+	// Header is the metadata sent before the response messages.
+	Header map[string]string
+
+	// Trailer is the metadata sent when the rpc ended.
+	Trailer map[string]string
+}
+
+type responseKey struct{}
+
+// WithResponse returns a context that records the metadata of the response of
+// the unary rpc issued with it into resp. Conns that support it, such as a
+// *drpcconn.Conn, then wait for the end of the rpc after receiving the
+// response so that the trailer is complete.
+func WithResponse(ctx context.Context, resp *Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, resp)
+}
+
+// ResponseFrom returns the *Response the rpc issued with the context records
+// its response metadata into, if any.
+func ResponseFrom(ctx context.Context) (*Response, bool) {
+	resp, ok := ctx.Value(responseKey{}).(*Response)
+	return resp, ok
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmetadata
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// QueueTimer accumulates the time an rpc spent waiting in server side queues,
+// such as the one of a drpcadmission.Controller, so that the server can report
+// it in the QueueTime trailer. It is safe for concurrent use.
+type QueueTimer struct {
+	total int64
+}
+
+// Add adds the duration to the time spent waiting.
+func (t *QueueTimer) Add(d time.Duration) {
+	if t != nil && d > 0 {
+		atomic.AddInt64(&t.total, int64(d))
+	}
+}
+
+// Total returns the time spent waiting so far.
+func (t *QueueTimer) Total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&t.total))
+}
+
+type queueTimerKey struct{}
+
+// WithQueueTimer returns a context whose rpc accumulates its queue time into
+// the QueueTimer.
+func WithQueueTimer(ctx context.Context, t *QueueTimer) context.Context {
+	return context.WithValue(ctx, queueTimerKey{}, t)
+}
+
+// AddQueueTime adds the duration to the QueueTimer of the rpc whose handler was
+// passed the context. It does nothing if the context has no QueueTimer, such as
+// when the server does not report the time it spends on rpcs.
+func AddQueueTime(ctx context.Context, d time.Duration) {
+	t, _ := ctx.Value(queueTimerKey{}).(*QueueTimer)
+	t.Add(d)
+}
@@ -0,0 +1,8 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcmuxext wraps an existing drpc.Handler, such as a *drpcmux.Mux,
+// with server side middleware, limits, panic recovery and metadata defaults,
+// so that servers using drpcserver or drpchttp can adopt them without changing
+// how handlers are registered.
+package drpcmuxext
@@ -0,0 +1,184 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmuxext
+
+import (
+	"context"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpcpriority"
+)
+
+// HandlerFunc adapts a function to a drpc.Handler.
+type HandlerFunc func(stream drpc.Stream, rpc string) error
+
+// HandleRPC calls the function.
+func (f HandlerFunc) HandleRPC(stream drpc.Stream, rpc string) error { return f(stream, rpc) }
+
+// Middleware wraps a drpc.Handler. Middleware that needs to change the context
+// seen by handlers should pass them a stream whose Context method returns the
+// new context, as WithStreamContext does.
+type Middleware func(next drpc.Handler) drpc.Handler
+
+// Option configures Wrap.
+type Option func(*options)
+
+type options struct {
+	mws             []Middleware
+	recover         bool
+	maxMetadataSize int
+	maxConcurrent   int
+	defaults        map[string]string
+	errorRules      []drpcerr.Rule
+}
+
+// WithMiddleware adds the middleware, with the first one being the outermost.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(opts *options) { opts.mws = append(opts.mws, mws...) }
+}
+
+// WithRecovery converts panics in handlers into errors returned to the client
+// with the gRPC INTERNAL code, instead of crashing the process.
+func WithRecovery() Option {
+	return func(opts *options) { opts.recover = true }
+}
+
+// WithMaxMetadataSize rejects rpcs whose metadata, counted as the total bytes
+// of its keys and values, is larger than n bytes.
+func WithMaxMetadataSize(n int) Option {
+	return func(opts *options) { opts.maxMetadataSize = n }
+}
+
+// WithMaxConcurrentRPCs rejects rpcs with the gRPC RESOURCE_EXHAUSTED code
+// while n rpcs are already being handled by the wrapped handler. Admission is
+// weighted by the drpcpriority of the rpcs, so that low priority rpcs are
+// rejected before the limit is reached.
+func WithMaxConcurrentRPCs(n int) Option {
+	return func(opts *options) { opts.maxConcurrent = n }
+}
+
+// WithMetadataDefaults adds the key value pairs to the metadata of rpcs that
+// do not already have the key.
+func WithMetadataDefaults(defaults map[string]string) Option {
+	return func(opts *options) {
+		if opts.defaults == nil {
+			opts.defaults = make(map[string]string, len(defaults))
+		}
+		for key, value := range defaults {
+			opts.defaults[key] = value
+		}
+	}
+}
+
+// WithErrorTranslation rewrites the errors returned by the handler with the
+// rules before they are sent to the client, for example to scrub internal
+// details with drpcerr.Scrub. The first rule that applies to an error wins.
+func WithErrorTranslation(rules ...drpcerr.Rule) Option {
+	return func(opts *options) { opts.errorRules = append(opts.errorRules, rules...) }
+}
+
+// Wrap returns a drpc.Handler that applies the options to every rpc before
+// passing it to the handler. Limits are checked first, then metadata defaults
+// are applied, then the middleware runs. Panics anywhere in the middleware or
+// handler are recovered if WithRecovery is passed. Errors are translated last,
+// including the ones of limits and recovered panics.
+func Wrap(handler drpc.Handler, opts ...Option) drpc.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	for i := len(o.mws) - 1; i >= 0; i-- {
+		handler = o.mws[i](handler)
+	}
+	if len(o.defaults) > 0 {
+		handler = metadataDefaults(o.defaults)(handler)
+	}
+	if o.maxMetadataSize > 0 {
+		handler = maxMetadataSize(o.maxMetadataSize)(handler)
+	}
+	if o.maxConcurrent > 0 {
+		handler = drpcpriority.NewHandler(handler, drpcpriority.NewLimiter(o.maxConcurrent))
+	}
+	if o.recover {
+		handler = recovery(handler)
+	}
+	if len(o.errorRules) > 0 {
+		handler = translateErrors(o.errorRules)(handler)
+	}
+	return handler
+}
+
+// WithStreamContext returns a stream that behaves like stream but whose
+// Context method returns ctx.
+func WithStreamContext(stream drpc.Stream, ctx context.Context) drpc.Stream {
+	return contextStream{Stream: stream, ctx: ctx}
+}
+
+type contextStream struct {
+	drpc.Stream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context { return s.ctx }
+
+// recovery converts panics in the handler into errors.
+func recovery(next drpc.Handler) drpc.Handler {
+	return HandlerFunc(func(stream drpc.Stream, rpc string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = drpcerr.WithCode(drpc.InternalError.New("panic handling %s: %v", rpc, r), drpcerr.Internal)
+			}
+		}()
+		return next.HandleRPC(stream, rpc)
+	})
+}
+
+// translateErrors rewrites the errors of the handler with the rules.
+func translateErrors(rules []drpcerr.Rule) Middleware {
+	return func(next drpc.Handler) drpc.Handler {
+		return HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			return drpcerr.Translate(rpc, next.HandleRPC(stream, rpc), rules)
+		})
+	}
+}
+
+// maxMetadataSize rejects rpcs whose metadata is larger than n bytes.
+func maxMetadataSize(n int) Middleware {
+	return func(next drpc.Handler) drpc.Handler {
+		return HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			metadata, _ := drpcmetadata.Get(stream.Context())
+			if size := drpcmetadata.Size(metadata); size > n {
+				return drpc.Error.New("metadata too large: %d > %d", size, n)
+			}
+			return next.HandleRPC(stream, rpc)
+		})
+	}
+}
+
+// metadataDefaults adds the defaults to the metadata of rpcs that lack them.
+func metadataDefaults(defaults map[string]string) Middleware {
+	return func(next drpc.Handler) drpc.Handler {
+		return HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			metadata, _ := drpcmetadata.Get(stream.Context())
+
+			var missing map[string]string
+			for key, value := range defaults {
+				if _, ok := metadata[key]; !ok {
+					if missing == nil {
+						missing = make(map[string]string, len(defaults))
+					}
+					missing[key] = value
+				}
+			}
+			if missing != nil {
+				ctx := drpcmetadata.AddPairs(stream.Context(), missing)
+				stream = WithStreamContext(stream, ctx)
+			}
+			return next.HandleRPC(stream, rpc)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcmuxext
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpcpriority"
+	"storj.io/drpc/drpctest"
+)
+
+func TestWrap(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next drpc.Handler) drpc.Handler {
+			return HandlerFunc(func(stream drpc.Stream, rpc string) error {
+				calls = append(calls, name)
+				return next.HandleRPC(stream, rpc)
+			})
+		}
+	}
+
+	var tenant string
+	handler := Wrap(HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		tenant, _ = drpcmetadata.Lookup(stream.Context(), drpcmetadata.Key{Name: "tenant"})
+		if rpc == "panic" {
+			panic("boom")
+		}
+		return nil
+	}),
+		WithMiddleware(record("first"), record("second")),
+		WithRecovery(),
+		WithMaxMetadataSize(32),
+		WithMetadataDefaults(map[string]string{"drpc-tenant": "default"}),
+	)
+
+	assert.NoError(t, handler.HandleRPC(drpctest.ContextStream(context.Background()), "rpc"))
+	assert.DeepEqual(t, calls, []string{"first", "second"})
+	assert.Equal(t, tenant, "default")
+
+	ctx := drpcmetadata.Add(context.Background(), "drpc-tenant", "acme")
+	assert.NoError(t, handler.HandleRPC(drpctest.ContextStream(ctx), "rpc"))
+	assert.Equal(t, tenant, "acme")
+
+	err := handler.HandleRPC(drpctest.ContextStream(context.Background()), "panic")
+	assert.That(t, strings.Contains(err.Error(), "panic handling panic: boom"))
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Internal)
+
+	ctx = drpcmetadata.Add(context.Background(), "baggage", strings.Repeat("x", 64))
+	assert.Error(t, handler.HandleRPC(drpctest.ContextStream(ctx), "rpc"))
+}
+
+func TestWrapErrorTranslation(t *testing.T) {
+	handler := Wrap(HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		panic("secret")
+	}), WithRecovery(), WithErrorTranslation(drpcerr.Scrub("internal error", 13)))
+
+	// recovered panics are translated too
+	err := handler.HandleRPC(drpctest.ContextStream(context.Background()), "rpc")
+	assert.Equal(t, err.Error(), "internal error")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Internal)
+}
+
+func TestWrapMaxConcurrentRPCs(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := Wrap(HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	}), WithMaxConcurrentRPCs(1))
+
+	errch := make(chan error, 1)
+	go func() { errch <- handler.HandleRPC(drpctest.ContextStream(context.Background()), "rpc") }()
+	<-entered
+
+	err := handler.HandleRPC(drpctest.ContextStream(context.Background()), "rpc")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.ResourceExhausted)
+
+	close(release)
+	assert.NoError(t, <-errch)
+}
+
+func TestWrapMaxConcurrentRPCsPriority(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := Wrap(HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	}), WithMaxConcurrentRPCs(2))
+
+	errch := make(chan error, 1)
+	go func() { errch <- handler.HandleRPC(drpctest.ContextStream(context.Background()), "rpc") }()
+	<-entered
+
+	// the second slot is reserved for higher priorities than low
+	low := drpcpriority.WithPriority(context.Background(), drpcpriority.Low)
+	err := handler.HandleRPC(drpctest.ContextStream(low), "rpc")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.ResourceExhausted)
+
+	close(release)
+	assert.NoError(t, <-errch)
+}
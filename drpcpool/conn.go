@@ -5,6 +5,7 @@ package drpcpool
 
 import (
 	"context"
+	"time"
 
 	"github.com/zeebo/errs"
 
@@ -54,14 +55,11 @@ func (p *poolConn[K, V]) Invoke(ctx context.Context, rpc string, enc drpc.Encodi
 		return errs.New("connection closed")
 	}
 
-	conn, ok := p.pool.Take(p.key)
-	if !ok {
-		conn, err = p.dial(ctx, p.key)
-		if err != nil {
-			return err
-		}
+	conn, err := p.checkout(ctx)
+	if err != nil {
+		return err
 	}
-	defer p.pool.Put(p.key, conn)
+	defer p.pool.put(p.key, conn, p.pool.priority(ctx))
 
 	return conn.Invoke(ctx, rpc, enc, in, out)
 }
@@ -76,17 +74,15 @@ func (p *poolConn[K, V]) NewStream(ctx context.Context, rpc string, enc drpc.Enc
 		return nil, errs.New("connection closed")
 	}
 
-	conn, ok := p.pool.Take(p.key)
-	if !ok {
-		conn, err = p.dial(ctx, p.key)
-		if err != nil {
-			return nil, err
-		}
+	conn, err := p.checkout(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	priority := p.pool.priority(ctx)
 	stream, err := conn.NewStream(ctx, rpc, enc)
 	if err != nil {
-		p.pool.Put(p.key, conn)
+		p.pool.put(p.key, conn, priority)
 		return nil, err
 	}
 
@@ -97,14 +93,40 @@ func (p *poolConn[K, V]) NewStream(ctx context.Context, rpc string, enc drpc.Enc
 		Stream: stream,
 		ctx:    streamWrapperContext{Context: ctx},
 	}
-	go p.monitorStream(stream, conn, &sw.ctx.done)
+	go p.monitorStream(stream, conn, priority, &sw.ctx.done)
 
 	return sw, nil
 }
 
-func (p *poolConn[K, V]) monitorStream(stream drpc.Stream, conn V, done *drpcsignal.Chan) {
+// checkout takes a healthy cached conn for the key from the Pool, or dials
+// one.
+func (p *poolConn[K, V]) checkout(ctx context.Context) (V, error) {
+	start := time.Now()
+	for {
+		conn, ok := p.pool.Take(p.key)
+		if !ok {
+			break
+		}
+		if err := p.pool.ping(ctx, p.key, conn); err != nil {
+			if ctx.Err() != nil {
+				return conn, err
+			}
+			continue
+		}
+		p.pool.checkedOut(p.key, start, true)
+		return conn, nil
+	}
+	conn, err := p.dial(ctx, p.key)
+	if err != nil {
+		return conn, err
+	}
+	p.pool.checkedOut(p.key, start, false)
+	return conn, nil
+}
+
+func (p *poolConn[K, V]) monitorStream(stream drpc.Stream, conn V, priority bool, done *drpcsignal.Chan) {
 	<-stream.Context().Done()
-	p.pool.Put(p.key, conn)
+	p.pool.put(p.key, conn, priority)
 	done.Close()
 }
 
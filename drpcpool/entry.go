@@ -5,15 +5,18 @@ package drpcpool
 
 import (
 	"fmt"
-	"time"
+
+	"storj.io/drpc/drpcclock"
 )
 
 type entry[K comparable, V Conn] struct {
-	key    K
-	val    V
-	exp    *time.Timer
-	global node[K, V]
-	local  node[K, V]
+	key K
+	val V
+	exp drpcclock.Timer
+	// borrowed is set for entries kept beyond the capacities.
+	borrowed bool
+	global   node[K, V]
+	local    node[K, V]
 }
 
 func (e *entry[K, V]) String() string {
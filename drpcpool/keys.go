@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcpool
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+)
+
+// KeyOptions override the options of a Pool for some keys. Zero fields use the
+// value of the Pool's Options.
+type KeyOptions struct {
+	// Capacity replaces KeyCapacity. Negative means no values.
+	Capacity int
+
+	// Expiration replaces Expiration.
+	Expiration time.Duration
+}
+
+// KeyRule applies KeyOptions to the keys matching a pattern.
+type KeyRule struct {
+	// Pattern is matched against the key formatted with fmt.Sprint using
+	// the syntax of path.Match, such as "us-east-1/*".
+	Pattern string
+
+	KeyOptions
+}
+
+// KeyPatterns returns a func for Options.KeyOptions that applies the options
+// of the first rule matching the key.
+func KeyPatterns(rules ...KeyRule) func(key interface{}) KeyOptions {
+	return func(key interface{}) KeyOptions {
+		name := fmt.Sprint(key)
+		for _, rule := range rules {
+			if ok, _ := path.Match(rule.Pattern, name); ok {
+				return rule.KeyOptions
+			}
+		}
+		return KeyOptions{}
+	}
+}
+
+// keyOptions returns the capacity and expiration of the key.
+func (p *Pool[K, V]) keyOptions(key K) (capacity int, expiration time.Duration) {
+	capacity, expiration = p.opts.KeyCapacity, p.opts.Expiration
+	if p.opts.KeyOptions == nil {
+		return capacity, expiration
+	}
+	ko := p.opts.KeyOptions(key)
+	if ko.Capacity != 0 {
+		capacity = ko.Capacity
+	}
+	if ko.Expiration != 0 {
+		expiration = ko.Expiration
+	}
+	return capacity, expiration
+}
+
+// priority returns true if the rpc with the context may borrow beyond the
+// capacities.
+func (p *Pool[K, V]) priority(ctx context.Context) bool {
+	return p.opts.Priority != nil && p.opts.Borrow > 0 && p.opts.Priority(ctx)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/zeebo/errs"
 
+	"storj.io/drpc/drpcclock"
 	"storj.io/drpc/drpcdebug"
 )
 
@@ -28,17 +29,68 @@ type Options struct {
 	// the Pool holds unlimited for any single key. Negative means
 	// no values for any single key.
 	KeyCapacity int
+
+	// KeyOptions, if set, returns options overriding KeyCapacity and
+	// Expiration for the key, for example with KeyPatterns to keep more
+	// conns to nodes in the local region.
+	KeyOptions func(key interface{}) KeyOptions
+
+	// Priority, if set, reports if the rpc with the context is high
+	// priority, for example with drpcpriority.FromContext. When a high
+	// priority rpc returns its value to a full cache, the value is kept
+	// beyond the capacities instead of evicting another, as long as fewer
+	// than Borrow values are borrowed. Borrowed values are evicted first
+	// once other values are returned, restoring the capacities.
+	Priority func(ctx context.Context) bool
+
+	// Borrow is the number of values high priority rpcs may keep beyond
+	// the capacities.
+	Borrow int
+
+	// Clock times the expiration of values. Nil means drpcclock.System.
+	Clock drpcclock.Clock
+
+	// Ping, if set, checks that a cached value is still healthy before a
+	// conn from Get uses it, for example with a cheap rpc. Values whose
+	// Ping fails are closed and evicted with EvictUnhealthy, and the next
+	// cached value is tried or a new one dialed, so that callers do not
+	// receive values that died while idle.
+	Ping func(ctx context.Context, conn Conn) error
+
+	// PingTimeout bounds each Ping. Zero means DefaultPingTimeout.
+	PingTimeout time.Duration
+
+	// OnCreate, if set, is called with the key of every value dialed by a
+	// conn from Get.
+	OnCreate func(key interface{})
+
+	// OnCheckout, if set, is called every time a conn from Get checks out a
+	// value for an rpc, with whether the value was reused from the cache
+	// and how long the checkout took, including dialing.
+	OnCheckout func(key interface{}, reused bool, latency time.Duration)
+
+	// OnEvict, if set, is called with the key of every value that leaves
+	// the cache without being taken and the reason why, after the Pool is
+	// unlocked. Frequent EvictExpired evictions followed by creations for
+	// the same keys mean the Expiration is too short for the traffic.
+	OnEvict func(key interface{}, reason EvictReason)
 }
 
+// DefaultPingTimeout is the PingTimeout used when it is zero.
+const DefaultPingTimeout = 100 * time.Millisecond
+
 // Pool is a connection pool with key type K. It maintains a cache of connections
 // per key and ensures the total number of connections in the cache is bounded by
 // configurable values. It does not limit the maximum concurrency of the number
 // of connections either in total or per key.
 type Pool[K comparable, V Conn] struct {
-	opts    Options
-	mu      sync.Mutex
-	entries map[K]*list[K, V]
-	order   list[K, V]
+	opts      Options
+	mu        sync.Mutex
+	entries   map[K]*list[K, V]
+	order     list[K, V]
+	stats     Stats
+	evictions []eviction
+	borrowed  int
 }
 
 // New constructs a new Pool with the provided Options.
@@ -59,15 +111,17 @@ func (p *Pool[K, V]) log(what string, cb func() string) {
 // of the combined errors from closing.
 func (p *Pool[K, V]) Close() (err error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	defer p.unlock()
 
 	var eg errs.Group
 	for ent := p.order.head; ent != nil; ent = ent.global.next {
 		eg.Add(p.closeEntry(ent))
+		p.evictedLocked(ent, EvictPoolClosed)
 	}
 
 	p.entries = make(map[K]*list[K, V])
 	p.order = list[K, V]{}
+	p.borrowed = 0
 
 	return eg.Err()
 }
@@ -88,18 +142,28 @@ func (p *Pool[K, V]) Get(ctx context.Context, key K,
 // helpers
 //
 
-func (p *Pool[K, V]) removeEntry(ent *entry[K, V]) {
+func (p *Pool[K, V]) removeEntry(ent *entry[K, V], reason EvictReason) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	defer p.unlock()
 
 	local := p.entries[ent.key]
 	if local == nil {
 		return
 	}
+	p.evictedLocked(ent, reason)
+	p.unlinkLocked(local, ent)
+}
 
+// unlinkLocked removes the entry from the lists, deleting the key's list if it
+// is empty. It must be called with p.mu held.
+func (p *Pool[K, V]) unlinkLocked(local *list[K, V], ent *entry[K, V]) {
 	local.removeEntry(ent, (*entry[K, V]).localList)
 	p.order.removeEntry(ent, (*entry[K, V]).globalList)
 
+	if ent.borrowed {
+		ent.borrowed = false
+		p.borrowed--
+	}
 	if local.count == 0 {
 		delete(p.entries, ent.key)
 	}
@@ -119,7 +183,7 @@ func (p *Pool[K, V]) closeEntry(ent *entry[K, V]) error {
 // the zero value for V and false if one does not.
 func (p *Pool[K, V]) Take(key K) (V, bool) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	defer p.unlock()
 
 	local := p.entries[key]
 	if local == nil {
@@ -135,12 +199,12 @@ func (p *Pool[K, V]) Take(key K) (V, bool) {
 			continue
 		}
 
-		local.removeEntry(ent, (*entry[K, V]).localList)
-		p.order.removeEntry(ent, (*entry[K, V]).globalList)
+		p.unlinkLocked(local, ent)
 
 		if ent.exp != nil && !ent.exp.Stop() {
 			continue
 		} else if closed(ent.val.Closed()) {
+			p.evictedLocked(ent, EvictClosed)
 			continue
 		}
 
@@ -153,8 +217,12 @@ func (p *Pool[K, V]) Take(key K) (V, bool) {
 
 // Put places the connection in to the cache with the provided key, ensuring
 // that the size limits the Pool is configured with are respected.
-func (p *Pool[K, V]) Put(key K, val V) {
-	if p.opts.Capacity < 0 || p.opts.KeyCapacity < 0 {
+func (p *Pool[K, V]) Put(key K, val V) { p.put(key, val, false) }
+
+// put is Put that lets high priority values borrow beyond the capacities.
+func (p *Pool[K, V]) put(key K, val V, priority bool) {
+	keyCapacity, expiration := p.keyOptions(key)
+	if p.opts.Capacity < 0 || keyCapacity < 0 {
 		_ = val.Close()
 		return
 	} else if closed(val.Closed()) {
@@ -162,7 +230,7 @@ func (p *Pool[K, V]) Put(key K, val V) {
 	}
 
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	defer p.unlock()
 
 	local := p.entries[key]
 	if local == nil {
@@ -170,38 +238,95 @@ func (p *Pool[K, V]) Put(key K, val V) {
 		p.entries[key] = local
 	}
 
-	for p.opts.KeyCapacity != 0 && local.count >= p.opts.KeyCapacity {
-		ent := local.head
+	full := (keyCapacity != 0 && local.count >= keyCapacity) ||
+		(p.opts.Capacity != 0 && p.order.count >= p.opts.Capacity)
+	borrow := full && priority && p.borrowed < p.opts.Borrow
 
-		_ = p.closeEntry(ent)
+	for !borrow && keyCapacity != 0 && local.count >= keyCapacity {
+		ent := p.oldestLocked(local.head, (*entry[K, V]).localList)
 
-		local.removeEntry(ent, (*entry[K, V]).localList)
-		p.order.removeEntry(ent, (*entry[K, V]).globalList)
+		_ = p.closeEntry(ent)
+		p.evictedLocked(ent, EvictKeyCapacity)
+		p.unlinkLocked(local, ent)
 	}
 
-	for p.opts.Capacity != 0 && p.order.count >= p.opts.Capacity {
-		ent := p.order.head
-		local := p.entries[ent.key]
+	for !borrow && p.opts.Capacity != 0 && p.order.count >= p.opts.Capacity {
+		ent := p.oldestLocked(p.order.head, (*entry[K, V]).globalList)
 
 		_ = p.closeEntry(ent)
+		p.evictedLocked(ent, EvictCapacity)
+		p.unlinkLocked(p.entries[ent.key], ent)
+	}
 
-		local.removeEntry(ent, (*entry[K, V]).localList)
-		p.order.removeEntry(ent, (*entry[K, V]).globalList)
-
-		if local.count == 0 {
-			delete(p.entries, ent.key)
-		}
+	if local.count == 0 {
+		// evicting may have deleted the key's list.
+		p.entries[key] = local
 	}
 
-	ent := &entry[K, V]{key: key, val: val}
+	ent := &entry[K, V]{key: key, val: val, borrowed: borrow}
 	local.appendEntry(ent, (*entry[K, V]).localList)
 	p.order.appendEntry(ent, (*entry[K, V]).globalList)
 	p.log("PUT", ent.String)
 
-	if p.opts.Expiration > 0 {
-		ent.exp = time.AfterFunc(p.opts.Expiration, func() {
+	if borrow {
+		p.borrowed++
+		p.stats.Borrows++
+	}
+
+	if expiration > 0 {
+		ent.exp = drpcclock.Or(p.opts.Clock).AfterFunc(expiration, func() {
 			_ = val.Close()
-			p.removeEntry(ent)
+			p.removeEntry(ent, EvictExpired)
 		})
 	}
 }
+
+// ping checks the conn taken for the key with the Ping option, if any. If the
+// ping fails because the context is done, the conn is put back. Otherwise a
+// failing conn is closed and evicted as unhealthy.
+func (p *Pool[K, V]) ping(ctx context.Context, key K, conn V) error {
+	if p.opts.Ping == nil {
+		return nil
+	}
+	timeout := p.opts.PingTimeout
+	if timeout <= 0 {
+		timeout = DefaultPingTimeout
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := p.opts.Ping(pctx, conn)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		p.Put(key, conn)
+		return ctx.Err()
+	}
+
+	p.log("UNHEALTHY", func() string { return fmt.Sprintf("<key %v err %v>", key, err) })
+	_ = conn.Close()
+
+	p.mu.Lock()
+	p.stats.Evictions[EvictUnhealthy]++
+	p.mu.Unlock()
+	if p.opts.OnEvict != nil {
+		p.opts.OnEvict(key, EvictUnhealthy)
+	}
+	return err
+}
+
+// oldestLocked returns the oldest borrowed entry of the list starting at head,
+// or head if none are borrowed, so that borrowed entries are evicted first. It
+// must be called with p.mu held.
+func (p *Pool[K, V]) oldestLocked(head *entry[K, V], node func(*entry[K, V]) *node[K, V]) *entry[K, V] {
+	if p.borrowed > 0 {
+		for ent := head; ent != nil; ent = node(ent).next {
+			if ent.borrowed {
+				return ent
+			}
+		}
+	}
+	return head
+}
@@ -5,12 +5,14 @@ package drpcpool
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/zeebo/assert"
 
 	"storj.io/drpc"
+	"storj.io/drpc/drpcclock"
 	"storj.io/drpc/drpctest"
 )
 
@@ -131,6 +133,181 @@ func TestPool_Expiration(t *testing.T) {
 	assert.Equal(t, <-closed, "key")
 }
 
+// TestPool_ExpirationClock checks that entries expire on the clock of the pool.
+func TestPool_ExpirationClock(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	clock := drpcclock.NewFake(time.Now())
+	closed := make(chan string, 1)
+	pool := New[string, Conn](Options{Expiration: time.Hour, Clock: clock})
+	defer func() { _ = pool.Close() }()
+
+	useConn(ctx, pool, closed, "key")
+	clock.Advance(time.Hour - time.Nanosecond)
+	assert.Equal(t, len(closed), 0)
+
+	clock.Advance(time.Nanosecond)
+	assert.Equal(t, len(closed), 1)
+	assert.Equal(t, <-closed, "key")
+}
+
+// TestPool_Stats checks that the callbacks are called and the metrics updated.
+func TestPool_Stats(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	var events []string
+	clock := drpcclock.NewFake(time.Now())
+	closed := make(chan string, 2)
+	pool := New[string, Conn](Options{
+		Capacity:   1,
+		Expiration: time.Hour,
+		Clock:      clock,
+		OnCreate:   func(key interface{}) { events = append(events, fmt.Sprint("create ", key)) },
+		OnCheckout: func(key interface{}, reused bool, latency time.Duration) {
+			events = append(events, fmt.Sprint("checkout ", key, " ", reused))
+		},
+		OnEvict: func(key interface{}, reason EvictReason) {
+			events = append(events, fmt.Sprint("evict ", key, " ", reason))
+		},
+	})
+	defer func() { _ = pool.Close() }()
+
+	conn := getConn(ctx, pool, closed, "key0")
+	invoke(ctx, conn)
+	invoke(ctx, conn)
+	assert.Equal(t, pool.Stats().Size, 1)
+
+	useConn(ctx, pool, closed, "key1")
+	clock.Advance(time.Hour)
+
+	assert.DeepEqual(t, events, []string{
+		"create key0",
+		"checkout key0 false",
+		"checkout key0 true",
+		"create key1",
+		"checkout key1 false",
+		"evict key0 capacity",
+		"evict key1 expired",
+	})
+
+	stats := pool.Stats()
+	assert.Equal(t, stats.Size, 0)
+	assert.Equal(t, stats.Created, uint64(2))
+	assert.Equal(t, stats.Reused, uint64(1))
+	assert.Equal(t, stats.Checkouts, uint64(3))
+	assert.Equal(t, stats.Evictions[EvictCapacity], uint64(1))
+	assert.Equal(t, stats.Evictions[EvictExpired], uint64(1))
+	assert.Equal(t, stats.TotalEvictions(), uint64(2))
+}
+
+// TestPool_Ping checks that cached values failing their ping are discarded.
+func TestPool_Ping(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	dead := &callbackConn{}
+	closed := make(chan string, 2)
+	dead.CloseFn = func() error { closed <- "dead"; return nil }
+	healthy := &callbackConn{}
+
+	var evicted []EvictReason
+	pool := New[string, Conn](Options{
+		Ping: func(ctx context.Context, conn Conn) error {
+			if conn == dead {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+		PingTimeout: time.Millisecond,
+		OnEvict:     func(key interface{}, reason EvictReason) { evicted = append(evicted, reason) },
+	})
+	defer func() { _ = pool.Close() }()
+
+	// a conn canceled while pinging puts the value back.
+	pool.Put("key", dead)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	conn := pool.Get(canceled, "key", func(ctx context.Context, key string) (Conn, error) {
+		t.Fatal("unexpected dial")
+		return nil, nil
+	})
+	assert.Error(t, conn.Invoke(canceled, "", nil, nil, nil))
+	assert.Equal(t, pool.Stats().Size, 1)
+
+	// the dead value is discarded and the healthy one used.
+	pool.Put("key", healthy)
+	var used Conn
+	healthy.InvokeFn = func(context.Context, string, drpc.Encoding, drpc.Message, drpc.Message) error {
+		used = healthy
+		return nil
+	}
+	conn = pool.Get(ctx, "key", func(ctx context.Context, key string) (Conn, error) {
+		t.Fatal("unexpected dial")
+		return nil, nil
+	})
+	invoke(ctx, conn)
+	assert.Equal(t, used, Conn(healthy))
+	assert.Equal(t, <-closed, "dead")
+	assert.DeepEqual(t, evicted, []EvictReason{EvictUnhealthy})
+	assert.Equal(t, pool.Stats().Evictions[EvictUnhealthy], uint64(1))
+}
+
+// TestPool_KeyOptions checks that key patterns override the key capacity.
+func TestPool_KeyOptions(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	pool := New[string, Conn](Options{
+		KeyCapacity: 1,
+		KeyOptions: KeyPatterns(
+			KeyRule{Pattern: "local/*", KeyOptions: KeyOptions{Capacity: 2}},
+			KeyRule{Pattern: "remote/*", KeyOptions: KeyOptions{Expiration: time.Hour}},
+		),
+	})
+	defer func() { _ = pool.Close() }()
+
+	for i := 0; i < 3; i++ {
+		pool.Put("local/a", new(callbackConn))
+		pool.Put("remote/a", new(callbackConn))
+	}
+	assert.Equal(t, pool.Stats().Size, 3)
+	assert.Equal(t, pool.entries["local/a"].count, 2)
+	assert.NotNil(t, pool.entries["remote/a"].head.exp)
+}
+
+// TestPool_Borrow checks that high priority values borrow beyond the capacity
+// and are evicted first.
+func TestPool_Borrow(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	closed := make(chan string, 3)
+	newConn := func(name string) Conn {
+		return &callbackConn{CloseFn: func() error { closed <- name; return nil }}
+	}
+	pool := New[string, Conn](Options{Capacity: 1, Borrow: 1})
+	defer func() { _ = pool.Close() }()
+
+	pool.put("key", newConn("a"), false)
+	pool.put("key", newConn("b"), true)
+	stats := pool.Stats()
+	assert.Equal(t, stats.Size, 2)
+	assert.Equal(t, stats.Borrowed, 1)
+	assert.Equal(t, stats.Borrows, uint64(1))
+	assert.Equal(t, len(closed), 0)
+
+	// no more borrowing is allowed
+	pool.put("key", newConn("c"), true)
+	assert.Equal(t, <-closed, "b")
+	assert.Equal(t, <-closed, "a")
+	stats = pool.Stats()
+	assert.Equal(t, stats.Size, 1)
+	assert.Equal(t, stats.Borrowed, 0)
+}
+
 // TestPool_Stale checks that the stale predicate is called on Take.
 func TestPool_Stale(t *testing.T) {
 	ctx := drpctest.NewTracker(t)
@@ -0,0 +1,159 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcpool
+
+import "time"
+
+// EvictReason is why a value left the Pool's cache without being taken.
+type EvictReason int
+
+const (
+	// EvictExpired is a value that was in the cache for the Expiration.
+	EvictExpired EvictReason = iota
+
+	// EvictCapacity is a value closed to respect the Capacity.
+	EvictCapacity
+
+	// EvictKeyCapacity is a value closed to respect the KeyCapacity.
+	EvictKeyCapacity
+
+	// EvictClosed is a value found closed when it was taken.
+	EvictClosed
+
+	// EvictPoolClosed is a value closed by closing the Pool.
+	EvictPoolClosed
+
+	// EvictUnhealthy is a value closed because its Ping failed when it was
+	// checked out.
+	EvictUnhealthy
+
+	numEvictReasons
+)
+
+// String returns a short name for the reason.
+func (r EvictReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "capacity"
+	case EvictKeyCapacity:
+		return "key capacity"
+	case EvictClosed:
+		return "closed"
+	case EvictPoolClosed:
+		return "pool closed"
+	case EvictUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats are the metrics of a Pool.
+type Stats struct {
+	// Size is the number of values currently cached, and Borrowed how many
+	// of them high priority rpcs keep beyond the capacities.
+	Size     int
+	Borrowed int
+
+	// Borrows counts the values high priority rpcs kept beyond the
+	// capacities.
+	Borrows uint64
+
+	// Created counts the values dialed by conns from Get, and Reused the
+	// checkouts that took a cached value instead.
+	Created uint64
+	Reused  uint64
+
+	// Evictions counts the values that left the cache without being taken,
+	// indexed by EvictReason.
+	Evictions [numEvictReasons]uint64
+
+	// Checkouts counts the values checked out by conns from Get, either
+	// taken from the cache or dialed. CheckoutTime is the total time the
+	// checkouts took, including dialing, and MaxCheckoutTime the longest.
+	Checkouts       uint64
+	CheckoutTime    time.Duration
+	MaxCheckoutTime time.Duration
+}
+
+// MeanCheckoutTime returns the average time a checkout took.
+func (s Stats) MeanCheckoutTime() time.Duration {
+	if s.Checkouts == 0 {
+		return 0
+	}
+	return s.CheckoutTime / time.Duration(s.Checkouts)
+}
+
+// TotalEvictions returns the number of evictions for every reason.
+func (s Stats) TotalEvictions() (n uint64) {
+	for _, count := range s.Evictions {
+		n += count
+	}
+	return n
+}
+
+// Stats returns the metrics of the Pool.
+func (p *Pool[K, V]) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Size, stats.Borrowed = p.order.count, p.borrowed
+	return stats
+}
+
+// eviction is an eviction waiting to be passed to the OnEvict callback.
+type eviction struct {
+	key    interface{}
+	reason EvictReason
+}
+
+// evictedLocked counts the eviction of the entry and queues it for the
+// OnEvict callback. It must be called with p.mu held.
+func (p *Pool[K, V]) evictedLocked(ent *entry[K, V], reason EvictReason) {
+	p.stats.Evictions[reason]++
+	if p.opts.OnEvict != nil {
+		p.evictions = append(p.evictions, eviction{key: ent.key, reason: reason})
+	}
+}
+
+// unlock unlocks p.mu and then calls the OnEvict callback with the queued
+// evictions, so that callbacks may use the Pool.
+func (p *Pool[K, V]) unlock() {
+	evictions := p.evictions
+	p.evictions = nil
+	p.mu.Unlock()
+
+	for _, ev := range evictions {
+		p.opts.OnEvict(ev.key, ev.reason)
+	}
+}
+
+// checkedOut records a checkout of a value for the key that started at start,
+// and calls the OnCreate and OnCheckout callbacks.
+func (p *Pool[K, V]) checkedOut(key K, start time.Time, reused bool) {
+	latency := time.Since(start)
+
+	p.mu.Lock()
+	if reused {
+		p.stats.Reused++
+	} else {
+		p.stats.Created++
+	}
+	p.stats.Checkouts++
+	p.stats.CheckoutTime += latency
+	if latency > p.stats.MaxCheckoutTime {
+		p.stats.MaxCheckoutTime = latency
+	}
+	p.mu.Unlock()
+
+	if !reused && p.opts.OnCreate != nil {
+		p.opts.OnCreate(key)
+	}
+	if p.opts.OnCheckout != nil {
+		p.opts.OnCheckout(key, reused, latency)
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcpriority tags calls with a priority that flows to servers in
+// metadata, and provides a Limiter that admits calls by priority so that low
+// priority work is shed or delayed first during overload, in the spirit of
+// admission control.
+package drpcpriority
@@ -0,0 +1,313 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcpriority
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+)
+
+// The shares of the limit of a Limiter that each priority may fill, so that
+// the remaining capacity is kept for higher priorities.
+const (
+	lowShare    = 0.5
+	normalShare = 0.9
+	highShare   = 1.0
+)
+
+// share returns the share of the limit the priority may fill.
+func share(p Priority) float64 {
+	switch {
+	case p < Normal:
+		return lowShare
+	case p > Normal:
+		return highShare
+	default:
+		return normalShare
+	}
+}
+
+// Limiter limits the number of calls in flight, weighting admission by
+// priority: Low calls are only admitted while less than half of the limit is
+// in use, Normal calls while less than 90% is, and High calls up to the full
+// limit. Calls waiting in Acquire are admitted highest priority first, and in
+// FIFO order within a priority. It is safe for concurrent use.
+type Limiter struct {
+	limit int
+
+	mu       sync.Mutex
+	inflight int
+	waiters  []*waiter
+	stats    LimiterStats
+}
+
+// LimiterStats are the metrics of a Limiter.
+type LimiterStats struct {
+	// InFlight and Queued are the calls currently admitted and waiting.
+	InFlight int
+	Queued   int
+
+	// Admitted counts the calls admitted, and Waited those of them that had
+	// to wait in Acquire.
+	Admitted uint64
+	Waited   uint64
+
+	// QueueTime is the total time admitted calls waited in Acquire, and
+	// MaxQueueTime the longest.
+	QueueTime    time.Duration
+	MaxQueueTime time.Duration
+}
+
+// MeanQueueTime returns the average time admitted calls that had to wait
+// spent waiting.
+func (s LimiterStats) MeanQueueTime() time.Duration {
+	if s.Waited == 0 {
+		return 0
+	}
+	return s.QueueTime / time.Duration(s.Waited)
+}
+
+// waiter is a call blocked in Acquire.
+type waiter struct {
+	p     Priority
+	ready chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing up to limit calls in flight.
+func NewLimiter(limit int) *Limiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &Limiter{limit: limit}
+}
+
+// capacity returns the number of calls in flight below which calls with the
+// priority are admitted. Every priority may use at least one slot.
+func (l *Limiter) capacity(p Priority) int {
+	c := int(math.Ceil(share(p) * float64(l.limit)))
+	if c < 1 {
+		c = 1
+	}
+	return c
+}
+
+// InFlight returns the number of calls admitted and not yet released.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inflight
+}
+
+// Stats returns the metrics of the Limiter.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.InFlight, stats.Queued = l.inflight, len(l.waiters)
+	return stats
+}
+
+// TryAcquire admits a call with the priority if there is capacity for it,
+// without waiting. The returned release func must be called once the call
+// finishes.
+func (l *Limiter) TryAcquire(p Priority) (release func(), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.admitLocked(p) {
+		return nil, false
+	}
+	return l.release, true
+}
+
+// Acquire admits a call with the priority, waiting for capacity until the
+// context is done. The returned release func must be called once the call
+// finishes.
+func (l *Limiter) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	release, _, err = l.acquire(ctx, p)
+	return release, err
+}
+
+// acquire is Acquire that also returns how long the call waited.
+func (l *Limiter) acquire(ctx context.Context, p Priority) (release func(), waited time.Duration, err error) {
+	l.mu.Lock()
+	if l.admitLocked(p) {
+		l.mu.Unlock()
+		return l.release, 0, nil
+	}
+	w := &waiter{p: p, ready: make(chan struct{})}
+	l.insertLocked(w)
+	l.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-w.ready:
+		waited = time.Since(start)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.stats.Waited++
+		l.stats.QueueTime += waited
+		if waited > l.stats.MaxQueueTime {
+			l.stats.MaxQueueTime = waited
+		}
+		return l.release, waited, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			// admitted concurrently with the cancel, so give the slot back.
+			l.stats.Admitted--
+			l.inflight--
+			l.wakeLocked()
+		default:
+			l.removeLocked(w)
+		}
+		return nil, 0, ctx.Err()
+	}
+}
+
+// admitLocked admits the call if no call of the same or higher priority is
+// waiting and there is capacity for it. It must be called with l.mu held.
+func (l *Limiter) admitLocked(p Priority) bool {
+	if len(l.waiters) > 0 && l.waiters[0].p >= p {
+		return false
+	}
+	if l.inflight >= l.capacity(p) {
+		return false
+	}
+	l.inflight++
+	l.stats.Admitted++
+	return true
+}
+
+// release ends an admitted call and admits waiting calls that now fit.
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	l.wakeLocked()
+}
+
+// wakeLocked admits the waiting calls in order while there is capacity for
+// them. It must be called with l.mu held.
+func (l *Limiter) wakeLocked() {
+	for len(l.waiters) > 0 && l.inflight < l.capacity(l.waiters[0].p) {
+		w := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inflight++
+		l.stats.Admitted++
+		close(w.ready)
+	}
+}
+
+// insertLocked queues the waiter after every waiter of the same or higher
+// priority. It must be called with l.mu held.
+func (l *Limiter) insertLocked(w *waiter) {
+	i := len(l.waiters)
+	for i > 0 && l.waiters[i-1].p < w.p {
+		i--
+	}
+	l.waiters = append(l.waiters, nil)
+	copy(l.waiters[i+1:], l.waiters[i:])
+	l.waiters[i] = w
+}
+
+// removeLocked removes the waiter from the queue. It must be called with l.mu
+// held.
+func (l *Limiter) removeLocked(w *waiter) {
+	for i, other := range l.waiters {
+		if other == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that waits in the Limiter
+// for the unary rpcs through it, using the priority of their context. The
+// wait counts against the deadline of the rpc, which is absolute, so the
+// deadline propagated to the server only covers the time left after queueing.
+// Rpcs whose deadline passes while queued fail without being sent. The wait
+// is recorded as the "queue" phase of rpcs traced with
+// drpcclient.WithCallTrace, so that time queued in the client can be told
+// apart from time spent in the server.
+func (l *Limiter) UnaryClientInterceptor() drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		start := time.Now()
+		release, waited, err := l.acquire(ctx, FromContext(ctx))
+		if waited > 0 || err != nil {
+			drpcclient.RecordPhase(ctx, "queue", start)
+		}
+		if err != nil {
+			return err
+		}
+		defer release()
+		return next(ctx, rpc, enc, in, out, cc)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that waits in the Limiter
+// for the streams through it, using the priority of their context. A stream
+// holds its slot until its context is done.
+func (l *Limiter) StreamClientInterceptor() drpcclient.StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+		release, err := l.Acquire(ctx, FromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		opened := false
+		defer func() {
+			// also release the slot if next panics.
+			if !opened {
+				release()
+			}
+		}()
+
+		stream, err := next(ctx, rpc, enc, cc)
+		if err != nil {
+			return nil, err
+		}
+		opened = true
+		go func() {
+			<-stream.Context().Done()
+			release()
+		}()
+		return stream, nil
+	}
+}
+
+// NewHandler returns a drpc.Handler that admits rpcs to the handler through
+// the Limiter using the priority sent by the client, rejecting those that do
+// not fit with the gRPC RESOURCE_EXHAUSTED code instead of waiting.
+func NewHandler(handler drpc.Handler, l *Limiter) drpc.Handler {
+	return limitHandler{handler: handler, limiter: l}
+}
+
+type limitHandler struct {
+	handler drpc.Handler
+	limiter *Limiter
+}
+
+func (h limitHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	p := FromContext(stream.Context())
+	release, ok := h.limiter.TryAcquire(p)
+	if !ok {
+		return drpcerr.WithCode(drpc.Error.New("too many concurrent rpcs for %s priority", p), drpcerr.ResourceExhausted)
+	}
+	defer release()
+	return h.handler.HandleRPC(stream, rpc)
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcpriority
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// Priority is the priority of a call. The zero value is Normal.
+type Priority int
+
+const (
+	// Low is for background work, such as compactions or rebalancing, that
+	// can be delayed without affecting users.
+	Low Priority = -1
+
+	// Normal is the priority of calls that are not tagged.
+	Normal Priority = 0
+
+	// High is for work that users wait on or that keeps the system healthy,
+	// such as liveness checks.
+	High Priority = 1
+)
+
+// String returns the name of the priority as sent in metadata.
+func (p Priority) String() string {
+	switch {
+	case p < Normal:
+		return "low"
+	case p > Normal:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// Parse parses a priority formatted by String.
+func Parse(value string) (Priority, error) {
+	switch value {
+	case "low":
+		return Low, nil
+	case "normal":
+		return Normal, nil
+	case "high":
+		return High, nil
+	default:
+		return Normal, errs.New("unknown priority %q", value)
+	}
+}
+
+// priorityKey is the context key for the priority of calls.
+type priorityKey struct{}
+
+// WithPriority returns a context whose calls have the priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// FromContext returns the priority set on the context by WithPriority. On a
+// server, it falls back to the priority sent by the client, so that the
+// priority flows to the calls made while handling an rpc. It is Normal if
+// neither is present or the sent priority is malformed.
+func FromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	if value, ok := drpcmetadata.Lookup(ctx, drpcmetadata.Priority); ok {
+		if p, err := Parse(value); err == nil {
+			return p
+		}
+	}
+	return Normal
+}
+
+// UnaryClientInterceptor sends the priority of the context to the server in
+// the drpcmetadata.Priority key.
+func UnaryClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+	return next(attach(ctx, cc), rpc, enc, in, out, cc)
+}
+
+// StreamClientInterceptor sends the priority of the context to the server in
+// the drpcmetadata.Priority key.
+func StreamClientInterceptor(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+	return next(attach(ctx, cc), rpc, enc, cc)
+}
+
+// attach adds the priority of the context to its outgoing metadata unless it
+// is Normal, which servers assume when it is absent.
+func attach(ctx context.Context, cc *drpcclient.ClientConn) context.Context {
+	if p := FromContext(ctx); p != Normal {
+		return cc.AddMetadata(ctx, drpcmetadata.Priority, p.String())
+	}
+	return ctx
+}
@@ -0,0 +1,164 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcpriority
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpctest"
+)
+
+func TestPriority(t *testing.T) {
+	for _, p := range []Priority{Low, Normal, High} {
+		parsed, err := Parse(p.String())
+		assert.NoError(t, err)
+		assert.Equal(t, parsed, p)
+	}
+	_, err := Parse("urgent")
+	assert.Error(t, err)
+
+	ctx := context.Background()
+	assert.Equal(t, FromContext(ctx), Normal)
+
+	incoming := drpcmetadata.Add(ctx, drpcmetadata.Priority.String(), "low")
+	assert.Equal(t, FromContext(incoming), Low)
+	assert.Equal(t, FromContext(WithPriority(incoming, High)), High)
+}
+
+func TestLimiterShares(t *testing.T) {
+	l := NewLimiter(10)
+
+	var releases []func()
+	acquire := func(p Priority) bool {
+		release, ok := l.TryAcquire(p)
+		if ok {
+			releases = append(releases, release)
+		}
+		return ok
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.That(t, acquire(Low))
+	}
+	assert.That(t, !acquire(Low))
+	for i := 0; i < 4; i++ {
+		assert.That(t, acquire(Normal))
+	}
+	assert.That(t, !acquire(Normal))
+	assert.That(t, acquire(High))
+	assert.That(t, !acquire(High))
+	assert.Equal(t, l.InFlight(), 10)
+
+	for _, release := range releases {
+		release()
+	}
+	assert.Equal(t, l.InFlight(), 0)
+}
+
+func TestLimiterQueue(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(1)
+
+	release, err := l.Acquire(ctx, Normal)
+	assert.NoError(t, err)
+
+	admitted := make(chan Priority, 2)
+	wait := func(p Priority) {
+		release, err := l.Acquire(ctx, p)
+		if err == nil {
+			admitted <- p
+			release()
+		}
+	}
+	go wait(Low)
+	waitQueued(l, 1)
+	go wait(High)
+	waitQueued(l, 2)
+
+	// a canceled waiter leaves the queue without being admitted
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = l.Acquire(canceled, Normal)
+	assert.Equal(t, err, context.Canceled)
+
+	release()
+	assert.Equal(t, <-admitted, High)
+	assert.Equal(t, <-admitted, Low)
+}
+
+func TestLimiterQueueTime(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	l := NewLimiter(1)
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		if rpc == "hold" {
+			entered <- struct{}{}
+			<-release
+		}
+		return in, nil
+	}), drpcclient.WithChainUnaryInterceptor(l.UnaryClientInterceptor()))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	errch := make(chan error, 1)
+	go func() {
+		in, out := "hold", ""
+		errch <- cc.Invoke(ctx, "hold", drpctest.StringEncoding{}, &in, &out)
+	}()
+	<-entered
+
+	// the deadline passes while queued, so the rpc is never sent
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	in, out := "short", ""
+	assert.Equal(t, cc.Invoke(short, "rpc", drpctest.StringEncoding{}, &in, &out), context.DeadlineExceeded)
+
+	var trace drpcclient.CallTrace
+	traced := make(chan error, 1)
+	go func() {
+		in, out := "traced", ""
+		traced <- cc.Invoke(drpcclient.WithCallTrace(ctx, &trace), "rpc", drpctest.StringEncoding{}, &in, &out)
+	}()
+	waitQueued(l, 1)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	assert.NoError(t, <-errch)
+	assert.NoError(t, <-traced)
+
+	var queue time.Duration
+	for _, phase := range trace.Phases {
+		if phase.Name == "queue" {
+			queue = phase.Duration
+		}
+	}
+	assert.That(t, queue >= 20*time.Millisecond)
+
+	stats := l.Stats()
+	assert.Equal(t, stats.Admitted, uint64(2))
+	assert.Equal(t, stats.Waited, uint64(1))
+	assert.That(t, stats.MaxQueueTime >= 20*time.Millisecond)
+	assert.Equal(t, stats.InFlight, 0)
+	assert.Equal(t, stats.Queued, 0)
+}
+
+// waitQueued spins until n calls wait in the limiter.
+func waitQueued(l *Limiter, n int) {
+	for {
+		l.mu.Lock()
+		queued := len(l.waiters)
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+	}
+}
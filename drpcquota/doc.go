@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcquota rate limits rpcs by cost rather than by count. Each rpc is
+// charged a cost, from a per-method table or a function inspecting the
+// request, against a token bucket per tenant, so that limits can be
+// proportional to the bytes or work of the rpcs. Clients wait for their
+// tokens, while servers reject rpcs over quota with a hint of when to retry.
+package drpcquota
@@ -0,0 +1,242 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcquota
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcadmission"
+	"storj.io/drpc/drpcclient"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcquota")
+
+// CostFunc returns the cost of an rpc given its request message. Requests of
+// streams are only known on servers, where they are the first message
+// received; clients pass nil.
+type CostFunc func(ctx context.Context, rpc string, req drpc.Message) float64
+
+// MethodCosts returns a CostFunc that charges the cost in the table for the
+// rpc, or def for rpcs not in the table.
+func MethodCosts(table map[string]float64, def float64) CostFunc {
+	return func(ctx context.Context, rpc string, req drpc.Message) float64 {
+		if cost, ok := table[rpc]; ok {
+			return cost
+		}
+		return def
+	}
+}
+
+// Options configures a Quota.
+type Options struct {
+	// Rate is the number of tokens each tenant gains per second.
+	Rate float64
+
+	// Burst is the most tokens a tenant can accumulate. It defaults to Rate.
+	// Rpcs costing more than Burst are admitted when the bucket is full.
+	Burst float64
+
+	// Tenant returns the tenant that pays for an rpc. It defaults to a single
+	// tenant for every rpc.
+	Tenant func(ctx context.Context) string
+
+	// Cost returns the cost of an rpc. It defaults to 1 for every rpc.
+	Cost CostFunc
+}
+
+// Quota is a set of token buckets, one per tenant. It is safe for concurrent
+// use.
+type Quota struct {
+	opts Options
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the token bucket of a tenant. The tokens go negative while
+// clients wait for reserved tokens.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Quota configured by opts.
+func New(opts Options) *Quota {
+	if opts.Burst <= 0 {
+		opts.Burst = opts.Rate
+	}
+	if opts.Tenant == nil {
+		opts.Tenant = func(ctx context.Context) string { return "" }
+	}
+	if opts.Cost == nil {
+		opts.Cost = func(ctx context.Context, rpc string, req drpc.Message) float64 { return 1 }
+	}
+	return &Quota{
+		opts:    opts,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Tokens returns the tokens the tenant currently has.
+func (q *Quota) Tokens(tenant string) float64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.bucketLocked(tenant, time.Now()).tokens
+}
+
+// bucketLocked returns the bucket of the tenant refilled up to now. It must be
+// called with q.mu held.
+func (q *Quota) bucketLocked(tenant string, now time.Time) *bucket {
+	b, ok := q.buckets[tenant]
+	if !ok {
+		b = &bucket{tokens: q.opts.Burst, last: now}
+		q.buckets[tenant] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(q.opts.Burst, b.tokens+elapsed.Seconds()*q.opts.Rate)
+		b.last = now
+	}
+	return b
+}
+
+// take charges the cost to the tenant if it has the tokens, and otherwise
+// returns how long until it will.
+func (q *Quota) take(tenant string, cost float64) (wait time.Duration, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b := q.bucketLocked(tenant, time.Now())
+	need := math.Min(cost, q.opts.Burst)
+	if b.tokens < need {
+		return q.durationOf(need - b.tokens), false
+	}
+	b.tokens -= cost
+	return 0, true
+}
+
+// reserve charges the cost to the tenant, going into debt if needed, and
+// returns how long until the debt is repaid.
+func (q *Quota) reserve(tenant string, cost float64) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b := q.bucketLocked(tenant, time.Now())
+	b.tokens -= cost
+	if b.tokens >= 0 {
+		return 0
+	}
+	return q.durationOf(-b.tokens)
+}
+
+// refund returns the cost of a reservation that was not used.
+func (q *Quota) refund(tenant string, cost float64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b := q.bucketLocked(tenant, time.Now())
+	b.tokens = math.Min(q.opts.Burst, b.tokens+cost)
+}
+
+// durationOf returns how long the bucket takes to gain the tokens.
+func (q *Quota) durationOf(tokens float64) time.Duration {
+	if q.opts.Rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / q.opts.Rate * float64(time.Second))
+}
+
+// wait charges the rpc to its tenant and waits until the tokens are available,
+// failing early if the context would be done first.
+func (q *Quota) wait(ctx context.Context, rpc string, req drpc.Message) error {
+	tenant, cost := q.opts.Tenant(ctx), q.opts.Cost(ctx, rpc, req)
+	delay := q.reserve(tenant, cost)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		q.refund(tenant, cost)
+		return Error.New("quota for %q exhausted until after the deadline", tenant)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		q.refund(tenant, cost)
+		return ctx.Err()
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that waits for the quota of
+// the unary rpcs through it before issuing them.
+func (q *Quota) UnaryClientInterceptor() drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		if err := q.wait(ctx, rpc, in); err != nil {
+			return err
+		}
+		return next(ctx, rpc, enc, in, out, cc)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that waits for the quota of
+// the streams through it before opening them. Their cost is computed without
+// a request.
+func (q *Quota) StreamClientInterceptor() drpcclient.StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+		if err := q.wait(ctx, rpc, nil); err != nil {
+			return nil, err
+		}
+		return next(ctx, rpc, enc, cc)
+	}
+}
+
+// NewHandler returns a drpc.Handler that charges rpcs to their tenant when
+// the handler receives their first message, which then fails with the gRPC
+// RESOURCE_EXHAUSTED code and a hint of when to retry, readable with
+// drpcadmission.RetryAfter, if the tenant is over quota.
+func (q *Quota) NewHandler(handler drpc.Handler) drpc.Handler {
+	return quotaHandler{handler: handler, q: q}
+}
+
+type quotaHandler struct {
+	handler drpc.Handler
+	q       *Quota
+}
+
+func (h quotaHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	return h.handler.HandleRPC(&quotaStream{Stream: stream, q: h.q, rpc: rpc}, rpc)
+}
+
+// quotaStream charges the rpc when its first message is received.
+type quotaStream struct {
+	drpc.Stream
+	q       *Quota
+	rpc     string
+	charged bool
+}
+
+func (s *quotaStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if err := s.Stream.MsgRecv(msg, enc); err != nil || s.charged {
+		return err
+	}
+	s.charged = true
+
+	ctx := s.Context()
+	tenant := s.q.opts.Tenant(ctx)
+	if wait, ok := s.q.take(tenant, s.q.opts.Cost(ctx, s.rpc, msg)); !ok {
+		return drpcadmission.WithRetryAfter(Error.New("quota for %q exhausted", tenant), wait)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcadmission"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+type tenantKey struct{}
+
+func tenantOf(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+func TestQuotaHandler(t *testing.T) {
+	q := New(Options{
+		Rate:   1,
+		Burst:  10,
+		Tenant: tenantOf,
+		Cost:   MethodCosts(map[string]float64{"big": 8}, 1),
+	})
+	handler := q.NewHandler(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		return stream.MsgRecv(&in, nil)
+	}))
+
+	acme := drpctest.ContextStream(context.WithValue(context.Background(), tenantKey{}, "acme"))
+	assert.NoError(t, handler.HandleRPC(acme, "big"))
+	assert.NoError(t, handler.HandleRPC(acme, "small"))
+
+	err := handler.HandleRPC(acme, "big")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.ResourceExhausted)
+	retryAfter, ok := drpcadmission.RetryAfter(err)
+	assert.That(t, ok)
+	assert.That(t, retryAfter > 6*time.Second && retryAfter <= 7*time.Second)
+
+	// other tenants have their own bucket
+	other := drpctest.ContextStream(context.WithValue(context.Background(), tenantKey{}, "other"))
+	assert.NoError(t, handler.HandleRPC(other, "big"))
+	assert.That(t, q.Tokens("other") < 3)
+}
+
+func TestQuotaClient(t *testing.T) {
+	q := New(Options{Rate: 100, Burst: 1})
+	interceptor := q.UnaryClientInterceptor()
+	invoke := func(ctx context.Context) error {
+		return interceptor(ctx, "rpc", nil, nil, nil, nil, func(context.Context, string, drpc.Encoding, drpc.Message, drpc.Message, *drpcclient.ClientConn) error {
+			return nil
+		})
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, invoke(ctx))
+
+	// the bucket is empty, so the next call waits for the refill
+	start := time.Now()
+	assert.NoError(t, invoke(ctx))
+	assert.That(t, time.Since(start) >= 5*time.Millisecond)
+
+	// calls that cannot get their tokens before the deadline fail early and
+	// do not consume them
+	tokens := q.Tokens("")
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	assert.Error(t, invoke(short))
+	assert.That(t, q.Tokens("") >= tokens)
+}
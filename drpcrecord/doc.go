@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcrecord records the unary rpcs of a client to a golden file and
+// replays them from it, so that code calling drpc services can be tested
+// hermetically. In record mode, a Recorder passes rpcs through and saves
+// their requests and responses. In replay mode, it answers rpcs from the file
+// without touching the network.
+package drpcrecord
@@ -0,0 +1,162 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcrecord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcrecord")
+
+// Mode selects whether a Recorder records or replays rpcs.
+type Mode int
+
+const (
+	// Record passes rpcs through and records them.
+	Record Mode = iota
+
+	// Replay answers rpcs from the recorded ones.
+	Replay
+)
+
+// Entry is a recorded rpc. Requests and responses are stored as encoded by
+// the drpc.Encoding of the rpc.
+type Entry struct {
+	RPC      string `json:"rpc"`
+	Request  []byte `json:"request"`
+	Response []byte `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Code     uint64 `json:"code,omitempty"`
+}
+
+// Recorder records or replays the unary rpcs through its interceptor. It is
+// safe for concurrent use.
+type Recorder struct {
+	mode Mode
+	path string
+
+	mu      sync.Mutex
+	entries []Entry
+	served  map[int]bool
+}
+
+// New returns a Recorder in the mode using the golden file at path. In
+// Replay mode, the file is read immediately. In Record mode, it is written by
+// Save.
+func New(mode Mode, path string) (*Recorder, error) {
+	r := &Recorder{mode: mode, path: path, served: make(map[int]bool)}
+	if mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, Error.Wrap(err)
+		}
+		if err := json.Unmarshal(data, &r.entries); err != nil {
+			return nil, Error.New("parsing %s: %v", path, err)
+		}
+	}
+	return r, nil
+}
+
+// Entries returns the recorded rpcs.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Entry(nil), r.entries...)
+}
+
+// Save writes the recorded rpcs to the golden file. It does nothing in
+// Replay mode.
+func (r *Recorder) Save() error {
+	if r.mode == Replay {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.entries, "", "\t")
+	r.mu.Unlock()
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	return Error.Wrap(os.WriteFile(r.path, append(data, '\n'), 0o644))
+}
+
+// UnaryInterceptor returns the interceptor that records or replays the unary
+// rpcs through it. It should be the innermost interceptor so that the rpcs it
+// sees are the ones sent on the wire. In Replay mode it never calls the next
+// invoker, so the ClientConn never dials, and rpcs whose rpc name and request
+// were not recorded fail. Repeated identical rpcs are answered with their
+// recordings in order, reusing the last one once they are exhausted.
+func (r *Recorder) UnaryInterceptor() drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		request, err := enc.Marshal(in)
+		if err != nil {
+			return err
+		}
+		if r.mode == Replay {
+			return r.replay(rpc, request, enc, out)
+		}
+
+		callErr := next(ctx, rpc, enc, in, out, cc)
+		if errors.Is(callErr, context.Canceled) || errors.Is(callErr, context.DeadlineExceeded) {
+			// cancellations depend on the caller rather than the server.
+			return callErr
+		}
+
+		entry := Entry{RPC: rpc, Request: request}
+		if callErr != nil {
+			entry.Error, entry.Code = callErr.Error(), drpcerr.Code(callErr)
+		} else if entry.Response, err = enc.Marshal(out); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		r.entries = append(r.entries, entry)
+		r.mu.Unlock()
+
+		return callErr
+	}
+}
+
+// replay answers the rpc from the first matching entry not yet served, or the
+// last matching entry.
+func (r *Recorder) replay(rpc string, request []byte, enc drpc.Encoding, out drpc.Message) error {
+	r.mu.Lock()
+	match := -1
+	for i, entry := range r.entries {
+		if entry.RPC != rpc || !bytes.Equal(entry.Request, request) {
+			continue
+		}
+		match = i
+		if !r.served[i] {
+			break
+		}
+	}
+	if match >= 0 {
+		r.served[match] = true
+	}
+	r.mu.Unlock()
+
+	if match < 0 {
+		return Error.New("no recording of %s with the request", rpc)
+	}
+
+	entry := r.entries[match]
+	if entry.Error != "" {
+		return drpcerr.WithCode(errors.New(entry.Error), entry.Code)
+	}
+	return enc.Unmarshal(entry.Response, out)
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcrecord
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "golden.json")
+
+	calls := 0
+	live := &drpcclienttest.Conn{InvokeFunc: func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+		calls++
+		if rpc == "Fail" {
+			return drpcerr.WithCode(errors.New("not found"), drpcerr.NotFound)
+		}
+		*out.(*string) = *in.(*string) + " reply " + string(rune('0'+calls))
+		return nil
+	}}
+
+	invoke := func(cc *drpcclient.ClientConn, rpc, in string) (string, error) {
+		var out string
+		err := cc.Invoke(ctx, rpc, drpctest.StringEncoding{}, &in, &out)
+		return out, err
+	}
+
+	recorder, err := New(Record, path)
+	assert.NoError(t, err)
+	cc := newClientConn(t, live, recorder)
+
+	out, err := invoke(cc, "Echo", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, out, "a reply 1")
+	out, err = invoke(cc, "Echo", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, out, "a reply 2")
+	_, err = invoke(cc, "Fail", "b")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.NotFound)
+	assert.NoError(t, recorder.Save())
+	assert.Equal(t, len(recorder.Entries()), 3)
+
+	replayer, err := New(Replay, path)
+	assert.NoError(t, err)
+	offline := &drpcclienttest.Conn{InvokeFunc: func(context.Context, string, drpc.Encoding, drpc.Message, drpc.Message) error {
+		return errors.New("network used during replay")
+	}}
+	cc = newClientConn(t, offline, replayer)
+
+	out, err = invoke(cc, "Echo", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, out, "a reply 1")
+	out, err = invoke(cc, "Echo", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, out, "a reply 2")
+	out, err = invoke(cc, "Echo", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, out, "a reply 2")
+
+	_, err = invoke(cc, "Fail", "b")
+	assert.Equal(t, drpcerr.Code(err), drpcerr.NotFound)
+	assert.Equal(t, err.Error(), "not found")
+
+	_, err = invoke(cc, "Echo", "unrecorded")
+	assert.That(t, Error.Has(err))
+	assert.Equal(t, len(offline.Calls()), 0)
+}
+
+func newClientConn(t *testing.T, conn drpc.Conn, r *Recorder) *drpcclient.ClientConn {
+	cc, err := drpcclient.NewClientConnWithOptions(context.Background(), func(context.Context) (drpc.Conn, error) {
+		return conn, nil
+	}, drpcclient.WithChainUnaryInterceptor(r.UnaryInterceptor()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+	return cc
+}
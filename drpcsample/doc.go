@@ -0,0 +1,10 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcsample decides which rpcs expensive observability, such as
+// payload logging, tracing or binary logging, captures. A Sampler samples a
+// fixed fraction of the rpcs of each method or adapts the fraction to keep a
+// steady number of samples per second, with rules that may be changed at
+// runtime. The decision is made once per rpc and carried on its context, so
+// every interceptor wrapped by the same Sampler captures the same rpcs.
+package drpcsample
@@ -0,0 +1,268 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsample
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpcmuxext"
+)
+
+// Hint is the interceptor hint consulted by a Sampler. Setting it to
+// drpcclient.HintSkip never samples a call and setting it to HintForce always
+// does, regardless of the rules.
+const Hint = "sample"
+
+// HintForce is the hint value that always samples a call.
+const HintForce = "force"
+
+// Rule configures how the rpcs of a method are sampled.
+type Rule struct {
+	// Rate is the fraction of rpcs sampled, from 0 for none to 1 for all.
+	Rate float64
+
+	// PerSecond, if positive, samples adaptively instead: the fraction of
+	// rpcs sampled follows the traffic of the method so that about PerSecond
+	// rpcs are sampled each second, and never more. Rate is ignored.
+	PerSecond int
+}
+
+// Options configures a Sampler.
+type Options struct {
+	// Default is the rule for methods without a rule of their own.
+	Default Rule
+
+	// Methods are the rules of individual methods, keyed by rpc name.
+	Methods map[string]Rule
+
+	// Clock is used to measure the traffic of adaptive rules. It defaults to
+	// drpcclock.System.
+	Clock drpcclock.Clock
+}
+
+// Stats are the sampling metrics of a method.
+type Stats struct {
+	// Seen counts the rpcs a decision was made for, and Sampled the ones
+	// that were sampled.
+	Seen    uint64
+	Sampled uint64
+}
+
+// Sampler decides which rpcs are sampled. It is safe for concurrent use, and
+// its rules may be changed while it is in use.
+type Sampler struct {
+	clock drpcclock.Clock
+
+	mu      sync.Mutex
+	def     Rule
+	rules   map[string]Rule
+	methods map[string]*methodState
+	rand    func() float64
+}
+
+// methodState is the sampling state of a method.
+type methodState struct {
+	stats Stats
+
+	// window is when the current second of an adaptive rule started, seen
+	// and sampled count the rpcs within it, and last is the number of rpcs
+	// seen in the previous one.
+	window  time.Time
+	seen    int
+	sampled int
+	last    int
+}
+
+// New returns a Sampler configured by opts.
+func New(opts Options) *Sampler {
+	s := &Sampler{
+		clock:   drpcclock.Or(opts.Clock),
+		def:     opts.Default,
+		rules:   make(map[string]Rule, len(opts.Methods)),
+		methods: make(map[string]*methodState),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}
+	for rpc, rule := range opts.Methods {
+		s.rules[rpc] = rule
+	}
+	return s
+}
+
+// SetDefault replaces the rule for methods without a rule of their own.
+func (s *Sampler) SetDefault(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.def = rule
+}
+
+// SetRule replaces the rule of the method.
+func (s *Sampler) SetRule(rpc string, rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules[rpc] = rule
+}
+
+// ClearRule removes the rule of the method so that the default applies.
+func (s *Sampler) ClearRule(rpc string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rules, rpc)
+}
+
+// Stats returns the metrics of every method a decision was made for.
+func (s *Sampler) Stats() map[string]Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]Stats, len(s.methods))
+	for rpc, ms := range s.methods {
+		out[rpc] = ms.stats
+	}
+	return out
+}
+
+// Sample decides whether an rpc of the method is sampled according to its
+// rule. Each call counts as a new rpc; use Decide to share the decision of an
+// rpc between interceptors.
+func (s *Sampler) Sample(rpc string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.methods[rpc]
+	if ms == nil {
+		ms = new(methodState)
+		s.methods[rpc] = ms
+	}
+	rule, ok := s.rules[rpc]
+	if !ok {
+		rule = s.def
+	}
+
+	var sampled bool
+	if rule.PerSecond > 0 {
+		sampled = s.adaptiveLocked(ms, rule.PerSecond)
+	} else {
+		sampled = rule.Rate >= 1 || (rule.Rate > 0 && s.rand() < rule.Rate)
+	}
+
+	ms.stats.Seen++
+	if sampled {
+		ms.stats.Sampled++
+	}
+	return sampled
+}
+
+// adaptiveLocked decides whether to sample an rpc of the method so that about
+// perSecond of its rpcs are sampled each second.
+func (s *Sampler) adaptiveLocked(ms *methodState, perSecond int) bool {
+	now := s.clock.Now()
+	if elapsed := now.Sub(ms.window); elapsed >= time.Second {
+		if elapsed >= 2*time.Second {
+			ms.seen = 0
+		}
+		ms.window, ms.last, ms.seen, ms.sampled = now, ms.seen, 0, 0
+	}
+	ms.seen++
+
+	if ms.sampled >= perSecond {
+		return false
+	}
+	// if the previous second saw more rpcs than the budget, sample the
+	// fraction of them that would have spent it. otherwise sample the first
+	// rpcs until it is spent.
+	if ms.last > perSecond && s.rand()*float64(ms.last) >= float64(perSecond) {
+		return false
+	}
+	ms.sampled++
+	return true
+}
+
+// decisionKey is the context key for the sampling decision of an rpc.
+type decisionKey struct{}
+
+// WithDecision returns a context carrying the sampling decision of an rpc.
+func WithDecision(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, decisionKey{}, sampled)
+}
+
+// Decision returns the sampling decision carried by the context, if any.
+func Decision(ctx context.Context) (sampled, ok bool) {
+	sampled, ok = ctx.Value(decisionKey{}).(bool)
+	return sampled, ok
+}
+
+// Sampled returns true if the rpc of the context was sampled.
+func Sampled(ctx context.Context) bool {
+	sampled, _ := Decision(ctx)
+	return sampled
+}
+
+// Decide returns whether the rpc is sampled and a context carrying the
+// decision. A decision already on the context is kept, so that every
+// interceptor of an rpc agrees. Otherwise the Hint of the context is
+// honored before the rule of the method is.
+func (s *Sampler) Decide(ctx context.Context, rpc string) (context.Context, bool) {
+	if sampled, ok := Decision(ctx); ok {
+		return ctx, sampled
+	}
+
+	var sampled bool
+	switch hint, _ := drpcclient.InterceptorHint(ctx, Hint); hint {
+	case HintForce:
+		sampled = true
+	case drpcclient.HintSkip:
+	default:
+		sampled = s.Sample(rpc)
+	}
+	return WithDecision(ctx, sampled), sampled
+}
+
+// Unary returns a UnaryClientInterceptor that runs the interceptor only for
+// sampled rpcs.
+func (s *Sampler) Unary(interceptor drpcclient.UnaryClientInterceptor) drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		ctx, sampled := s.Decide(ctx, rpc)
+		if !sampled {
+			return next(ctx, rpc, enc, in, out, cc)
+		}
+		return interceptor(ctx, rpc, enc, in, out, cc, next)
+	}
+}
+
+// Stream returns a StreamClientInterceptor that runs the interceptor only for
+// sampled rpcs.
+func (s *Sampler) Stream(interceptor drpcclient.StreamClientInterceptor) drpcclient.StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, streamer drpcclient.Streamer) (drpc.Stream, error) {
+		ctx, sampled := s.Decide(ctx, rpc)
+		if !sampled {
+			return streamer(ctx, rpc, enc, cc)
+		}
+		return interceptor(ctx, rpc, enc, cc, streamer)
+	}
+}
+
+// Middleware returns a drpcmuxext.Middleware that runs the middleware only
+// for sampled rpcs.
+func (s *Sampler) Middleware(mw drpcmuxext.Middleware) drpcmuxext.Middleware {
+	return func(next drpc.Handler) drpc.Handler {
+		sampled := mw(next)
+		return drpcmuxext.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			ctx, ok := s.Decide(stream.Context(), rpc)
+			stream = drpcmuxext.WithStreamContext(stream, ctx)
+			if !ok {
+				return next.HandleRPC(stream, rpc)
+			}
+			return sampled.HandleRPC(stream, rpc)
+		})
+	}
+}
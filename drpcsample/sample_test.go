@@ -0,0 +1,125 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsample
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclock"
+	"storj.io/drpc/drpctest"
+)
+
+func TestSamplerRate(t *testing.T) {
+	s := New(Options{
+		Default: Rule{Rate: 0.25},
+		Methods: map[string]Rule{"/all": {Rate: 1}, "/none": {Rate: 0}},
+	})
+	draws := []float64{0.1, 0.3, 0.2, 0.9}
+	s.rand = func() float64 { r := draws[0]; draws = draws[1:]; return r }
+
+	var got []bool
+	for i := 0; i < 4; i++ {
+		got = append(got, s.Sample("/some"))
+	}
+	assert.DeepEqual(t, got, []bool{true, false, true, false})
+	assert.That(t, s.Sample("/all"))
+	assert.False(t, s.Sample("/none"))
+
+	// rules change at runtime
+	s.SetRule("/none", Rule{Rate: 1})
+	assert.That(t, s.Sample("/none"))
+	s.ClearRule("/all")
+	s.SetDefault(Rule{})
+	assert.False(t, s.Sample("/all"))
+
+	assert.Equal(t, s.Stats()["/some"], Stats{Seen: 4, Sampled: 2})
+	assert.Equal(t, s.Stats()["/none"], Stats{Seen: 2, Sampled: 1})
+}
+
+func TestSamplerAdaptive(t *testing.T) {
+	clock := drpcclock.NewFake(time.Unix(1000, 0))
+	s := New(Options{Default: Rule{PerSecond: 2}, Clock: clock})
+	s.rand = func() float64 { return 0.1 }
+
+	count := func(n int) (sampled int) {
+		for i := 0; i < n; i++ {
+			if s.Sample("/rpc") {
+				sampled++
+			}
+		}
+		return sampled
+	}
+
+	// the first second has no traffic to go by, so the budget is spent first.
+	assert.Equal(t, count(10), 2)
+
+	// the next second samples 2 in 10 rpcs, up to the budget.
+	clock.Advance(time.Second)
+	s.rand = func() float64 { return 0.15 }
+	assert.Equal(t, count(10), 2)
+	s.rand = func() float64 { return 0.25 }
+	clock.Advance(time.Second)
+	assert.Equal(t, count(10), 0)
+
+	// after an idle second, the budget is spent first again.
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, count(10), 2)
+}
+
+func TestSamplerInterceptors(t *testing.T) {
+	s := New(Options{Methods: map[string]Rule{"/sampled": {Rate: 1}}})
+
+	var ran []string
+	unary := s.Unary(func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		ran = append(ran, rpc)
+		return next(ctx, rpc, enc, in, out, cc)
+	})
+	var decided []bool
+	next := func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn) error {
+		sampled, ok := Decision(ctx)
+		assert.That(t, ok)
+		decided = append(decided, sampled)
+		return nil
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, unary(ctx, "/sampled", nil, nil, nil, nil, next))
+	assert.NoError(t, unary(ctx, "/other", nil, nil, nil, nil, next))
+	assert.NoError(t, unary(drpcclient.WithInterceptorHints(ctx, drpcclient.InterceptorHints{Hint: HintForce}), "/other", nil, nil, nil, nil, next))
+	assert.NoError(t, unary(drpcclient.WithInterceptorHints(ctx, drpcclient.InterceptorHints{Hint: drpcclient.HintSkip}), "/sampled", nil, nil, nil, nil, next))
+	assert.NoError(t, unary(WithDecision(ctx, true), "/other", nil, nil, nil, nil, next))
+
+	assert.DeepEqual(t, ran, []string{"/sampled", "/other", "/other"})
+	assert.DeepEqual(t, decided, []bool{true, false, true, false, true})
+
+	// hints and earlier decisions are not counted as rpcs of the rules.
+	assert.Equal(t, s.Stats()["/other"], Stats{Seen: 1})
+}
+
+func TestSamplerMiddleware(t *testing.T) {
+	s := New(Options{Methods: map[string]Rule{"/sampled": {Rate: 1}}})
+
+	var ran []string
+	mw := s.Middleware(func(next drpc.Handler) drpc.Handler {
+		return drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			ran = append(ran, rpc)
+			return next.HandleRPC(stream, rpc)
+		})
+	})
+	handler := mw(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		_, ok := Decision(stream.Context())
+		assert.That(t, ok)
+		return nil
+	}))
+
+	assert.NoError(t, handler.HandleRPC(drpctest.ContextStream(context.Background()), "/sampled"))
+	assert.NoError(t, handler.HandleRPC(drpctest.ContextStream(context.Background()), "/other"))
+	assert.DeepEqual(t, ran, []string{"/sampled"})
+}
@@ -2,4 +2,14 @@
 // See LICENSE for copying information.
 
 // Package drpcserver allows one to execute registered rpcs.
+//
+// When a client cancels an rpc, the context of the handler is done as soon as
+// the cancellation reaches the server, either as a soft cancel or as the
+// transport closing, and the handler is not in the middle of a send or
+// receive. A handler blocked in a send or receive returns an error first. The
+// transport is only read while the handler consumes the messages sent to it,
+// so a handler that stops receiving while the client keeps sending delays the
+// cancellation until it receives again. With CollectStats set, the Cancels
+// and CancelLatency stats of an rpc measure how long its handlers took to
+// observe cancellations.
 package drpcserver
@@ -6,6 +6,8 @@ package drpcserver
 import (
 	"context"
 	"net"
+	"runtime/pprof"
+	"runtime/trace"
 	"sync"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	"storj.io/drpc/drpccache"
 	"storj.io/drpc/drpcctx"
 	"storj.io/drpc/drpcmanager"
+	"storj.io/drpc/drpcmetadata"
 	"storj.io/drpc/drpcstats"
 	"storj.io/drpc/drpcstream"
 	"storj.io/drpc/internal/drpcopts"
@@ -33,6 +36,42 @@ type Options struct {
 	// CollectStats controls whether the server should collect stats on the
 	// rpcs it serves.
 	CollectStats bool
+
+	// HonorDeadlines controls whether the deadline a client sends in the
+	// drpcmetadata.Deadline key bounds the context that handlers see, so that
+	// handlers can abandon work the client no longer waits for. The deadline
+	// does not cancel the stream itself.
+	HonorDeadlines bool
+
+	// DeadlineSkew is subtracted from deadlines sent by clients to account for
+	// clock skew between the client and the server and for the time it takes
+	// the response to reach the client.
+	DeadlineSkew time.Duration
+
+	// MaxMetadataSize, if positive, rejects rpcs whose metadata, counted as
+	// the total bytes of its keys and values, is larger than this many bytes
+	// before they reach the handler.
+	MaxMetadataSize int
+
+	// PprofLabels controls whether handlers run with the runtime/pprof labels
+	// from drpcctx.PprofLabels set on their goroutine and stream context, so
+	// that CPU profiles attribute time to specific rpc methods.
+	PprofLabels bool
+
+	// ExecutionTrace controls whether every rpc is recorded as a runtime/trace
+	// task named after the rpc, with its error logged on the task with the
+	// category "error", so that go tool trace shows the lifecycle of each rpc
+	// and the goroutines that worked on it. Nothing is recorded while no
+	// execution trace is being collected.
+	ExecutionTrace bool
+
+	// ReportServerTime controls whether the trailer of every rpc reports how
+	// long the server spent on it in the drpcmetadata.ServerTime key, and how
+	// much of that it waited in queues, as added with
+	// drpcmetadata.AddQueueTime, in the drpcmetadata.QueueTime key. Clients
+	// subtract it from the latency they observe to tell the time spent in the
+	// server apart from the network and client overhead.
+	ReportServerTime bool
 }
 
 // Server is an implementation of drpc.Server to serve drpc connections.
@@ -99,6 +138,7 @@ func (s *Server) ServeOne(ctx context.Context, tr drpc.Transport) (err error) {
 	defer cache.Clear()
 
 	ctx = drpccache.WithContext(ctx, cache)
+	ctx = drpcctx.WithFrameLimiter(ctx, man)
 
 	for {
 		stream, rpc, err := man.NewServerStream(ctx)
@@ -163,9 +203,96 @@ func (s *Server) Serve(ctx context.Context, lis net.Listener) (err error) {
 
 // handleRPC handles the rpc that has been requested by the stream.
 func (s *Server) handleRPC(stream *drpcstream.Stream, rpc string) (err error) {
-	err = s.handler.HandleRPC(stream, rpc)
+	if err := s.checkMetadataSize(stream.Context()); err != nil {
+		return errs.Wrap(stream.SendError(err))
+	}
+
+	// clients running a newer build may send metadata keys this build does
+	// not know, so they are hidden from the handler.
+	ctx := drpcmetadata.DropNewer(stream.Context())
+	var timer *drpcmetadata.QueueTimer
+	var start time.Time
+	if s.opts.ReportServerTime {
+		timer, start = new(drpcmetadata.QueueTimer), time.Now()
+		ctx = drpcmetadata.WithQueueTimer(ctx, timer)
+	}
+	var task *trace.Task
+	if s.opts.ExecutionTrace && trace.IsEnabled() {
+		ctx, task = trace.NewTask(ctx, rpc)
+		defer task.End()
+	}
+	if s.opts.HonorDeadlines {
+		if dctx, cancel, ok := s.deadlineContext(ctx); ok {
+			defer cancel()
+			ctx = dctx
+		}
+	}
+
+	if s.opts.PprofLabels {
+		pprof.Do(ctx, drpcctx.PprofLabels(rpc), func(ctx context.Context) {
+			err = s.handler.HandleRPC(contextStream{Stream: stream, ctx: ctx}, rpc)
+		})
+	} else if ctx != stream.Context() {
+		err = s.handler.HandleRPC(contextStream{Stream: stream, ctx: ctx}, rpc)
+	} else {
+		err = s.handler.HandleRPC(stream, rpc)
+	}
+	if timer != nil {
+		reportServerTime(stream, timer, start)
+	}
 	if err != nil {
+		if task != nil {
+			trace.Log(ctx, "error", err.Error())
+		}
 		return errs.Wrap(stream.SendError(err))
 	}
 	return errs.Wrap(stream.CloseSend())
 }
+
+// reportServerTime sets the trailer of the stream to the time spent on the rpc
+// since start and the part of it spent in queues.
+func reportServerTime(stream *drpcstream.Stream, timer *drpcmetadata.QueueTimer, start time.Time) {
+	trailer := map[string]string{drpcmetadata.ServerTime.String(): time.Since(start).String()}
+	if queued := timer.Total(); queued > 0 {
+		trailer[drpcmetadata.QueueTime.String()] = queued.String()
+	}
+	stream.SetTrailer(trailer)
+}
+
+// deadlineContext returns a context bounded by the deadline the client sent,
+// if any.
+func (s *Server) deadlineContext(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+	value, ok := drpcmetadata.Lookup(ctx, drpcmetadata.Deadline)
+	if !ok {
+		return nil, nil, false
+	}
+	deadline, err := drpcmetadata.ParseDeadline(value)
+	if err != nil {
+		return nil, nil, false
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline.Add(-s.opts.DeadlineSkew))
+	return ctx, cancel, true
+}
+
+// contextStream is a stream whose context is bounded by the client's deadline
+// or carries pprof labels or a trace task.
+type contextStream struct {
+	*drpcstream.Stream
+	ctx context.Context
+}
+
+// Context returns the context handlers should see.
+func (c contextStream) Context() context.Context { return c.ctx }
+
+// checkMetadataSize returns an error if the metadata sent by the client is
+// larger than the configured limit.
+func (s *Server) checkMetadataSize(ctx context.Context) error {
+	if s.opts.MaxMetadataSize <= 0 {
+		return nil
+	}
+	metadata, _ := drpcmetadata.Get(ctx)
+	if size := drpcmetadata.Size(metadata); size > s.opts.MaxMetadataSize {
+		return drpc.Error.New("metadata too large: %d > %d", size, s.opts.MaxMetadataSize)
+	}
+	return nil
+}
@@ -4,11 +4,26 @@
 package drpcserver
 
 import (
+	"bytes"
+	"context"
 	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/zeebo/assert"
+	"github.com/zeebo/errs"
 
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcmanager"
+	"storj.io/drpc/drpcmetadata"
 	"storj.io/drpc/drpctest"
 )
 
@@ -35,6 +50,273 @@ func TestServerTemporarySleep(t *testing.T) {
 	assert.NoError(t, New(nil).Serve(ctx, l))
 }
 
+func TestServerHonorsDeadlines(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	deadlines := make(chan time.Time, 1)
+	srv := NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, drpctest.StringEncoding{}); err != nil {
+			return err
+		}
+		deadline, _ := stream.Context().Deadline()
+		deadlines <- deadline
+		return stream.MsgSend(&in, drpctest.StringEncoding{})
+	}), Options{HonorDeadlines: true, DeadlineSkew: time.Second})
+
+	c1, c2 := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, c1) })
+
+	cc, err := drpcclient.NewClientConnWithOptions(ctx, func(context.Context) (drpc.Conn, error) {
+		return drpcconn.New(c2), nil
+	}, drpcclient.WithDeadlinePropagation())
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	deadline := time.Now().Add(time.Hour)
+	callCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	in, out := "", ""
+	assert.NoError(t, cc.Invoke(callCtx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.That(t, (<-deadlines).Equal(deadline.Add(-time.Second)))
+}
+
+func TestServerCancellationPropagation(t *testing.T) {
+	// cancelBound is how long a handler may take to observe that the client
+	// canceled the stream. it is generous so that the test is not flaky under
+	// the race detector.
+	const cancelBound = time.Second
+
+	run := func(t *testing.T, soft bool) {
+		ctx := drpctest.NewTracker(t)
+		defer ctx.Close()
+
+		started := make(chan struct{})
+		observed := make(chan time.Time, 1)
+		srv := NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			var in string
+			if err := stream.MsgRecv(&in, drpctest.StringEncoding{}); err != nil {
+				return err
+			}
+			close(started)
+			<-stream.Context().Done()
+			observed <- time.Now()
+			return nil
+		}), Options{CollectStats: true})
+
+		c1, c2 := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, c1) })
+
+		conn := drpcconn.NewWithOptions(c2, drpcconn.Options{
+			Manager: drpcmanager.Options{SoftCancel: soft},
+		})
+		defer func() { _ = conn.Close() }()
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := conn.NewStream(streamCtx, "rpc", drpctest.StringEncoding{})
+		assert.NoError(t, err)
+		in := "hello"
+		assert.NoError(t, stream.MsgSend(&in, drpctest.StringEncoding{}))
+		<-started
+
+		canceled := time.Now()
+		cancel()
+
+		select {
+		case at := <-observed:
+			assert.That(t, at.Sub(canceled) < cancelBound)
+		case <-time.After(cancelBound):
+			t.Fatal("handler did not observe the cancellation in time")
+		}
+
+		stats := srv.Stats()["rpc"]
+		assert.Equal(t, stats.Cancels, uint64(1))
+		assert.That(t, stats.MeanCancelLatency() < cancelBound)
+	}
+
+	for i := 0; i < 10; i++ {
+		t.Run("Hard", func(t *testing.T) { run(t, false) })
+		t.Run("Soft", func(t *testing.T) { run(t, true) })
+	}
+}
+
+func TestServerMaxMetadataSize(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	srv := NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, drpctest.StringEncoding{}); err != nil {
+			return err
+		}
+		return stream.MsgSend(&in, drpctest.StringEncoding{})
+	}), Options{MaxMetadataSize: 16})
+
+	c1, c2 := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, c1) })
+
+	conn := drpcconn.New(c2)
+	defer func() { _ = conn.Close() }()
+
+	in, out := "hello", ""
+	assert.NoError(t, conn.Invoke(drpcmetadata.Add(ctx, "small", "x"), "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "hello")
+
+	err := conn.Invoke(drpcmetadata.Add(ctx, "baggage", "0123456789"), "rpc", drpctest.StringEncoding{}, &in, &out)
+	assert.Error(t, err)
+	assert.That(t, strings.Contains(err.Error(), "metadata too large"))
+}
+
+func TestServerPprofLabels(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	srv := NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, drpctest.StringEncoding{}); err != nil {
+			return err
+		}
+		service, _ := pprof.Label(stream.Context(), "rpc_service")
+		method, _ := pprof.Label(stream.Context(), "rpc_method")
+		out := service + " " + method
+		return stream.MsgSend(&out, drpctest.StringEncoding{})
+	}), Options{PprofLabels: true})
+
+	c1, c2 := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, c1) })
+
+	conn := drpcconn.New(c2)
+	defer func() { _ = conn.Close() }()
+
+	in, out := "hello", ""
+	assert.NoError(t, conn.Invoke(ctx, "/pkg.Service/Method", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "pkg.Service Method")
+}
+
+func TestServerExecutionTrace(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("an execution trace is already being collected")
+	}
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	srv := NewWithOptions(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		return errs.New("handler failed")
+	}), Options{ExecutionTrace: true})
+
+	c1, c2 := net.Pipe()
+	ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, c1) })
+
+	conn := drpcconn.New(c2)
+	defer func() { _ = conn.Close() }()
+
+	var buf bytes.Buffer
+	assert.NoError(t, trace.Start(&buf))
+	in, out := "hello", ""
+	assert.Error(t, conn.Invoke(ctx, "/pkg.Service/Method", drpctest.StringEncoding{}, &in, &out))
+	trace.Stop()
+
+	assert.That(t, bytes.Contains(buf.Bytes(), []byte("/pkg.Service/Method")))
+	assert.That(t, bytes.Contains(buf.Bytes(), []byte("handler failed")))
+}
+
+func TestServerListenUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket file modes are not supported on windows")
+	}
+
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	path := filepath.Join(t.TempDir(), "drpc.sock")
+
+	// a regular file is never removed
+	assert.NoError(t, os.WriteFile(path, nil, 0600))
+	_, err := ListenUnix(path, 0600)
+	assert.Error(t, err)
+	assert.NoError(t, os.Remove(path))
+
+	// a socket left behind by a previous process is removed
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	assert.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	assert.NoError(t, stale.Close())
+
+	serve := func(ctx context.Context, name string) {
+		lis, err := ListenUnix(path, 0600)
+		assert.NoError(t, err)
+
+		fi, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, fi.Mode().Perm(), os.FileMode(0600))
+
+		srv := New(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+			var in string
+			if err := stream.MsgRecv(&in, drpctest.StringEncoding{}); err != nil {
+				return err
+			}
+			out := name + ":" + in
+			return stream.MsgSend(&out, drpctest.StringEncoding{})
+		}))
+		_ = srv.Serve(ctx, lis)
+	}
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	done1 := make(chan struct{})
+	go func() { defer close(done1); serve(ctx1, "first") }()
+
+	dialer, err := drpcclient.NewTargetDialer("unix://"+path, nil, nil)
+	assert.NoError(t, err)
+
+	var cc *drpcclient.ClientConn
+	for i := 0; ; i++ {
+		cc, err = drpcclient.NewClientConnWithOptions(ctx, dialer,
+			drpcclient.WithIdleTimeout(time.Millisecond))
+		if err == nil || i > 100 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "hi", ""
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, out, "first:hi")
+
+	// a listener that is in use is not replaced
+	_, err = ListenUnix(path, 0600)
+	assert.Error(t, err)
+
+	// once idle, the client redials whichever server now listens at the path
+	for cc.State() != drpcclient.Idle {
+		time.Sleep(time.Millisecond)
+	}
+	cancel1()
+	<-done1
+
+	ready := make(chan struct{})
+	ctx.Run(func(ctx context.Context) {
+		close(ready)
+		serve(ctx, "second")
+	})
+	<-ready
+
+	for i := 0; ; i++ {
+		err = cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out)
+		if err == nil || i > 100 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, out, "second:hi")
+}
+
 type listener func() (net.Conn, error)
 
 func (l listener) Accept() (net.Conn, error) { return l() }
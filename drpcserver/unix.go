@@ -0,0 +1,56 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcserver
+
+import (
+	"net"
+	"os"
+
+	"github.com/zeebo/errs"
+)
+
+// ListenUnix listens on a unix domain socket at path whose file has the
+// permissions perm, so that only the intended local clients can connect. A
+// socket left behind by a previous process that no longer accepts connections
+// is removed first, but any other existing file is an error. The socket file
+// is removed when the listener is closed.
+//
+// The permissions are applied after the socket is created, so a restrictive
+// umask or a private parent directory should be used if no other user may
+// connect even briefly.
+func ListenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		_ = lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
+
+// removeStaleSocket removes the socket at path if nothing is listening on it.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return errs.New("%q exists and is not a socket", path)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		_ = conn.Close()
+		return errs.New("%q is in use", path)
+	}
+	return os.Remove(path)
+}
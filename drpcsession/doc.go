@@ -0,0 +1,7 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcsession authenticates a conn once with a challenge and response
+// exchanged on a stream when it is dialed, and caches the result on the conn,
+// so that high QPS links do not send and validate credentials on every rpc.
+package drpcsession
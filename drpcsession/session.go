@@ -0,0 +1,244 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsession
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpccache"
+	"storj.io/drpc/drpcerr"
+)
+
+// RPC is the name of the stream rpc authenticating a conn.
+const RPC = "/drpc.Session/Authenticate"
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcsession")
+
+// Prover answers the challenges of servers for a client.
+type Prover interface {
+	// Respond returns the response proving the identity of the client to the
+	// server that sent the challenge.
+	Respond(ctx context.Context, challenge []byte) ([]byte, error)
+}
+
+// Challenger challenges clients for a server.
+type Challenger interface {
+	// Challenge returns a new challenge for a client.
+	Challenge(ctx context.Context) ([]byte, error)
+
+	// Verify returns the identity of the client if the response answers the
+	// challenge.
+	Verify(ctx context.Context, challenge, response []byte) (identity string, err error)
+}
+
+// Authenticate authenticates the conn with the server by answering its
+// challenge with the prover. It is meant to be called once right after the
+// conn is dialed, which drpcclient.WithSessionAuthentication does.
+func Authenticate(ctx context.Context, conn drpc.Conn, prover Prover) (err error) {
+	stream, err := conn.NewStream(ctx, RPC, encoding{})
+	if err != nil {
+		return err
+	}
+	defer func() { err = errs.Combine(err, stream.Close()) }()
+
+	var challenge []byte
+	if err := stream.MsgRecv(&challenge, encoding{}); err != nil {
+		return err
+	}
+	response, err := prover.Respond(ctx, challenge)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err := stream.MsgSend(&response, encoding{}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	// the server ends the stream once it accepted the response.
+	var extra []byte
+	if err := stream.MsgRecv(&extra, encoding{}); !errors.Is(err, io.EOF) {
+		if err == nil {
+			err = Error.New("unexpected message from server")
+		}
+		return err
+	}
+	return nil
+}
+
+// identityKey is the drpccache key for the identity of an authenticated conn.
+type identityKey struct{}
+
+// Identity returns the identity of the client of the conn of the rpc whose
+// handler was passed the context, and false if the conn is not authenticated.
+func Identity(ctx context.Context) (string, bool) {
+	cache := drpccache.FromContext(ctx)
+	if cache == nil {
+		return "", false
+	}
+	identity, ok := cache.Load(identityKey{}).(string)
+	return identity, ok
+}
+
+// Register registers the authentication rpc on the mux, which challenges
+// clients with the challenger and records the identity of those that answer
+// in the drpccache of their conn.
+func Register(mux drpc.Mux, challenger Challenger) error {
+	return mux.Register(&server{challenger: challenger}, description{})
+}
+
+// NewHandler returns a drpc.Handler that rejects the rpcs on conns that did
+// not authenticate with the gRPC UNAUTHENTICATED code, except for the
+// authentication rpc itself, which the handler must serve, such as with a mux
+// passed to Register.
+func NewHandler(handler drpc.Handler) drpc.Handler {
+	return sessionHandler{handler: handler}
+}
+
+type sessionHandler struct {
+	handler drpc.Handler
+}
+
+func (h sessionHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	if _, ok := Identity(stream.Context()); !ok && rpc != RPC {
+		return drpcerr.WithCode(Error.New("conn is not authenticated"), drpcerr.Unauthenticated)
+	}
+	return h.handler.HandleRPC(stream, rpc)
+}
+
+// server challenges clients on the authentication rpc.
+type server struct {
+	challenger Challenger
+}
+
+func (s *server) Authenticate(stream drpc.Stream) error {
+	ctx := stream.Context()
+	cache := drpccache.FromContext(ctx)
+	if cache == nil {
+		return Error.New("server has no conn cache")
+	}
+
+	challenge, err := s.challenger.Challenge(ctx)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err := stream.MsgSend(&challenge, encoding{}); err != nil {
+		return err
+	}
+	var response []byte
+	if err := stream.MsgRecv(&response, encoding{}); err != nil {
+		return err
+	}
+	identity, err := s.challenger.Verify(ctx, challenge, response)
+	if err != nil {
+		return drpcerr.WithCode(Error.Wrap(err), drpcerr.Unauthenticated)
+	}
+
+	cache.Store(identityKey{}, identity)
+	return nil
+}
+
+// description describes the authentication rpc to a drpc.Mux.
+type description struct{}
+
+func (description) NumMethods() int { return 1 }
+
+func (description) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	if n != 0 {
+		return "", nil, nil, nil, false
+	}
+	return RPC, encoding{},
+		func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+			return nil, srv.(*server).Authenticate(in1.(drpc.Stream))
+		}, (*server).Authenticate, true
+}
+
+// encoding is the drpc.Encoding of the *[]byte messages of the rpc.
+type encoding struct{}
+
+func (encoding) Marshal(msg drpc.Message) ([]byte, error) {
+	buf, ok := msg.(*[]byte)
+	if !ok {
+		return nil, drpc.InternalError.New("invalid session message type: %T", msg)
+	}
+	return *buf, nil
+}
+
+func (encoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	out, ok := msg.(*[]byte)
+	if !ok {
+		return drpc.InternalError.New("invalid session message type: %T", msg)
+	}
+	*out = append((*out)[:0], buf...)
+	return nil
+}
+
+// HMAC returns a Prover answering challenges with the identity and the
+// HMAC-SHA256 of the challenge by the shared secret.
+func HMAC(identity string, secret []byte) Prover {
+	return hmacProver{identity: identity, secret: secret}
+}
+
+type hmacProver struct {
+	identity string
+	secret   []byte
+}
+
+func (p hmacProver) Respond(ctx context.Context, challenge []byte) ([]byte, error) {
+	response := append([]byte(p.identity), 0)
+	return append(response, hmacSum(p.secret, challenge)...), nil
+}
+
+// HMACChallenger returns a Challenger sending random challenges and accepting
+// the responses of HMAC Provers with one of the secrets, keyed by identity.
+func HMACChallenger(secrets map[string][]byte) Challenger {
+	copied := make(map[string][]byte, len(secrets))
+	for identity, secret := range secrets {
+		copied[identity] = secret
+	}
+	return hmacChallenger{secrets: copied}
+}
+
+type hmacChallenger struct {
+	secrets map[string][]byte
+}
+
+func (c hmacChallenger) Challenge(ctx context.Context) ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (c hmacChallenger) Verify(ctx context.Context, challenge, response []byte) (string, error) {
+	i := bytes.IndexByte(response, 0)
+	if i < 0 {
+		return "", Error.New("malformed response")
+	}
+	identity := string(response[:i])
+	secret, ok := c.secrets[identity]
+	if !ok || !hmac.Equal(hmacSum(secret, challenge), response[i+1:]) {
+		return "", Error.New("invalid response for %q", identity)
+	}
+	return identity, nil
+}
+
+// hmacSum returns the HMAC-SHA256 of msg with the secret.
+func hmacSum(secret, msg []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(msg)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsession
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+	"storj.io/drpc/drpctest"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	mux := drpcmux.New()
+	assert.NoError(t, Register(mux, HMACChallenger(map[string][]byte{"alice": []byte("secret")})))
+	srv := drpcserver.New(NewHandler(drpctest.HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		if rpc == RPC {
+			return mux.HandleRPC(stream, rpc)
+		}
+		var in []byte
+		if err := stream.MsgRecv(&in, encoding{}); err != nil {
+			return err
+		}
+		identity, _ := Identity(stream.Context())
+		out := []byte(identity)
+		return stream.MsgSend(&out, encoding{})
+	})))
+
+	dial := func() drpc.Conn {
+		pc, ps := net.Pipe()
+		ctx.Run(func(ctx context.Context) { _ = srv.ServeOne(ctx, ps) })
+		return drpcconn.New(pc)
+	}
+	invoke := func(conn drpc.Conn) (string, error) {
+		var in, out []byte
+		err := conn.Invoke(ctx, "rpc", encoding{}, &in, &out)
+		return string(out), err
+	}
+
+	// an authenticated conn serves every rpc without credentials
+	conn := dial()
+	assert.NoError(t, Authenticate(ctx, conn, HMAC("alice", []byte("secret"))))
+	for i := 0; i < 2; i++ {
+		identity, err := invoke(conn)
+		assert.NoError(t, err)
+		assert.Equal(t, identity, "alice")
+	}
+	assert.NoError(t, conn.Close())
+
+	// other conns are rejected
+	conn = dial()
+	_, err := invoke(conn)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Unauthenticated)
+	assert.NoError(t, conn.Close())
+
+	conn = dial()
+	err = Authenticate(ctx, conn, HMAC("alice", []byte("guess")))
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Unauthenticated)
+	_, err = invoke(conn)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Unauthenticated)
+	assert.NoError(t, conn.Close())
+}
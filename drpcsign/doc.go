@@ -0,0 +1,9 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcsign signs requests with HMAC or ed25519 keys and verifies them
+// on the server, for deployments that need per-request authenticity beyond
+// what the transport provides. A signature covers the rpc name, a hash of the
+// request message of unary rpcs, the time it was made, and the key ID, so
+// servers can rotate keys by accepting several key IDs at once.
+package drpcsign
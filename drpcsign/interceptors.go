@@ -0,0 +1,162 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsign
+
+import (
+	"context"
+	"time"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// DefaultMaxSkew is how far the time of a signature may be from the clock of
+// the server when NewHandler is passed a non-positive skew.
+const DefaultMaxSkew = 5 * time.Minute
+
+// UnaryClientInterceptor returns an interceptor that signs unary rpcs with the
+// signer. The signature covers a hash of the request message, which is
+// marshaled an extra time to compute it, so the encoding must marshal equal
+// messages to the same bytes.
+func UnaryClientInterceptor(signer Signer) drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		payload, err := enc.Marshal(in)
+		if err != nil {
+			return err
+		}
+		ctx, err = sign(ctx, cc, signer, rpc, modeUnary, payload)
+		if err != nil {
+			return err
+		}
+		return next(ctx, rpc, enc, in, out, cc)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that signs the opening of
+// streams with the signer. The messages sent on the stream are not covered by
+// the signature.
+func StreamClientInterceptor(signer Signer) drpcclient.StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+		ctx, err := sign(ctx, cc, signer, rpc, modeStream, nil)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, rpc, enc, cc)
+	}
+}
+
+// sign adds the signature of the rpc to the outgoing metadata of the context.
+func sign(ctx context.Context, cc *drpcclient.ClientConn, signer Signer, rpc, mode string, payload []byte) (context.Context, error) {
+	h := header{keyID: signer.KeyID(), at: time.Now(), mode: mode}
+	sig, err := signer.Sign(message(h.keyID, rpc, mode, h.at, payload))
+	if err != nil {
+		return ctx, Error.Wrap(err)
+	}
+	h.sig = sig
+	return cc.AddMetadata(ctx, drpcmetadata.Signature, h.String()), nil
+}
+
+// keyIDKey is the context key for the ID of the key that signed an rpc.
+type keyIDKey struct{}
+
+// KeyID returns the ID of the key whose signature was verified for the rpc
+// whose handler was passed the context.
+func KeyID(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(keyIDKey{}).(string)
+	return keyID, ok
+}
+
+// NewHandler returns a drpc.Handler that rejects rpcs without a valid
+// signature by the verifier, or whose signature was made more than maxSkew
+// away from now, with the gRPC UNAUTHENTICATED code. The signature of a unary
+// rpc is verified against the request message when the handler receives it,
+// which then fails instead of returning the message. Signatures are not
+// protected against replay within the skew.
+func NewHandler(handler drpc.Handler, verifier Verifier, maxSkew time.Duration) drpc.Handler {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return signHandler{handler: handler, verifier: verifier, maxSkew: maxSkew}
+}
+
+type signHandler struct {
+	handler  drpc.Handler
+	verifier Verifier
+	maxSkew  time.Duration
+}
+
+func (h signHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	value, ok := drpcmetadata.Lookup(stream.Context(), drpcmetadata.Signature)
+	if !ok {
+		return drpcerr.WithCode(Error.New("missing signature"), drpcerr.Unauthenticated)
+	}
+	hdr, err := parseHeader(value)
+	if err != nil {
+		return drpcerr.WithCode(err, drpcerr.Unauthenticated)
+	}
+	if skew := time.Since(hdr.at); skew > h.maxSkew || skew < -h.maxSkew {
+		return drpcerr.WithCode(Error.New("signature time outside of allowed skew"), drpcerr.Unauthenticated)
+	}
+
+	vs := &verifyStream{
+		Stream: stream,
+		ctx:    context.WithValue(stream.Context(), keyIDKey{}, hdr.keyID),
+		h:      h,
+		hdr:    hdr,
+		rpc:    rpc,
+	}
+	if hdr.mode == modeStream {
+		if err := vs.verify(nil); err != nil {
+			return err
+		}
+	}
+	return h.handler.HandleRPC(vs, rpc)
+}
+
+// verifyStream verifies the signature of a unary rpc against the first
+// message received on it.
+type verifyStream struct {
+	drpc.Stream
+	ctx context.Context
+
+	h        signHandler
+	hdr      header
+	rpc      string
+	verified bool
+}
+
+func (s *verifyStream) Context() context.Context { return s.ctx }
+
+func (s *verifyStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if s.verified {
+		return s.Stream.MsgRecv(msg, enc)
+	}
+	return s.Stream.MsgRecv(msg, verifyEncoding{Encoding: enc, s: s})
+}
+
+// verify checks the signature against the payload.
+func (s *verifyStream) verify(payload []byte) error {
+	msg := message(s.hdr.keyID, s.rpc, s.hdr.mode, s.hdr.at, payload)
+	if err := s.h.verifier.Verify(s.hdr.keyID, msg, s.hdr.sig); err != nil {
+		return drpcerr.WithCode(err, drpcerr.Unauthenticated)
+	}
+	s.verified = true
+	return nil
+}
+
+// verifyEncoding verifies the signature against the bytes of a message before
+// unmarshaling it.
+type verifyEncoding struct {
+	drpc.Encoding
+	s *verifyStream
+}
+
+func (e verifyEncoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	if err := e.s.verify(buf); err != nil {
+		return err
+	}
+	return e.Encoding.Unmarshal(buf, msg)
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsign
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcsign")
+
+// Signer signs requests with a key.
+type Signer interface {
+	// KeyID identifies the key to the Verifier.
+	KeyID() string
+
+	// Sign returns the signature of the message.
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Verifier verifies the signatures of requests.
+type Verifier interface {
+	// Verify returns an error unless sig is a valid signature of msg by the
+	// key with the ID.
+	Verify(keyID string, msg, sig []byte) error
+}
+
+// HMAC returns a Signer that signs with HMAC-SHA256 using the shared secret.
+func HMAC(keyID string, secret []byte) Signer {
+	return hmacSigner{keyID: keyID, secret: secret}
+}
+
+type hmacSigner struct {
+	keyID  string
+	secret []byte
+}
+
+func (s hmacSigner) KeyID() string { return s.keyID }
+
+func (s hmacSigner) Sign(msg []byte) ([]byte, error) {
+	return hmacSum(s.secret, msg), nil
+}
+
+// Ed25519 returns a Signer that signs with the ed25519 private key.
+func Ed25519(keyID string, key ed25519.PrivateKey) Signer {
+	return ed25519Signer{keyID: keyID, key: key}
+}
+
+type ed25519Signer struct {
+	keyID string
+	key   ed25519.PrivateKey
+}
+
+func (s ed25519Signer) KeyID() string { return s.keyID }
+
+func (s ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	if len(s.key) != ed25519.PrivateKeySize {
+		return nil, Error.New("invalid ed25519 private key size: %d", len(s.key))
+	}
+	return ed25519.Sign(s.key, msg), nil
+}
+
+// Rotating is a Signer that delegates to the Signer most recently passed to
+// Set, so that clients can rotate keys without rebuilding their interceptors.
+// It is safe for concurrent use.
+type Rotating struct {
+	signer atomic.Value // of signerBox
+}
+
+// signerBox gives the values stored in Rotating a single concrete type.
+type signerBox struct{ Signer }
+
+// NewRotating returns a Rotating that starts with the signer.
+func NewRotating(signer Signer) *Rotating {
+	r := new(Rotating)
+	r.Set(signer)
+	return r
+}
+
+// Set replaces the signer used for new requests.
+func (r *Rotating) Set(signer Signer) { r.signer.Store(signerBox{signer}) }
+
+// KeyID implements Signer.
+func (r *Rotating) KeyID() string { return r.load().KeyID() }
+
+// Sign implements Signer.
+func (r *Rotating) Sign(msg []byte) ([]byte, error) { return r.load().Sign(msg) }
+
+func (r *Rotating) load() Signer { return r.signer.Load().(signerBox).Signer }
+
+// KeySet is a Verifier that accepts signatures by any of a set of keys. Keys
+// are rotated by adding the new key, moving the clients to it, and then
+// removing the old key. It is safe for concurrent use.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]verifyKey
+}
+
+// verifyKey is either an HMAC secret or an ed25519 public key.
+type verifyKey struct {
+	secret []byte
+	public ed25519.PublicKey
+}
+
+// NewKeySet returns an empty KeySet.
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]verifyKey)}
+}
+
+// AddHMAC accepts HMAC-SHA256 signatures by the shared secret with the key ID.
+func (k *KeySet) AddHMAC(keyID string, secret []byte) {
+	k.add(keyID, verifyKey{secret: secret})
+}
+
+// AddEd25519 accepts ed25519 signatures by the public key with the key ID.
+func (k *KeySet) AddEd25519(keyID string, key ed25519.PublicKey) {
+	k.add(keyID, verifyKey{public: key})
+}
+
+// Remove stops accepting signatures by the key with the ID.
+func (k *KeySet) Remove(keyID string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.keys, keyID)
+}
+
+func (k *KeySet) add(keyID string, key verifyKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[keyID] = key
+}
+
+// Verify implements Verifier.
+func (k *KeySet) Verify(keyID string, msg, sig []byte) error {
+	k.mu.RLock()
+	key, ok := k.keys[keyID]
+	k.mu.RUnlock()
+
+	switch {
+	case !ok:
+		return Error.New("unknown key %q", keyID)
+	case key.public != nil:
+		if len(key.public) != ed25519.PublicKeySize || !ed25519.Verify(key.public, msg, sig) {
+			return Error.New("invalid signature")
+		}
+	default:
+		if !hmac.Equal(hmacSum(key.secret, msg), sig) {
+			return Error.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+// hmacSum returns the HMAC-SHA256 of msg with the secret.
+func hmacSum(secret, msg []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+// The modes of a signature: unary signatures cover the hash of the request
+// message, while stream signatures only cover the rpc and the time.
+const (
+	modeUnary  = "u"
+	modeStream = "s"
+)
+
+// header is the parsed value of the drpcmetadata.Signature key, formatted as
+// "keyID,unixnano,mode,base64(signature)".
+type header struct {
+	keyID string
+	at    time.Time
+	mode  string
+	sig   []byte
+}
+
+// String formats the header for the metadata.
+func (h header) String() string {
+	return h.keyID + "," +
+		strconv.FormatInt(h.at.UnixNano(), 10) + "," +
+		h.mode + "," +
+		base64.RawStdEncoding.EncodeToString(h.sig)
+}
+
+// parseHeader parses a header formatted by String.
+func parseHeader(value string) (h header, err error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return h, Error.New("malformed signature")
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return h, Error.New("malformed signature time")
+	}
+	if parts[2] != modeUnary && parts[2] != modeStream {
+		return h, Error.New("unknown signature mode %q", parts[2])
+	}
+	sig, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return h, Error.New("malformed signature encoding")
+	}
+	return header{keyID: parts[0], at: time.Unix(0, nanos), mode: parts[2], sig: sig}, nil
+}
+
+// message returns the bytes that are signed for the rpc. Every variable length
+// field is prefixed with its length so that distinct requests never produce
+// the same message.
+func message(keyID, rpc, mode string, at time.Time, payload []byte) []byte {
+	hash := sha256.Sum256(payload)
+
+	msg := make([]byte, 0, 64+len(keyID)+len(rpc)+len(hash))
+	msg = append(msg, "drpcsign-v1"...)
+	msg = appendField(msg, keyID)
+	msg = appendField(msg, rpc)
+	msg = appendField(msg, mode)
+	msg = strconv.AppendInt(append(msg, ','), at.UnixNano(), 10)
+	msg = append(msg, hash[:]...)
+	return msg
+}
+
+// appendField appends the length prefixed field.
+func appendField(msg []byte, field string) []byte {
+	msg = strconv.AppendInt(append(msg, ','), int64(len(field)), 10)
+	return append(append(msg, ':'), field...)
+}
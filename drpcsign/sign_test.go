@@ -0,0 +1,108 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcsign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+func TestKeySet(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	keys := NewKeySet()
+	keys.AddHMAC("h1", []byte("secret"))
+	keys.AddEd25519("e1", pub)
+
+	for _, signer := range []Signer{HMAC("h1", []byte("secret")), Ed25519("e1", priv)} {
+		msg := message(signer.KeyID(), "rpc", modeUnary, time.Unix(0, 1), []byte("payload"))
+		sig, err := signer.Sign(msg)
+		assert.NoError(t, err)
+		assert.NoError(t, keys.Verify(signer.KeyID(), msg, sig))
+
+		tampered := message(signer.KeyID(), "rpc", modeUnary, time.Unix(0, 1), []byte("payloaD"))
+		assert.Error(t, keys.Verify(signer.KeyID(), tampered, sig))
+	}
+
+	assert.Error(t, keys.Verify("h1", []byte("msg"), hmacSum([]byte("other"), []byte("msg"))))
+
+	keys.Remove("h1")
+	assert.Error(t, keys.Verify("h1", []byte("msg"), hmacSum([]byte("secret"), []byte("msg"))))
+
+	h := header{keyID: "k", at: time.Unix(0, 42), mode: modeStream, sig: []byte{1, 2, 3}}
+	parsed, err := parseHeader(h.String())
+	assert.NoError(t, err)
+	assert.DeepEqual(t, parsed, h)
+
+	_, err = parseHeader("k,42,x,AQID")
+	assert.Error(t, err)
+}
+
+func TestSignedRequests(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	keys := NewKeySet()
+	keys.AddHMAC("old", []byte("old secret"))
+
+	received := make(chan string, 1)
+	signer := NewRotating(HMAC("old", []byte("old secret")))
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		keyID, _ := KeyID(ctx)
+		received <- keyID
+		return in, nil
+	}), keys, 0), drpcclient.WithChainUnaryInterceptor(UnaryClientInterceptor(signer)))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	in, out := "hello", ""
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, <-received, "old")
+
+	// rotate to a new key while the old one is still accepted, then retire it
+	keys.AddHMAC("new", []byte("new secret"))
+	signer.Set(HMAC("new", []byte("new secret")))
+	keys.Remove("old")
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, <-received, "new")
+
+	signer.Set(HMAC("old", []byte("old secret")))
+	err = cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out)
+	assert.Error(t, err)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Unauthenticated)
+}
+
+func TestSignedStreams(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	keys := NewKeySet()
+	keys.AddEd25519("e1", pub)
+
+	cc, err := drpcclienttest.NewPipeClientConn(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		return in, nil
+	}), keys, time.Minute), drpcclient.WithChainStreamInterceptor(StreamClientInterceptor(Ed25519("e1", priv))))
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	stream, err := cc.NewStream(ctx, "rpc", drpctest.StringEncoding{})
+	assert.NoError(t, err)
+	in, out := "hello", ""
+	assert.NoError(t, stream.MsgSend(&in, drpctest.StringEncoding{}))
+	assert.NoError(t, stream.MsgRecv(&out, drpctest.StringEncoding{}))
+	assert.Equal(t, out, "hello")
+	assert.NoError(t, stream.Close())
+}
@@ -0,0 +1,10 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcspnego authenticates clients with Kerberos through SPNEGO tokens
+// sent in metadata, for enterprise environments that mandate Kerberos. The
+// token is sent on the first rpc of a conn, and the server caches the security
+// context it establishes for the rest of the rpcs on that conn. The GSSAPI
+// mechanism itself is provided by the application, such as with a Kerberos
+// library, through the Initiator and Acceptor interfaces.
+package drpcspnego
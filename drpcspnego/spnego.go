@@ -0,0 +1,194 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcspnego
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpccache"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+// Error is the class of errors returned by this package.
+var Error = errs.Class("drpcspnego")
+
+// Initiator initiates security contexts for a client, such as with the
+// credentials of its Kerberos principal.
+type Initiator interface {
+	// InitSecContext returns the initial SPNEGO token for the service
+	// principal of the server. DRPC responses carry no metadata, so the token
+	// must establish the context on its own, as a Kerberos AP-REQ without
+	// mutual authentication does.
+	InitSecContext(ctx context.Context) ([]byte, error)
+}
+
+// Acceptor accepts security contexts for a server, such as with the keytab of
+// its Kerberos service principal.
+type Acceptor interface {
+	// AcceptSecContext validates the SPNEGO token and returns the principal of
+	// the client and when the established context expires, such as the end
+	// time of its Kerberos ticket.
+	AcceptSecContext(ctx context.Context, token []byte) (principal string, expires time.Time, err error)
+}
+
+// Client authenticates the rpcs of a ClientConn with the tokens of an
+// Initiator. It is safe for concurrent use.
+type Client struct {
+	initiator   Initiator
+	established atomic.Bool
+}
+
+// NewClient returns a Client initiating contexts with the initiator.
+func NewClient(initiator Initiator) *Client {
+	return &Client{initiator: initiator}
+}
+
+// UnaryClientInterceptor returns an interceptor that sends a token with the
+// rpcs until one of them succeeds, after which the server authenticates the
+// conn by the context it established. If an rpc without a token fails with
+// the gRPC UNAUTHENTICATED code, such as after the ClientConn redialed or the
+// context expired, it is issued again once with a new token.
+func (c *Client) UnaryClientInterceptor() drpcclient.UnaryClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message, cc *drpcclient.ClientConn, next drpcclient.UnaryInvoker) error {
+		if c.established.Load() {
+			err := next(ctx, rpc, enc, in, out, cc)
+			if !drpcerr.HasCode(err, drpcerr.Unauthenticated) {
+				return err
+			}
+			c.established.Store(false)
+		}
+
+		tctx, err := c.negotiate(ctx, cc)
+		if err != nil {
+			return err
+		}
+		err = next(tctx, rpc, enc, in, out, cc)
+		if err == nil {
+			c.established.Store(true)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that sends a token with the
+// streams opened while no unary rpc established a context. Streams fail with
+// the gRPC UNAUTHENTICATED code when they are received on a conn without a
+// context, and are not issued again.
+func (c *Client) StreamClientInterceptor() drpcclient.StreamClientInterceptor {
+	return func(ctx context.Context, rpc string, enc drpc.Encoding, cc *drpcclient.ClientConn, next drpcclient.Streamer) (drpc.Stream, error) {
+		if !c.established.Load() {
+			var err error
+			if ctx, err = c.negotiate(ctx, cc); err != nil {
+				return nil, err
+			}
+		}
+		return next(ctx, rpc, enc, cc)
+	}
+}
+
+// negotiate adds a new token to the outgoing metadata of the context.
+func (c *Client) negotiate(ctx context.Context, cc *drpcclient.ClientConn) (context.Context, error) {
+	token, err := c.initiator.InitSecContext(ctx)
+	if err != nil {
+		return ctx, Error.Wrap(err)
+	}
+	return cc.AddMetadata(ctx, drpcmetadata.Negotiate, scheme+base64.StdEncoding.EncodeToString(token)), nil
+}
+
+// scheme prefixes the tokens in the metadata, as in the HTTP Negotiate scheme.
+const scheme = "Negotiate "
+
+// principalKey is the context key for the principal of an rpc.
+type principalKey struct{}
+
+// Principal returns the authenticated principal of the client of the rpc whose
+// handler was passed the context.
+func Principal(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok
+}
+
+// NewHandler returns a drpc.Handler that authenticates rpcs with the acceptor,
+// and rejects those without a valid token or an established context on their
+// conn with the gRPC UNAUTHENTICATED code. Established contexts are cached in
+// the drpccache of the conn until they expire.
+func NewHandler(handler drpc.Handler, acceptor Acceptor) drpc.Handler {
+	return spnegoHandler{handler: handler, acceptor: acceptor}
+}
+
+type spnegoHandler struct {
+	handler  drpc.Handler
+	acceptor Acceptor
+}
+
+// secContextKey is the drpccache key for the context established on a conn.
+type secContextKey struct{}
+
+// secContext is a context established on a conn.
+type secContext struct {
+	principal string
+	expires   time.Time
+}
+
+func (h spnegoHandler) HandleRPC(stream drpc.Stream, rpc string) error {
+	ctx := stream.Context()
+	cache := drpccache.FromContext(ctx)
+
+	var sc secContext
+	if value, ok := drpcmetadata.Lookup(ctx, drpcmetadata.Negotiate); ok {
+		var err error
+		if sc, err = h.accept(ctx, value); err != nil {
+			return drpcerr.WithCode(err, drpcerr.Unauthenticated)
+		}
+		if cache != nil {
+			cache.Store(secContextKey{}, sc)
+		}
+	} else {
+		var ok bool
+		if cache != nil {
+			sc, ok = cache.Load(secContextKey{}).(secContext)
+		}
+		if !ok || !time.Now().Before(sc.expires) {
+			return drpcerr.WithCode(Error.New("no established context"), drpcerr.Unauthenticated)
+		}
+	}
+
+	return h.handler.HandleRPC(principalStream{
+		Stream: stream,
+		ctx:    context.WithValue(ctx, principalKey{}, sc.principal),
+	}, rpc)
+}
+
+// accept establishes a context with the token in the metadata value.
+func (h spnegoHandler) accept(ctx context.Context, value string) (secContext, error) {
+	if !strings.HasPrefix(value, scheme) {
+		return secContext{}, Error.New("malformed token")
+	}
+	token, err := base64.StdEncoding.DecodeString(value[len(scheme):])
+	if err != nil {
+		return secContext{}, Error.New("malformed token encoding")
+	}
+	principal, expires, err := h.acceptor.AcceptSecContext(ctx, token)
+	if err != nil {
+		return secContext{}, Error.Wrap(err)
+	}
+	return secContext{principal: principal, expires: expires}, nil
+}
+
+// principalStream is a stream whose context carries the principal.
+type principalStream struct {
+	drpc.Stream
+	ctx context.Context
+}
+
+func (s principalStream) Context() context.Context { return s.ctx }
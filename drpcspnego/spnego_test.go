@@ -0,0 +1,95 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcspnego
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcclienttest"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpctest"
+)
+
+func TestNegotiate(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	acceptor := &testAcceptor{}
+	received := make(chan string, 1)
+	dialer := drpcclienttest.NewPipeDialer(ctx, NewHandler(drpctest.StringHandler(func(ctx context.Context, rpc, in string) (string, error) {
+		principal, _ := Principal(ctx)
+		received <- principal
+		return in, nil
+	}), acceptor))
+
+	initiator := &testInitiator{token: "alice@EXAMPLE.ORG"}
+	client := NewClient(initiator)
+	cc, err := drpcclient.NewClientConnWithOptions(ctx, dialer.Dial,
+		drpcclient.WithChainUnaryInterceptor(client.UnaryClientInterceptor()),
+		drpcclient.WithChainStreamInterceptor(client.StreamClientInterceptor()),
+	)
+	assert.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	// the first rpc establishes the context that the next ones reuse
+	in, out := "hello", ""
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+		assert.Equal(t, <-received, "alice@EXAMPLE.ORG")
+	}
+	assert.Equal(t, initiator.calls.Load(), int32(1))
+	assert.Equal(t, acceptor.calls.Load(), int32(1))
+
+	stream, err := cc.NewStream(ctx, "rpc", drpctest.StringEncoding{})
+	assert.NoError(t, err)
+	assert.NoError(t, stream.MsgSend(&in, drpctest.StringEncoding{}))
+	assert.NoError(t, stream.MsgRecv(&out, drpctest.StringEncoding{}))
+	assert.Equal(t, <-received, "alice@EXAMPLE.ORG")
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, initiator.calls.Load(), int32(1))
+
+	// a redialed conn has no context, so it is sent a new token
+	assert.NoError(t, dialer.Conns()[0].Close())
+	assert.NoError(t, cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out))
+	assert.Equal(t, <-received, "alice@EXAMPLE.ORG")
+	assert.Equal(t, len(dialer.Conns()), 2)
+	assert.Equal(t, initiator.calls.Load(), int32(2))
+	assert.Equal(t, acceptor.calls.Load(), int32(2))
+
+	// rejected tokens fail the rpc
+	assert.NoError(t, dialer.Conns()[1].Close())
+	initiator.token = "mallory@EXAMPLE.ORG"
+	err = cc.Invoke(ctx, "rpc", drpctest.StringEncoding{}, &in, &out)
+	assert.Error(t, err)
+	assert.Equal(t, drpcerr.Code(err), drpcerr.Unauthenticated)
+}
+
+type testInitiator struct {
+	token string
+	calls atomic.Int32
+}
+
+func (i *testInitiator) InitSecContext(ctx context.Context) ([]byte, error) {
+	i.calls.Add(1)
+	return []byte(i.token), nil
+}
+
+type testAcceptor struct {
+	calls atomic.Int32
+}
+
+func (a *testAcceptor) AcceptSecContext(ctx context.Context, token []byte) (string, time.Time, error) {
+	a.calls.Add(1)
+	if string(token) != "alice@EXAMPLE.ORG" {
+		return "", time.Time{}, errors.New("unknown principal")
+	}
+	return string(token), time.Now().Add(time.Hour), nil
+}
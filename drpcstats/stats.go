@@ -5,12 +5,21 @@ package drpcstats
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 // Stats keeps counters of read and written bytes.
 type Stats struct {
 	Read    uint64
 	Written uint64
+
+	// Cancels counts the streams that were canceled, either locally or by the
+	// remote.
+	Cancels uint64
+
+	// CancelLatency is the total time between streams being canceled and their
+	// contexts being done, which is when handlers observe the cancellation.
+	CancelLatency time.Duration
 }
 
 // AddRead atomically adds n bytes to the Read counter.
@@ -27,10 +36,30 @@ func (s *Stats) AddWritten(n uint64) {
 	}
 }
 
+// AddCancel atomically records a canceled stream whose context was done the
+// latency after it was canceled.
+func (s *Stats) AddCancel(latency time.Duration) {
+	if s != nil {
+		atomic.AddUint64(&s.Cancels, 1)
+		atomic.AddInt64((*int64)(&s.CancelLatency), int64(latency))
+	}
+}
+
+// MeanCancelLatency returns the average cancellation latency, or zero if no
+// streams were canceled.
+func (s Stats) MeanCancelLatency() time.Duration {
+	if s.Cancels == 0 {
+		return 0
+	}
+	return s.CancelLatency / time.Duration(s.Cancels)
+}
+
 // AtomicClone returns a copy of the stats that is safe to use concurrently with Add methods.
 func (s *Stats) AtomicClone() Stats {
 	return Stats{
-		Read:    atomic.LoadUint64(&s.Read),
-		Written: atomic.LoadUint64(&s.Written),
+		Read:          atomic.LoadUint64(&s.Read),
+		Written:       atomic.LoadUint64(&s.Written),
+		Cancels:       atomic.LoadUint64(&s.Cancels),
+		CancelLatency: time.Duration(atomic.LoadInt64((*int64)(&s.CancelLatency))),
 	}
 }
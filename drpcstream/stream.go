@@ -9,6 +9,8 @@ import (
 	"io"
 	"runtime/trace"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/zeebo/errs"
 
@@ -16,6 +18,7 @@ import (
 	"storj.io/drpc/drpcctx"
 	"storj.io/drpc/drpcdebug"
 	"storj.io/drpc/drpcenc"
+	"storj.io/drpc/drpcmetadata"
 	"storj.io/drpc/drpcsignal"
 	"storj.io/drpc/drpcwire"
 	"storj.io/drpc/internal/drpcopts"
@@ -32,6 +35,13 @@ type Options struct {
 	// RawFlush dynamically.
 	ManualFlush bool
 
+	// FlushDelay, if positive and ManualFlush is not set, causes message sends
+	// to be buffered for up to this long before being flushed, so that many
+	// small messages sent in quick succession are coalesced into fewer writes.
+	// Receiving on the stream and sending control packets still flush
+	// immediately.
+	FlushDelay time.Duration
+
 	// MaximumBufferSize causes the Stream to drop any internal buffers that are
 	// larger than this amount to control maximum memory usage at the expense of
 	// more allocations. 0 is unlimited.
@@ -57,6 +67,16 @@ type Stream struct {
 	pbuf packetBuffer
 	wbuf []byte
 
+	lastRecv int64 // unix nanoseconds of the last packet received, atomically accessed
+	canceled int64 // unix nanoseconds of when the stream was canceled, atomically accessed
+
+	// header and trailer are the response metadata received from the remote,
+	// and pending is the trailer sent before the stream ends. They are
+	// protected by mu.
+	header  map[string]string
+	trailer map[string]string
+	pending map[string]string
+
 	mu   sync.Mutex // protects state transitions
 	sigs struct {
 		send   drpcsignal.Signal // set when done sending messages
@@ -105,6 +125,9 @@ func NewWithOptions(ctx context.Context, sid uint64, wr *drpcwire.Writer, opts O
 	// initialize the packet buffer
 	s.pbuf.init()
 
+	// let handlers send response metadata through the context
+	s.ctx.rw = s
+
 	return s
 }
 
@@ -132,16 +155,20 @@ func (s *Stream) log(what string, cb func() string) {
 type streamCtx struct {
 	context.Context
 	tr  drpc.Transport
+	rw  *Stream
 	sig drpcsignal.Signal
 }
 
-// Value checks for the drpc.Transport key and forwards if necessary.
-// We do this because using drpcctx to make a new context would cause
-// an extra allocation.
+// Value checks for the drpc.Transport and response writer keys and forwards
+// if necessary. We do this because using drpcctx to make a new context would
+// cause an extra allocation.
 func (s *streamCtx) Value(key interface{}) interface{} {
 	if s.tr != nil && key == (drpcctx.TransportKey{}) {
 		return s.tr
 	}
+	if s.rw != nil && key == (drpcctx.ResponseKey{}) {
+		return s.rw
+	}
 	return s.Context.Value(key)
 }
 
@@ -206,6 +233,10 @@ func (s *Stream) IsFinished() bool { return s.sigs.fin.IsSet() }
 //	}
 func (s *Stream) SetManualFlush(mf bool) { s.opts.ManualFlush = mf }
 
+// SetFlushDelay sets the FlushDelay option. It cannot be called concurrently
+// with any sends or receives on the stream.
+func (s *Stream) SetFlushDelay(d time.Duration) { s.opts.FlushDelay = d }
+
 //
 // packet handler
 //
@@ -220,6 +251,7 @@ func (s *Stream) HandlePacket(pkt drpcwire.Packet) (err error) {
 	}
 
 	drpcopts.GetStreamStats(&s.opts.Internal).AddRead(uint64(len(pkt.Data)))
+	atomic.StoreInt64(&s.lastRecv, time.Now().UnixNano())
 
 	if s.sigs.term.IsSet() {
 		return nil
@@ -249,6 +281,7 @@ func (s *Stream) HandlePacket(pkt drpcwire.Packet) (err error) {
 
 	case drpcwire.KindCancel:
 		err := context.Canceled
+		s.markCanceled()
 		s.sigs.cancel.Set(err)
 		s.sigs.send.Set(io.EOF) // in this state, gRPC returns io.EOF on send.
 		s.terminate(err)
@@ -266,6 +299,26 @@ func (s *Stream) HandlePacket(pkt drpcwire.Packet) (err error) {
 		s.terminateIfBothClosed()
 		return nil
 
+	case drpcwire.KindHeader, drpcwire.KindTrailer:
+		// metadata that does not decode is ignored like an unknown control
+		// packet so that it cannot fail an rpc that otherwise succeeded.
+		if metadata, err := drpcmetadata.Decode(pkt.Data); err == nil {
+			if pkt.Kind == drpcwire.KindHeader {
+				s.header = merge(s.header, metadata)
+			} else {
+				s.trailer = merge(s.trailer, metadata)
+			}
+		}
+		return nil
+
+	case drpcwire.KindKeepalive:
+		// answer pings asynchronously so that a blocked writer cannot stall
+		// the reader delivering packets.
+		if len(pkt.Data) == 0 {
+			go func() { _ = s.sendKeepalive(keepaliveAck) }()
+		}
+		return nil
+
 	default:
 		// ignore any unknown control packets for forwards compatibility
 		if pkt.Control {
@@ -289,6 +342,10 @@ func (s *Stream) checkFinished() {
 	if s.sigs.term.IsSet() && s.write.Unlocked() && s.read.Unlocked() {
 		if s.sigs.fin.Set(nil) {
 			s.log("FIN", func() string { return "" })
+			if canceled := atomic.LoadInt64(&s.canceled); canceled != 0 {
+				latency := time.Duration(time.Now().UnixNano() - canceled)
+				drpcopts.GetStreamStats(&s.opts.Internal).AddCancel(latency)
+			}
 			s.ctx.sig.Set(context.Canceled)
 			if s.fin != nil {
 				s.fin <- struct{}{}
@@ -300,6 +357,12 @@ func (s *Stream) checkFinished() {
 	}
 }
 
+// markCanceled records the time the stream was first canceled so that the
+// latency until its context is done can be measured.
+func (s *Stream) markCanceled() {
+	atomic.CompareAndSwapInt64(&s.canceled, 0, time.Now().UnixNano())
+}
+
 // checkCancelError will replace the error with one from the cancel signal if it
 // is set. This is to prevent errors from reads/writes to a transport after it
 // has been asynchronously closed due to context cancelation.
@@ -434,7 +497,7 @@ func (s *Stream) checkRecvFlush() (err error) {
 		return err
 	}
 
-	if s.opts.ManualFlush && !s.wr.Empty() {
+	if (s.opts.ManualFlush || s.opts.FlushDelay > 0) && !s.wr.Empty() {
 		if err := s.RawFlush(); err != nil {
 			return err
 		}
@@ -485,10 +548,13 @@ func (s *Stream) MsgSend(msg drpc.Message, enc drpc.Encoding) (err error) {
 	if err := s.rawWriteLocked(drpcwire.KindMessage, wbuf); err != nil {
 		return err
 	}
-	if !s.opts.ManualFlush {
-		return s.rawFlushLocked()
+	if s.opts.ManualFlush {
+		return nil
 	}
-	return nil
+	if s.opts.FlushDelay > 0 {
+		return s.checkCancelError(errs.Wrap(s.wr.FlushAfter(s.opts.FlushDelay)))
+	}
+	return s.rawFlushLocked()
 }
 
 // MsgRecv recives some message data and unmarshals it with enc into msg.
@@ -539,8 +605,12 @@ func (s *Stream) SendError(serr error) (err error) {
 
 	s.sigs.send.Set(io.EOF) // in this state, gRPC returns io.EOF on send.
 	s.terminate(termError)
+	trailer := s.takeTrailerLocked()
 	s.mu.Unlock()
 
+	if err := s.writeTrailerLocked(trailer); err != nil {
+		return s.checkCancelError(err)
+	}
 	return s.checkCancelError(s.sendPacketLocked(drpcwire.KindError, false, drpcwire.MarshalError(serr)))
 }
 
@@ -573,6 +643,148 @@ func (s *Stream) SendCancel(err error) (busy bool, _ error) {
 	return false, s.checkCancelError(s.sendPacketLocked(drpcwire.KindCancel, true, nil))
 }
 
+// keepaliveAck is the body of a KindKeepalive packet answering a ping.
+var keepaliveAck = []byte{1}
+
+// SendKeepalive sends a keepalive ping to the remote, which answers it if it
+// supports keepalives. It is a no-op if the stream is terminated.
+func (s *Stream) SendKeepalive() (err error) {
+	s.log("CALL", func() string { return "SendKeepalive()" })
+
+	return s.sendKeepalive(nil)
+}
+
+// sendKeepalive sends a keepalive control packet with the data.
+func (s *Stream) sendKeepalive(data []byte) (err error) {
+	s.mu.Lock()
+	if s.sigs.term.IsSet() {
+		s.mu.Unlock()
+		return nil
+	}
+
+	defer s.checkFinished()
+	s.write.Lock()
+	defer s.write.Unlock()
+
+	s.mu.Unlock()
+
+	return s.checkCancelError(s.sendPacketLocked(drpcwire.KindKeepalive, true, data))
+}
+
+//
+// response metadata
+//
+
+// SendHeader sends the metadata to the remote as the header of the response,
+// which is typically done by a server before it sends any messages. It may be
+// called more than once, merging the metadata. It is a no-op if the stream is
+// terminated.
+func (s *Stream) SendHeader(metadata map[string]string) (err error) {
+	s.log("CALL", func() string { return fmt.Sprintf("SendHeader(%v)", metadata) })
+
+	data, err := drpcmetadata.Encode(nil, metadata)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.sigs.term.IsSet() {
+		s.mu.Unlock()
+		return nil
+	}
+
+	defer s.checkFinished()
+	s.write.Lock()
+	defer s.write.Unlock()
+
+	s.mu.Unlock()
+
+	return s.checkCancelError(s.sendPacketLocked(drpcwire.KindHeader, true, data))
+}
+
+// SetTrailer adds the metadata to the trailer of the response, which is sent
+// to the remote right before the stream is ended by CloseSend, SendError or
+// Close. It is a no-op if the trailer was already sent.
+func (s *Stream) SetTrailer(metadata map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sigs.send.IsSet() || s.sigs.term.IsSet() {
+		return
+	}
+	s.pending = merge(s.pending, metadata)
+}
+
+// Header returns a copy of the header metadata received from the remote.
+func (s *Stream) Header() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return merge(nil, s.header)
+}
+
+// Trailer returns a copy of the trailer metadata received from the remote. It
+// is complete once the remote ended the stream.
+func (s *Stream) Trailer() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return merge(nil, s.trailer)
+}
+
+// takeTrailerLocked returns the pending trailer and forgets it so that it is
+// sent at most once. It must be called with s.mu held.
+func (s *Stream) takeTrailerLocked() map[string]string {
+	trailer := s.pending
+	s.pending = nil
+	return trailer
+}
+
+// writeTrailerLocked writes the trailer, if any, without flushing so that it
+// goes out with the packet ending the stream. It must be called with the
+// write lock held.
+func (s *Stream) writeTrailerLocked(trailer map[string]string) error {
+	if len(trailer) == 0 {
+		return nil
+	}
+	data, err := drpcmetadata.Encode(nil, trailer)
+	if err != nil {
+		return err
+	}
+	fr := s.newFrameLocked(drpcwire.KindTrailer)
+	fr.Data = data
+	fr.Control = true
+	fr.Done = true
+
+	drpcopts.GetStreamStats(&s.opts.Internal).AddWritten(uint64(len(data)))
+	s.log("SEND", fr.String)
+
+	return errs.Wrap(s.wr.WriteFrame(fr))
+}
+
+// merge returns dst with the entries of src added, allocating dst if needed.
+func merge(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
+
+// LastReceived returns the time the last packet was received for the stream,
+// or the zero time if none has been.
+func (s *Stream) LastReceived() time.Time {
+	if ns := atomic.LoadInt64(&s.lastRecv); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
 // Close terminates the stream and sends that the stream has been closed to the
 // remote. It is a no-op if the stream is already terminated.
 func (s *Stream) Close() (err error) {
@@ -589,8 +801,12 @@ func (s *Stream) Close() (err error) {
 	defer s.write.Unlock()
 
 	s.terminate(termClosed)
+	trailer := s.takeTrailerLocked()
 	s.mu.Unlock()
 
+	if err := s.writeTrailerLocked(trailer); err != nil {
+		return s.checkCancelError(err)
+	}
 	return s.checkCancelError(s.sendPacketLocked(drpcwire.KindClose, false, nil))
 }
 
@@ -612,8 +828,12 @@ func (s *Stream) CloseSend() (err error) {
 
 	s.sigs.send.Set(sendClosed)
 	s.terminateIfBothClosed()
+	trailer := s.takeTrailerLocked()
 	s.mu.Unlock()
 
+	if err := s.writeTrailerLocked(trailer); err != nil {
+		return s.checkCancelError(err)
+	}
 	return s.checkCancelError(s.sendPacketLocked(drpcwire.KindCloseSend, false, nil))
 }
 
@@ -630,6 +850,7 @@ func (s *Stream) Cancel(err error) bool {
 		return true
 	}
 
+	s.markCanceled()
 	s.sigs.cancel.Set(err)
 	s.sigs.send.Set(io.EOF) // in this state, gRPC returns io.EOF on send.
 	s.terminate(err)
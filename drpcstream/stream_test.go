@@ -7,8 +7,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/zeebo/assert"
 	"github.com/zeebo/errs"
@@ -252,6 +255,89 @@ func TestStream_CorkUntilFirstRead(t *testing.T) {
 	}
 }
 
+func TestStream_KeepaliveAnswersPing(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	pr, pw := io.Pipe()
+	defer func() { _ = pr.Close() }()
+
+	st := New(ctx, 1, drpcwire.NewWriter(pw, 0))
+	assert.That(t, st.LastReceived().IsZero())
+
+	assert.NoError(t, st.HandlePacket(drpcwire.Packet{
+		ID:      drpcwire.ID{Stream: 1, Message: 1},
+		Kind:    drpcwire.KindKeepalive,
+		Control: true,
+	}))
+	assert.That(t, !st.LastReceived().IsZero())
+
+	pkt, err := drpcwire.NewReader(pr).ReadPacket()
+	assert.NoError(t, err)
+	assert.Equal(t, pkt.Kind, drpcwire.KindKeepalive)
+	assert.That(t, pkt.Control)
+	assert.That(t, len(pkt.Data) > 0)
+}
+
+func TestStream_FlushDelayCoalesces(t *testing.T) {
+	ctx := drpctest.NewTracker(t)
+	defer ctx.Close()
+
+	var writes int64
+	wr := drpcwire.NewWriter(writerFunc(func(p []byte) (int, error) {
+		atomic.AddInt64(&writes, 1)
+		return len(p), nil
+	}), 0)
+
+	st := NewWithOptions(ctx, 1, wr, Options{FlushDelay: time.Hour})
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, st.MsgSend([]byte("small"), byteEncoding{}))
+	}
+	assert.Equal(t, atomic.LoadInt64(&writes), int64(0))
+
+	// closing the send side flushes every buffered message in one write.
+	assert.NoError(t, st.CloseSend())
+	assert.Equal(t, atomic.LoadInt64(&writes), int64(1))
+}
+
+// BenchmarkStream_SmallMessages measures sending small messages on a transport
+// where every write costs a few microseconds, like a syscall on a real
+// connection, with and without a flush delay.
+func BenchmarkStream_SmallMessages(b *testing.B) {
+	for _, delay := range []time.Duration{0, 100 * time.Microsecond} {
+		b.Run(fmt.Sprintf("delay=%v", delay), func(b *testing.B) {
+			ctx := drpctest.NewTracker(b)
+			defer ctx.Close()
+
+			var writes int64
+			wr := drpcwire.NewWriter(writerFunc(func(p []byte) (int, error) {
+				atomic.AddInt64(&writes, 1)
+				for start := time.Now(); time.Since(start) < 5*time.Microsecond; {
+				}
+				return len(p), nil
+			}), 0)
+
+			st := NewWithOptions(ctx, 1, wr, Options{FlushDelay: delay})
+			msg := []byte("small message")
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_ = st.MsgSend(msg, byteEncoding{})
+			}
+			_ = st.CloseSend()
+
+			b.ReportMetric(float64(atomic.LoadInt64(&writes))/float64(b.N), "writes/op")
+		})
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
 type byteEncoding struct{}
 
 func (byteEncoding) Marshal(msg drpc.Message) ([]byte, error) { return msg.([]byte), nil }
@@ -0,0 +1,6 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package drpcstreamutil contains generic helpers that remove the boilerplate
+// around raw MsgSend and MsgRecv loops on drpc streams.
+package drpcstreamutil
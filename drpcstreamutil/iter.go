@@ -0,0 +1,34 @@
+//go:build go1.23
+// +build go1.23
+
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"errors"
+	"io"
+	"iter"
+)
+
+// All returns an iterator over the messages of r until io.EOF, so that a
+// server stream or paginated rpc can be consumed with range. An error other
+// than io.EOF is yielded with the zero message and ends the iteration.
+// Breaking out of the loop does not close the stream r receives from.
+func All[M any](r Receiver[M]) iter.Seq2[M, error] {
+	return func(yield func(M, error) bool) {
+		for {
+			msg, err := r.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				yield(*new(M), err)
+				return
+			}
+			if !yield(msg, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build go1.23
+// +build go1.23
+
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc/drpctest"
+)
+
+func TestAll(t *testing.T) {
+	src := &sliceStream{recv: []string{"a", "b", "c"}}
+	r := NewReceiver(src, drpctest.StringEncoding{}, func() *string { return new(string) })
+
+	var got []string
+	for msg, err := range All(r) {
+		assert.NoError(t, err)
+		got = append(got, *msg)
+		if len(got) == 2 {
+			break
+		}
+	}
+	assert.DeepEqual(t, got, []string{"a", "b"})
+
+	failed := errors.New("failed")
+	var errs []error
+	for _, err := range All[int](ReceiverFunc[int](func() (int, error) { return 0, failed })) {
+		errs = append(errs, err)
+	}
+	assert.DeepEqual(t, errs, []error{failed})
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"context"
+	"io"
+)
+
+// PageFunc fetches the page of items of a paginated unary rpc that starts at
+// the page token, which is empty for the first page, and returns the token of
+// the next page, which is empty after the last page.
+type PageFunc[T any] func(ctx context.Context, token string) (items []T, next string, err error)
+
+// Paginate returns a Receiver of the items of every page fetched with fn, so
+// that a paginated unary rpc is consumed like a server stream. Pages are
+// fetched as the items of the previous page are received.
+func Paginate[T any](ctx context.Context, fn PageFunc[T]) Receiver[T] {
+	var items []T
+	var token string
+	done := false
+
+	return ReceiverFunc[T](func() (T, error) {
+		for len(items) == 0 {
+			if done {
+				return *new(T), io.EOF
+			}
+			page, next, err := fn(ctx, token)
+			if err != nil {
+				return *new(T), err
+			}
+			items, token, done = page, next, next == ""
+		}
+
+		item := items[0]
+		items = items[1:]
+		return item, nil
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// SendQueue sends messages on a stream from a background goroutine through a
+// bounded queue, so that producers only block when the queue is full. The
+// goroutine stops at the first failed send or once the context of the stream
+// is done, even if CloseSend is never called.
+type SendQueue struct {
+	stream  drpc.Stream
+	enc     drpc.Encoding
+	queue   chan drpc.Message
+	closing chan struct{}
+	done    chan struct{}
+
+	mu     sync.RWMutex // held for reading while enqueuing
+	closed bool
+	err    error // set before done is closed
+}
+
+// NewSendQueue returns a SendQueue that holds up to size messages waiting to
+// be sent on the stream with the encoding.
+func NewSendQueue(stream drpc.Stream, enc drpc.Encoding, size int) *SendQueue {
+	q := &SendQueue{
+		stream:  stream,
+		enc:     enc,
+		queue:   make(chan drpc.Message, size),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// run sends queued messages until the queue is closed and drained, a send
+// fails or the stream is done, so that the goroutine does not outlive a
+// stream whose caller gave up without calling CloseSend.
+func (q *SendQueue) run() {
+	defer close(q.done)
+
+	ctx := q.stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			q.err = ctx.Err()
+			return
+		case msg := <-q.queue:
+			if q.err = q.stream.MsgSend(msg, q.enc); q.err != nil {
+				return
+			}
+		case <-q.closing:
+			for {
+				select {
+				case msg := <-q.queue:
+					if q.err = q.stream.MsgSend(msg, q.enc); q.err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Send queues the message, waiting for space in the queue until the context is
+// done. It returns the error of an earlier failed send, or of the context of
+// the stream if it is done, once the queue stopped.
+func (q *SendQueue) Send(ctx context.Context, msg drpc.Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return drpc.ClosedError.New("send queue closed")
+	}
+	select {
+	case <-q.done:
+		return q.err
+	default:
+	}
+
+	select {
+	case q.queue <- msg:
+		return nil
+	case <-q.done:
+		return q.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseSend waits for the queued messages to be sent and then calls CloseSend
+// on the stream. It returns the first error encountered.
+func (q *SendQueue) CloseSend() error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.closing)
+	}
+	q.mu.Unlock()
+
+	<-q.done
+	if q.err != nil {
+		return q.err
+	}
+	return q.stream.CloseSend()
+}
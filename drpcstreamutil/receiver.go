@@ -0,0 +1,90 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"errors"
+	"io"
+
+	"storj.io/drpc"
+)
+
+// Receiver is a typed source of messages. Recv returns io.EOF once there are
+// no more messages.
+type Receiver[M any] interface {
+	Recv() (M, error)
+}
+
+// ReceiverFunc adapts a function to a Receiver.
+type ReceiverFunc[M any] func() (M, error)
+
+// Recv calls the function.
+func (f ReceiverFunc[M]) Recv() (M, error) { return f() }
+
+// NewReceiver returns a Receiver that receives messages from the stream with
+// the encoding into values returned by newMsg, such as new(pb.Response).
+func NewReceiver[M drpc.Message](stream drpc.Stream, enc drpc.Encoding, newMsg func() M) Receiver[M] {
+	return ReceiverFunc[M](func() (M, error) {
+		msg := newMsg()
+		if err := stream.MsgRecv(msg, enc); err != nil {
+			return *new(M), err
+		}
+		return msg, nil
+	})
+}
+
+// Map returns a Receiver that transforms every message of r with fn.
+func Map[M, N any](r Receiver[M], fn func(M) (N, error)) Receiver[N] {
+	return ReceiverFunc[N](func() (N, error) {
+		msg, err := r.Recv()
+		if err != nil {
+			return *new(N), err
+		}
+		return fn(msg)
+	})
+}
+
+// Filter returns a Receiver that skips the messages of r for which keep
+// returns false.
+func Filter[M any](r Receiver[M], keep func(M) bool) Receiver[M] {
+	return ReceiverFunc[M](func() (M, error) {
+		for {
+			msg, err := r.Recv()
+			if err != nil || keep(msg) {
+				return msg, err
+			}
+		}
+	})
+}
+
+// Collect receives every message from r until io.EOF. It is mostly useful to
+// turn a server stream into a slice in tests.
+func Collect[M any](r Receiver[M]) ([]M, error) {
+	var out []M
+	for {
+		msg, err := r.Recv()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		} else if err != nil {
+			return out, err
+		}
+		out = append(out, msg)
+	}
+}
+
+// Pipe sends every message from r on dst with the encoding until r returns
+// io.EOF, and then calls CloseSend on dst.
+func Pipe[M drpc.Message](dst drpc.Stream, enc drpc.Encoding, r Receiver[M]) error {
+	for {
+		msg, err := r.Recv()
+		if errors.Is(err, io.EOF) {
+			return dst.CloseSend()
+		} else if err != nil {
+			return err
+		}
+		if err := dst.MsgSend(msg, enc); err != nil {
+			return err
+		}
+	}
+}
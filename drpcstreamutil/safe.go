@@ -0,0 +1,45 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"storj.io/drpc"
+	"storj.io/drpc/drpcenc"
+)
+
+// SafeStream wraps a drpc.Stream so that MsgSend and CloseSend may be called
+// from many goroutines at once. Messages are marshaled by the caller and then
+// sent and flushed by a single goroutine in the order MsgSend was called, so a
+// message may be reused as soon as MsgSend returns. MsgRecv and Close are
+// passed through to the wrapped stream.
+type SafeStream struct {
+	drpc.Stream
+	queue *SendQueue
+}
+
+// NewSafeStream returns a SafeStream that holds up to size marshaled messages
+// waiting to be sent on the stream. MsgSend blocks while the queue is full.
+func NewSafeStream(stream drpc.Stream, size int) *SafeStream {
+	return &SafeStream{
+		Stream: stream,
+		queue:  NewSendQueue(stream, drpcenc.Raw{}, size),
+	}
+}
+
+// MsgSend marshals the message with the encoding and queues it to be sent. It
+// returns once the message is queued, so an error from sending is reported by
+// a later MsgSend or by CloseSend.
+func (s *SafeStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	data, err := drpcenc.MarshalAppend(msg, enc, nil)
+	if err != nil {
+		return err
+	}
+	return s.queue.Send(s.Stream.Context(), &data)
+}
+
+// CloseSend waits for every queued message to be sent and then calls CloseSend
+// on the wrapped stream.
+func (s *SafeStream) CloseSend() error {
+	return s.queue.CloseSend()
+}
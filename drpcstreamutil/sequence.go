@@ -0,0 +1,207 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// Sequencer assigns consecutive sequence numbers per logical key, starting at
+// zero, to the messages of a feed that is sharded across parallel streams, so
+// that a Reorderer on the receiving side can restore the order of each key. It
+// is safe for concurrent use.
+type Sequencer struct {
+	mu   sync.Mutex
+	next map[string]uint64
+}
+
+// Next returns the sequence number of the next message with the key.
+func (s *Sequencer) Next(key string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == nil {
+		s.next = make(map[string]uint64)
+	}
+	seq := s.next[key]
+	s.next[key] = seq + 1
+	return seq
+}
+
+// SequenceFunc returns the logical key and sequence number carried by a
+// message, as assigned by a Sequencer.
+type SequenceFunc[M any] func(msg M) (key string, seq uint64)
+
+// Reorderer restores the order of the messages of each logical key of a feed
+// sharded across parallel streams. Messages that arrive ahead of their turn
+// are buffered, up to a bound per key, until the messages before them arrive.
+// Messages whose sequence number was already delivered are dropped. It is
+// safe for concurrent use.
+type Reorderer[M any] struct {
+	seq SequenceFunc[M]
+	max int
+
+	mu   sync.Mutex
+	keys map[string]*reorderKey[M]
+}
+
+// reorderKey is the reordering state of a key.
+type reorderKey[M any] struct {
+	next    uint64
+	pending map[uint64]M
+}
+
+// NewReorderer returns a Reorderer that buffers at most max messages per key
+// while waiting for the message due next.
+func NewReorderer[M any](max int, seq SequenceFunc[M]) *Reorderer[M] {
+	return &Reorderer[M]{
+		seq:  seq,
+		max:  max,
+		keys: make(map[string]*reorderKey[M]),
+	}
+}
+
+// Push adds a received message and returns the messages of its key that are
+// now in order, which is none if the message arrived ahead of its turn. It
+// returns an error if the buffer of the key is full.
+func (r *Reorderer[M]) Push(msg M) ([]M, error) {
+	key, seq := r.seq(msg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rk := r.keys[key]
+	if rk == nil {
+		rk = &reorderKey[M]{pending: make(map[uint64]M)}
+		r.keys[key] = rk
+	}
+
+	switch {
+	case seq < rk.next:
+		return nil, nil
+	case seq > rk.next:
+		if _, ok := rk.pending[seq]; !ok && len(rk.pending) >= r.max {
+			return nil, drpc.Error.New("reorder buffer of key %q is full waiting for sequence %d", key, rk.next)
+		}
+		rk.pending[seq] = msg
+		return nil, nil
+	}
+
+	ready := []M{msg}
+	rk.next++
+	for {
+		msg, ok := rk.pending[rk.next]
+		if !ok {
+			break
+		}
+		delete(rk.pending, rk.next)
+		ready = append(ready, msg)
+		rk.next++
+	}
+	return ready, nil
+}
+
+// Missing returns the keys that have buffered messages waiting for an earlier
+// message, sorted.
+func (r *Reorderer[M]) Missing() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []string
+	for key, rk := range r.keys {
+		if len(rk.pending) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MergeOrdered returns a Receiver of the messages of every Receiver in rs,
+// which receive the shards of one feed in parallel, with the order of each
+// logical key restored by a Reorderer that buffers at most max messages per
+// key. It returns io.EOF once every Receiver in rs did, and an error if
+// messages are still waiting for an earlier one at that point. The first
+// error of any Receiver in rs is returned as is. Receiving stops once the
+// context is done.
+func MergeOrdered[M any](ctx context.Context, rs []Receiver[M], max int, seq SequenceFunc[M]) Receiver[M] {
+	ctx, cancel := context.WithCancel(ctx)
+	reorder := NewReorderer(max, seq)
+	results := make(chan mergeResult[M])
+
+	var wg sync.WaitGroup
+	for _, r := range rs {
+		wg.Add(1)
+		go func(r Receiver[M]) {
+			defer wg.Done()
+			for {
+				msg, err := r.Recv()
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				select {
+				case results <- mergeResult[M]{msg: msg, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(r)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// messages are pushed by the caller of Recv rather than by the goroutines
+	// receiving them so that the ready messages of a key are returned in the
+	// order they became ready.
+	var ready []M
+	var err error
+	return ReceiverFunc[M](func() (M, error) {
+		for len(ready) == 0 && err == nil {
+			select {
+			case res, ok := <-results:
+				switch {
+				case !ok:
+					err = io.EOF
+					if missing := reorder.Missing(); len(missing) > 0 {
+						err = drpc.Error.New("streams ended with keys missing messages: %q", missing)
+					}
+				case res.err != nil:
+					err = res.err
+				default:
+					ready, err = reorder.Push(res.msg)
+				}
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err != nil {
+				cancel()
+			}
+		}
+
+		if len(ready) > 0 {
+			msg := ready[0]
+			ready = ready[1:]
+			return msg, nil
+		}
+		return *new(M), err
+	})
+}
+
+// mergeResult is a message received by a Receiver merged by MergeOrdered, or
+// the error it failed with.
+type mergeResult[M any] struct {
+	msg M
+	err error
+}
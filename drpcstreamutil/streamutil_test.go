@@ -0,0 +1,262 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcstreamutil
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpctest"
+)
+
+func TestMapFilterCollect(t *testing.T) {
+	src := &sliceStream{recv: []string{"1", "2", "3", "4"}}
+
+	r := NewReceiver(src, drpctest.StringEncoding{}, func() *string { return new(string) })
+	evens := Filter(Map(r, func(s *string) (int, error) { return strconv.Atoi(*s) }),
+		func(n int) bool { return n%2 == 0 })
+
+	got, err := Collect(evens)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, got, []int{2, 4})
+}
+
+func TestPipe(t *testing.T) {
+	src := &sliceStream{recv: []string{"a", "b"}}
+	dst := &sliceStream{}
+
+	r := NewReceiver(src, drpctest.StringEncoding{}, func() *string { return new(string) })
+	assert.NoError(t, Pipe(dst, drpctest.StringEncoding{}, r))
+	assert.DeepEqual(t, dst.sent, []string{"a", "b"})
+	assert.That(t, dst.closeSent)
+}
+
+func TestPaginate(t *testing.T) {
+	pages := map[string][]int{"": {1, 2}, "p2": {}, "p3": {3}}
+	next := map[string]string{"": "p2", "p2": "p3", "p3": ""}
+
+	var fetched []string
+	r := Paginate(context.Background(), func(ctx context.Context, token string) ([]int, string, error) {
+		fetched = append(fetched, token)
+		return pages[token], next[token], nil
+	})
+
+	got, err := Collect[int](r)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, got, []int{1, 2, 3})
+	assert.DeepEqual(t, fetched, []string{"", "p2", "p3"})
+
+	_, err = r.Recv()
+	assert.Equal(t, err, io.EOF)
+}
+
+func TestSendQueue(t *testing.T) {
+	dst := &sliceStream{}
+	q := NewSendQueue(dst, drpctest.StringEncoding{}, 1)
+
+	for _, s := range []string{"a", "b", "c"} {
+		s := s
+		assert.NoError(t, q.Send(context.Background(), &s))
+	}
+	assert.NoError(t, q.CloseSend())
+	assert.DeepEqual(t, dst.sent, []string{"a", "b", "c"})
+	assert.That(t, dst.closeSent)
+	assert.Error(t, q.Send(context.Background(), new(string)))
+}
+
+func TestSendQueueStopsWithStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dst := &ctxStream{sliceStream: &sliceStream{}, ctx: ctx, block: make(chan struct{})}
+	q := NewSendQueue(dst, drpctest.StringEncoding{}, 2)
+
+	for _, s := range []string{"a", "b"} {
+		s := s
+		assert.NoError(t, q.Send(context.Background(), &s))
+	}
+
+	// the caller gives up on the stream without calling CloseSend.
+	before := runtime.NumGoroutine()
+	cancel()
+	close(dst.block)
+	select {
+	case <-q.done:
+	case <-time.After(time.Second):
+		t.Fatal("send queue goroutine leaked")
+	}
+	for i := 0; runtime.NumGoroutine() >= before && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.That(t, runtime.NumGoroutine() < before)
+	assert.Equal(t, q.Send(context.Background(), new(string)), context.Canceled)
+
+	// a failed send stops the queue too.
+	failing := &ctxStream{sliceStream: &sliceStream{}, ctx: context.Background(), err: io.ErrClosedPipe}
+	q = NewSendQueue(failing, drpctest.StringEncoding{}, 2)
+	msg := "a"
+	assert.NoError(t, q.Send(context.Background(), &msg))
+	<-q.done
+	assert.Equal(t, q.Send(context.Background(), &msg), io.ErrClosedPipe)
+	assert.Equal(t, failing.sends, 1)
+}
+
+func TestSafeStream(t *testing.T) {
+	dst := &sliceStream{}
+	s := NewSafeStream(dst, 4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := strconv.Itoa(i)
+			assert.NoError(t, s.MsgSend(&msg, drpctest.StringEncoding{}))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NoError(t, s.CloseSend())
+	assert.Equal(t, len(dst.sent), 10)
+	assert.That(t, dst.closeSent)
+
+	// messages from a single goroutine keep their order.
+	dst = &sliceStream{}
+	s = NewSafeStream(dst, 1)
+	for _, msg := range []string{"a", "b", "c"} {
+		msg := msg
+		assert.NoError(t, s.MsgSend(&msg, drpctest.StringEncoding{}))
+	}
+	assert.NoError(t, s.CloseSend())
+	assert.DeepEqual(t, dst.sent, []string{"a", "b", "c"})
+}
+
+// seqMsg is a message of a sharded feed.
+type seqMsg struct {
+	key string
+	seq uint64
+}
+
+func seqOf(m seqMsg) (string, uint64) { return m.key, m.seq }
+
+func TestReorderer(t *testing.T) {
+	var s Sequencer
+	a0, b0, a1, a2, a3 := s.Next("a"), s.Next("b"), s.Next("a"), s.Next("a"), s.Next("a")
+	assert.DeepEqual(t, []uint64{a0, b0, a1, a2, a3}, []uint64{0, 0, 1, 2, 3})
+
+	r := NewReorderer(1, seqOf)
+	push := func(key string, seq uint64) []seqMsg {
+		ready, err := r.Push(seqMsg{key, seq})
+		assert.NoError(t, err)
+		return ready
+	}
+
+	assert.Equal(t, len(push("a", 1)), 0)
+	assert.DeepEqual(t, push("b", 0), []seqMsg{{"b", 0}})
+	assert.DeepEqual(t, r.Missing(), []string{"a"})
+
+	// the buffer of a key is bounded.
+	_, err := r.Push(seqMsg{"a", 2})
+	assert.Error(t, err)
+
+	assert.DeepEqual(t, push("a", 0), []seqMsg{{"a", 0}, {"a", 1}})
+	assert.Equal(t, len(push("a", 0)), 0) // duplicates are dropped
+	assert.Equal(t, len(r.Missing()), 0)
+}
+
+func TestMergeOrdered(t *testing.T) {
+	shard := func(msgs ...seqMsg) Receiver[seqMsg] {
+		return ReceiverFunc[seqMsg](func() (seqMsg, error) {
+			if len(msgs) == 0 {
+				return seqMsg{}, io.EOF
+			}
+			msg := msgs[0]
+			msgs = msgs[1:]
+			return msg, nil
+		})
+	}
+
+	r := MergeOrdered(context.Background(), []Receiver[seqMsg]{
+		shard(seqMsg{"a", 2}, seqMsg{"b", 1}, seqMsg{"a", 3}),
+		shard(seqMsg{"a", 1}, seqMsg{"b", 0}),
+		shard(seqMsg{"a", 0}),
+	}, 4, seqOf)
+	got, err := Collect(r)
+	assert.NoError(t, err)
+
+	var a, b []uint64
+	for _, m := range got {
+		if m.key == "a" {
+			a = append(a, m.seq)
+		} else {
+			b = append(b, m.seq)
+		}
+	}
+	assert.DeepEqual(t, a, []uint64{0, 1, 2, 3})
+	assert.DeepEqual(t, b, []uint64{0, 1})
+
+	// a feed whose shards end with a gap fails.
+	r = MergeOrdered(context.Background(), []Receiver[seqMsg]{shard(seqMsg{"a", 1})}, 4, seqOf)
+	_, err = Collect(r)
+	assert.Error(t, err)
+}
+
+// sliceStream is a drpc.Stream that receives from and sends to slices.
+type sliceStream struct {
+	recv      []string
+	sent      []string
+	closeSent bool
+}
+
+func (s *sliceStream) Context() context.Context { return context.Background() }
+func (s *sliceStream) Close() error             { return nil }
+
+func (s *sliceStream) CloseSend() error {
+	s.closeSent = true
+	return nil
+}
+
+func (s *sliceStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	data, err := enc.Marshal(msg)
+	s.sent = append(s.sent, string(data))
+	return err
+}
+
+func (s *sliceStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if len(s.recv) == 0 {
+		return io.EOF
+	}
+	data := s.recv[0]
+	s.recv = s.recv[1:]
+	return enc.Unmarshal([]byte(data), msg)
+}
+
+// ctxStream is a sliceStream with a context whose sends block until block is
+// closed, if set, and fail with err, if set.
+type ctxStream struct {
+	*sliceStream
+	ctx   context.Context
+	block chan struct{}
+	err   error
+	sends int
+}
+
+func (s *ctxStream) Context() context.Context { return s.ctx }
+
+func (s *ctxStream) MsgSend(msg drpc.Message, enc drpc.Encoding) error {
+	s.sends++
+	if s.block != nil {
+		<-s.block
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return s.sliceStream.MsgSend(msg, enc)
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpctest
+
+import (
+	"context"
+
+	"storj.io/drpc"
+)
+
+// HandlerFunc is a drpc.Handler that calls the function.
+type HandlerFunc func(stream drpc.Stream, rpc string) error
+
+// HandleRPC calls the function.
+func (fn HandlerFunc) HandleRPC(stream drpc.Stream, rpc string) error { return fn(stream, rpc) }
+
+// StringHandler returns a drpc.Handler that receives a *string request with
+// StringEncoding, passes it to fn with the context of the stream, and sends
+// the response fn returns, if it returns no error. It serves unary rpcs, and
+// streams that send one message before receiving one.
+func StringHandler(fn func(ctx context.Context, rpc, in string) (string, error)) drpc.Handler {
+	return HandlerFunc(func(stream drpc.Stream, rpc string) error {
+		var in string
+		if err := stream.MsgRecv(&in, StringEncoding{}); err != nil {
+			return err
+		}
+		out, err := fn(stream.Context(), rpc, in)
+		if err != nil {
+			return err
+		}
+		return stream.MsgSend(&out, StringEncoding{})
+	})
+}
+
+// ContextStream returns a drpc.Stream with the context whose sends and
+// receives do nothing, for testing handlers that only use the context of their
+// stream.
+func ContextStream(ctx context.Context) drpc.Stream { return contextStream{ctx: ctx} }
+
+type contextStream struct{ ctx context.Context }
+
+func (s contextStream) Context() context.Context                { return s.ctx }
+func (contextStream) MsgSend(drpc.Message, drpc.Encoding) error { return nil }
+func (contextStream) MsgRecv(drpc.Message, drpc.Encoding) error { return nil }
+func (contextStream) CloseSend() error                          { return nil }
+func (contextStream) Close() error                              { return nil }
+
+// StringEncoding is a drpc.Encoding for *string messages, which it marshals as
+// their bytes.
+type StringEncoding struct{}
+
+// Marshal returns the bytes of the *string message.
+func (StringEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return []byte(*msg.(*string)), nil
+}
+
+// Unmarshal sets the *string message to buf.
+func (StringEncoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	*msg.(*string) = string(buf)
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpctest
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/drpc"
+)
+
+// Point is a point in the life of a conn or rpc where a Scheduler can pause
+// it.
+type Point string
+
+const (
+	// BeforeDial is reached before the dialer wrapped by Scheduler.Dialer
+	// dials a conn.
+	BeforeDial Point = "before dial"
+
+	// BeforeInvoke is reached before a unary rpc is invoked or a stream is
+	// opened on the conn.
+	BeforeInvoke Point = "before invoke"
+
+	// AfterFirstFrame is reached after the response of a unary rpc or the
+	// first message of a stream is received, before it is returned.
+	AfterFirstFrame Point = "after first frame"
+
+	// OnCancel is reached when the context of an rpc is done, before the
+	// conn sees the cancellation.
+	OnCancel Point = "on cancel"
+)
+
+// Scheduler pauses the rpcs of the conns it wraps at armed points, so that
+// tests can interleave them with other calls, such as closing a ClientConn
+// or forcing a redial, in a chosen order instead of relying on timing.
+type Scheduler struct {
+	mu    sync.Mutex
+	armed map[Point][]*Breakpoint
+}
+
+// NewScheduler returns a Scheduler with no armed points.
+func NewScheduler() *Scheduler {
+	return &Scheduler{armed: make(map[Point][]*Breakpoint)}
+}
+
+// Break arms the point so that the next goroutine to reach it is paused until
+// the returned Breakpoint is resumed. Breakpoints armed for the same point
+// pause the goroutines reaching it in order.
+func (s *Scheduler) Break(p Point) *Breakpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bp := &Breakpoint{
+		point:   p,
+		reached: make(chan struct{}),
+		resumed: make(chan struct{}),
+	}
+	s.armed[p] = append(s.armed[p], bp)
+	return bp
+}
+
+// reach pauses the caller at the point if it is armed.
+func (s *Scheduler) reach(p Point, rpc string) {
+	s.mu.Lock()
+	var bp *Breakpoint
+	if queue := s.armed[p]; len(queue) > 0 {
+		bp, s.armed[p] = queue[0], queue[1:]
+	}
+	s.mu.Unlock()
+
+	if bp != nil {
+		bp.rpc = rpc
+		close(bp.reached)
+		<-bp.resumed
+	}
+}
+
+// Dialer wraps the dial function so that it reaches BeforeDial and returns
+// conns wrapped by Conn. It has the signature of a drpcclient.DialerFunc.
+func (s *Scheduler) Dialer(dial func(context.Context) (drpc.Conn, error)) func(context.Context) (drpc.Conn, error) {
+	return func(ctx context.Context) (drpc.Conn, error) {
+		s.reach(BeforeDial, "")
+		conn, err := dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return s.Conn(conn), nil
+	}
+}
+
+// Conn wraps the conn so that its rpcs reach BeforeInvoke, AfterFirstFrame and
+// OnCancel.
+func (s *Scheduler) Conn(conn drpc.Conn) drpc.Conn {
+	return &scheduledConn{Conn: conn, s: s}
+}
+
+// Breakpoint is an armed point of a Scheduler.
+type Breakpoint struct {
+	point   Point
+	rpc     string
+	reached chan struct{}
+	resumed chan struct{}
+	once    sync.Once
+}
+
+// Point returns the point the Breakpoint is armed at.
+func (bp *Breakpoint) Point() Point { return bp.point }
+
+// Reached returns a channel that is closed once a goroutine is paused at the
+// Breakpoint.
+func (bp *Breakpoint) Reached() <-chan struct{} { return bp.reached }
+
+// RPC returns the rpc paused at the Breakpoint, which is empty for BeforeDial.
+// It must only be called once Reached is closed.
+func (bp *Breakpoint) RPC() string { return bp.rpc }
+
+// Wait blocks until a goroutine is paused at the Breakpoint or the context is
+// done.
+func (bp *Breakpoint) Wait(ctx context.Context) error {
+	select {
+	case <-bp.reached:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resume lets the goroutine paused at the Breakpoint continue. If none is
+// paused yet, the goroutine that reaches it does not pause. It may be called
+// more than once.
+func (bp *Breakpoint) Resume() { bp.once.Do(func() { close(bp.resumed) }) }
+
+// scheduledConn is a drpc.Conn whose rpcs reach the points of a Scheduler.
+type scheduledConn struct {
+	drpc.Conn
+	s *Scheduler
+}
+
+func (c *scheduledConn) Invoke(ctx context.Context, rpc string, enc drpc.Encoding, in, out drpc.Message) error {
+	ctx, finish := c.s.holdCancel(ctx, rpc)
+	defer finish()
+
+	c.s.reach(BeforeInvoke, rpc)
+	if err := c.Conn.Invoke(ctx, rpc, enc, in, out); err != nil {
+		return err
+	}
+	c.s.reach(AfterFirstFrame, rpc)
+	return nil
+}
+
+func (c *scheduledConn) NewStream(ctx context.Context, rpc string, enc drpc.Encoding) (drpc.Stream, error) {
+	ctx, finish := c.s.holdCancel(ctx, rpc)
+
+	c.s.reach(BeforeInvoke, rpc)
+	stream, err := c.Conn.NewStream(ctx, rpc, enc)
+	if err != nil {
+		finish()
+		return nil, err
+	}
+	go func() {
+		<-stream.Context().Done()
+		finish()
+	}()
+	return &scheduledStream{Stream: stream, s: c.s, rpc: rpc}, nil
+}
+
+// scheduledStream is a drpc.Stream that reaches AfterFirstFrame when its
+// first message is received.
+type scheduledStream struct {
+	drpc.Stream
+	s        *Scheduler
+	rpc      string
+	received bool
+}
+
+func (s *scheduledStream) MsgRecv(msg drpc.Message, enc drpc.Encoding) error {
+	if err := s.Stream.MsgRecv(msg, enc); err != nil {
+		return err
+	}
+	if !s.received {
+		s.received = true
+		s.s.reach(AfterFirstFrame, s.rpc)
+	}
+	return nil
+}
+
+// holdCancel returns a context for the conn that is done only after the
+// context of the rpc is done and OnCancel is passed. The returned func must be
+// called once the rpc is finished.
+func (s *Scheduler) holdCancel(ctx context.Context, rpc string) (context.Context, func()) {
+	held := &heldContext{Context: ctx, done: make(chan struct{})}
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.reach(OnCancel, rpc)
+			held.cancel(ctx.Err())
+		case <-finished:
+		}
+	}()
+
+	var once sync.Once
+	return held, func() { once.Do(func() { close(finished) }) }
+}
+
+// heldContext is a context with the values and deadline of its parent that is
+// only done when canceled.
+type heldContext struct {
+	context.Context
+	done chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *heldContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+	close(c.done)
+}
+
+func (c *heldContext) Done() <-chan struct{} { return c.done }
+
+func (c *heldContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
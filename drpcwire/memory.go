@@ -0,0 +1,24 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcwire
+
+import (
+	"context"
+)
+
+// MemoryAccount is consulted by a Reader before it buffers the data of
+// received packets, so that an application with its own memory budget can
+// apply backpressure to remotes instead of running out of memory when many
+// of them send at once. It must be safe for concurrent use by many readers.
+type MemoryAccount interface {
+	// Reserve is called before n more bytes of packet data are buffered. It
+	// may block until the bytes are available, which stops reading from the
+	// remote, and returns an error if they cannot be reserved, which fails
+	// the read. It must return once the context is done.
+	Reserve(ctx context.Context, n int) error
+
+	// Release returns n bytes reserved with Reserve once the packet data is
+	// no longer used.
+	Release(n int)
+}
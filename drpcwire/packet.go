@@ -36,6 +36,24 @@ const (
 
 	// KindInvokeMetadata includes metadata about the next Invoke packet.
 	KindInvokeMetadata Kind = 7
+
+	// KindKeepalive is sent as a control packet to check that an idle stream
+	// is still alive. An empty body is a ping which is answered by a
+	// KindKeepalive with a non-empty body. Peers that do not understand it
+	// ignore it like any unknown control packet.
+	KindKeepalive Kind = 8
+
+	// KindHeader is sent by the server as a control packet with metadata
+	// about the response before the messages of the rpc. The body is encoded
+	// metadata. Peers that do not understand it ignore it like any unknown
+	// control packet.
+	KindHeader Kind = 9
+
+	// KindTrailer is sent by the server as a control packet with metadata
+	// about the response right before the packet that ends the rpc. The body
+	// is encoded metadata. Peers that do not understand it ignore it like any
+	// unknown control packet.
+	KindTrailer Kind = 10
 )
 
 //
@@ -15,11 +15,14 @@ func _() {
 	_ = x[KindClose-5]
 	_ = x[KindCloseSend-6]
 	_ = x[KindInvokeMetadata-7]
+	_ = x[KindKeepalive-8]
+	_ = x[KindHeader-9]
+	_ = x[KindTrailer-10]
 }
 
-const _Kind_name = "InvokeMessageErrorCancelCloseCloseSendInvokeMetadata"
+const _Kind_name = "InvokeMessageErrorCancelCloseCloseSendInvokeMetadataKeepaliveHeaderTrailer"
 
-var _Kind_index = [...]uint8{0, 6, 13, 18, 24, 29, 38, 52}
+var _Kind_index = [...]uint8{0, 6, 13, 18, 24, 29, 38, 52, 61, 67, 74}
 
 func (i Kind) String() string {
 	i -= 1
@@ -4,9 +4,11 @@
 package drpcwire
 
 import (
+	"context"
 	"io"
 
 	"storj.io/drpc"
+	"storj.io/drpc/internal/drpcopts"
 )
 
 // ReaderOptions controls configuration settings for a reader.
@@ -14,6 +16,19 @@ type ReaderOptions struct {
 	// MaximumBufferSize controls the maximum size of buffered
 	// packet data.
 	MaximumBufferSize int
+
+	// MaximumFrameSize, if positive, is the most data a single frame may
+	// carry. Larger frames are a protocol error. Peers learn it through the
+	// drpcfeatures.MaxFrameSize feature.
+	MaximumFrameSize int
+
+	// Memory, if set, is consulted before the data of received packets is
+	// buffered. The memory reserved for a packet is released when the next
+	// packet is read, when the read fails, or when Release is called.
+	Memory MemoryAccount
+
+	// Internal contains options that are for internal use only.
+	Internal drpcopts.Reader
 }
 
 // Reader reconstructs packets from frames read from an io.Reader.
@@ -24,6 +39,7 @@ type Reader struct {
 	buf  []byte
 	id   ID
 	rerr error
+	mem  int // bytes reserved from opts.Memory
 }
 
 // A frame adds at most this many bytes of overhead to some data by prefixing
@@ -73,6 +89,32 @@ func (r *Reader) read(p []byte) (n int, err error) {
 	return 0, drpc.InternalError.Wrap(io.ErrNoProgress)
 }
 
+// reserve reserves memory for n bytes of packet data if a MemoryAccount is
+// configured.
+func (r *Reader) reserve(n int) error {
+	if r.opts.Memory == nil || n <= r.mem {
+		return nil
+	}
+	ctx := drpcopts.GetReaderContext(&r.opts.Internal)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := r.opts.Memory.Reserve(ctx, n-r.mem); err != nil {
+		return err
+	}
+	r.mem = n
+	return nil
+}
+
+// Release releases the memory reserved for the data of the last packet read,
+// which must no longer be used.
+func (r *Reader) Release() {
+	if r.opts.Memory != nil && r.mem > 0 {
+		r.opts.Memory.Release(r.mem)
+	}
+	r.mem = 0
+}
+
 // ReadPacket reads a packet from the io.Reader. It is equivalent to
 // calling ReadPacketUsing(nil).
 func (r *Reader) ReadPacket() (pkt Packet, err error) {
@@ -86,6 +128,13 @@ func (r *Reader) ReadPacket() (pkt Packet, err error) {
 // returned. The returned packet's Data field is constructed by appending
 // to the provided buf after it has been resliced to be zero length.
 func (r *Reader) ReadPacketUsing(buf []byte) (pkt Packet, err error) {
+	r.Release()
+	defer func() {
+		if err != nil {
+			r.Release()
+		}
+	}()
+
 	pkt.Data = buf[:0]
 
 	var fr Frame
@@ -157,13 +206,19 @@ func (r *Reader) ReadPacketUsing(buf []byte) (pkt Packet, err error) {
 			return Packet{}, drpc.ProtocolError.New("packet kind change (fr:%v pkt:%v)", fr.Kind, pkt.Kind)
 		}
 
-		pkt.Data = append(pkt.Data, fr.Data...)
+		if r.opts.MaximumFrameSize > 0 && len(fr.Data) > r.opts.MaximumFrameSize {
+			return Packet{}, drpc.ProtocolError.New("frame too large (len:%v)", len(fr.Data))
+		}
 
-		switch {
-		case len(pkt.Data) > r.opts.MaximumBufferSize:
-			return Packet{}, drpc.ProtocolError.New("data overflow (len:%v)", len(pkt.Data))
+		if len(pkt.Data)+len(fr.Data) > r.opts.MaximumBufferSize {
+			return Packet{}, drpc.ProtocolError.New("data overflow (len:%v)", len(pkt.Data)+len(fr.Data))
+		}
+		if err := r.reserve(len(pkt.Data) + len(fr.Data)); err != nil {
+			return Packet{}, err
+		}
+		pkt.Data = append(pkt.Data, fr.Data...)
 
-		case fr.Done:
+		if fr.Done {
 			// increment the message id so that we do not accept any frames
 			// with the same id.
 			r.id.Message++
@@ -5,6 +5,7 @@ package drpcwire
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"math/rand"
@@ -113,6 +114,26 @@ func TestReader(t *testing.T) {
 			Options: ReaderOptions{MaximumBufferSize: 1000},
 		},
 
+		{ // frames up to the maximum frame size are accepted
+			Packets: []Packet{
+				p(KindMessage, 1, false, "hello world"),
+			},
+			Frames: []Frame{
+				f(KindMessage, 1, "hello", false, false),
+				f(KindMessage, 1, " world", true, false),
+			},
+			Options: ReaderOptions{MaximumFrameSize: 6},
+		},
+
+		{ // a frame larger than the maximum frame size
+			Frames: []Frame{
+				f(KindMessage, 1, "hello", false, false),
+				f(KindMessage, 1, " world!", true, false),
+			},
+			Error:   "frame too large",
+			Options: ReaderOptions{MaximumFrameSize: 6},
+		},
+
 		{ // Control bit is preserved
 			Packets: []Packet{
 				p(KindClose, 2, false, ""),
@@ -267,3 +288,55 @@ func TestReaderErrorNoProgress(t *testing.T) {
 	_, err := r.ReadPacket()
 	assert.That(t, errors.Is(err, io.ErrNoProgress))
 }
+
+func TestReaderMemory(t *testing.T) {
+	var buf []byte
+	buf = AppendFrame(buf, Frame{Data: []byte("hello"), ID: ID{1, 1}, Kind: KindMessage})
+	buf = AppendFrame(buf, Frame{Data: []byte(" world"), ID: ID{1, 1}, Kind: KindMessage, Done: true})
+	buf = AppendFrame(buf, Frame{Data: []byte("hi"), ID: ID{1, 2}, Kind: KindMessage, Done: true})
+	buf = AppendFrame(buf, Frame{Data: []byte("too large"), ID: ID{1, 3}, Kind: KindMessage, Done: true})
+
+	mem := &testAccount{limit: 12}
+	r := NewReaderWithOptions(bytes.NewReader(buf), ReaderOptions{Memory: mem})
+
+	// a packet reserves its data as its frames are buffered
+	pkt, err := r.ReadPacket()
+	assert.NoError(t, err)
+	assert.Equal(t, string(pkt.Data), "hello world")
+	assert.Equal(t, mem.used, 11)
+	assert.DeepEqual(t, mem.reserved, []int{5, 6})
+
+	// and releases it when the next packet is read
+	pkt, err = r.ReadPacket()
+	assert.NoError(t, err)
+	assert.Equal(t, string(pkt.Data), "hi")
+	assert.Equal(t, mem.used, 2)
+
+	r.Release()
+	assert.Equal(t, mem.used, 0)
+
+	// failing to reserve fails the read
+	mem.limit = 4
+	_, err = r.ReadPacket()
+	assert.Equal(t, err, errOutOfMemory)
+	assert.Equal(t, mem.used, 0)
+}
+
+var errOutOfMemory = errors.New("out of memory")
+
+type testAccount struct {
+	limit    int
+	used     int
+	reserved []int
+}
+
+func (a *testAccount) Reserve(ctx context.Context, n int) error {
+	if a.used+n > a.limit {
+		return errOutOfMemory
+	}
+	a.used += n
+	a.reserved = append(a.reserved, n)
+	return nil
+}
+
+func (a *testAccount) Release(n int) { a.used -= n }
@@ -29,13 +29,16 @@ func SplitN(pkt Packet, n int, cb func(fr Frame) error) error {
 	}
 }
 
+// DefaultSplitSize is the size data is split into when no size is given.
+const DefaultSplitSize = 64 * 1024
+
 // SplitData is used to split a buffer if it is larger than n bytes.
 // If n is zero, a reasonable default is used. If n is less than zero
 // then it does not split.
 func SplitData(buf []byte, n int) (prefix, suffix []byte) {
 	switch {
 	case n == 0:
-		n = 64 * 1024
+		n = DefaultSplitSize
 	case n < 0:
 		n = 0
 	}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"storj.io/drpc/drpcdebug"
 )
@@ -23,6 +24,9 @@ type Writer struct {
 	size  int
 	mu    sync.Mutex
 	buf   []byte
+	timer *time.Timer // pending delayed flush
+	due   time.Time   // when the pending delayed flush runs
+	err   error       // error from a delayed flush
 }
 
 // NewWriter returns a Writer that will attempt to buffer size data before
@@ -62,13 +66,19 @@ func (b *Writer) Empty() bool {
 	return atomic.LoadUint32(&b.empty) == 0
 }
 
-// Reset clears any pending data in the buffer.
+// Reset clears any pending data in the buffer and the error of an earlier
+// delayed flush.
 func (b *Writer) Reset() *Writer {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	b.buf = b.buf[:0]
+	b.err = nil
 	atomic.StoreUint32(&b.empty, 0)
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
 	return b
 }
 
@@ -78,6 +88,10 @@ func (b *Writer) WriteFrame(fr Frame) (err error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	if b.err != nil {
+		return b.err
+	}
+
 	if len(b.buf) == 0 {
 		atomic.StoreUint32(&b.empty, 1)
 	}
@@ -97,6 +111,10 @@ func (b *Writer) Flush() (err error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	if b.err != nil {
+		return b.err
+	}
+
 	if len(b.buf) > 0 {
 		_, err = b.w.Write(b.buf)
 		b.log("FLUSH", func() string { return fmt.Sprintf("explicit: %d", len(b.buf)) })
@@ -105,3 +123,45 @@ func (b *Writer) Flush() (err error) {
 	}
 	return err
 }
+
+// FlushAfter schedules a flush of any buffered data to the io.Writer once the
+// delay has passed, unless a flush is already scheduled sooner. Data written
+// in the meantime is coalesced into the same write, trading up to delay of
+// latency for fewer writes. It returns the error of an earlier delayed flush,
+// which is also returned by every later call to the Writer until Reset.
+func (b *Writer) FlushAfter(delay time.Duration) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+
+	if len(b.buf) == 0 {
+		return nil
+	}
+
+	due := time.Now().Add(delay)
+	if b.timer != nil {
+		// a timer that already fired is about to flush, so it is left alone.
+		if !due.Before(b.due) || !b.timer.Stop() {
+			return nil
+		}
+	}
+	b.timer, b.due = time.AfterFunc(delay, b.delayedFlush), due
+	return nil
+}
+
+// delayedFlush is called by the timer scheduled in FlushAfter.
+func (b *Writer) delayedFlush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timer = nil
+	if len(b.buf) > 0 {
+		_, b.err = b.w.Write(b.buf)
+		b.log("FLUSH", func() string { return fmt.Sprintf("delayed: %d", len(b.buf)) })
+		b.buf = b.buf[:0]
+		atomic.StoreUint32(&b.empty, 0)
+	}
+}
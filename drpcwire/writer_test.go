@@ -5,7 +5,9 @@ package drpcwire
 
 import (
 	"bytes"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/zeebo/assert"
 )
@@ -30,3 +32,71 @@ func TestWriter(t *testing.T) {
 	t.Run("Size 0B", run(0))
 	t.Run("Size 1MB", run(1024*1024))
 }
+
+func TestWriter_FlushAfter(t *testing.T) {
+	writes := make(chan []byte, 10)
+	wr := NewWriter(writerFunc(func(p []byte) (int, error) {
+		writes <- append([]byte(nil), p...)
+		return len(p), nil
+	}), 0)
+
+	var exp []byte
+	for i := 0; i < 3; i++ {
+		fr := RandFrame()
+		exp = AppendFrame(exp, fr)
+		assert.NoError(t, wr.WriteFrame(fr))
+		assert.NoError(t, wr.FlushAfter(10*time.Millisecond))
+	}
+	assert.That(t, !wr.Empty())
+
+	// all of the frames are coalesced into a single write.
+	assert.That(t, bytes.Equal(<-writes, exp))
+	assert.That(t, wr.Empty())
+	assert.Equal(t, len(writes), 0)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestWriter_FlushAfterSooner(t *testing.T) {
+	writes := make(chan []byte, 10)
+	wr := NewWriter(writerFunc(func(p []byte) (int, error) {
+		writes <- append([]byte(nil), p...)
+		return len(p), nil
+	}), 0)
+
+	assert.NoError(t, wr.WriteFrame(RandFrame()))
+	assert.NoError(t, wr.FlushAfter(time.Hour))
+	assert.NoError(t, wr.WriteFrame(RandFrame()))
+	assert.NoError(t, wr.FlushAfter(time.Millisecond))
+
+	// the sooner delay reschedules the pending flush.
+	select {
+	case <-writes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flush was not rescheduled")
+	}
+	assert.That(t, wr.Empty())
+}
+
+func TestWriter_ResetClearsError(t *testing.T) {
+	writes := make(chan error, 1)
+	fail := errors.New("write failed")
+	wr := NewWriter(writerFunc(func(p []byte) (int, error) {
+		err := fail
+		fail = nil
+		writes <- err
+		return len(p), err
+	}), 0)
+
+	assert.NoError(t, wr.WriteFrame(RandFrame()))
+	assert.NoError(t, wr.FlushAfter(time.Millisecond))
+	assert.Error(t, <-writes)
+	assert.Error(t, wr.WriteFrame(RandFrame()))
+
+	// a reset writer can be used again.
+	assert.NoError(t, wr.Reset().WriteFrame(RandFrame()))
+	assert.NoError(t, wr.Flush())
+	assert.NoError(t, <-writes)
+}
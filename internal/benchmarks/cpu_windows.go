@@ -0,0 +1,13 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build windows
+// +build windows
+
+package main
+
+import "time"
+
+// cpuTime reports that the CPU time of the process is not measured on
+// windows.
+func cpuTime() (time.Duration, bool) { return 0, false }
@@ -0,0 +1,104 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// benchmarks runs identical workloads over a drpcclient.ClientConn and over
+// grpc-go against the drpcecho service on the same machine, and reports the
+// throughput, latency percentiles, allocations and CPU time of each so that
+// performance claims and regressions are measurable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type config struct {
+	duration    time.Duration
+	warmup      time.Duration
+	concurrency int
+	sizes       []int
+	workloads   []string
+}
+
+func main() {
+	var conf config
+	var sizes, workloads string
+	flag.DurationVar(&conf.duration, "duration", 5*time.Second, "how long each workload is measured on each transport")
+	flag.DurationVar(&conf.warmup, "warmup", time.Second, "how long each workload runs before it is measured")
+	flag.IntVar(&conf.concurrency, "concurrency", 1, "rpcs or streams in flight at once")
+	flag.StringVar(&sizes, "sizes", "16,2048,65536", "comma separated payload sizes in bytes")
+	flag.StringVar(&workloads, "workloads", "unary,stream", "comma separated workloads to run: unary and stream")
+	flag.Parse()
+
+	var err error
+	conf.sizes, err = parseSizes(sizes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf.workloads = strings.Split(workloads, ",")
+	if conf.duration <= 0 || conf.concurrency <= 0 {
+		log.Fatal("duration and concurrency must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, conf, os.Stdout); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
+
+// run measures every workload at every size on both transports and writes
+// the comparison report to w.
+func run(ctx context.Context, conf config, w io.Writer) error {
+	var selected []workload
+	for _, name := range conf.workloads {
+		wl, ok := workloads[name]
+		if !ok {
+			return fmt.Errorf("unknown workload %q", name)
+		}
+		selected = append(selected, wl)
+	}
+
+	srv, err := startServers()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = srv.Close() }()
+
+	var results []result
+	for _, wl := range selected {
+		for _, size := range conf.sizes {
+			for _, tr := range transports {
+				res, err := measureOn(ctx, conf, srv, tr, wl, size)
+				if err != nil {
+					return fmt.Errorf("%s %s at %d bytes: %w", tr.name, wl.name, size, err)
+				}
+				results = append(results, res)
+			}
+		}
+	}
+
+	return report(w, conf, results)
+}
+
+// parseSizes parses a comma separated list of payload sizes.
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, field := range strings.Split(s, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid size %q", field)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeebo/assert"
+)
+
+func TestRun(t *testing.T) {
+	var out strings.Builder
+	err := run(context.Background(), config{
+		duration:    20 * time.Millisecond,
+		concurrency: 2,
+		sizes:       []int{16, 2048},
+		workloads:   []string{"unary", "stream"},
+	}, &out)
+	assert.NoError(t, err)
+
+	lines := strings.Split(out.String(), "\n")
+	for _, wl := range []string{"unary", "stream"} {
+		for _, size := range []string{"16B", "2KiB"} {
+			for _, tr := range []string{"drpc", "grpc"} {
+				assert.That(t, hasRow(lines, wl, size, tr))
+			}
+		}
+	}
+	assert.Equal(t, strings.Count(out.String(), "drpc/grpc"), 4)
+}
+
+func TestRunUnknownWorkload(t *testing.T) {
+	err := run(context.Background(), config{
+		duration:    time.Millisecond,
+		concurrency: 1,
+		sizes:       []int{16},
+		workloads:   []string{"bogus"},
+	}, new(strings.Builder))
+	assert.Error(t, err)
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := parseSizes("16, 2048,0")
+	assert.NoError(t, err)
+	assert.DeepEqual(t, sizes, []int{16, 2048, 0})
+
+	_, err = parseSizes("16,-1")
+	assert.Error(t, err)
+	_, err = parseSizes("1k")
+	assert.Error(t, err)
+}
+
+func hasRow(lines []string, fields ...string) bool {
+next:
+	for _, line := range lines {
+		got := strings.Fields(line)
+		if len(got) < len(fields) {
+			continue
+		}
+		for i, field := range fields {
+			if got[i] != field {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
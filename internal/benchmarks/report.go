@@ -0,0 +1,102 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// report writes a table of the results, followed after each workload and
+// size by the ratio of drpc to grpc for every column.
+func report(w io.Writer, conf config, results []result) error {
+	fmt.Fprintf(w, "each workload ran for %v after %v of warmup with %d in flight.\n",
+		conf.duration, conf.warmup, conf.concurrency)
+	fmt.Fprintf(w, "allocations and CPU are per operation and include the server.\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "workload\tsize\ttransport\tops/s\tp50\tp99\tallocs/op\tB/op\tCPU/op\t")
+
+	byTransport := make(map[string]result)
+	for i, res := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f\t%v\t%v\t%.1f\t%.0f\t%s\t\n",
+			res.workload, formatSize(res.size), res.transport, res.rate(),
+			roundDuration(res.p50), roundDuration(res.p99),
+			res.allocs, res.bytes, formatCPU(res))
+		byTransport[res.transport] = res
+
+		if i+1 < len(results) && results[i+1].workload == res.workload && results[i+1].size == res.size {
+			continue
+		}
+		d, dok := byTransport["drpc"]
+		g, gok := byTransport["grpc"]
+		if dok && gok {
+			fmt.Fprintf(tw, "\t\tdrpc/grpc\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				ratio(d.rate(), g.rate()),
+				ratio(float64(d.p50), float64(g.p50)),
+				ratio(float64(d.p99), float64(g.p99)),
+				ratio(d.allocs, g.allocs),
+				ratio(d.bytes, g.bytes),
+				cpuRatio(d, g))
+		}
+		byTransport = make(map[string]result)
+	}
+	return tw.Flush()
+}
+
+// rate returns the operations per second.
+func (r result) rate() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.ops) / r.elapsed.Seconds()
+}
+
+func formatCPU(r result) string {
+	if !r.hasCPU {
+		return "-"
+	}
+	return roundDuration(r.cpu).String()
+}
+
+func cpuRatio(d, g result) string {
+	if !d.hasCPU || !g.hasCPU {
+		return "-"
+	}
+	return ratio(float64(d.cpu), float64(g.cpu))
+}
+
+// ratio formats a as a multiple of b.
+func ratio(a, b float64) string {
+	if b == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fx", a/b)
+}
+
+// roundDuration drops digits of d that are too small to be meaningful.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d < time.Microsecond:
+		return d
+	case d < time.Millisecond:
+		return d.Round(10 * time.Nanosecond)
+	default:
+		return d.Round(10 * time.Microsecond)
+	}
+}
+
+// formatSize formats a payload size in bytes.
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMiB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKiB", size>>10)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
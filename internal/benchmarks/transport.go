@@ -0,0 +1,117 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcecho"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcpool"
+	"storj.io/drpc/drpcserver"
+)
+
+// servers serves the echo service over drpc and over gRPC on loopback ports.
+type servers struct {
+	drpcLis net.Listener
+	grpcLis net.Listener
+	grpc    *grpc.Server
+	cancel  func()
+	done    chan struct{}
+}
+
+// startServers starts both servers. They serve until Close is called.
+func startServers() (*servers, error) {
+	mux := drpcmux.New()
+	if err := drpcecho.DRPCRegisterEcho(mux, drpcecho.Server{}); err != nil {
+		return nil, err
+	}
+	gsrv := grpc.NewServer()
+	drpcecho.RegisterEchoServer(gsrv, drpcecho.GRPCServer{})
+
+	drpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	grpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		_ = drpcLis.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &servers{
+		drpcLis: drpcLis,
+		grpcLis: grpcLis,
+		grpc:    gsrv,
+		cancel:  cancel,
+		done:    make(chan struct{}, 2),
+	}
+	go func() { _ = drpcserver.New(mux).Serve(ctx, drpcLis); s.done <- struct{}{} }()
+	go func() { _ = gsrv.Serve(grpcLis); s.done <- struct{}{} }()
+	return s, nil
+}
+
+// Close stops both servers and waits for them to finish serving.
+func (s *servers) Close() error {
+	s.cancel()
+	s.grpc.Stop()
+	<-s.done
+	<-s.done
+	return nil
+}
+
+// transport dials a client of the echo service served by the servers.
+type transport struct {
+	name string
+	dial func(ctx context.Context, s *servers) (drpcecho.RPCEchoClient, func(), error)
+}
+
+// transports are the transports compared by every workload.
+var transports = []transport{
+	{name: "drpc", dial: dialDRPC},
+	{name: "grpc", dial: dialGRPC},
+}
+
+// dialDRPC dials a ClientConn over a drpcpool conn, so that concurrent rpcs
+// use their own connections like they would in a service.
+func dialDRPC(ctx context.Context, s *servers) (drpcecho.RPCEchoClient, func(), error) {
+	addr := s.drpcLis.Addr().String()
+	pool := drpcpool.New[string, drpcpool.Conn](drpcpool.Options{})
+	cc, err := drpcclient.NewClientConnWithOptions(ctx, func(ctx context.Context) (drpc.Conn, error) {
+		return pool.Get(ctx, addr, func(ctx context.Context, addr string) (drpcpool.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return drpcconn.New(conn), nil
+		}), nil
+	})
+	if err != nil {
+		_ = pool.Close()
+		return nil, nil, err
+	}
+	return drpcecho.NewDRPCEchoClientAdapter(cc), func() {
+		_ = cc.Close()
+		_ = pool.Close()
+	}, nil
+}
+
+// dialGRPC dials a grpc.ClientConn, which multiplexes concurrent rpcs over
+// one connection.
+func dialGRPC(ctx context.Context, s *servers) (drpcecho.RPCEchoClient, func(), error) {
+	cc, err := grpc.NewClient(s.grpcLis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return drpcecho.NewGRPCEchoClientAdapter(cc), func() { _ = cc.Close() }, nil
+}
@@ -0,0 +1,226 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc/drpcecho"
+)
+
+// maxSamples bounds the latencies kept by each worker for percentiles, which
+// are sampled uniformly from every operation once there are more.
+const maxSamples = 1 << 16
+
+// workload is an operation issued repeatedly by every worker.
+type workload struct {
+	name string
+
+	// start prepares a worker to issue operations with payloads of size
+	// bytes. op issues one operation and finish releases anything the worker
+	// holds once it is done.
+	start func(ctx context.Context, client drpcecho.RPCEchoClient, size int) (op, finish func() error, err error)
+}
+
+// workloads are the workloads that can be selected by name.
+var workloads = map[string]workload{
+	"unary":  {name: "unary", start: startUnary},
+	"stream": {name: "stream", start: startStream},
+}
+
+// startUnary issues a unary rpc per operation.
+func startUnary(ctx context.Context, client drpcecho.RPCEchoClient, size int) (op, finish func() error, err error) {
+	req := &drpcecho.Request{Payload: make([]byte, size)}
+	op = func() error {
+		resp, err := client.Unary(ctx, req)
+		if err != nil {
+			return err
+		}
+		return checkSize(resp, size)
+	}
+	return op, func() error { return nil }, nil
+}
+
+// startStream opens a bidirectional stream per worker and makes a round trip
+// on it per operation.
+func startStream(ctx context.Context, client drpcecho.RPCEchoClient, size int) (op, finish func() error, err error) {
+	stream, err := client.Bidi(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := &drpcecho.Request{Payload: make([]byte, size)}
+	op = func() error {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		return checkSize(resp, size)
+	}
+	finish = func() error {
+		if err := stream.CloseSend(); err != nil {
+			return err
+		}
+		if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+			return fmt.Errorf("stream did not end cleanly: %w", err)
+		}
+		return nil
+	}
+	return op, finish, nil
+}
+
+func checkSize(resp *drpcecho.Response, size int) error {
+	if len(resp.GetPayload()) != size {
+		return fmt.Errorf("response has %d bytes, expected %d", len(resp.GetPayload()), size)
+	}
+	return nil
+}
+
+// result is what was measured for a workload at a size on a transport.
+type result struct {
+	workload  string
+	size      int
+	transport string
+
+	ops     int64
+	elapsed time.Duration
+	p50     time.Duration
+	p99     time.Duration
+
+	// allocs, bytes and cpu are per operation and include the server, since
+	// it runs in the same process. cpu is only set if hasCPU is.
+	allocs float64
+	bytes  float64
+	cpu    time.Duration
+	hasCPU bool
+}
+
+// measureOn dials the transport and measures the workload on it after
+// warming it up.
+func measureOn(ctx context.Context, conf config, srv *servers, tr transport, wl workload, size int) (result, error) {
+	client, closeClient, err := tr.dial(ctx, srv)
+	if err != nil {
+		return result{}, err
+	}
+	defer closeClient()
+
+	if conf.warmup > 0 {
+		if _, err := measure(ctx, conf.concurrency, conf.warmup, client, wl, size); err != nil {
+			return result{}, err
+		}
+	}
+	res, err := measure(ctx, conf.concurrency, conf.duration, client, wl, size)
+	res.workload, res.size, res.transport = wl.name, size, tr.name
+	return res, err
+}
+
+// worker records the operations of one goroutine.
+type worker struct {
+	op      func() error
+	finish  func() error
+	rng     *rand.Rand
+	ops     int64
+	samples []time.Duration
+	err     error
+}
+
+// record records an operation that took d.
+func (w *worker) record(d time.Duration) {
+	w.ops++
+	if len(w.samples) < cap(w.samples) {
+		w.samples = append(w.samples, d)
+	} else if i := w.rng.Int63n(w.ops); i < int64(len(w.samples)) {
+		w.samples[i] = d
+	}
+}
+
+// measure issues operations of the workload on concurrency workers for d.
+// Everything the workers need is allocated before the measurement starts so
+// that only the operations are counted.
+func measure(ctx context.Context, concurrency int, d time.Duration, client drpcecho.RPCEchoClient, wl workload, size int) (result, error) {
+	workers := make([]*worker, 0, concurrency)
+	finish := func() (err error) {
+		for _, w := range workers {
+			err = errs.Combine(err, w.err, w.finish())
+		}
+		return err
+	}
+	for i := 0; i < concurrency; i++ {
+		op, fin, err := wl.start(ctx, client, size)
+		if err != nil {
+			return result{}, errs.Combine(err, finish())
+		}
+		workers = append(workers, &worker{
+			op:      op,
+			finish:  fin,
+			rng:     rand.New(rand.NewSource(int64(i))),
+			samples: make([]time.Duration, 0, maxSamples),
+		})
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	cpuBefore, hasCPU := cpuTime()
+	start := time.Now()
+	deadline := start.Add(d)
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				opStart := time.Now()
+				if opStart.After(deadline) {
+					return
+				}
+				if err := w.op(); err != nil {
+					w.err = err
+					return
+				}
+				w.record(time.Since(opStart))
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := result{elapsed: time.Since(start)}
+	cpuAfter, _ := cpuTime()
+	runtime.ReadMemStats(&after)
+	if err := finish(); err != nil {
+		return result{}, err
+	}
+
+	var samples []time.Duration
+	for _, w := range workers {
+		res.ops += w.ops
+		samples = append(samples, w.samples...)
+	}
+	if res.ops == 0 {
+		return res, ctx.Err()
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	res.p50 = samples[int(0.5*float64(len(samples)-1))]
+	res.p99 = samples[int(0.99*float64(len(samples)-1))]
+	res.allocs = float64(after.Mallocs-before.Mallocs) / float64(res.ops)
+	res.bytes = float64(after.TotalAlloc-before.TotalAlloc) / float64(res.ops)
+	if hasCPU {
+		res.cpu, res.hasCPU = (cpuAfter-cpuBefore)/time.Duration(res.ops), true
+	}
+	return res, ctx.Err()
+}
@@ -0,0 +1,17 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package drpcopts
+
+import "context"
+
+// Reader contains internal options for the drpcwire package.
+type Reader struct {
+	ctx context.Context
+}
+
+// GetReaderContext returns the context stored in the options.
+func GetReaderContext(opts *Reader) context.Context { return opts.ctx }
+
+// SetReaderContext sets the context stored in the options.
+func SetReaderContext(opts *Reader, ctx context.Context) { opts.ctx = ctx }
@@ -0,0 +1,19 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package interop holds wire-compatibility tests between this tree and an
+// upstream release of drpc.
+//
+// The fork and upstream directories are main modules that build the same
+// service and client against different versions of storj.io/drpc: fork uses
+// the tree this module lives in, and upstream uses the release pinned in its
+// go.mod. The tests launch every combination of client and server that
+// involves the fork as separate processes talking over tcp, so any accidental
+// change to the wire format shows up as a failing case.
+//
+// The upstream module is downloaded and checked against its go.sum the first
+// time the tests run, so they need network access unless it is already in the
+// module cache. Building and running every combination takes a few minutes, so
+// the tests are skipped with -short. Only APIs that both versions share may be used in this
+// package; anything specific to one side belongs in its main package.
+package interop
@@ -0,0 +1,18 @@
+module storj.io/drpc/internal/interop/fork
+
+go 1.19
+
+require (
+	storj.io/drpc v0.0.0-00010101000000-000000000000
+	storj.io/drpc/internal/interop v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/zeebo/errs v1.2.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	storj.io/drpc => ../../..
+	storj.io/drpc/internal/interop => ../
+)
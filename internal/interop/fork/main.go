@@ -0,0 +1,40 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// fork runs the interop service and client built against this tree.
+package main
+
+import (
+	"context"
+	"net"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcclient"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/drpcfeatures"
+	"storj.io/drpc/internal/interop"
+)
+
+// features are negotiated by the client and served by the server. Upstream
+// does not know the negotiation rpc, so both sides must fall back to the
+// empty set when talking to it.
+var features = drpcfeatures.Set{drpcfeatures.Keepalive: ""}
+
+func main() {
+	interop.Main(context.Background(), interop.Side{
+		Dial: func(ctx context.Context, addr string) (drpc.Conn, error) {
+			return drpcclient.NewClientConnWithOptions(ctx,
+				func(ctx context.Context) (drpc.Conn, error) {
+					conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+					if err != nil {
+						return nil, err
+					}
+					return drpcconn.New(conn), nil
+				},
+				drpcclient.WithFeatures(features))
+		},
+		Register: func(mux drpc.Mux) error {
+			return drpcfeatures.Register(mux, features)
+		},
+	})
+}
@@ -0,0 +1,11 @@
+module storj.io/drpc/internal/interop
+
+go 1.19
+
+require (
+	github.com/zeebo/assert v1.3.0
+	github.com/zeebo/errs v1.2.2
+	storj.io/drpc v0.0.0-00010101000000-000000000000
+)
+
+replace storj.io/drpc => ../..
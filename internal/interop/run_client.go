@@ -0,0 +1,170 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package interop
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+)
+
+func runClient(ctx context.Context, side Side, addr string) error {
+	conn, err := side.Dial(ctx, addr)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	{ // check unary
+		in, out := "hello", ""
+		if err := conn.Invoke(ctx, rpcUnary, encoding{}, &in, &out); err != nil {
+			return errs.Wrap(err)
+		} else if out != in {
+			return errs.New("invalid out value (unary): %q", out)
+		}
+	}
+
+	{ // check client stream
+		stream, err := conn.NewStream(ctx, rpcClientStream, encoding{})
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		for _, in := range []string{"a", "b", "c"} {
+			if err := stream.MsgSend(&in, encoding{}); err != nil {
+				return errs.Wrap(err)
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			return errs.Wrap(err)
+		}
+		var out string
+		if err := stream.MsgRecv(&out, encoding{}); err != nil {
+			return errs.Wrap(err)
+		} else if out != "abc" {
+			return errs.New("invalid out value (client stream): %q", out)
+		}
+	}
+
+	{ // check server stream
+		stream, err := conn.NewStream(ctx, rpcServerStream, encoding{})
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		in := "xyz"
+		if err := stream.MsgSend(&in, encoding{}); err != nil {
+			return errs.Wrap(err)
+		}
+		if err := stream.CloseSend(); err != nil {
+			return errs.Wrap(err)
+		}
+		for i := 0; i < len(in); i++ {
+			var out string
+			if err := stream.MsgRecv(&out, encoding{}); err != nil {
+				return errs.Wrap(err)
+			} else if out != in[i:i+1] {
+				return errs.New("invalid out value (server stream): %q", out)
+			}
+		}
+		if err := expectEOF(stream); err != nil {
+			return errs.New("invalid last receive (server stream): %w", err)
+		}
+	}
+
+	{ // check bidi
+		stream, err := conn.NewStream(ctx, rpcBidi, encoding{})
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		for _, in := range []string{"ping", "", "pong"} {
+			if err := stream.MsgSend(&in, encoding{}); err != nil {
+				return errs.Wrap(err)
+			}
+			var out string
+			if err := stream.MsgRecv(&out, encoding{}); err != nil {
+				return errs.Wrap(err)
+			} else if out != in {
+				return errs.New("invalid out value (bidi): %q", out)
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			return errs.Wrap(err)
+		}
+		if err := expectEOF(stream); err != nil {
+			return errs.New("invalid last receive (bidi): %w", err)
+		}
+	}
+
+	{ // check errors
+		in, out := "interop failure", ""
+		err := conn.Invoke(ctx, rpcError, encoding{}, &in, &out)
+		if err == nil {
+			return errs.New("expected error")
+		} else if err.Error() != in {
+			return errs.New("invalid error message: %q", err.Error())
+		} else if code := drpcerr.Code(err); code != errorCode {
+			return errs.New("invalid error code: %d", code)
+		}
+	}
+
+	{ // check metadata
+		ctx := drpcmetadata.Add(ctx, metadataKey, "metadata value")
+		in, out := "", ""
+		if err := conn.Invoke(ctx, rpcMetadata, encoding{}, &in, &out); err != nil {
+			return errs.Wrap(err)
+		} else if out != "metadata value" {
+			return errs.New("invalid out value (metadata): %q", out)
+		}
+	}
+
+	// check cancellation last because the transport may be closed to
+	// interrupt the remote.
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := conn.NewStream(ctx, rpcBlock, encoding{})
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		in, out := "block", ""
+		if err := stream.MsgSend(&in, encoding{}); err != nil {
+			return errs.Wrap(err)
+		}
+		if err := stream.CloseSend(); err != nil {
+			return errs.Wrap(err)
+		}
+		if err := stream.MsgRecv(&out, encoding{}); err != nil {
+			return errs.Wrap(err)
+		} else if out != in {
+			return errs.New("invalid out value (block): %q", out)
+		}
+
+		cancel()
+
+		err = stream.MsgRecv(&out, encoding{})
+		if !errors.Is(err, context.Canceled) {
+			return errs.New("invalid receive after cancel: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// expectEOF returns an error unless the next receive on the stream is io.EOF.
+func expectEOF(stream drpc.Stream) error {
+	var out string
+	err := stream.MsgRecv(&out, encoding{})
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err == nil {
+		return errs.New("unexpected message: %q", out)
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package interop
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	"storj.io/drpc"
+)
+
+// Side configures the parts of a client or server that depend on which
+// version of drpc it is built against.
+type Side struct {
+	// Dial returns a conn to the server listening on addr.
+	Dial func(ctx context.Context, addr string) (drpc.Conn, error)
+
+	// Register, if set, registers any additional services on the server's mux.
+	Register func(mux drpc.Mux) error
+}
+
+// Main runs the service as either a client or server depending on os.Args.
+func Main(ctx context.Context, side Side) {
+	var err error
+	switch os.Args[1] {
+	case "server":
+		err = runServer(ctx, side, os.Args[2])
+	case "client":
+		err = runClient(ctx, side, os.Args[2])
+	default:
+		err = errors.New("unknown mode")
+	}
+	if err != nil {
+		log.Fatalf("%+v", err)
+	} else if err = ctx.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package interop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcerr"
+	"storj.io/drpc/drpcmetadata"
+	"storj.io/drpc/drpcmux"
+	"storj.io/drpc/drpcserver"
+)
+
+const (
+	rpcUnary        = "/interop.Service/Unary"
+	rpcClientStream = "/interop.Service/ClientStream"
+	rpcServerStream = "/interop.Service/ServerStream"
+	rpcBidi         = "/interop.Service/Bidi"
+	rpcError        = "/interop.Service/Error"
+	rpcMetadata     = "/interop.Service/Metadata"
+	rpcBlock        = "/interop.Service/Block"
+
+	// errorCode is the code attached to errors returned by the Error rpc.
+	errorCode = 42
+
+	// metadataKey is the metadata key echoed back by the Metadata rpc.
+	metadataKey = "interop-key"
+)
+
+func runServer(ctx context.Context, side Side, addr string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { _ = lis.Close() }()
+
+	fmt.Println(lis.Addr())
+
+	conn, err := lis.Accept()
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	mux := drpcmux.New()
+	if err := mux.Register(new(server), description{}); err != nil {
+		return errs.Wrap(err)
+	}
+	if side.Register != nil {
+		if err := side.Register(mux); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	_ = drpcserver.New(mux).ServeOne(ctx, conn)
+	return nil
+}
+
+// server implements the interop service. Every message is a *string.
+type server struct{}
+
+// Unary echoes its input.
+func (*server) Unary(ctx context.Context, in *string) (*string, error) {
+	return in, nil
+}
+
+// ClientStream responds with the concatenation of every message sent.
+func (*server) ClientStream(stream drpc.Stream) error {
+	var out string
+	for {
+		var in string
+		err := stream.MsgRecv(&in, encoding{})
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return errs.Wrap(err)
+		}
+		out += in
+	}
+	if err := stream.MsgSend(&out, encoding{}); err != nil {
+		return errs.Wrap(err)
+	}
+	return errs.Wrap(stream.CloseSend())
+}
+
+// ServerStream sends each byte of its input as a separate message.
+func (*server) ServerStream(in *string, stream drpc.Stream) error {
+	for i := 0; i < len(*in); i++ {
+		out := (*in)[i : i+1]
+		if err := stream.MsgSend(&out, encoding{}); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Bidi echoes every message sent until the client closes its send side.
+func (*server) Bidi(stream drpc.Stream) error {
+	for {
+		var in string
+		err := stream.MsgRecv(&in, encoding{})
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return errs.Wrap(err)
+		}
+		if err := stream.MsgSend(&in, encoding{}); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+}
+
+// Error fails with its input as the message and errorCode as the code.
+func (*server) Error(ctx context.Context, in *string) (*string, error) {
+	return nil, drpcerr.WithCode(errors.New(*in), errorCode)
+}
+
+// Metadata responds with the value the client sent for metadataKey.
+func (*server) Metadata(ctx context.Context, in *string) (*string, error) {
+	metadata, _ := drpcmetadata.Get(ctx)
+	value, ok := metadata[metadataKey]
+	if !ok {
+		return nil, errs.New("missing metadata key %q", metadataKey)
+	}
+	return &value, nil
+}
+
+// Block sends its input back and then waits for the client to cancel.
+func (*server) Block(in *string, stream drpc.Stream) error {
+	if err := stream.MsgSend(in, encoding{}); err != nil {
+		return errs.Wrap(err)
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// description describes the interop service to a drpc.Mux.
+type description struct{}
+
+func (description) NumMethods() int { return 7 }
+
+func (description) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	switch n {
+	case 0:
+		return rpcUnary, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(*server).Unary(ctx, in1.(*string))
+			}, (*server).Unary, true
+	case 1:
+		return rpcClientStream, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return nil, srv.(*server).ClientStream(in1.(drpc.Stream))
+			}, (*server).ClientStream, true
+	case 2:
+		return rpcServerStream, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return nil, srv.(*server).ServerStream(in1.(*string), in2.(drpc.Stream))
+			}, (*server).ServerStream, true
+	case 3:
+		return rpcBidi, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return nil, srv.(*server).Bidi(in1.(drpc.Stream))
+			}, (*server).Bidi, true
+	case 4:
+		return rpcError, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(*server).Error(ctx, in1.(*string))
+			}, (*server).Error, true
+	case 5:
+		return rpcMetadata, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(*server).Metadata(ctx, in1.(*string))
+			}, (*server).Metadata, true
+	case 6:
+		return rpcBlock, encoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return nil, srv.(*server).Block(in1.(*string), in2.(drpc.Stream))
+			}, (*server).Block, true
+	default:
+		return "", nil, nil, nil, false
+	}
+}
+
+// encoding is the drpc.Encoding for the *string messages of the service.
+type encoding struct{}
+
+func (encoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return []byte(*msg.(*string)), nil
+}
+
+func (encoding) Unmarshal(buf []byte, msg drpc.Message) error {
+	*msg.(*string) = string(buf)
+	return nil
+}
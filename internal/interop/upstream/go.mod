@@ -0,0 +1,12 @@
+module storj.io/drpc/internal/interop/upstream
+
+go 1.19
+
+require (
+	storj.io/drpc v0.0.34
+	storj.io/drpc/internal/interop v0.0.0-00010101000000-000000000000
+)
+
+require github.com/zeebo/errs v1.2.2 // indirect
+
+replace storj.io/drpc/internal/interop => ../
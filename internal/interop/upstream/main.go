@@ -0,0 +1,27 @@
+// Copyright (C) 2026 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// upstream runs the interop service and client built against the upstream
+// release pinned in go.mod.
+package main
+
+import (
+	"context"
+	"net"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+	"storj.io/drpc/internal/interop"
+)
+
+func main() {
+	interop.Main(context.Background(), interop.Side{
+		Dial: func(ctx context.Context, addr string) (drpc.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return drpcconn.New(conn), nil
+		},
+	})
+}